@@ -1,12 +1,11 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
 	"flag"
 	"fmt"
 	"image"
-	"image/jpeg"
-	"image/png"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,8 +15,21 @@ import (
 )
 
 func main() {
-	var scriptPath = flag.String("i", "", "Path to the PXP script file")
-	var outputPath = flag.String("o", "", "Path to the output image file")
+	var scriptPath = flag.String("i", "", "Path to the PXP script file, - to read it from stdin")
+	var outputPath = flag.String("o", "", "Path to the output image file (png, jpg, webp, tif, gif, bmp), - to write to stdout")
+	var format = flag.String("format", "", "Output format, derived from the output path if empty")
+	var quality = flag.Int("quality", 100, "JPEG quality (1..100)")
+	var subsampling = flag.String("subsampling", language.JPEGSubsampling420, "JPEG chroma subsampling (444, 422, 420)")
+	var compression = flag.String("compression", "default", "PNG (none, fast, default, best) or TIFF (none, default) compression")
+	var colors = flag.Int("colors", 256, "GIF palette size (2..256)")
+	var dither = flag.Bool("dither", true, "Dither GIFs")
+	var depth = flag.Int("depth", 0, "Bits per channel of PNGs and TIFFs (8, 16 or 0 to keep the image's depth)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -i script.pxp -o output.png [input ...]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "Inputs are image files bound to $1, $2, etc. or, given as name=path, to the variable name.\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Use - as path to read an input from stdin.\n\n")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if *scriptPath == "" {
@@ -32,16 +44,61 @@ func main() {
 		os.Exit(1)
 	}
 
-	scriptFile := flo.File(*scriptPath)
-	if !scriptFile.Exists() {
-		fmt.Fprintf(os.Stderr, "ERROR: script file does not exist: %s\n", *scriptPath)
-		os.Exit(1)
+	stdinUsed := false
+	readStdin := func(what string) []byte {
+		if stdinUsed {
+			fmt.Fprintf(os.Stderr, "ERROR: stdin can only be used once (%s)\n", what)
+			os.Exit(1)
+		}
+		stdinUsed = true
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to read %s from stdin: %v\n", what, err)
+			os.Exit(1)
+		}
+		return data
 	}
 
-	script := scriptFile.AsString()
-	baseDir := filepath.Dir(*scriptPath)
+	var script string
+	baseDir, _ := os.Getwd()
+	if *scriptPath == "-" {
+		script = string(readStdin("script"))
+	} else {
+		scriptFile := flo.File(*scriptPath)
+		if !scriptFile.Exists() {
+			fmt.Fprintf(os.Stderr, "ERROR: script file does not exist: %s\n", *scriptPath)
+			os.Exit(1)
+		}
+		script = scriptFile.AsString()
+		baseDir = filepath.Dir(*scriptPath)
+	}
 
-	res, err := language.New().Run(string(script), baseDir, nil)
+	lang := language.New()
+	args := []any{}
+	for _, input := range flag.Args() {
+		name, path, named := strings.Cut(input, "=")
+		if !named {
+			path = input
+		}
+		var data []byte
+		if path == "-" {
+			data = readStdin("input " + input)
+		} else {
+			f := flo.File(path)
+			if !f.Exists() {
+				fmt.Fprintf(os.Stderr, "ERROR: input file does not exist: %s\n", path)
+				os.Exit(1)
+			}
+			data = f.AsBytes()
+		}
+		if named {
+			lang.SetInput(name, data)
+		} else {
+			args = append(args, data)
+		}
+	}
+
+	res, err := lang.Run(script, baseDir, nil, args...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
 		os.Exit(1)
@@ -68,31 +125,32 @@ func main() {
 		os.Exit(1)
 	}
 
-	var buf bytes.Buffer
-	ext := strings.ToLower(filepath.Ext(*outputPath))
-	switch ext {
-	case ".png":
-		if err := png.Encode(&buf, img); err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: Failed to encode PNG: %v\n", err)
-			os.Exit(1)
+	opts := language.SaveOptions{
+		Format:      *format,
+		Quality:     *quality,
+		Subsampling: *subsampling,
+		Compression: *compression,
+		Colors:      *colors,
+		Dither:      *dither,
+		Depth:       *depth,
+		Metadata:    language.MetadataOf(img),
+	}
+	if *outputPath == "-" {
+		if opts.Format == "" {
+			opts.Format = language.FormatPNG
+		}
+		w := bufio.NewWriter(os.Stdout)
+		err := language.EncodeImage(w, img, opts)
+		if err == nil {
+			err = w.Flush()
 		}
-	case ".jpg", ".jpeg":
-		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: Failed to encode JPEG: %v\n", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: Failed to write image to stdout: %v\n", err)
 			os.Exit(1)
 		}
-	default:
-		fmt.Fprintf(os.Stderr, "ERROR: Unsupported file format: %s (supported: .png, .jpg, .jpeg)\n", ext)
-		os.Exit(1)
+		return
 	}
-
-	outputFile := flo.File(*outputPath)
-	if err := outputFile.Mkparent(0755); err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: Failed to create output directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := outputFile.StoreBytes(buf.Bytes()); err != nil {
+	if err := language.SaveImage(*outputPath, img, opts); err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: Failed to save image: %v\n", err)
 		os.Exit(1)
 	}
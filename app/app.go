@@ -12,10 +12,8 @@ import (
 	"strings"
 	"time"
 
-	"image/jpeg"
 	"image/png"
 
-	"github.com/gen2brain/heic"
 	"github.com/toxyl/flo"
 	"github.com/toxyl/math"
 	"github.com/toxyl/pxp/language"
@@ -39,7 +37,8 @@ type OpResult struct {
 // App struct
 type App struct {
 	ctx             context.Context
-	lastRunImage    image.Image // Store the last run image
+	lastRunImage    image.Image        // Store the last run image
+	lastRunMetadata *language.Metadata // Metadata (EXIF, XMP, ICC) of the last run image
 	cancelRendering bool
 	batchProgress   float64 // used for visual feedback during batch processing
 	batchLen        int
@@ -134,9 +133,9 @@ func (a *App) FileToBase64(file string, maxWidth, maxHeight int) OpResult {
 		return OpResult{"", fmt.Errorf("failed to read image: %v", err)}
 	}
 	ext := filepath.Ext(file)
-	if language.DetectImageType(data) == "heic" {
-		// our viewers do not support HEIC, so we first need to convert them to PNG
-		img, err := heic.Decode(bytes.NewReader(data))
+	if t := language.DetectImageType(data); t == "heic" || t == "avif" || t == "tiff" {
+		// our viewers do not support HEIC, AVIF and TIFF, so we first need to convert them to PNG
+		img, err := language.DecodeImage(data)
 		if err != nil {
 			return OpResult{"", err}
 		}
@@ -196,8 +195,8 @@ func (a *App) LoadMultipleImages() OpResult {
 	files, err := runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
 		Filters: []runtime.FileFilter{
 			{
-				DisplayName: "Images (*.png;*.jpg;*.jpeg;*.heic)",
-				Pattern:     "*.png;*.jpg;*.jpeg;*.heic;*.PNG;*.JPG;*.JPEG;*.HEIC",
+				DisplayName: "Images (*.png;*.jpg;*.jpeg;*.heic;*.avif;*.webp;*.tif;*.tiff;*.bmp;*.gif)",
+				Pattern:     "*.png;*.jpg;*.jpeg;*.heic;*.avif;*.webp;*.tif;*.tiff;*.bmp;*.gif;*.PNG;*.JPG;*.JPEG;*.HEIC;*.AVIF;*.WEBP;*.TIF;*.TIFF;*.BMP;*.GIF",
 			}, {
 				DisplayName: "PNG Images (*.png)",
 				Pattern:     "*.png;*.PNG",
@@ -207,6 +206,18 @@ func (a *App) LoadMultipleImages() OpResult {
 			}, {
 				DisplayName: "HEIC Images (*.heic)",
 				Pattern:     "*.heic;*.HEIC",
+			}, {
+				DisplayName: "AVIF Images (*.avif)",
+				Pattern:     "*.avif;*.AVIF",
+			}, {
+				DisplayName: "WebP Images (*.webp)",
+				Pattern:     "*.webp;*.WEBP",
+			}, {
+				DisplayName: "TIFF Images (*.tif;*.tiff)",
+				Pattern:     "*.tif;*.tiff;*.TIF;*.TIFF",
+			}, {
+				DisplayName: "BMP Images (*.bmp)",
+				Pattern:     "*.bmp;*.BMP",
 			},
 		},
 	})
@@ -222,9 +233,9 @@ func (a *App) LoadMultipleImages() OpResult {
 			return OpResult{nil, fmt.Errorf("failed to read image: %v", err)}
 		}
 		ext := filepath.Base(file)
-		if language.DetectImageType(data) == "heic" {
-			// our viewers do not support HEIC, so we first need to convert them to PNG
-			img, err := heic.Decode(bytes.NewReader(data))
+		if t := language.DetectImageType(data); t == "heic" || t == "avif" || t == "tiff" {
+			// our viewers do not support HEIC, AVIF and TIFF, so we first need to convert them to PNG
+			img, err := language.DecodeImage(data)
 			if err != nil {
 				return OpResult{nil, err}
 			}
@@ -256,8 +267,8 @@ func (a *App) LoadMultipleInputImages() OpResult {
 	res, err := runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
 		Filters: []runtime.FileFilter{
 			{
-				DisplayName: "Images (*.png;*.jpg;*.jpeg;*.heic)",
-				Pattern:     "*.png;*.jpg;*.jpeg;*.heic;*.PNG;*.JPG;*.JPEG;*.HEIC",
+				DisplayName: "Images (*.png;*.jpg;*.jpeg;*.heic;*.avif;*.webp;*.tif;*.tiff;*.bmp;*.gif)",
+				Pattern:     "*.png;*.jpg;*.jpeg;*.heic;*.avif;*.webp;*.tif;*.tiff;*.bmp;*.gif;*.PNG;*.JPG;*.JPEG;*.HEIC;*.AVIF;*.WEBP;*.TIF;*.TIFF;*.BMP;*.GIF",
 			}, {
 				DisplayName: "PNG Images (*.png)",
 				Pattern:     "*.png;*.PNG",
@@ -267,6 +278,18 @@ func (a *App) LoadMultipleInputImages() OpResult {
 			}, {
 				DisplayName: "HEIC Images (*.heic)",
 				Pattern:     "*.heic;*.HEIC",
+			}, {
+				DisplayName: "AVIF Images (*.avif)",
+				Pattern:     "*.avif;*.AVIF",
+			}, {
+				DisplayName: "WebP Images (*.webp)",
+				Pattern:     "*.webp;*.WEBP",
+			}, {
+				DisplayName: "TIFF Images (*.tif;*.tiff)",
+				Pattern:     "*.tif;*.tiff;*.TIF;*.TIFF",
+			}, {
+				DisplayName: "BMP Images (*.bmp)",
+				Pattern:     "*.bmp;*.BMP",
 			},
 		},
 	})
@@ -318,6 +341,7 @@ func (a *App) Run(script string, filePaths []string) OpResult {
 
 	// Store the image
 	a.lastRunImage = img
+	a.lastRunMetadata = language.MetadataOf(img)
 
 	// Get image dimensions
 	bounds := img.Bounds()
@@ -516,14 +540,26 @@ func (a *App) SaveOutput() OpResult {
 	file, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
 		Filters: []runtime.FileFilter{
 			{
-				DisplayName: "Images (*.png;*.jpg;*.jpeg)",
-				Pattern:     "*.png;*.jpg;*.jpeg;*.PNG;*.JPG;*.JPEG",
+				DisplayName: "Images (*.png;*.jpg;*.jpeg;*.webp;*.tif;*.tiff;*.gif;*.bmp)",
+				Pattern:     "*.png;*.jpg;*.jpeg;*.webp;*.tif;*.tiff;*.gif;*.bmp;*.PNG;*.JPG;*.JPEG;*.WEBP;*.TIF;*.TIFF;*.GIF;*.BMP",
 			}, {
 				DisplayName: "PNG Images (*.png)",
 				Pattern:     "*.png;*.PNG",
 			}, {
 				DisplayName: "JPEG Images (*.jpg;*.jpeg)",
 				Pattern:     "*.jpg;*.jpeg;*.JPG;*.JPEG",
+			}, {
+				DisplayName: "WebP Images (*.webp)",
+				Pattern:     "*.webp;*.WEBP",
+			}, {
+				DisplayName: "TIFF Images (*.tif;*.tiff)",
+				Pattern:     "*.tif;*.tiff;*.TIF;*.TIFF",
+			}, {
+				DisplayName: "GIF Images (*.gif)",
+				Pattern:     "*.gif;*.GIF",
+			}, {
+				DisplayName: "BMP Images (*.bmp)",
+				Pattern:     "*.bmp;*.BMP",
 			},
 		},
 	})
@@ -531,26 +567,12 @@ func (a *App) SaveOutput() OpResult {
 		return OpResult{false, fmt.Errorf("failed to open save dialog: %v", err)}
 	}
 
-	// Create the output file
-	outFile, err := os.Create(file)
-	if err != nil {
-		return OpResult{false, fmt.Errorf("failed to create output file: %v", err)}
-	}
-	defer outFile.Close()
-
 	// Save based on file extension
-	ext := strings.ToLower(filepath.Ext(file))
-	switch ext {
-	case ".png":
-		if err := png.Encode(outFile, a.lastRunImage); err != nil {
-			return OpResult{false, fmt.Errorf("failed to encode PNG: %v", err)}
-		}
-	case ".jpg", ".jpeg":
-		if err := jpeg.Encode(outFile, a.lastRunImage, &jpeg.Options{Quality: 100}); err != nil {
-			return OpResult{false, fmt.Errorf("failed to encode JPEG: %v", err)}
-		}
-	default:
-		return OpResult{false, fmt.Errorf("unsupported file format: %s", ext)}
+	opts := language.DefaultSaveOptions()
+	opts.Quality = 100
+	opts.Metadata = a.lastRunMetadata
+	if err := language.SaveImage(file, a.lastRunImage, opts); err != nil {
+		return OpResult{false, fmt.Errorf("failed to save image: %v", err)}
 	}
 
 	return OpResult{true, nil}
@@ -46,6 +46,251 @@ func NewLanguage() *dslCollection {
     l.vars.storeState() // Store the state of variables, so we can reset the language without losing them
 
     // Register functions
+    l.funcs.register("align", "Aligns an image to a reference image, e.g. exposure brackets or before/after shots taken without a tripod. The result has the size of the reference, uncovered areas are transparent. It can be used as image, and as matrix to apply the same alignment to other images or masks, e.g. warp-affine(mask result).",
+        []dslParamMeta{ 
+            { 
+                name: "ref",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The reference image",
+            },
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to align",
+            },
+            { 
+                name: "mode",
+                typ:  "string", 
+                def:  "translation", 
+                desc: "The transformation to estimate: translation (using phase correlation, robust against noise and brightness changes), similarity (translation, rotation and uniform scale) or affine (also shear and non-uniform scale)",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The aligned image with the transformation that maps img onto ref",
+            },
+        },
+        func(a ...any) (any, error) {
+            return align(
+                a[0].(*image.NRGBA64),
+                a[1].(*image.NRGBA64),
+                a[2].(string),
+                a[3].(string), 
+            )
+        },
+    )
+    l.funcs.register("load-frames", "Loads all frames of an animation (GIF, APNG or WebP). Multi-page TIFFs return one frame per page, other images a single frame.",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the animation",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The frames",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadFrames(
+                a[0].(string), 
+            )
+        },
+    )
+    l.funcs.register("make-frames", "Creates an animation from a list of images",
+        []dslParamMeta{ 
+            { 
+                name: "images",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The images to use as frames",
+            },
+            { 
+                name: "delay",
+                typ:  "int", 
+                min:  1, 
+                max:  60000, 
+                def:  100, 
+                unit: "\"ms\"", 
+                desc: "The delay of each frame",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The frames",
+            },
+        },
+        func(a ...any) (any, error) {
+            return makeFrames(
+                a[0].([]any),
+                a[1].(int), 
+            )
+        },
+    )
+    l.funcs.register("frame-count", "Returns the number of frames of an animation",
+        []dslParamMeta{ 
+            { 
+                name: "frames",
+                typ:  "*Frames", 
+                def:  "-", 
+                desc: "The frames",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The number of frames",
+            },
+        },
+        func(a ...any) (any, error) {
+            return frameCount(
+                a[0].(*Frames), 
+            )
+        },
+    )
+    l.funcs.register("frame", "Returns a frame of an animation",
+        []dslParamMeta{ 
+            { 
+                name: "frames",
+                typ:  "*Frames", 
+                def:  "-", 
+                desc: "The frames",
+            },
+            { 
+                name: "i",
+                typ:  "int", 
+                def:  0, 
+                desc: "The index of the frame (negative values count from the end)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The frame",
+            },
+        },
+        func(a ...any) (any, error) {
+            return frame(
+                a[0].(*Frames),
+                a[1].(int), 
+            )
+        },
+    )
+    l.funcs.register("map-frames", "Runs a pipeline on every frame of an animation. The pipeline is a script that references the frame as $1 and its index as $2, its last statement must return an image.",
+        []dslParamMeta{ 
+            { 
+                name: "frames",
+                typ:  "*Frames", 
+                def:  "-", 
+                desc: "The frames",
+            },
+            { 
+                name: "pipeline",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - The script to run for every frame, e.g. \"blur-gaussian($1 2)\"",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The processed frames",
+            },
+        },
+        func(a ...any) (any, error) {
+            return mapFrames(
+                a[0].(*Frames),
+                a[1].(string), 
+            )
+        },
+    )
+    l.funcs.register("save-animation", "Saves an animation as GIF, APNG or lossless WebP. Only the changed area of each frame is stored and frames without changes are merged.",
+        []dslParamMeta{ 
+            { 
+                name: "frames",
+                typ:  "*Frames", 
+                def:  "-", 
+                desc: "The frames to save",
+            },
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path where to save",
+            },
+            { 
+                name: "format",
+                typ:  "string", 
+                def:  "", 
+                desc: "The format (gif, apng, webp), empty to use the file extension",
+            },
+            { 
+                name: "colors",
+                typ:  "int", 
+                min:  2, 
+                max:  256, 
+                def:  256, 
+                desc: "The size of the GIF palettes",
+            },
+            { 
+                name: "dither",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether to dither GIFs",
+            },
+            { 
+                name: "palette",
+                typ:  "string", 
+                def:  "local", 
+                desc: "The GIF palette mode (local: one optimized palette per frame, global: one palette for all frames)",
+            },
+            { 
+                name: "depth",
+                typ:  "int", 
+                min:  8, 
+                max:  16, 
+                def:  8, 
+                desc: "The bits per channel of APNGs (8 or 16)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The frames",
+            },
+        },
+        func(a ...any) (any, error) {
+            return saveAnimation(
+                a[0].(*Frames),
+                a[1].(string),
+                a[2].(string),
+                a[3].(int),
+                a[4].(bool),
+                a[5].(string),
+                a[6].(int), 
+            )
+        },
+    )
     l.funcs.register("blend", "Blends the two images using the given blendmode (defaults to normal)",
         []dslParamMeta{ 
             { 
@@ -1647,133 +1892,297 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("load-csv", "Loads a CSV file",
+    l.funcs.register("grid-compose", "Arranges images in a grid. All images are scaled to fit the size of the largest image and centered in their cells.",
         []dslParamMeta{ 
             { 
-                name: "path",
-                typ:  "string", 
+                name: "images",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "- - Path to the CSV file",
+                desc: "The images to arrange",
             },
             { 
-                name: "sep",
-                typ:  "string", 
-                def:  "\\t", 
-                desc: "The separator to split columns with",
+                name: "cols",
+                typ:  "int", 
+                min:  0, 
+                max:  "", 
+                def:  0, 
+                desc: "The number of columns (0 to make the grid as square as possible)",
             },
             { 
-                name: "hasHeader",
-                typ:  "bool", 
-                def:  true, 
-                desc: "Whether the first row is a header row",
+                name: "gap",
+                typ:  "int", 
+                min:  0, 
+                max:  "", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The space between cells",
+            },
+            { 
+                name: "bg",
+                typ:  "any", 
+                def:  "transparent", 
+                desc: "The background color",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A 2D slice with the data",
+                desc: "- - - The composed image",
             },
         },
         func(a ...any) (any, error) {
-            return loadCSV(
-                a[0].(string),
-                a[1].(string),
-                a[2].(bool), 
+            return gridCompose(
+                a[0].([]any),
+                a[1].(int),
+                a[2].(int),
+                a[3].(any), 
             )
         },
     )
-    l.funcs.register("load-csv-column", "Loads a column from a CSV file",
+    l.funcs.register("hstack", "Places images next to each other. All images are scaled to the height of the tallest image.",
         []dslParamMeta{ 
             { 
-                name: "path",
-                typ:  "string", 
+                name: "images",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "- - Path to the CSV file",
+                desc: "The images to stack (left to right)",
             },
             { 
-                name: "index",
+                name: "gap",
                 typ:  "int", 
-                def:  "-", 
-                desc: "The index of the column to retrieve",
-            },
-            { 
-                name: "sep",
-                typ:  "string", 
-                def:  "\\t", 
-                desc: "The separator to split columns with",
+                min:  0, 
+                max:  "", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The space between images",
             },
             { 
-                name: "hasHeader",
-                typ:  "bool", 
-                def:  true, 
-                desc: "Whether the first row is a header row",
+                name: "bg",
+                typ:  "any", 
+                def:  "transparent", 
+                desc: "The background color",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A slice with the data",
+                desc: "- - - The composed image",
             },
         },
         func(a ...any) (any, error) {
-            return loadCSVColumn(
-                a[0].(string),
+            return hstack(
+                a[0].([]any),
                 a[1].(int),
-                a[2].(string),
-                a[3].(bool), 
+                a[2].(any), 
             )
         },
     )
-    l.funcs.register("load-csv-row", "Loads a row from a CSV file",
+    l.funcs.register("vstack", "Places images below each other. All images are scaled to the width of the widest image.",
         []dslParamMeta{ 
             { 
-                name: "path",
-                typ:  "string", 
+                name: "images",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "- - Path to the CSV file",
+                desc: "The images to stack (top to bottom)",
             },
             { 
-                name: "index",
+                name: "gap",
                 typ:  "int", 
-                def:  "-", 
-                desc: "The index of the row to retrieve",
-            },
-            { 
-                name: "sep",
-                typ:  "string", 
-                def:  "\\t", 
-                desc: "The separator to split columns with",
+                min:  0, 
+                max:  "", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The space between images",
             },
             { 
-                name: "hasHeader",
-                typ:  "bool", 
-                def:  true, 
-                desc: "Whether the first row is a header row",
+                name: "bg",
+                typ:  "any", 
+                def:  "transparent", 
+                desc: "The background color",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A slice with the data",
+                desc: "- - - The composed image",
             },
         },
         func(a ...any) (any, error) {
-            return loadCSVRow(
-                a[0].(string),
+            return vstack(
+                a[0].([]any),
                 a[1].(int),
-                a[2].(string),
-                a[3].(bool), 
+                a[2].(any), 
             )
         },
     )
-    l.funcs.register("first-csv-row", "Returns the first row of CSV data",
+    l.funcs.register("contact-sheet", "Arranges images in a grid on a white background with a caption below each image. The caption is the result of labelFn, a script that references the image as $1 and its index as $2 (e.g. \"$2\" to caption images with their index). Without labelFn images are numbered starting at 1.",
         []dslParamMeta{ 
             { 
-                name: "data",
-                typ:  "[][]float64", 
+                name: "images",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The images to arrange",
+            },
+            { 
+                name: "cols",
+                typ:  "int", 
+                min:  0, 
+                max:  "", 
+                def:  0, 
+                desc: "The number of columns (0 to make the grid as square as possible)",
+            },
+            { 
+                name: "labelFn",
+                typ:  "string", 
+                def:  "", 
+                desc: "The script returning the caption of an image",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The contact sheet",
+            },
+        },
+        func(a ...any) (any, error) {
+            return contactSheet(
+                a[0].([]any),
+                a[1].(int),
+                a[2].(string), 
+            )
+        },
+    )
+    l.funcs.register("load-csv", "Loads a CSV file",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the CSV file",
+            },
+            { 
+                name: "sep",
+                typ:  "string", 
+                def:  "\\t", 
+                desc: "The separator to split columns with",
+            },
+            { 
+                name: "hasHeader",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether the first row is a header row",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - A 2D slice with the data",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadCSV(
+                a[0].(string),
+                a[1].(string),
+                a[2].(bool), 
+            )
+        },
+    )
+    l.funcs.register("load-csv-column", "Loads a column from a CSV file",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the CSV file",
+            },
+            { 
+                name: "index",
+                typ:  "int", 
+                def:  "-", 
+                desc: "The index of the column to retrieve",
+            },
+            { 
+                name: "sep",
+                typ:  "string", 
+                def:  "\\t", 
+                desc: "The separator to split columns with",
+            },
+            { 
+                name: "hasHeader",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether the first row is a header row",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - A slice with the data",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadCSVColumn(
+                a[0].(string),
+                a[1].(int),
+                a[2].(string),
+                a[3].(bool), 
+            )
+        },
+    )
+    l.funcs.register("load-csv-row", "Loads a row from a CSV file",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the CSV file",
+            },
+            { 
+                name: "index",
+                typ:  "int", 
+                def:  "-", 
+                desc: "The index of the row to retrieve",
+            },
+            { 
+                name: "sep",
+                typ:  "string", 
+                def:  "\\t", 
+                desc: "The separator to split columns with",
+            },
+            { 
+                name: "hasHeader",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether the first row is a header row",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - A slice with the data",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadCSVRow(
+                a[0].(string),
+                a[1].(int),
+                a[2].(string),
+                a[3].(bool), 
+            )
+        },
+    )
+    l.funcs.register("first-csv-row", "Returns the first row of CSV data",
+        []dslParamMeta{ 
+            { 
+                name: "data",
+                typ:  "[][]float64", 
                 def:  "-", 
                 desc: "Data to return first row of",
             },
@@ -4481,7 +4890,7 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("select-hue", "Selects a specific hue from the image and makes everything else transparent",
+    l.funcs.register("select-hue", "Selects a specific hue from the image. Used as an image, the mask keeps the selected pixels and makes everything else transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.",
         []dslParamMeta{ 
             { 
                 name: "img",
@@ -4537,7 +4946,7 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The image with only the selected hue visible (16-bit)",
+                desc: "- - - The mask of the selected hue",
             },
         },
         func(a ...any) (any, error) {
@@ -4551,7 +4960,7 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("select-hsl", "Selects pixels based on their hue, saturation, and luminance, making pixels outside the specified ranges transparent",
+    l.funcs.register("select-hsl", "Selects pixels based on their hue, saturation, and luminance. Used as an image, the mask makes pixels outside the specified ranges transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.",
         []dslParamMeta{ 
             { 
                 name: "img",
@@ -4664,7 +5073,7 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The image with only pixels in all specified ranges visible (16-bit)",
+                desc: "- - - The mask of the pixels in all specified ranges",
             },
         },
         func(a ...any) (any, error) {
@@ -5292,7 +5701,7 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("select-brightness", "Selects pixels based on their brightness, making pixels outside the specified range transparent",
+    l.funcs.register("select-brightness", "Selects pixels based on their brightness. Used as an image, the mask makes pixels outside the specified range transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.",
         []dslParamMeta{ 
             { 
                 name: "img",
@@ -5337,7 +5746,7 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The image with only pixels in the specified brightness range visible (16-bit)",
+                desc: "- - - The mask of the pixels in the specified brightness range",
             },
         },
         func(a ...any) (any, error) {
@@ -5508,295 +5917,426 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("rectangular-to-polar", "Converts a rectangular coordinate image to polar coordinates",
+    l.funcs.register("median", "Replaces every pixel with the median of its neighbourhood per channel, which removes salt-and-pepper noise and specks while keeping edges sharp. Transparent pixels are ignored.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to transform",
+                desc: "The image to denoise",
+            },
+            { 
+                name: "radius",
+                typ:  "int", 
+                min:  1, 
+                max:  10, 
+                def:  1, 
+                unit: "\"px\"", 
+                desc: "The radius of the neighbourhood",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The transformed image",
+                desc: "- - - The denoised image",
             },
         },
         func(a ...any) (any, error) {
-            return distortRectangularToPolar(
-                a[0].(*image.NRGBA64), 
+            return median(
+                a[0].(*image.NRGBA64),
+                a[1].(int), 
             )
         },
     )
-    l.funcs.register("polar-to-rectangular", "Converts a polar coordinate image to rectangular coordinates",
+    l.funcs.register("bilateral", "Smooths an image while preserving edges: every pixel is averaged with the neighbours that are close to it and have a similar color. Transparent pixels are ignored.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to transform",
+                desc: "The image to denoise",
+            },
+            { 
+                name: "spatialSigma",
+                typ:  "float64", 
+                min:  0.5, 
+                max:  10, 
+                def:  2, 
+                unit: "\"px\"", 
+                desc: "The spatial extent of the filter (higher = smoother areas)",
+            },
+            { 
+                name: "rangeSigma",
+                typ:  "float64", 
+                min:  0.01, 
+                max:  1, 
+                def:  0.1, 
+                desc: "The color difference that is still averaged (higher = more edges are smoothed)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The transformed image",
+                desc: "- - - The denoised image",
             },
         },
         func(a ...any) (any, error) {
-            return distortPolarToRectangular(
-                a[0].(*image.NRGBA64), 
+            return bilateral(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("pixelate", "Creates a pixelation effect by averaging colors in blocks",
+    l.funcs.register("nl-means", "Removes noise with non-local means: every pixel is averaged with all pixels in the search window whose surrounding patch looks similar, so textures and edges are preserved much better than by blurring. Slower than the other denoise filters. Transparent pixels are ignored.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to pixelate",
+                desc: "The image to denoise",
             },
             { 
-                name: "size",
+                name: "h",
+                typ:  "float64", 
+                min:  0.01, 
+                max:  0.5, 
+                def:  0.05, 
+                desc: "The filter strength, roughly the standard deviation of the noise (higher = smoother)",
+            },
+            { 
+                name: "patch",
                 typ:  "int", 
                 min:  1, 
-                max:  50, 
-                def:  8, 
-                desc: "The size of the pixel blocks",
+                max:  5, 
+                def:  1, 
+                unit: "\"px\"", 
+                desc: "The radius of the patches that are compared",
+            },
+            { 
+                name: "window",
+                typ:  "int", 
+                min:  2, 
+                max:  15, 
+                def:  7, 
+                unit: "\"px\"", 
+                desc: "The radius of the search window",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The pixelated image",
+                desc: "- - - The denoised image",
             },
         },
         func(a ...any) (any, error) {
-            return distortPixelate(
+            return nlMeans(
                 a[0].(*image.NRGBA64),
-                a[1].(int), 
+                a[1].(float64),
+                a[2].(int),
+                a[3].(int), 
             )
         },
     )
-    l.funcs.register("displace", "Displaces pixels based on the brightness of a displacement map",
+    l.funcs.register("denoise-chroma", "Removes color noise (the colored blotches of high-ISO photos) by smoothing only the chroma, the luma and with it all details stay untouched. Chroma is not smoothed across edges in the luma. Transparent pixels are ignored.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to displace",
+                desc: "The image to denoise",
             },
             { 
-                name: "dMap",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The displacement map image",
+                name: "radius",
+                typ:  "int", 
+                min:  1, 
+                max:  20, 
+                def:  4, 
+                unit: "\"px\"", 
+                desc: "The radius of the smoothing (color noise is usually coarse)",
             },
             { 
                 name: "amount",
                 typ:  "float64", 
                 min:  0, 
-                max:  50, 
-                def:  10, 
-                desc: "The amount of displacement",
+                max:  1, 
+                def:  1, 
+                desc: "The strength of the effect",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The displaced image",
+                desc: "- - - The denoised image",
             },
         },
         func(a ...any) (any, error) {
-            return distortDisplace(
+            return denoiseChroma(
                 a[0].(*image.NRGBA64),
-                a[1].(*image.NRGBA64),
+                a[1].(int),
                 a[2].(float64), 
             )
         },
     )
-    l.funcs.register("defisheye", "Corrects fisheye lens distortion in an image",
+    l.funcs.register("rectangular-to-polar", "Converts a rectangular coordinate image to polar coordinates",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to correct",
-            },
-            { 
-                name: "strength",
-                typ:  "float64", 
-                min:  0, 
-                max:  2, 
-                def:  1, 
-                desc: "The strength of the correction",
+                desc: "The image to transform",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The corrected image",
+                desc: "- - - The transformed image",
             },
         },
         func(a ...any) (any, error) {
-            return distortDefisheye(
-                a[0].(*image.NRGBA64),
-                a[1].(float64), 
+            return distortRectangularToPolar(
+                a[0].(*image.NRGBA64), 
             )
         },
     )
-    l.funcs.register("fisheye", "Applies a fisheye lens distortion effect to the image",
+    l.funcs.register("polar-to-rectangular", "Converts a polar coordinate image to rectangular coordinates",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to distort",
+                desc: "The image to transform",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "strength",
-                typ:  "float64", 
-                min:  0, 
-                max:  2, 
-                def:  1, 
-                desc: "The strength of the fisheye effect",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The transformed image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return distortPolarToRectangular(
+                a[0].(*image.NRGBA64), 
+            )
+        },
+    )
+    l.funcs.register("pixelate", "Creates a pixelation effect by averaging colors in blocks",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to pixelate",
+            },
+            { 
+                name: "size",
+                typ:  "int", 
+                min:  1, 
+                max:  50, 
+                def:  8, 
+                desc: "The size of the pixel blocks",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The distorted image",
+                desc: "- - - The pixelated image",
             },
         },
         func(a ...any) (any, error) {
-            return distortFisheye(
+            return distortPixelate(
                 a[0].(*image.NRGBA64),
-                a[1].(float64), 
+                a[1].(int), 
             )
         },
     )
-    l.funcs.register("enhance", "Enhances colors and sharpness of an image",
+    l.funcs.register("displace", "Displaces pixels based on the brightness of a displacement map",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to enhance",
+                desc: "The image to displace",
             },
             { 
-                name: "brightness",
-                typ:  "float64", 
-                min:  -1, 
-                max:  1, 
-                def:  0, 
-                desc: "The brightness adjustment of the image",
+                name: "dMap",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The displacement map image",
             },
             { 
-                name: "contrast",
+                name: "amount",
                 typ:  "float64", 
-                min:  -1, 
-                max:  1, 
-                def:  0, 
-                desc: "The contrast adjustment of the image",
+                min:  0, 
+                max:  50, 
+                def:  10, 
+                desc: "The amount of displacement",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "sharpening",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The displaced image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return distortDisplace(
+                a[0].(*image.NRGBA64),
+                a[1].(*image.NRGBA64),
+                a[2].(float64), 
+            )
+        },
+    )
+    l.funcs.register("defisheye", "Corrects fisheye lens distortion in an image",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to correct",
+            },
+            { 
+                name: "strength",
                 typ:  "float64", 
                 min:  0, 
-                max:  5, 
+                max:  2, 
                 def:  1, 
-                desc: "The sharpening intensity in pixels (higher values detect larger edges)",
+                desc: "The strength of the correction",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "rMin",
-                typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  0.75, 
-                desc: "The minimum intensity of the red channel",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The corrected image",
             },
+        },
+        func(a ...any) (any, error) {
+            return distortDefisheye(
+                a[0].(*image.NRGBA64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("fisheye", "Applies a fisheye lens distortion effect to the image",
+        []dslParamMeta{ 
             { 
-                name: "rMax",
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to distort",
+            },
+            { 
+                name: "strength",
                 typ:  "float64", 
                 min:  0, 
-                max:  1, 
+                max:  2, 
                 def:  1, 
-                desc: "The maximum intensity of the red channel",
+                desc: "The strength of the fisheye effect",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "gMin",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The distorted image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return distortFisheye(
+                a[0].(*image.NRGBA64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("lens-correct", "Removes lens distortion using the Brown-Conrady model, e.g. the barrel distortion of drone and action cameras. Coordinates are normalized so a radius of 1 is half the shorter side of the image. Areas without source pixels are transparent.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to correct",
+            },
+            { 
+                name: "k1",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  0.75, 
-                desc: "The minimum intensity of the green channel",
+                def:  0, 
+                desc: "The first radial distortion coefficient (negative = barrel, positive = pincushion)",
             },
             { 
-                name: "gMax",
+                name: "k2",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  1, 
-                desc: "The maximum intensity of the green channel",
+                def:  0, 
+                desc: "The second radial distortion coefficient",
             },
             { 
-                name: "bMin",
+                name: "k3",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  0.75, 
-                desc: "The minimum intensity of the blue channel",
+                def:  0, 
+                desc: "The third radial distortion coefficient",
             },
             { 
-                name: "bMax",
+                name: "p1",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  1, 
-                desc: "The maximum intensity of the blue channel",
+                min:  -0.1, 
+                max:  0.1, 
+                def:  0, 
+                desc: "The first tangential distortion coefficient",
             },
             { 
-                name: "rWeight",
+                name: "p2",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  0.299, 
-                desc: "The weight of the red channel (used for sharpening)",
+                min:  -0.1, 
+                max:  0.1, 
+                def:  0, 
+                desc: "The second tangential distortion coefficient",
             },
             { 
-                name: "gWeight",
+                name: "cx",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.587, 
-                desc: "The weight of the green channel (used for sharpening)",
+                def:  0.5, 
+                desc: "The horizontal position of the optical center (fraction of the width)",
             },
             { 
-                name: "bWeight",
+                name: "cy",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.114, 
-                desc: "The weight of the blue channel (used for sharpening)",
+                def:  0.5, 
+                desc: "The vertical position of the optical center (fraction of the height)",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The enhanceed image",
+                desc: "- - - The corrected image",
             },
         },
         func(a ...any) (any, error) {
-            return enhance(
+            return lensCorrect(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
@@ -5805,357 +6345,422 @@ func NewLanguage() *dslCollection {
                 a[5].(float64),
                 a[6].(float64),
                 a[7].(float64),
-                a[8].(float64),
-                a[9].(float64),
-                a[10].(float64),
-                a[11].(float64),
-                a[12].(float64), 
+                a[8].(string), 
             )
         },
     )
-    l.funcs.register("sharpen", "Sharpens an image using a highpass combined with vivid light blending",
+    l.funcs.register("lens-distort", "Applies lens distortion using the Brown-Conrady model, the inverse of lens-correct with the same coefficients. Coordinates are normalized so a radius of 1 is half the shorter side of the image. Areas without source pixels are transparent.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to sharpen",
+                desc: "The image to distort",
             },
             { 
-                name: "intensity",
+                name: "k1",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  1, 
-                desc: "The intensity of the sharpening effect",
+                def:  0, 
+                desc: "The first radial distortion coefficient (negative = barrel, positive = pincushion)",
             },
             { 
-                name: "radius",
+                name: "k2",
                 typ:  "float64", 
-                min:  0.1, 
-                max:  2, 
-                def:  1, 
-                desc: "The radius of the filter in pixels (higher values detect larger edges)",
+                min:  -1, 
+                max:  1, 
+                def:  0, 
+                desc: "The second radial distortion coefficient",
             },
             { 
-                name: "rWeight",
+                name: "k3",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  0.299, 
-                desc: "The weight of the red channel",
+                def:  0, 
+                desc: "The third radial distortion coefficient",
             },
             { 
-                name: "gWeight",
+                name: "p1",
+                typ:  "float64", 
+                min:  -0.1, 
+                max:  0.1, 
+                def:  0, 
+                desc: "The first tangential distortion coefficient",
+            },
+            { 
+                name: "p2",
+                typ:  "float64", 
+                min:  -0.1, 
+                max:  0.1, 
+                def:  0, 
+                desc: "The second tangential distortion coefficient",
+            },
+            { 
+                name: "cx",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.587, 
-                desc: "The weight of the green channel",
+                def:  0.5, 
+                desc: "The horizontal position of the optical center (fraction of the width)",
             },
             { 
-                name: "bWeight",
+                name: "cy",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.114, 
-                desc: "The weight of the blue channel",
+                def:  0.5, 
+                desc: "The vertical position of the optical center (fraction of the height)",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The sharpened image",
+                desc: "- - - The distorted image",
             },
         },
         func(a ...any) (any, error) {
-            return sharpen(
+            return lensDistort(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
                 a[3].(float64),
                 a[4].(float64),
-                a[5].(float64), 
+                a[5].(float64),
+                a[6].(float64),
+                a[7].(float64),
+                a[8].(string), 
             )
         },
     )
-    l.funcs.register("highpass", "Creates a high pass filter effect, resulting in a gray image with embossed edges",
+    l.funcs.register("vignette-correct", "Removes lens vignetting by brightening the image towards the edges. The brightness falloff of the lens is modelled as 1 + k1*r² + k2*r⁴ + k3*r⁶, where a radius of 1 is half the shorter side of the image, and divided out in linear light.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to apply the high-pass filter to",
+                desc: "The image to correct",
             },
             { 
-                name: "radius",
+                name: "k1",
                 typ:  "float64", 
-                min:  0.1, 
-                max:  2, 
-                def:  1, 
-                desc: "The radius of the filter in pixels (higher values detect larger edges)",
+                min:  -1, 
+                max:  1, 
+                def:  -0.3, 
+                desc: "The first falloff coefficient (negative = darker edges)",
             },
             { 
-                name: "rWeight",
+                name: "k2",
                 typ:  "float64", 
-                min:  0, 
+                min:  -1, 
                 max:  1, 
-                def:  0.299, 
-                desc: "The weight of the red channel",
+                def:  0, 
+                desc: "The second falloff coefficient",
             },
             { 
-                name: "gWeight",
+                name: "k3",
+                typ:  "float64", 
+                min:  -1, 
+                max:  1, 
+                def:  0, 
+                desc: "The third falloff coefficient",
+            },
+            { 
+                name: "cx",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.587, 
-                desc: "The weight of the green channel",
+                def:  0.5, 
+                desc: "The horizontal position of the optical center (fraction of the width)",
             },
             { 
-                name: "bWeight",
+                name: "cy",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.114, 
-                desc: "The weight of the blue channel",
+                def:  0.5, 
+                desc: "The vertical position of the optical center (fraction of the height)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The filtered image",
+                desc: "- - - The corrected image",
             },
         },
         func(a ...any) (any, error) {
-            return sharpenHighpass(
+            return vignetteCorrect(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
                 a[3].(float64),
-                a[4].(float64), 
+                a[4].(float64),
+                a[5].(float64), 
             )
         },
     )
-    l.funcs.register("clarity", "Enhances local contrast while preserving overall image structure",
+    l.funcs.register("tca-correct", "Removes transverse (lateral) chromatic aberration, the colored fringes towards the edges of an image that appear because the lens renders the red and blue channels at a slightly different size than the green channel.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to enhance",
+                desc: "The image to correct",
             },
             { 
-                name: "intensity",
+                name: "red",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
+                min:  0.99, 
+                max:  1.01, 
                 def:  1, 
-                desc: "The intensity of the clarity effect",
+                desc: "The size of the red channel relative to the green channel (1 = no aberration)",
             },
             { 
-                name: "radius",
+                name: "blue",
                 typ:  "float64", 
-                min:  0.1, 
-                max:  2, 
+                min:  0.99, 
+                max:  1.01, 
                 def:  1, 
-                desc: "The radius of the filter in pixels (higher values affect larger areas)",
+                desc: "The size of the blue channel relative to the green channel (1 = no aberration)",
             },
             { 
-                name: "rWeight",
+                name: "cx",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.299, 
-                desc: "The weight of the red channel",
+                def:  0.5, 
+                desc: "The horizontal position of the optical center (fraction of the width)",
             },
             { 
-                name: "gWeight",
+                name: "cy",
                 typ:  "float64", 
                 min:  0, 
                 max:  1, 
-                def:  0.587, 
-                desc: "The weight of the green channel",
+                def:  0.5, 
+                desc: "The vertical position of the optical center (fraction of the height)",
             },
             { 
-                name: "bWeight",
-                typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  0.114, 
-                desc: "The weight of the blue channel",
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The enhanced image",
+                desc: "- - - The corrected image",
             },
         },
         func(a ...any) (any, error) {
-            return clarity(
+            return tcaCorrect(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
                 a[3].(float64),
                 a[4].(float64),
-                a[5].(float64), 
+                a[5].(string), 
             )
         },
     )
-    l.funcs.register("translate", "Translates (moves) an image by a specified amount",
+    l.funcs.register("enhance", "Enhances colors and sharpness of an image",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to translate",
+                desc: "The image to enhance",
             },
             { 
-                name: "dx",
+                name: "brightness",
                 typ:  "float64", 
+                min:  -1, 
+                max:  1, 
                 def:  0, 
-                desc: "The horizontal translation amount in % (positive = right)",
+                desc: "The brightness adjustment of the image",
             },
             { 
-                name: "dy",
+                name: "contrast",
                 typ:  "float64", 
+                min:  -1, 
+                max:  1, 
                 def:  0, 
-                desc: "The vertical translation amount in % (positive = down)",
+                desc: "The contrast adjustment of the image",
             },
-        },
-        []dslParamMeta{     
             { 
-                name: "result",
-                typ:  "error", 
-                desc: "- - - The translated image",
+                name: "sharpening",
+                typ:  "float64", 
+                min:  0, 
+                max:  5, 
+                def:  1, 
+                desc: "The sharpening intensity in pixels (higher values detect larger edges)",
             },
-        },
-        func(a ...any) (any, error) {
-            return translate(
-                a[0].(*image.NRGBA64),
-                a[1].(float64),
-                a[2].(float64), 
-            )
-        },
-    )
-    l.funcs.register("rotate", "Rotates an image around its center by a specified angle",
-        []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to rotate",
+                name: "rMin",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.75, 
+                desc: "The minimum intensity of the red channel",
             },
             { 
-                name: "angle",
+                name: "rMax",
                 typ:  "float64", 
-                min:  -360, 
-                max:  360, 
-                def:  0, 
-                desc: "The rotation angle in degrees (positive = clockwise)",
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "The maximum intensity of the red channel",
             },
-        },
-        []dslParamMeta{     
             { 
-                name: "result",
-                typ:  "error", 
-                desc: "- - - The rotated image",
+                name: "gMin",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.75, 
+                desc: "The minimum intensity of the green channel",
             },
-        },
-        func(a ...any) (any, error) {
-            return rotate(
-                a[0].(*image.NRGBA64),
-                a[1].(float64), 
-            )
-        },
-    )
-    l.funcs.register("scale", "Scales an image by specified factors",
-        []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to scale",
+                name: "gMax",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "The maximum intensity of the green channel",
             },
             { 
-                name: "sx",
+                name: "bMin",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The horizontal scale factor",
+                min:  0, 
+                max:  1, 
+                def:  0.75, 
+                desc: "The minimum intensity of the blue channel",
             },
             { 
-                name: "sy",
+                name: "bMax",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The vertical scale factor",
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "The maximum intensity of the blue channel",
+            },
+            { 
+                name: "rWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.299, 
+                desc: "The weight of the red channel (used for sharpening)",
+            },
+            { 
+                name: "gWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.587, 
+                desc: "The weight of the green channel (used for sharpening)",
+            },
+            { 
+                name: "bWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.114, 
+                desc: "The weight of the blue channel (used for sharpening)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The scaled image",
+                desc: "- - - The enhanceed image",
             },
         },
         func(a ...any) (any, error) {
-            return scale(
+            return enhance(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
-                a[2].(float64), 
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64),
+                a[5].(float64),
+                a[6].(float64),
+                a[7].(float64),
+                a[8].(float64),
+                a[9].(float64),
+                a[10].(float64),
+                a[11].(float64),
+                a[12].(float64), 
             )
         },
     )
-    l.funcs.register("transform", "Applies translation, rotation, and scaling to an image in one operation",
+    l.funcs.register("sharpen", "Sharpens an image using a highpass combined with vivid light blending",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to transform",
+                desc: "The image to sharpen",
             },
             { 
-                name: "dx",
+                name: "intensity",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The horizontal translation in pixels",
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "The intensity of the sharpening effect",
             },
             { 
-                name: "dy",
+                name: "radius",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The vertical translation in pixels",
+                min:  0.1, 
+                max:  2, 
+                def:  1, 
+                desc: "The radius of the filter in pixels (higher values detect larger edges)",
             },
             { 
-                name: "angle",
+                name: "rWeight",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The rotation angle in degrees (clockwise)",
+                min:  0, 
+                max:  1, 
+                def:  0.299, 
+                desc: "The weight of the red channel",
             },
             { 
-                name: "sx",
+                name: "gWeight",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The horizontal scale factor",
+                min:  0, 
+                max:  1, 
+                def:  0.587, 
+                desc: "The weight of the green channel",
             },
             { 
-                name: "sy",
+                name: "bWeight",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The vertical scale factor",
+                min:  0, 
+                max:  1, 
+                def:  0.114, 
+                desc: "The weight of the blue channel",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The transformed image",
+                desc: "- - - The sharpened image",
             },
         },
         func(a ...any) (any, error) {
-            return transform(
+            return sharpen(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
@@ -6165,339 +6770,457 @@ func NewLanguage() *dslCollection {
             )
         },
     )
-    l.funcs.register("flip-v", "Flips an image vertically (top to bottom)",
+    l.funcs.register("highpass", "Creates a high pass filter effect, resulting in a gray image with embossed edges",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to flip vertically",
+                desc: "The image to apply the high-pass filter to",
             },
-        },
-        []dslParamMeta{     
             { 
-                name: "result",
-                typ:  "error", 
-                desc: "- - - The vertically flipped image",
+                name: "radius",
+                typ:  "float64", 
+                min:  0.1, 
+                max:  2, 
+                def:  1, 
+                desc: "The radius of the filter in pixels (higher values detect larger edges)",
             },
-        },
-        func(a ...any) (any, error) {
-            return flipVertical(
-                a[0].(*image.NRGBA64), 
-            )
-        },
-    )
-    l.funcs.register("flip-h", "Flips an image horizontally (left to right)",
-        []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to flip horizontally",
+                name: "rWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.299, 
+                desc: "The weight of the red channel",
+            },
+            { 
+                name: "gWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.587, 
+                desc: "The weight of the green channel",
+            },
+            { 
+                name: "bWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.114, 
+                desc: "The weight of the blue channel",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The horizontally flipped image",
+                desc: "- - - The filtered image",
             },
         },
         func(a ...any) (any, error) {
-            return flipHorizontal(
-                a[0].(*image.NRGBA64), 
+            return sharpenHighpass(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64), 
             )
         },
     )
-    l.funcs.register("crop", "Crops an image by specified percentages from each side",
+    l.funcs.register("clarity", "Enhances local contrast while preserving overall image structure",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
+                desc: "The image to enhance",
             },
             { 
-                name: "left",
+                name: "intensity",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to crop from the left side (0-1)",
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "The intensity of the clarity effect",
             },
             { 
-                name: "right",
+                name: "radius",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to crop from the right side (0-1)",
+                min:  0.1, 
+                max:  2, 
+                def:  1, 
+                desc: "The radius of the filter in pixels (higher values affect larger areas)",
             },
             { 
-                name: "top",
+                name: "rWeight",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to crop from the top side (0-1)",
+                min:  0, 
+                max:  1, 
+                def:  0.299, 
+                desc: "The weight of the red channel",
             },
             { 
-                name: "bottom",
+                name: "gWeight",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to crop from the bottom side (0-1)",
+                min:  0, 
+                max:  1, 
+                def:  0.587, 
+                desc: "The weight of the green channel",
+            },
+            { 
+                name: "bWeight",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.114, 
+                desc: "The weight of the blue channel",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The cropped image",
+                desc: "- - - The enhanced image",
             },
         },
         func(a ...any) (any, error) {
-            return crop(
+            return clarity(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
                 a[3].(float64),
-                a[4].(float64), 
+                a[4].(float64),
+                a[5].(float64), 
             )
         },
     )
-    l.funcs.register("crop-px", "Crops an image by specified amounts of pixels from each side",
+    l.funcs.register("translate", "Translates (moves) an image by a specified amount",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
-            },
-            { 
-                name: "left",
-                typ:  "int", 
-                def:  0, 
-                desc: "The number of pixels to crop from the left side",
-            },
-            { 
-                name: "right",
-                typ:  "int", 
-                def:  0, 
-                desc: "The number of pixels to crop from the right side",
+                desc: "The image to translate",
             },
             { 
-                name: "top",
-                typ:  "int", 
+                name: "dx",
+                typ:  "float64", 
                 def:  0, 
-                desc: "The number of pixels to crop from the top side",
+                desc: "The horizontal translation amount in % (positive = right)",
             },
             { 
-                name: "bottom",
-                typ:  "int", 
+                name: "dy",
+                typ:  "float64", 
                 def:  0, 
-                desc: "The number of pixels to crop from the bottom side",
+                desc: "The vertical translation amount in % (positive = down)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The cropped image",
+                desc: "- - - The translated image",
             },
         },
         func(a ...any) (any, error) {
-            return cropPx(
+            return translate(
                 a[0].(*image.NRGBA64),
-                a[1].(int),
-                a[2].(int),
-                a[3].(int),
-                a[4].(int), 
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("crop-circle", "Crops an image using a circular mask. The circle is centered at (centerX+offsetX, centerY+offsetY) and the radius is a percentage (0-1) of half the minimum image dimension.",
+    l.funcs.register("rotate", "Rotates an image around its center by a specified angle",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
-            },
-            { 
-                name: "radius",
-                typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  1, 
-                desc: "Radius as a percentage of half the min(width, height)",
+                desc: "The image to rotate",
             },
             { 
-                name: "offsetX",
+                name: "angle",
                 typ:  "float64", 
-                min:  -1, 
-                max:  1, 
+                min:  -360, 
+                max:  360, 
                 def:  0, 
-                desc: "Horizontal offset from image center (percentage of width, -1..1)",
+                desc: "The rotation angle in degrees (positive = clockwise)",
             },
             { 
-                name: "offsetY",
-                typ:  "float64", 
-                min:  -1, 
-                max:  1, 
-                def:  0, 
-                desc: "Vertical offset from image center (percentage of height, -1..1)",
+                name: "filter",
+                typ:  "string", 
+                def:  "nearest", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The circularly cropped image (pixels outside the circle are transparent)",
+                desc: "- - - The rotated image",
             },
         },
         func(a ...any) (any, error) {
-            return cropCircle(
+            return rotate(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+                a[2].(string), 
             )
         },
     )
-    l.funcs.register("crop-circle-px", "Crops an image using a circular mask. The circle is centered at (centerX+offsetX, centerY+offsetY) and the radius is a percentage (0-1) of half the minimum image dimension.",
+    l.funcs.register("scale", "Scales an image by specified factors",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
+                desc: "The image to scale",
             },
             { 
-                name: "radius",
+                name: "sx",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  1, 
-                desc: "Radius as a percentage of half the min(width, height)",
+                def:  0, 
+                desc: "The horizontal scale factor",
             },
             { 
-                name: "offsetX",
+                name: "sy",
                 typ:  "float64", 
                 def:  0, 
-                desc: "Horizontal offset from image center (pixels)",
+                desc: "The vertical scale factor",
             },
             { 
-                name: "offsetY",
-                typ:  "float64", 
-                def:  0, 
-                desc: "Vertical offset from image center (pixels)",
+                name: "filter",
+                typ:  "string", 
+                def:  "nearest", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The circularly cropped image (pixels outside the circle are transparent)",
+                desc: "- - - The scaled image",
             },
         },
         func(a ...any) (any, error) {
-            return cropCirclePx(
+            return scale(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
-                a[3].(float64), 
+                a[3].(string), 
             )
         },
     )
-    l.funcs.register("crop-square", "Crops an image using a square mask. The square is centered at (centerX+offsetX, centerY+offsetY) and the size is a percentage (0-1) of the minimum image dimension.",
+    l.funcs.register("transform", "Applies translation, rotation, and scaling to an image in one operation",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
+                desc: "The image to transform",
             },
             { 
-                name: "size",
+                name: "dx",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  1, 
-                desc: "Size as a percentage of the min(width, height)",
+                def:  0, 
+                desc: "The horizontal translation in pixels",
             },
             { 
-                name: "offsetX",
+                name: "dy",
                 typ:  "float64", 
-                min:  -1, 
-                max:  1, 
                 def:  0, 
-                desc: "Horizontal offset from image center (percentage of width, -1..1)",
+                desc: "The vertical translation in pixels",
             },
             { 
-                name: "offsetY",
+                name: "angle",
                 typ:  "float64", 
-                min:  -1, 
-                max:  1, 
                 def:  0, 
-                desc: "Vertical offset from image center (percentage of height, -1..1)",
+                desc: "The rotation angle in degrees (clockwise)",
+            },
+            { 
+                name: "sx",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The horizontal scale factor",
+            },
+            { 
+                name: "sy",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The vertical scale factor",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "nearest", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The square-cropped image (pixels outside the square are transparent)",
+                desc: "- - - The transformed image",
             },
         },
         func(a ...any) (any, error) {
-            return cropSquare(
+            return transform(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
-                a[3].(float64), 
+                a[3].(float64),
+                a[4].(float64),
+                a[5].(float64),
+                a[6].(string), 
             )
         },
     )
-    l.funcs.register("crop-square-px", "Crops an image using a square mask. The square is centered at (centerX+offsetX, centerY+offsetY) and the size is a percentage (0-1) of the minimum image dimension.",
+    l.funcs.register("flip-v", "Flips an image vertically (top to bottom)",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to crop",
+                desc: "The image to flip vertically",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "size",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The vertically flipped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return flipVertical(
+                a[0].(*image.NRGBA64), 
+            )
+        },
+    )
+    l.funcs.register("flip-h", "Flips an image horizontally (left to right)",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to flip horizontally",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The horizontally flipped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return flipHorizontal(
+                a[0].(*image.NRGBA64), 
+            )
+        },
+    )
+    l.funcs.register("crop", "Crops an image by specified percentages from each side",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to crop",
+            },
+            { 
+                name: "left",
                 typ:  "float64", 
-                min:  0, 
-                max:  1, 
-                def:  1, 
-                desc: "Size as a percentage of the min(width, height)",
+                def:  0, 
+                desc: "The percentage to crop from the left side (0-1)",
             },
             { 
-                name: "offsetX",
+                name: "right",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to crop from the right side (0-1)",
+            },
+            { 
+                name: "top",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to crop from the top side (0-1)",
+            },
+            { 
+                name: "bottom",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to crop from the bottom side (0-1)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The cropped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return crop(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64), 
+            )
+        },
+    )
+    l.funcs.register("crop-px", "Crops an image by specified amounts of pixels from each side",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to crop",
+            },
+            { 
+                name: "left",
                 typ:  "int", 
                 def:  0, 
-                desc: "Horizontal offset from image center (pixels)",
+                desc: "The number of pixels to crop from the left side",
             },
             { 
-                name: "offsetY",
+                name: "right",
                 typ:  "int", 
                 def:  0, 
-                desc: "Vertical offset from image center (pixels)",
+                desc: "The number of pixels to crop from the right side",
+            },
+            { 
+                name: "top",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to crop from the top side",
+            },
+            { 
+                name: "bottom",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to crop from the bottom side",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The square-cropped image (pixels outside the square are transparent)",
+                desc: "- - - The cropped image",
             },
         },
         func(a ...any) (any, error) {
-            return cropSquarePx(
+            return cropPx(
                 a[0].(*image.NRGBA64),
-                a[1].(float64),
+                a[1].(int),
                 a[2].(int),
-                a[3].(int), 
+                a[3].(int),
+                a[4].(int), 
             )
         },
     )
-    l.funcs.register("crop-arc", "Crops an image using an arc mask. The arc is a portion of a circle centered at (centerX+offsetX, centerY+offsetY) with the radius as a percentage (0-1) of half the minimum image dimension. Only pixels within the arc angle range are kept.",
+    l.funcs.register("crop-circle", "Crops an image using a circular mask. The circle is centered at (centerX+offsetX, centerY+offsetY) and the radius is a percentage (0-1) of half the minimum image dimension.",
         []dslParamMeta{ 
             { 
                 name: "img",
@@ -6513,22 +7236,6 @@ func NewLanguage() *dslCollection {
                 def:  1, 
                 desc: "Radius as a percentage of half the min(width, height)",
             },
-            { 
-                name: "startAngle",
-                typ:  "float64", 
-                min:  -360, 
-                max:  360, 
-                def:  0, 
-                desc: "Starting angle of the arc in degrees (-360 to 360, clockwise from right)",
-            },
-            { 
-                name: "endAngle",
-                typ:  "float64", 
-                min:  -360, 
-                max:  360, 
-                def:  360, 
-                desc: "Ending angle of the arc in degrees (-360 to 360, clockwise from right)",
-            },
             { 
                 name: "offsetX",
                 typ:  "float64", 
@@ -6550,21 +7257,19 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The arc-cropped image (pixels outside the arc are transparent)",
+                desc: "- - - The circularly cropped image (pixels outside the circle are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return cropArc(
+            return cropCircle(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
-                a[3].(float64),
-                a[4].(float64),
-                a[5].(float64), 
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("crop-arc-px", "Crops an image using an arc mask. The arc is a portion of a circle centered at (centerX+offsetX, centerY+offsetY) with the radius as a percentage (0-1) of half the minimum image dimension. Only pixels within the arc angle range are kept.",
+    l.funcs.register("crop-circle-px", "Crops an image using a circular mask. The circle is centered at (centerX+offsetX, centerY+offsetY) and the radius is a percentage (0-1) of half the minimum image dimension.",
         []dslParamMeta{ 
             { 
                 name: "img",
@@ -6580,22 +7285,6 @@ func NewLanguage() *dslCollection {
                 def:  1, 
                 desc: "Radius as a percentage of half the min(width, height)",
             },
-            { 
-                name: "startAngle",
-                typ:  "float64", 
-                min:  -360, 
-                max:  360, 
-                def:  0, 
-                desc: "Starting angle of the arc in degrees (-360 to 360, clockwise from right)",
-            },
-            { 
-                name: "endAngle",
-                typ:  "float64", 
-                min:  -360, 
-                max:  360, 
-                def:  360, 
-                desc: "Ending angle of the arc in degrees (-360 to 360, clockwise from right)",
-            },
             { 
                 name: "offsetX",
                 typ:  "float64", 
@@ -6613,1178 +7302,2823 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The arc-cropped image (pixels outside the arc are transparent)",
+                desc: "- - - The circularly cropped image (pixels outside the circle are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return cropArcPx(
+            return cropCirclePx(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
-                a[3].(float64),
-                a[4].(float64),
-                a[5].(float64), 
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("expand", "Expands an image by adding transparent borders with specified percentage widths",
+    l.funcs.register("crop-square", "Crops an image using a square mask. The square is centered at (centerX+offsetX, centerY+offsetY) and the size is a percentage (0-1) of the minimum image dimension.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to expand",
-            },
-            { 
-                name: "left",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to add to the left side (relative to original width)",
+                desc: "The image to crop",
             },
             { 
-                name: "right",
+                name: "size",
                 typ:  "float64", 
-                def:  0, 
-                desc: "The percentage to add to the right side (relative to original width)",
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "Size as a percentage of the min(width, height)",
             },
             { 
-                name: "top",
+                name: "offsetX",
                 typ:  "float64", 
+                min:  -1, 
+                max:  1, 
                 def:  0, 
-                desc: "The percentage to add to the top side (relative to original height)",
+                desc: "Horizontal offset from image center (percentage of width, -1..1)",
             },
             { 
-                name: "bottom",
+                name: "offsetY",
                 typ:  "float64", 
+                min:  -1, 
+                max:  1, 
                 def:  0, 
-                desc: "The percentage to add to the bottom side (relative to original height)",
+                desc: "Vertical offset from image center (percentage of height, -1..1)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The expanded image",
+                desc: "- - - The square-cropped image (pixels outside the square are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return expand(
+            return cropSquare(
                 a[0].(*image.NRGBA64),
                 a[1].(float64),
                 a[2].(float64),
-                a[3].(float64),
-                a[4].(float64), 
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("expand-px", "Expands an image by adding transparent borders with specified pixel widths",
+    l.funcs.register("crop-square-px", "Crops an image using a square mask. The square is centered at (centerX+offsetX, centerY+offsetY) and the size is a percentage (0-1) of the minimum image dimension.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to expand",
-            },
-            { 
-                name: "left",
-                typ:  "int", 
-                def:  0, 
-                desc: "The number of pixels to add to the left side",
+                desc: "The image to crop",
             },
             { 
-                name: "right",
-                typ:  "int", 
-                def:  0, 
-                desc: "The number of pixels to add to the right side",
+                name: "size",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "Size as a percentage of the min(width, height)",
             },
             { 
-                name: "top",
+                name: "offsetX",
                 typ:  "int", 
                 def:  0, 
-                desc: "The number of pixels to add to the top side",
+                desc: "Horizontal offset from image center (pixels)",
             },
             { 
-                name: "bottom",
+                name: "offsetY",
                 typ:  "int", 
                 def:  0, 
-                desc: "The number of pixels to add to the bottom side",
+                desc: "Vertical offset from image center (pixels)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The expanded image",
+                desc: "- - - The square-cropped image (pixels outside the square are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return expandPx(
+            return cropSquarePx(
                 a[0].(*image.NRGBA64),
-                a[1].(int),
+                a[1].(float64),
                 a[2].(int),
-                a[3].(int),
-                a[4].(int), 
+                a[3].(int), 
             )
         },
     )
-    l.funcs.register("resize-max-mp", "Resize an image to stay within a maximum amount of megapixels",
+    l.funcs.register("crop-arc", "Crops an image using an arc mask. The arc is a portion of a circle centered at (centerX+offsetX, centerY+offsetY) with the radius as a percentage (0-1) of half the minimum image dimension. Only pixels within the arc angle range are kept.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to resize",
+                desc: "The image to crop",
             },
             { 
-                name: "mpMax",
-                typ:  "int", 
-                def:  0, 
-                desc: "The maximum amount of megapixels",
+                name: "radius",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "Radius as a percentage of half the min(width, height)",
+            },
+            { 
+                name: "startAngle",
+                typ:  "float64", 
+                min:  -360, 
+                max:  360, 
+                def:  0, 
+                desc: "Starting angle of the arc in degrees (-360 to 360, clockwise from right)",
+            },
+            { 
+                name: "endAngle",
+                typ:  "float64", 
+                min:  -360, 
+                max:  360, 
+                def:  360, 
+                desc: "Ending angle of the arc in degrees (-360 to 360, clockwise from right)",
+            },
+            { 
+                name: "offsetX",
+                typ:  "float64", 
+                min:  -1, 
+                max:  1, 
+                def:  0, 
+                desc: "Horizontal offset from image center (percentage of width, -1..1)",
+            },
+            { 
+                name: "offsetY",
+                typ:  "float64", 
+                min:  -1, 
+                max:  1, 
+                def:  0, 
+                desc: "Vertical offset from image center (percentage of height, -1..1)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The resized image",
+                desc: "- - - The arc-cropped image (pixels outside the arc are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return resizeToMaxMP(
+            return cropArc(
                 a[0].(*image.NRGBA64),
-                a[1].(int), 
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64),
+                a[5].(float64), 
             )
         },
     )
-    l.funcs.register("resize-fit", "Resize an image to fit within a bounding box while preserving aspect ratio",
+    l.funcs.register("crop-arc-px", "Crops an image using an arc mask. The arc is a portion of a circle centered at (centerX+offsetX, centerY+offsetY) with the radius as a percentage (0-1) of half the minimum image dimension. Only pixels within the arc angle range are kept.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to resize",
+                desc: "The image to crop",
             },
             { 
-                name: "maxW",
-                typ:  "int", 
+                name: "radius",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                desc: "Radius as a percentage of half the min(width, height)",
+            },
+            { 
+                name: "startAngle",
+                typ:  "float64", 
+                min:  -360, 
+                max:  360, 
                 def:  0, 
-                desc: "The maximum width (pixels)",
+                desc: "Starting angle of the arc in degrees (-360 to 360, clockwise from right)",
             },
             { 
-                name: "maxH",
-                typ:  "int", 
+                name: "endAngle",
+                typ:  "float64", 
+                min:  -360, 
+                max:  360, 
+                def:  360, 
+                desc: "Ending angle of the arc in degrees (-360 to 360, clockwise from right)",
+            },
+            { 
+                name: "offsetX",
+                typ:  "float64", 
                 def:  0, 
-                desc: "The maximum height (pixels)",
+                desc: "Horizontal offset from image center (pixels)",
+            },
+            { 
+                name: "offsetY",
+                typ:  "float64", 
+                def:  0, 
+                desc: "Vertical offset from image center (pixels)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The resized image",
+                desc: "- - - The arc-cropped image (pixels outside the arc are transparent)",
             },
         },
         func(a ...any) (any, error) {
-            return resizeToFit(
+            return cropArcPx(
                 a[0].(*image.NRGBA64),
-                a[1].(int),
-                a[2].(int), 
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64),
+                a[5].(float64), 
             )
         },
     )
-    l.funcs.register("It", "Translates the given image by expanding/cropping the left + top borders.",
+    l.funcs.register("expand", "Expands an image by adding transparent borders with specified percentage widths",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to translate",
+                desc: "The image to expand",
             },
             { 
-                name: "dt",
-                typ:  "Point", 
-                def:  "-", 
-                desc: "The translation to apply",
+                name: "left",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to add to the left side (relative to original width)",
+            },
+            { 
+                name: "right",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to add to the right side (relative to original width)",
+            },
+            { 
+                name: "top",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to add to the top side (relative to original height)",
+            },
+            { 
+                name: "bottom",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The percentage to add to the bottom side (relative to original height)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The new image",
+                desc: "- - - The expanded image",
             },
         },
         func(a ...any) (any, error) {
-            return translateImage(
+            return expand(
                 a[0].(*image.NRGBA64),
-                a[1].(Point), 
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64), 
             )
         },
     )
-    l.funcs.register("blend-aligned", "Aligns two images using the given anchor (left-top, top, top-right, left, center, right, bottom-left, bottom, bottom-right) and blends them using the given blendmode (defaults to normal).",
+    l.funcs.register("expand-px", "Expands an image by adding transparent borders with specified pixel widths",
         []dslParamMeta{ 
             { 
-                name: "imgA",
+                name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The bottom image",
+                desc: "The image to expand",
             },
             { 
-                name: "imgB",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The top image",
+                name: "left",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to add to the left side",
             },
             { 
-                name: "anchor",
-                typ:  "string", 
-                def:  "C", 
-                desc: "The anchor to align to (TL, T, TR, L, C, R, BL, B, BR)",
+                name: "right",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to add to the right side",
             },
             { 
-                name: "mode",
-                typ:  "string", 
-                def:  "normal", 
-                desc: "The blendmode name",
+                name: "top",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to add to the top side",
+            },
+            { 
+                name: "bottom",
+                typ:  "int", 
+                def:  0, 
+                desc: "The number of pixels to add to the bottom side",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The aligned and blended image",
+                desc: "- - - The expanded image",
             },
         },
         func(a ...any) (any, error) {
-            return blendAligned(
+            return expandPx(
                 a[0].(*image.NRGBA64),
-                a[1].(*image.NRGBA64),
-                a[2].(string),
-                a[3].(string), 
+                a[1].(int),
+                a[2].(int),
+                a[3].(int),
+                a[4].(int), 
             )
         },
     )
-    l.funcs.register("load", "Loads an image",
+    l.funcs.register("resize-max-mp", "Resize an image to stay within a maximum amount of megapixels",
         []dslParamMeta{ 
             { 
-                name: "path",
-                typ:  "string", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "- - Path to the image",
+                desc: "The image to resize",
+            },
+            { 
+                name: "mpMax",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The maximum amount of megapixels (e.g. 0.5 for 500,000 pixels), 0 to keep the size",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "nearest", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The loaded image",
+                desc: "- - - The resized image",
             },
         },
         func(a ...any) (any, error) {
-            return load(
-                a[0].(string), 
+            return resizeToMaxMP(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(string), 
             )
         },
     )
-    l.funcs.register("save", "Saves an image",
+    l.funcs.register("resize-fit", "Resize an image to fit within a bounding box while preserving aspect ratio",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The image to save",
+                desc: "The image to resize",
             },
             { 
-                name: "path",
+                name: "maxW",
+                typ:  "int", 
+                def:  0, 
+                desc: "The maximum width (pixels)",
+            },
+            { 
+                name: "maxH",
+                typ:  "int", 
+                def:  0, 
+                desc: "The maximum height (pixels)",
+            },
+            { 
+                name: "filter",
                 typ:  "string", 
-                def:  "-", 
-                desc: "- - Path where to save",
+                def:  "nearest", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
             },
         },
-        []dslParamMeta{ 
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The resized image",
+            },
         },
         func(a ...any) (any, error) {
-            return save(
+            return resizeToFit(
                 a[0].(*image.NRGBA64),
-                a[1].(string), 
+                a[1].(int),
+                a[2].(int),
+                a[3].(string), 
             )
         },
     )
-    l.funcs.register("add", "Adds the two numbers",
+    l.funcs.register("resize", "Resize an image to exact dimensions. If either width or height is 0, it is derived from the aspect ratio.",
         []dslParamMeta{ 
             { 
-                name: "a",
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to resize",
+            },
+            { 
+                name: "w",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The width of the result",
+            },
+            { 
+                name: "h",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The height of the result",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "lanczos3", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The resized image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return resize(
+                a[0].(*image.NRGBA64),
+                a[1].(int),
+                a[2].(int),
+                a[3].(string), 
+            )
+        },
+    )
+    l.funcs.register("resize-fill", "Resize an image to cover exact dimensions while preserving aspect ratio, the overflow is cropped",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to resize",
+            },
+            { 
+                name: "w",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The width of the result",
+            },
+            { 
+                name: "h",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The height of the result",
+            },
+            { 
+                name: "anchor",
+                typ:  "string", 
+                def:  "C", 
+                desc: "The part of the image to keep (TL, T, TR, L, C, R, BL, B, BR)",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "lanczos3", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The resized image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return resizeFill(
+                a[0].(*image.NRGBA64),
+                a[1].(int),
+                a[2].(int),
+                a[3].(string),
+                a[4].(string), 
+            )
+        },
+    )
+    l.funcs.register("smart-crop", "Resize an image to cover exact dimensions while preserving aspect ratio and crops the overflow where the image has the least detail. The detail is measured using edge-detect.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to crop",
+            },
+            { 
+                name: "w",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The width of the result",
+            },
+            { 
+                name: "h",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The height of the result",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "lanczos3", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The cropped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return smartCrop(
+                a[0].(*image.NRGBA64),
+                a[1].(int),
+                a[2].(int),
+                a[3].(string), 
+            )
+        },
+    )
+    l.funcs.register("detect-skew", "Detects how much the dominant lines of an image (e.g. text lines, edges of documents or the horizon) are tilted, using a Hough transform of the edge-detect result",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to analyze",
+            },
+            { 
+                name: "maxAngle",
+                typ:  "float64", 
+                min:  0, 
+                max:  45, 
+                def:  45, 
+                unit: "\"°\"", 
+                desc: "The largest tilt to look for",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The tilt in degrees (positive = clockwise), rotate by the negative value to straighten the image, 0 if the image has no lines",
+            },
+        },
+        func(a ...any) (any, error) {
+            return detectSkew(
+                a[0].(*image.NRGBA64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("deskew", "Straightens an image by detecting its tilt with detect-skew and rotating it back. The result is cropped to the largest rectangle without transparent corners.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to straighten",
+            },
+            { 
+                name: "maxAngle",
+                typ:  "float64", 
+                min:  0, 
+                max:  45, 
+                def:  10, 
+                unit: "\"°\"", 
+                desc: "The largest tilt to correct",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The straightened image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return deskew(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(string), 
+            )
+        },
+    )
+    l.funcs.register("focus-stack", "Combines a focus bracket (e.g. macro shots focused at different distances) into a single image that is sharp everywhere. The images are aligned to the first one, then every region is taken from the image where it's sharpest (measured by the energy of the Laplacian) and the transitions are blended smoothly.",
+        []dslParamMeta{ 
+            { 
+                name: "images",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The images of the bracket, ordered by focus distance",
+            },
+            { 
+                name: "radius",
+                typ:  "float64", 
+                min:  1, 
+                max:  10, 
+                def:  2, 
+                unit: "\"px\"", 
+                desc: "The radius sharpness is measured over (higher = less noise in the selection, but less precise at edges)",
+            },
+            { 
+                name: "smoothing",
+                typ:  "float64", 
+                min:  0, 
+                max:  50, 
+                def:  4, 
+                unit: "\"px\"", 
+                desc: "How much the selection is smoothed",
+            },
+            { 
+                name: "autoAlign",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether to align the images first (corrects shifts and the change of scale caused by refocusing)",
+            },
+            { 
+                name: "depth",
+                typ:  "bool", 
+                def:  false, 
+                desc: "Whether to return the depth map instead, a mask from 0 (sharpest in the first image) to 1 (sharpest in the last image)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The stacked image or the depth map",
+            },
+        },
+        func(a ...any) (any, error) {
+            return focusStack(
+                a[0].([]any),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(bool),
+                a[4].(bool), 
+            )
+        },
+    )
+    l.funcs.register("merge-hdr", "Merges an exposure bracket into a linear-light HDR image with the radiance of the scene. Each pixel is a weighted average of the exposures, well-exposed values count most while clipped highlights and noisy shadows are ignored (Debevec weighting). The images have to be aligned (see align). Use a tonemap function to display the result.",
+        []dslParamMeta{ 
+            { 
+                name: "images",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The images of the bracket, in any order",
+            },
+            { 
+                name: "exposuresEV",
+                typ:  "[]any", 
+                def:  "-", 
+                unit: "\"EV\"", 
+                desc: "The exposure value of each image relative to the result (e.g. {-2 0 2}), an empty list ({}) calculates them from the EXIF exposure time, aperture and ISO",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The linear-light HDR image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return mergeHDR(
+                a[0].([]any),
+                a[1].([]any), 
+            )
+        },
+    )
+    l.funcs.register("tonemap-reinhard", "Maps an HDR image to the displayable range with the photographic operator of Reinhard et al. The scene is scaled to the given key, then highlights are compressed smoothly.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*LinearImage", 
+                def:  "-", 
+                desc: "The HDR image",
+            },
+            { 
+                name: "key",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  0.18, 
+                desc: "The brightness of the average luminance of the result (0.18 = middle gray)",
+            },
+            { 
+                name: "white",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The scaled luminance that becomes pure white (0 = the brightest pixel)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The tone mapped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return tonemapReinhard(
+                a[0].(*LinearImage),
+                a[1].(float64),
+                a[2].(float64), 
+            )
+        },
+    )
+    l.funcs.register("tonemap-aces", "Maps an HDR image to the displayable range with a fit of the ACES filmic curve (Narkowicz), which gives contrasty midtones and soft highlight roll-off",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*LinearImage", 
+                def:  "-", 
+                desc: "The HDR image",
+            },
+            { 
+                name: "exposure",
+                typ:  "float64", 
+                min:  -4, 
+                max:  4, 
+                def:  0, 
+                unit: "\"EV\"", 
+                desc: "The exposure adjustment before mapping",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The tone mapped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return tonemapACES(
+                a[0].(*LinearImage),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("tonemap-drago", "Maps an HDR image to the displayable range with the adaptive logarithmic operator of Drago et al., which keeps details in very bright and very dark areas",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*LinearImage", 
+                def:  "-", 
+                desc: "The HDR image",
+            },
+            { 
+                name: "bias",
+                typ:  "float64", 
+                min:  0.5, 
+                max:  1, 
+                def:  0.85, 
+                desc: "How much dark areas are brightened and contrast is compressed (lower = brighter, flatter result)",
+            },
+            { 
+                name: "exposure",
+                typ:  "float64", 
+                min:  -4, 
+                max:  4, 
+                def:  0, 
+                unit: "\"EV\"", 
+                desc: "The exposure adjustment before mapping",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The tone mapped image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return tonemapDrago(
+                a[0].(*LinearImage),
+                a[1].(float64),
+                a[2].(float64), 
+            )
+        },
+    )
+    l.funcs.register("exposure-fusion", "Fuses an exposure bracket directly into a displayable image (Mertens et al.), without exposure values or tone mapping. Every pixel is weighted by its contrast, saturation and well-exposedness and the images are blended seamlessly at multiple scales. The images have to be aligned (see align).",
+        []dslParamMeta{ 
+            { 
+                name: "images",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The images of the bracket, in any order",
+            },
+            { 
+                name: "contrast",
+                typ:  "float64", 
+                min:  0, 
+                max:  2, 
+                def:  1, 
+                desc: "The importance of local contrast (details)",
+            },
+            { 
+                name: "saturation",
+                typ:  "float64", 
+                min:  0, 
+                max:  2, 
+                def:  1, 
+                desc: "The importance of color saturation",
+            },
+            { 
+                name: "exposure",
+                typ:  "float64", 
+                min:  0, 
+                max:  2, 
+                def:  1, 
+                desc: "The importance of values close to middle gray",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The fused image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return exposureFusion(
+                a[0].([]any),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
+            )
+        },
+    )
+    l.funcs.register("working-space", "Sets the working space of images loaded afterwards, scripts start in \"srgb\". In \"linear\" mode images are decoded to linear-light float32 values, so blurs, scaling and blend modes are physically correct and values outside 0..1 are preserved until saving. Only exposure, brightness, contrast, grayscale, opacity, blur-gaussian, blur-box, the blend functions and the resampling functions (scale, resize, resize-fit, resize-fill, resize-max-mp, smart-crop, rotate, transform, skew, the warps and the lens corrections except vignette-correct) process linear-light values, all other functions receive the image converted to sRGB, which clips values outside 0..1.",
+        []dslParamMeta{ 
+            { 
+                name: "space",
+                typ:  "string", 
+                def:  "srgb", 
+                desc: "The working space (srgb, linear)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The previous working space",
+            },
+        },
+        func(a ...any) (any, error) {
+            return workingSpaceSet(
+                a[0].(string), 
+            )
+        },
+    )
+    l.funcs.register("to-linear", "Converts an image to linear-light float32 values",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to convert",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The linear-light image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return imageToLinear(
+                a[0].(*image.NRGBA64), 
+            )
+        },
+    )
+    l.funcs.register("to-srgb", "Converts a linear-light image to sRGB, clipping values outside of the displayable range",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*LinearImage", 
+                def:  "-", 
+                desc: "The image to convert",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The sRGB image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return imageToSRGB(
+                a[0].(*LinearImage), 
+            )
+        },
+    )
+    l.funcs.register("It", "Translates the given image by expanding/cropping the left + top borders.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to translate",
+            },
+            { 
+                name: "dt",
+                typ:  "Point", 
+                def:  "-", 
+                desc: "The translation to apply",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The new image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return translateImage(
+                a[0].(*image.NRGBA64),
+                a[1].(Point), 
+            )
+        },
+    )
+    l.funcs.register("blend-aligned", "Aligns two images using the given anchor (left-top, top, top-right, left, center, right, bottom-left, bottom, bottom-right) and blends them using the given blendmode (defaults to normal).",
+        []dslParamMeta{ 
+            { 
+                name: "imgA",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The bottom image",
+            },
+            { 
+                name: "imgB",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The top image",
+            },
+            { 
+                name: "anchor",
+                typ:  "string", 
+                def:  "C", 
+                desc: "The anchor to align to (TL, T, TR, L, C, R, BL, B, BR)",
+            },
+            { 
+                name: "mode",
+                typ:  "string", 
+                def:  "normal", 
+                desc: "The blendmode name",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The aligned and blended image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return blendAligned(
+                a[0].(*image.NRGBA64),
+                a[1].(*image.NRGBA64),
+                a[2].(string),
+                a[3].(string), 
+            )
+        },
+    )
+    l.funcs.register("load", "Loads an image (PNG, JPEG, GIF, WebP, TIFF, BMP, HEIC, AVIF or DNG). Multi-page TIFFs are returned as a slice of images. EXIF, XMP and ICC metadata is kept with the image. AVIF needs a system libheif with an AV1 decoder.",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the image",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The loaded image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return load(
+                a[0].(string), 
+            )
+        },
+    )
+    l.funcs.register("load-dng", "Loads a DNG raw image and develops it with the given demosaicing algorithm. AHD produces fewer artifacts along edges, bilinear is faster. `load` uses AHD.",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the DNG file",
+            },
+            { 
+                name: "demosaic",
+                typ:  "string", 
+                def:  "ahd", 
+                desc: "The demosaicing algorithm (ahd, bilinear)",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The developed image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadDNG(
+                a[0].(string),
+                a[1].(string), 
+            )
+        },
+    )
+    l.funcs.register("save", "Saves an image. The format is derived from the file extension unless given explicitly. JPEGs and PNGs include the image's EXIF, XMP and ICC metadata.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image to save",
+            },
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path where to save",
+            },
+            { 
+                name: "format",
+                typ:  "string", 
+                def:  "", 
+                desc: "The format (png, jpeg, webp, tiff, gif, bmp), empty to use the file extension",
+            },
+            { 
+                name: "quality",
+                typ:  "int", 
+                min:  1, 
+                max:  100, 
+                def:  90, 
+                desc: "The JPEG quality",
+            },
+            { 
+                name: "subsampling",
+                typ:  "string", 
+                def:  "420", 
+                desc: "The JPEG chroma subsampling (444, 422, 420)",
+            },
+            { 
+                name: "compression",
+                typ:  "string", 
+                def:  "default", 
+                desc: "The PNG (none, fast, default, best) or TIFF (none, default) compression",
+            },
+            { 
+                name: "colors",
+                typ:  "int", 
+                min:  2, 
+                max:  256, 
+                def:  256, 
+                desc: "The size of the GIF palette",
+            },
+            { 
+                name: "dither",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether to dither GIFs",
+            },
+            { 
+                name: "depth",
+                typ:  "int", 
+                min:  0, 
+                max:  16, 
+                def:  0, 
+                desc: "The bits per channel of PNGs and TIFFs (8, 16 or 0 to keep the image's depth)",
+            },
+        },
+        []dslParamMeta{ 
+        },
+        func(a ...any) (any, error) {
+            return save(
+                a[0].(*image.NRGBA64),
+                a[1].(string),
+                a[2].(string),
+                a[3].(int),
+                a[4].(string),
+                a[5].(string),
+                a[6].(int),
+                a[7].(bool),
+                a[8].(int), 
+            )
+        },
+    )
+    l.funcs.register("layer", "Creates a layer that can be composited with flatten",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image of the layer",
+            },
+            { 
+                name: "mode",
+                typ:  "string", 
+                def:  "normal", 
+                desc: "The blend mode used to composite the layer onto the layers below",
+            },
+            { 
+                name: "opacity",
+                typ:  "float64", 
+                min:  0, 
+                max:  1, 
+                def:  1, 
+                unit: "\"%\"", 
+                desc: "The opacity of the layer",
+            },
+            { 
+                name: "x",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The horizontal offset on the canvas",
+            },
+            { 
+                name: "y",
+                typ:  "int", 
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The vertical offset on the canvas",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The layer",
+            },
+        },
+        func(a ...any) (any, error) {
+            return makeLayer(
+                a[0].(*image.NRGBA64),
+                a[1].(string),
+                a[2].(float64),
+                a[3].(int),
+                a[4].(int), 
+            )
+        },
+    )
+    l.funcs.register("layer-mask", "Sets the mask of a layer. The mask scales the opacity of the layer and is placed at the position of the layer. Images can be used as masks, their luminance multiplied by their alpha is used.",
+        []dslParamMeta{ 
+            { 
+                name: "l",
+                typ:  "*Layer", 
+                def:  "-", 
+                desc: "The layer",
+            },
+            { 
+                name: "mask",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The masked layer",
+            },
+        },
+        func(a ...any) (any, error) {
+            return layerMask(
+                a[0].(*Layer),
+                a[1].(*Mask), 
+            )
+        },
+    )
+    l.funcs.register("layer-visible", "Shows or hides a layer",
+        []dslParamMeta{ 
+            { 
+                name: "l",
+                typ:  "*Layer", 
+                def:  "-", 
+                desc: "The layer",
+            },
+            { 
+                name: "visible",
+                typ:  "bool", 
+                def:  true, 
+                desc: "Whether the layer is visible",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The layer",
+            },
+        },
+        func(a ...any) (any, error) {
+            return layerVisible(
+                a[0].(*Layer),
+                a[1].(bool), 
+            )
+        },
+    )
+    l.funcs.register("layer-image", "Returns the image of a layer",
+        []dslParamMeta{ 
+            { 
+                name: "l",
+                typ:  "*Layer", 
+                def:  "-", 
+                desc: "The layer",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return layerImage(
+                a[0].(*Layer), 
+            )
+        },
+    )
+    l.funcs.register("flatten", "Composites a stack of layers (bottom to top) into a single image. The canvas spans from 0,0 to the far edges of all visible layers. Blend modes are applied according to the W3C compositing model, so layers blend with what's below them and keep their colors where nothing is below.",
+        []dslParamMeta{ 
+            { 
+                name: "layers",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The layers to composite, the first one is the bottom layer",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The flattened image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return flatten(
+                a[0].([]any), 
+            )
+        },
+    )
+    l.funcs.register("load-layers", "Loads the layers of an OpenRaster (.ora) or Photoshop document (.psd, RGB with 8 or 16 bits per channel), bottom layer first. Names, blend modes, opacity, offsets, visibility and PSD layer masks are kept, use flatten to composite the layers.",
+        []dslParamMeta{ 
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path to the document",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The layers",
+            },
+        },
+        func(a ...any) (any, error) {
+            return loadLayers(
+                a[0].(string), 
+            )
+        },
+    )
+    l.funcs.register("save-layers", "Saves layers as OpenRaster document (.ora) that can be opened by GIMP, Krita and MyPaint. Blend modes without an OpenRaster equivalent are stored as \"pxp:<mode>\", masks are applied to the alpha of their layers.",
+        []dslParamMeta{ 
+            { 
+                name: "layers",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "The layers to save, the first one is the bottom layer",
+            },
+            { 
+                name: "path",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - Path where to save",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The layers",
+            },
+        },
+        func(a ...any) (any, error) {
+            return saveLayers(
+                a[0].([]any),
+                a[1].(string), 
+            )
+        },
+    )
+    l.funcs.register("mask-union", "Combines two masks, a pixel is selected as much as it is selected in either mask",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The first mask",
+            },
+            { 
+                name: "b",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The second mask",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The combined mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskUnion(
+                a[0].(*Mask),
+                a[1].(*Mask), 
+            )
+        },
+    )
+    l.funcs.register("mask-intersect", "Intersects two masks, a pixel is selected as much as it is selected in both masks",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The first mask",
+            },
+            { 
+                name: "b",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The second mask",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The intersected mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskIntersect(
+                a[0].(*Mask),
+                a[1].(*Mask), 
+            )
+        },
+    )
+    l.funcs.register("mask-invert", "Inverts a mask, selecting everything that wasn't selected",
+        []dslParamMeta{ 
+            { 
+                name: "m",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask to invert",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The inverted mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskInvert(
+                a[0].(*Mask), 
+            )
+        },
+    )
+    l.funcs.register("mask-feather", "Softens the edges of a mask with a Gaussian blur",
+        []dslParamMeta{ 
+            { 
+                name: "m",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask to feather",
+            },
+            { 
+                name: "radius",
+                typ:  "float64", 
+                min:  0, 
+                max:  100, 
+                def:  5, 
+                unit: "\"px\"", 
+                desc: "The width of the soft edge",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The feathered mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskFeather(
+                a[0].(*Mask),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("mask-grow", "Expands the selected area of a mask",
+        []dslParamMeta{ 
+            { 
+                name: "m",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask to grow",
+            },
+            { 
+                name: "radius",
+                typ:  "int", 
+                min:  0, 
+                max:  100, 
+                def:  1, 
+                unit: "\"px\"", 
+                desc: "The number of pixels to grow by",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The grown mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskGrow(
+                a[0].(*Mask),
+                a[1].(int), 
+            )
+        },
+    )
+    l.funcs.register("mask-shrink", "Contracts the selected area of a mask",
+        []dslParamMeta{ 
+            { 
+                name: "m",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask to shrink",
+            },
+            { 
+                name: "radius",
+                typ:  "int", 
+                min:  0, 
+                max:  100, 
+                def:  1, 
+                unit: "\"px\"", 
+                desc: "The number of pixels to shrink by",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The shrunk mask",
+            },
+        },
+        func(a ...any) (any, error) {
+            return maskShrink(
+                a[0].(*Mask),
+                a[1].(int), 
+            )
+        },
+    )
+    l.funcs.register("apply-masked", "Restricts an effect to the selected area: where the mask is selected the result of the effect is used, elsewhere the original image. Partially selected pixels are mixed.",
+        []dslParamMeta{ 
+            { 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The original image",
+            },
+            { 
+                name: "effectResult",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The image with the effect applied (e.g. sharpen(img 1))",
+            },
+            { 
+                name: "mask",
+                typ:  "*Mask", 
+                def:  "-", 
+                desc: "The mask restricting the effect, aligned with the upper-left corner of img",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The resulting image",
+            },
+        },
+        func(a ...any) (any, error) {
+            return applyMasked(
+                a[0].(*image.NRGBA64),
+                a[1].(*image.NRGBA64),
+                a[2].(*Mask), 
+            )
+        },
+    )
+    l.funcs.register("add", "Adds the two numbers",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a+b",
+            },
+        },
+        func(a ...any) (any, error) {
+            return add(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("add-n", "Multiplies b by n and adds the result to a",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+            { 
+                name: "n",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The multiplier for the second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a + (n * b)",
+            },
+        },
+        func(a ...any) (any, error) {
+            return addN(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
+            )
+        },
+    )
+    l.funcs.register("sub", "Subtracts the two numbers",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a-b",
+            },
+        },
+        func(a ...any) (any, error) {
+            return sub(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("sub-n", "Multiplies b by n and subtracts the result from a",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+            { 
+                name: "n",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The multiplier for the second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a - (n * b)",
+            },
+        },
+        func(a ...any) (any, error) {
+            return subN(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
+            )
+        },
+    )
+    l.funcs.register("mul", "Multiplies the two numbers",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a*b",
+            },
+        },
+        func(a ...any) (any, error) {
+            return mul(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("div", "Divides the two numbers",
+        []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first number",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second number",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - a/b",
+            },
+        },
+        func(a ...any) (any, error) {
+            return div(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("fibonacci", "Calculates the nth fibonacci number using 1-based indexing with memoization",
+        []dslParamMeta{ 
+            { 
+                name: "nth",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The nth fibonacci number to calculate",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The nth fibonacci number",
+            },
+        },
+        func(a ...any) (any, error) {
+            return fibonacci(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("floor", "Returns the largest integer less than or equal to x",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The input value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The largest integer less than or equal to x",
+            },
+        },
+        func(a ...any) (any, error) {
+            return floor(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("ceil", "Returns the smallest integer greater than or equal to x",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The input value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The smallest integer greater than or equal to x",
+            },
+        },
+        func(a ...any) (any, error) {
+            return ceil(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("round", "Returns the nearest integer to x, rounding to even on ties",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The input value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The nearest integer to x",
+            },
+        },
+        func(a ...any) (any, error) {
+            return round(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("min", "Returns the minimum value of x and y",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x value",
+            },
+            { 
+                name: "y",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The minimum value of x and y",
+            },
+        },
+        func(a ...any) (any, error) {
+            return min(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("max", "Returns the maximum value of x and y",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x value",
+            },
+            { 
+                name: "y",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The maximum value of x and y",
+            },
+        },
+        func(a ...any) (any, error) {
+            return max(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("delta", "Returns the delta between x and y",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x value",
+            },
+            { 
+                name: "y",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The delta between x and y",
+            },
+        },
+        func(a ...any) (any, error) {
+            return delta(
+                a[0].(float64),
+                a[1].(float64), 
+            )
+        },
+    )
+    l.funcs.register("abs", "Returns the absolute value of x",
+        []dslParamMeta{ 
+            { 
+                name: "x",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The input value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The absolute value of x",
+            },
+        },
+        func(a ...any) (any, error) {
+            return abs(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("slope", "Calculates the slope between two points",
+        []dslParamMeta{ 
+            { 
+                name: "x1",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x coordinate of the first point",
+            },
+            { 
+                name: "y1",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y coordinate of the first point",
+            },
+            { 
+                name: "x2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x coordinate of the second point",
+            },
+            { 
+                name: "y2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y coordinate of the second point",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The slope value",
+            },
+        },
+        func(a ...any) (any, error) {
+            return slope(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
+            )
+        },
+    )
+    l.funcs.register("tan-of-slope", "Calculates the angle from a slope value",
+        []dslParamMeta{ 
+            { 
+                name: "m",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The slope value",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The angle in radians",
+            },
+        },
+        func(a ...any) (any, error) {
+            return tanOfSlope(
+                a[0].(float64), 
+            )
+        },
+    )
+    l.funcs.register("hypotenuse-of-triangle", "Calculates hypotenuse from adjacent, opposite and gamma angle",
+        []dslParamMeta{ 
+            { 
+                name: "adjacent",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The adjacent side length",
+            },
+            { 
+                name: "opposite",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The first number",
+                desc: "The opposite side length",
             },
             { 
-                name: "b",
+                name: "gamma",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second number",
+                desc: "The gamma angle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a+b",
+                desc: "- - - The hypotenuse length",
             },
         },
         func(a ...any) (any, error) {
-            return add(
+            return hypotenuseOfTriangle(
                 a[0].(float64),
-                a[1].(float64), 
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("add-n", "Multiplies b by n and adds the result to a",
+    l.funcs.register("adjacent-of-triangle", "Calculates adjacent side from hypotenuse, opposite and alpha angle",
         []dslParamMeta{ 
             { 
-                name: "a",
+                name: "hypotenuse",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The first number",
+                desc: "The hypotenuse length",
             },
             { 
-                name: "b",
+                name: "opposite",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second number",
+                desc: "The opposite side length",
             },
             { 
-                name: "n",
+                name: "alpha",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The multiplier for the second number",
+                desc: "The alpha angle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a + (n * b)",
+                desc: "- - - The adjacent side length",
             },
         },
         func(a ...any) (any, error) {
-            return addN(
+            return adjacentOfTriangle(
                 a[0].(float64),
                 a[1].(float64),
                 a[2].(float64), 
             )
         },
     )
-    l.funcs.register("sub", "Subtracts the two numbers",
+    l.funcs.register("opposite-of-triangle", "Calculates opposite side from hypotenuse, adjacent and beta angle",
         []dslParamMeta{ 
             { 
-                name: "a",
+                name: "hypotenuse",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The first number",
+                desc: "The hypotenuse length",
             },
             { 
-                name: "b",
+                name: "adjacent",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second number",
+                desc: "The adjacent side length",
+            },
+            { 
+                name: "beta",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The beta angle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a-b",
+                desc: "- - - The opposite side length",
             },
         },
         func(a ...any) (any, error) {
-            return sub(
+            return oppositeOfTriangle(
                 a[0].(float64),
-                a[1].(float64), 
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("sub-n", "Multiplies b by n and subtracts the result from a",
+    l.funcs.register("circumference-of-a_circle", "Calculates circumference from radius",
         []dslParamMeta{ 
             { 
-                name: "a",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first number",
-            },
-            { 
-                name: "b",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The second number",
-            },
-            { 
-                name: "n",
+                name: "radius",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The multiplier for the second number",
+                desc: "The radius of the circle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a - (n * b)",
+                desc: "- - - The circumference length",
             },
         },
         func(a ...any) (any, error) {
-            return subN(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+            return circumferenceOfACircle(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("mul", "Multiplies the two numbers",
+    l.funcs.register("distance-between", "Calculates distance between two points",
         []dslParamMeta{ 
             { 
-                name: "a",
+                name: "x1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The first number",
+                desc: "The x coordinate of the first point",
             },
             { 
-                name: "b",
+                name: "y1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second number",
+                desc: "The y coordinate of the first point",
+            },
+            { 
+                name: "x2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x coordinate of the second point",
+            },
+            { 
+                name: "y2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y coordinate of the second point",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a*b",
+                desc: "- - - The distance between the points",
             },
         },
         func(a ...any) (any, error) {
-            return mul(
+            return distanceBetween(
                 a[0].(float64),
-                a[1].(float64), 
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("div", "Divides the two numbers",
+    l.funcs.register("angle-between", "Calculates angle between two points",
         []dslParamMeta{ 
             { 
-                name: "a",
+                name: "x1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The first number",
+                desc: "The x coordinate of the first point",
             },
             { 
-                name: "b",
+                name: "y1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second number",
+                desc: "The y coordinate of the first point",
+            },
+            { 
+                name: "x2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The x coordinate of the second point",
+            },
+            { 
+                name: "y2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The y coordinate of the second point",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - a/b",
+                desc: "- - - The angle in radians",
             },
         },
         func(a ...any) (any, error) {
-            return div(
+            return angleBetween(
                 a[0].(float64),
-                a[1].(float64), 
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("fibonacci", "Calculates the nth fibonacci number using 1-based indexing with memoization",
+    l.funcs.register("square", "Calculates the square of a number",
         []dslParamMeta{ 
             { 
-                name: "nth",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The nth fibonacci number to calculate",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The nth fibonacci number",
+                desc: "- - - The square of x",
             },
         },
         func(a ...any) (any, error) {
-            return fibonacci(
+            return square(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("floor", "Returns the largest integer less than or equal to x",
+    l.funcs.register("pow", "Calculates base raised to the power of n, using lookup tables for integer bases when possible",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "base",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The base value",
+            },
+            { 
+                name: "n",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The largest integer less than or equal to x",
+                desc: "- - - Base^n",
             },
         },
         func(a ...any) (any, error) {
-            return floor(
-                a[0].(float64), 
+            return pow(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("ceil", "Returns the smallest integer greater than or equal to x",
+    l.funcs.register("pow2", "Calculates 2 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The smallest integer greater than or equal to x",
+                desc: "- - - 2^n",
             },
         },
         func(a ...any) (any, error) {
-            return ceil(
+            return pow2(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("round", "Returns the nearest integer to x, rounding to even on ties",
+    l.funcs.register("pow4", "Calculates 4 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The nearest integer to x",
+                desc: "- - - 4^n",
             },
         },
         func(a ...any) (any, error) {
-            return round(
+            return pow4(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("min", "Returns the minimum value of x and y",
+    l.funcs.register("pow8", "Calculates 8 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x value",
-            },
-            { 
-                name: "y",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The minimum value of x and y",
+                desc: "- - - 8^n",
             },
         },
         func(a ...any) (any, error) {
-            return min(
-                a[0].(float64),
-                a[1].(float64), 
+            return pow8(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("max", "Returns the maximum value of x and y",
+    l.funcs.register("pow10", "Calculates 10 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x value",
-            },
-            { 
-                name: "y",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The maximum value of x and y",
+                desc: "- - - 10^n",
             },
         },
         func(a ...any) (any, error) {
-            return max(
-                a[0].(float64),
-                a[1].(float64), 
+            return pow10(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("delta", "Returns the delta between x and y",
+    l.funcs.register("pow12", "Calculates 12 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x value",
-            },
-            { 
-                name: "y",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The delta between x and y",
+                desc: "- - - 12^n",
             },
         },
         func(a ...any) (any, error) {
-            return delta(
-                a[0].(float64),
-                a[1].(float64), 
+            return pow12(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("abs", "Returns the absolute value of x",
+    l.funcs.register("pow16", "Calculates 16 raised to the power of n",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "n",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The exponent",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The absolute value of x",
+                desc: "- - - 16^n",
             },
         },
         func(a ...any) (any, error) {
-            return abs(
+            return pow16(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("slope", "Calculates the slope between two points",
+    l.funcs.register("sqrt", "Returns the square root of x",
         []dslParamMeta{ 
             { 
-                name: "x1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the first point",
-            },
-            { 
-                name: "y1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The y coordinate of the first point",
-            },
-            { 
-                name: "x2",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the second point",
-            },
-            { 
-                name: "y2",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y coordinate of the second point",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The slope value",
+                desc: "- - - The square root of x",
             },
         },
         func(a ...any) (any, error) {
-            return slope(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+            return sqrt(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("tan-of-slope", "Calculates the angle from a slope value",
+    l.funcs.register("log", "Returns the natural logarithm of x",
         []dslParamMeta{ 
             { 
-                name: "m",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The slope value",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The angle in radians",
+                desc: "- - - The natural logarithm of x",
             },
         },
         func(a ...any) (any, error) {
-            return tanOfSlope(
+            return logX(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("hypotenuse-of-triangle", "Calculates hypotenuse from adjacent, opposite and gamma angle",
+    l.funcs.register("random-range", "Returns a random number between min and max",
         []dslParamMeta{ 
             { 
-                name: "adjacent",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The adjacent side length",
-            },
-            { 
-                name: "opposite",
+                name: "min",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "The minimum value",
             },
             { 
-                name: "gamma",
+                name: "max",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The gamma angle",
+                desc: "The maximum value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The hypotenuse length",
+                desc: "- - - A random float64 value between min and max, with NaN handling",
             },
         },
         func(a ...any) (any, error) {
-            return hypotenuseOfTriangle(
+            return randomRange(
                 a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("adjacent-of-triangle", "Calculates adjacent side from hypotenuse, opposite and alpha angle",
+    l.funcs.register("or", "Returns either value1 or value2 randomly",
         []dslParamMeta{ 
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The hypotenuse length",
-            },
-            { 
-                name: "opposite",
+                name: "value1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "The first value",
             },
             { 
-                name: "alpha",
+                name: "value2",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The alpha angle",
+                desc: "The second value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The adjacent side length",
+                desc: "- - - One of the two input values randomly",
             },
         },
         func(a ...any) (any, error) {
-            return adjacentOfTriangle(
+            return randomOr(
                 a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("opposite-of-triangle", "Calculates opposite side from hypotenuse, adjacent and beta angle",
+    l.funcs.register("degrees2radians", "converts degrees to radians",
         []dslParamMeta{ 
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The hypotenuse length",
-            },
-            { 
-                name: "adjacent",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The adjacent side length",
-            },
-            { 
-                name: "beta",
+                name: "degrees",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The beta angle",
+                desc: "The angle in degrees",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The opposite side length",
+                desc: "- - - angle in radians",
             },
         },
         func(a ...any) (any, error) {
-            return oppositeOfTriangle(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+            return degrees2Radians(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("circumference-of-a_circle", "Calculates circumference from radius",
+    l.funcs.register("grads2radians", "converts grads to radians",
         []dslParamMeta{ 
             { 
-                name: "radius",
+                name: "grads",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The radius of the circle",
+                desc: "The angle in grads",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The circumference length",
+                desc: "- - - angle in radians",
             },
         },
         func(a ...any) (any, error) {
-            return circumferenceOfACircle(
+            return grads2Radians(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("distance-between", "Calculates distance between two points",
+    l.funcs.register("radians2degrees", "converts radians to degrees",
         []dslParamMeta{ 
             { 
-                name: "x1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the first point",
-            },
-            { 
-                name: "y1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The y coordinate of the first point",
-            },
-            { 
-                name: "x2",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the second point",
-            },
-            { 
-                name: "y2",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y coordinate of the second point",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The distance between the points",
+                desc: "- - - angle in degrees",
             },
         },
         func(a ...any) (any, error) {
-            return distanceBetween(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+            return radians2Degrees(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("angle-between", "Calculates angle between two points",
+    l.funcs.register("radians2grads", "converts radians to grads",
         []dslParamMeta{ 
             { 
-                name: "x1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the first point",
-            },
-            { 
-                name: "y1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The y coordinate of the first point",
-            },
-            { 
-                name: "x2",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The x coordinate of the second point",
-            },
-            { 
-                name: "y2",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The y coordinate of the second point",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The angle in radians",
+                desc: "- - - angle in grads",
             },
         },
         func(a ...any) (any, error) {
-            return angleBetween(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+            return radians2Grads(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("square", "Calculates the square of a number",
+    l.funcs.register("normalize-angle", "normalizes an angle to [0, 2π)",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The square of x",
+                desc: "- - - normalized angle in radians",
             },
         },
         func(a ...any) (any, error) {
-            return square(
+            return normalizeAngle(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("pow", "Calculates base raised to the power of n, using lookup tables for integer bases when possible",
+    l.funcs.register("normalize-angle-degrees", "normalizes an angle to [0, 360)",
         []dslParamMeta{ 
             { 
-                name: "base",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The base value",
-            },
-            { 
-                name: "n",
+                name: "degrees",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The angle in degrees",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - Base^n",
+                desc: "- - - normalized angle in degrees",
             },
         },
         func(a ...any) (any, error) {
-            return pow(
-                a[0].(float64),
-                a[1].(float64), 
+            return normalizeAngleDegrees(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("pow2", "Calculates 2 raised to the power of n",
+    l.funcs.register("angle-difference", "calculates the smallest difference between two angles",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "angle1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The first angle in radians",
+            },
+            { 
+                name: "angle2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 2^n",
+                desc: "- - - smallest angle difference in radians",
             },
         },
         func(a ...any) (any, error) {
-            return pow2(
-                a[0].(float64), 
+            return angleDifference(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("pow4", "Calculates 4 raised to the power of n",
+    l.funcs.register("angle-difference-degrees", "calculates the smallest difference between two angles in degrees",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "angle1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The first angle in degrees",
+            },
+            { 
+                name: "angle2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second angle in degrees",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 4^n",
+                desc: "- - - smallest angle difference in degrees",
             },
         },
         func(a ...any) (any, error) {
-            return pow4(
-                a[0].(float64), 
+            return angleDifferenceDegrees(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("pow8", "Calculates 8 raised to the power of n",
+    l.funcs.register("sin", "calculates the sine of an angle",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 8^n",
+                desc: "- - - sine value between -1 and 1",
             },
         },
         func(a ...any) (any, error) {
-            return pow8(
+            return sin(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("pow10", "Calculates 10 raised to the power of n",
+    l.funcs.register("asin", "calculates the arcsine (inverse sine) of x",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 10^n",
+                desc: "- - - angle in radians between -PI/2 and PI/2",
             },
         },
         func(a ...any) (any, error) {
-            return pow10(
+            return asin(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("pow12", "Calculates 12 raised to the power of n",
+    l.funcs.register("cos", "calculates the cosine of an angle in radians",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 12^n",
+                desc: "- - - cosine value between -1 and 1",
             },
         },
         func(a ...any) (any, error) {
-            return pow12(
+            return cos(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("pow16", "Calculates 16 raised to the power of n",
+    l.funcs.register("acos", "calculates the arccosine (inverse cosine) of x",
         []dslParamMeta{ 
             { 
-                name: "n",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The exponent",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - 16^n",
+                desc: "- - - angle in radians between 0 and PI",
             },
         },
         func(a ...any) (any, error) {
-            return pow16(
+            return acos(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("sqrt", "Returns the square root of x",
+    l.funcs.register("tan", "calculates the tangent of an angle in radians",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The square root of x",
+                desc: "- - - tangent value (unbounded)",
             },
         },
         func(a ...any) (any, error) {
-            return sqrt(
+            return tan(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("log", "Returns the natural logarithm of x",
+    l.funcs.register("atan", "calculates the arctangent (inverse tangent) of x",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
                 desc: "The input value",
@@ -7794,289 +10128,261 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The natural logarithm of x",
+                desc: "- - - angle in radians between -PI/2 and PI/2",
             },
         },
         func(a ...any) (any, error) {
-            return logX(
+            return atan(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("random-range", "Returns a random number between min and max",
+    l.funcs.register("sec", "calculates the secant of an angle in radians",
         []dslParamMeta{ 
             { 
-                name: "min",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The minimum value",
-            },
-            { 
-                name: "max",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The maximum value",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A random float64 value between min and max, with NaN handling",
+                desc: "- - - secant value (1/cos)",
             },
         },
         func(a ...any) (any, error) {
-            return randomRange(
-                a[0].(float64),
-                a[1].(float64), 
+            return sec(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("or", "Returns either value1 or value2 randomly",
+    l.funcs.register("cosec", "calculates the cosecant of an angle in radians",
         []dslParamMeta{ 
             { 
-                name: "value1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first value",
-            },
-            { 
-                name: "value2",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second value",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - One of the two input values randomly",
+                desc: "- - - cosecant value (1/sin)",
             },
         },
         func(a ...any) (any, error) {
-            return randomOr(
-                a[0].(float64),
-                a[1].(float64), 
+            return cosec(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("degrees2radians", "converts degrees to radians",
+    l.funcs.register("cot", "calculates the cotangent of an angle in radians",
         []dslParamMeta{ 
             { 
-                name: "degrees",
+                name: "radians",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in degrees",
+                desc: "The angle in radians",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in radians",
+                desc: "- - - cotangent value (1/tan)",
             },
         },
         func(a ...any) (any, error) {
-            return degrees2Radians(
+            return cot(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("grads2radians", "converts grads to radians",
+    l.funcs.register("sinh", "calculates the hyperbolic sine of x",
         []dslParamMeta{ 
             { 
-                name: "grads",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in grads",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in radians",
+                desc: "- - - hyperbolic sine value",
             },
         },
         func(a ...any) (any, error) {
-            return grads2Radians(
+            return sinh(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("radians2degrees", "converts radians to degrees",
+    l.funcs.register("cosh", "calculates the hyperbolic cosine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in degrees",
+                desc: "- - - hyperbolic cosine value (always positive)",
             },
         },
         func(a ...any) (any, error) {
-            return radians2Degrees(
+            return cosh(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("radians2grads", "converts radians to grads",
+    l.funcs.register("tanh", "calculates the hyperbolic tangent of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in grads",
+                desc: "- - - hyperbolic tangent value between -1 and 1",
             },
         },
         func(a ...any) (any, error) {
-            return radians2Grads(
+            return tanh(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("normalize-angle", "normalizes an angle to [0, 2π)",
+    l.funcs.register("sech", "calculates the hyperbolic secant of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - normalized angle in radians",
+                desc: "- - - hyperbolic secant value (1/cosh)",
             },
         },
         func(a ...any) (any, error) {
-            return normalizeAngle(
+            return sech(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("normalize-angle-degrees", "normalizes an angle to [0, 360)",
+    l.funcs.register("csch", "calculates the hyperbolic cosecant of x",
         []dslParamMeta{ 
             { 
-                name: "degrees",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in degrees",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - normalized angle in degrees",
+                desc: "- - - hyperbolic cosecant value (1/sinh)",
             },
         },
         func(a ...any) (any, error) {
-            return normalizeAngleDegrees(
+            return csch(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("angle-difference", "calculates the smallest difference between two angles",
+    l.funcs.register("coth", "calculates the hyperbolic cotangent of x",
         []dslParamMeta{ 
             { 
-                name: "angle1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first angle in radians",
-            },
-            { 
-                name: "angle2",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - smallest angle difference in radians",
+                desc: "- - - hyperbolic cotangent value (1/tanh)",
             },
         },
         func(a ...any) (any, error) {
-            return angleDifference(
-                a[0].(float64),
-                a[1].(float64), 
+            return coth(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("angle-difference-degrees", "calculates the smallest difference between two angles in degrees",
+    l.funcs.register("asinh", "calculates the inverse hyperbolic sine of x",
         []dslParamMeta{ 
             { 
-                name: "angle1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first angle in degrees",
-            },
-            { 
-                name: "angle2",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The second angle in degrees",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - smallest angle difference in degrees",
+                desc: "- - - inverse hyperbolic sine value",
             },
         },
         func(a ...any) (any, error) {
-            return angleDifferenceDegrees(
-                a[0].(float64),
-                a[1].(float64), 
+            return asinh(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("sin", "calculates the sine of an angle",
+    l.funcs.register("acosh", "calculates the inverse hyperbolic cosine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - sine value between -1 and 1",
+                desc: "- - - inverse hyperbolic cosine value (x >= 1)",
             },
         },
         func(a ...any) (any, error) {
-            return sin(
+            return acosh(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("asin", "calculates the arcsine (inverse sine) of x",
+    l.funcs.register("atanh", "calculates the inverse hyperbolic tangent of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
                 desc: "The input value",
@@ -8086,41 +10392,41 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in radians between -PI/2 and PI/2",
+                desc: "- - - inverse hyperbolic tangent value (|x| < 1)",
             },
         },
         func(a ...any) (any, error) {
-            return asin(
+            return atanh(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("cos", "calculates the cosine of an angle in radians",
+    l.funcs.register("asech", "calculates the inverse hyperbolic secant of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cosine value between -1 and 1",
+                desc: "- - - inverse hyperbolic secant value (0 < x <= 1)",
             },
         },
         func(a ...any) (any, error) {
-            return cos(
+            return asech(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("acos", "calculates the arccosine (inverse cosine) of x",
+    l.funcs.register("acsch", "calculates the inverse hyperbolic cosecant of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
                 desc: "The input value",
@@ -8130,41 +10436,41 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in radians between 0 and PI",
+                desc: "- - - inverse hyperbolic cosecant value (x != 0)",
             },
         },
         func(a ...any) (any, error) {
-            return acos(
+            return acsch(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("tan", "calculates the tangent of an angle in radians",
+    l.funcs.register("acoth", "calculates the inverse hyperbolic cotangent of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - tangent value (unbounded)",
+                desc: "- - - inverse hyperbolic cotangent value (|x| > 1)",
             },
         },
         func(a ...any) (any, error) {
-            return tan(
+            return acoth(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("atan", "calculates the arctangent (inverse tangent) of x",
+    l.funcs.register("versin", "calculates the versed sine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
                 desc: "The input value",
@@ -8174,82 +10480,82 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - angle in radians between -PI/2 and PI/2",
+                desc: "- - - versed sine value (1 - cos(x))",
             },
         },
         func(a ...any) (any, error) {
-            return atan(
+            return versin(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("sec", "calculates the secant of an angle in radians",
+    l.funcs.register("vercos", "calculates the versed cosine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - secant value (1/cos)",
+                desc: "- - - versed cosine value (1 + cos(x))",
             },
         },
         func(a ...any) (any, error) {
-            return sec(
+            return vercos(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("cosec", "calculates the cosecant of an angle in radians",
+    l.funcs.register("coversin", "calculates the coversed sine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cosecant value (1/sin)",
+                desc: "- - - coversed sine value (1 - sin(x))",
             },
         },
         func(a ...any) (any, error) {
-            return cosec(
+            return coversin(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("cot", "calculates the cotangent of an angle in radians",
+    l.funcs.register("covercos", "calculates the coversed cosine of x",
         []dslParamMeta{ 
             { 
-                name: "radians",
+                name: "x",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The angle in radians",
+                desc: "The input value",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cotangent value (1/tan)",
+                desc: "- - - coversed cosine value (1 + sin(x))",
             },
         },
         func(a ...any) (any, error) {
-            return cot(
+            return covercos(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("sinh", "calculates the hyperbolic sine of x",
+    l.funcs.register("haversin", "calculates the haversine of x",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8262,16 +10568,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic sine value",
+                desc: "- - - haversine value (0.5 * (1 - cos(x)))",
             },
         },
         func(a ...any) (any, error) {
-            return sinh(
+            return haversin(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("cosh", "calculates the hyperbolic cosine of x",
+    l.funcs.register("exsec", "calculates the exsecant of x",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8284,16 +10590,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic cosine value (always positive)",
+                desc: "- - - exsecant value (sec(x) - 1)",
             },
         },
         func(a ...any) (any, error) {
-            return cosh(
+            return exsec(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("tanh", "calculates the hyperbolic tangent of x",
+    l.funcs.register("excsc", "calculates the excosecant of x",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8306,16 +10612,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic tangent value between -1 and 1",
+                desc: "- - - excosecant value (cosec(x) - 1)",
             },
         },
         func(a ...any) (any, error) {
-            return tanh(
+            return excsc(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("sech", "calculates the hyperbolic secant of x",
+    l.funcs.register("chord", "calculates the chord of x",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8328,170 +10634,226 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic secant value (1/cosh)",
+                desc: "- - - chord value (2 * sin(x/2))",
             },
         },
         func(a ...any) (any, error) {
-            return sech(
+            return chord(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("csch", "calculates the hyperbolic cosecant of x",
+    l.funcs.register("sin-of-triangle", "calculates sine using opposite and hypotenuse sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "opposite",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The opposite side length",
+            },
+            { 
+                name: "hypotenuse",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The hypotenuse length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic cosecant value (1/sinh)",
+                desc: "- - - sine value (opposite/hypotenuse)",
             },
         },
         func(a ...any) (any, error) {
-            return csch(
-                a[0].(float64), 
+            return sinOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("coth", "calculates the hyperbolic cotangent of x",
+    l.funcs.register("cos-of-triangle", "calculates cosine using adjacent and hypotenuse sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "adjacent",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The adjacent side length",
+            },
+            { 
+                name: "hypotenuse",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The hypotenuse length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - hyperbolic cotangent value (1/tanh)",
+                desc: "- - - cosine value (adjacent/hypotenuse)",
             },
         },
         func(a ...any) (any, error) {
-            return coth(
-                a[0].(float64), 
+            return cosOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("asinh", "calculates the inverse hyperbolic sine of x",
+    l.funcs.register("tan-of-triangle", "calculates tangent using opposite and adjacent sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "opposite",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The opposite side length",
+            },
+            { 
+                name: "adjacent",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The adjacent side length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic sine value",
+                desc: "- - - tangent value (opposite/adjacent)",
             },
         },
         func(a ...any) (any, error) {
-            return asinh(
-                a[0].(float64), 
+            return tanOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("acosh", "calculates the inverse hyperbolic cosine of x",
+    l.funcs.register("sec-of-triangle", "calculates secant using hypotenuse and adjacent sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "hypotenuse",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The hypotenuse length",
+            },
+            { 
+                name: "adjacent",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The adjacent side length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic cosine value (x >= 1)",
+                desc: "- - - secant value (hypotenuse/adjacent)",
             },
         },
         func(a ...any) (any, error) {
-            return acosh(
-                a[0].(float64), 
+            return secOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("atanh", "calculates the inverse hyperbolic tangent of x",
+    l.funcs.register("cosec-of-triangle", "calculates cosecant using hypotenuse and opposite sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "hypotenuse",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The hypotenuse length",
+            },
+            { 
+                name: "opposite",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The opposite side length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic tangent value (|x| < 1)",
+                desc: "- - - cosecant value (hypotenuse/opposite)",
             },
         },
         func(a ...any) (any, error) {
-            return atanh(
-                a[0].(float64), 
+            return cosecOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("asech", "calculates the inverse hyperbolic secant of x",
+    l.funcs.register("cot-of-triangle", "calculates cotangent using adjacent and opposite sides",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "adjacent",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The adjacent side length",
+            },
+            { 
+                name: "opposite",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The opposite side length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic secant value (0 < x <= 1)",
+                desc: "- - - cotangent value (adjacent/opposite)",
             },
         },
         func(a ...any) (any, error) {
-            return asech(
-                a[0].(float64), 
+            return cotOfTriangle(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("acsch", "calculates the inverse hyperbolic cosecant of x",
+    l.funcs.register("radians-of-triangle", "calculates angle in radians using all three sides of a triangle",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "adjacent",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The adjacent side length",
+            },
+            { 
+                name: "opposite",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The opposite side length",
+            },
+            { 
+                name: "hypotenuse",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The hypotenuse length",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic cosecant value (x != 0)",
+                desc: "- - - angle in radians between adjacent and opposite sides",
             },
         },
         func(a ...any) (any, error) {
-            return acsch(
-                a[0].(float64), 
+            return radiansOfTriangle(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("acoth", "calculates the inverse hyperbolic cotangent of x",
+    l.funcs.register("sin2", "calculates the square of sine (sin²(x))",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8504,16 +10866,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - inverse hyperbolic cotangent value (|x| > 1)",
+                desc: "- - - squared sine value (sin(x)²)",
             },
         },
         func(a ...any) (any, error) {
-            return acoth(
+            return sin2(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("versin", "calculates the versed sine of x",
+    l.funcs.register("cos2", "calculates the square of cosine (cos²(x))",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8526,16 +10888,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - versed sine value (1 - cos(x))",
+                desc: "- - - squared cosine value (cos(x)²)",
             },
         },
         func(a ...any) (any, error) {
-            return versin(
+            return cos2(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("vercos", "calculates the versed cosine of x",
+    l.funcs.register("tan2", "calculates the square of tangent (tan²(x))",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8548,16 +10910,16 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - versed cosine value (1 + cos(x))",
+                desc: "- - - squared tangent value (tan(x)²)",
             },
         },
         func(a ...any) (any, error) {
-            return vercos(
+            return tan2(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("coversin", "calculates the coversed sine of x",
+    l.funcs.register("sinc", "calculates the sinc function (sin(x)/x)",
         []dslParamMeta{ 
             { 
                 name: "x",
@@ -8570,1530 +10932,1681 @@ func NewLanguage() *dslCollection {
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - coversed sine value (1 - sin(x))",
+                desc: "- - - sinc value (sin(x)/x, with sinc(0) = 1)",
             },
         },
         func(a ...any) (any, error) {
-            return coversin(
+            return sinc(
                 a[0].(float64), 
             )
         },
     )
-    l.funcs.register("covercos", "calculates the coversed cosine of x",
+    l.funcs.register("lerp-angle", "linearly interpolates between two angles in radians",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "angle1",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The first angle in radians",
+            },
+            { 
+                name: "angle2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second angle in radians",
+            },
+            { 
+                name: "t",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The interpolation factor (0-1)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - coversed cosine value (1 + sin(x))",
+                desc: "- - - interpolated angle in radians",
             },
         },
         func(a ...any) (any, error) {
-            return covercos(
-                a[0].(float64), 
+            return lerpAngle(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("haversin", "calculates the haversine of x",
+    l.funcs.register("lerp-angle-degrees", "linearly interpolates between two angles in degrees",
         []dslParamMeta{ 
             { 
-                name: "x",
+                name: "angle1",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The first angle in degrees",
+            },
+            { 
+                name: "angle2",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The second angle in degrees",
+            },
+            { 
+                name: "t",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The interpolation factor (0-1)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - haversine value (0.5 * (1 - cos(x)))",
+                desc: "- - - interpolated angle in degrees",
             },
         },
         func(a ...any) (any, error) {
-            return haversin(
-                a[0].(float64), 
+            return lerpAngleDegrees(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("exsec", "calculates the exsecant of x",
+    l.funcs.register("len", "Returns the length of the given value.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "v",
+                typ:  "any", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The value to get the length of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - exsecant value (sec(x) - 1)",
+                desc: "- - - The length of v",
             },
         },
         func(a ...any) (any, error) {
-            return exsec(
-                a[0].(float64), 
+            return len2(
+                a[0].(any), 
             )
         },
     )
-    l.funcs.register("excsc", "calculates the excosecant of x",
+    l.funcs.register("exif", "Returns the EXIF tags of an image as a map, tags are accessed by name, e.g. tags[\"Model\"]. Rationals are returned as numbers, tags with multiple values as lists.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "img",
+                typ:  "image.Image", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The image",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - excosecant value (cosec(x) - 1)",
+                desc: "- - - The EXIF tags",
             },
         },
         func(a ...any) (any, error) {
-            return excsc(
-                a[0].(float64), 
+            return exifMap(
+                a[0].(image.Image), 
             )
         },
     )
-    l.funcs.register("chord", "calculates the chord of x",
+    l.funcs.register("set-exif", "Sets an EXIF tag of an image. Text tags take the value as is, numeric tags one or more space-separated numbers.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "img",
+                typ:  "image.Image", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The image",
+            },
+            { 
+                name: "tag",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - The name of the tag, e.g. \"Artist\" or \"Copyright\"",
+            },
+            { 
+                name: "value",
+                typ:  "string", 
+                def:  "-", 
+                desc: "- - The value of the tag",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - chord value (2 * sin(x/2))",
+                desc: "- - - The image with the new tag",
             },
         },
         func(a ...any) (any, error) {
-            return chord(
-                a[0].(float64), 
+            return setEXIF(
+                a[0].(image.Image),
+                a[1].(string),
+                a[2].(string), 
             )
         },
     )
-    l.funcs.register("sin-of-triangle", "calculates sine using opposite and hypotenuse sides",
+    l.funcs.register("strip-metadata", "Removes all metadata (EXIF, XMP and optionally the ICC profile) from an image",
         []dslParamMeta{ 
             { 
-                name: "opposite",
-                typ:  "float64", 
+                name: "img",
+                typ:  "image.Image", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "The image",
             },
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The hypotenuse length",
+                name: "keepProfile",
+                typ:  "bool", 
+                def:  false, 
+                desc: "Whether to keep the ICC color profile",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - sine value (opposite/hypotenuse)",
+                desc: "- - - The image without metadata",
             },
         },
         func(a ...any) (any, error) {
-            return sinOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
+            return stripMetadata(
+                a[0].(image.Image),
+                a[1].(bool), 
             )
         },
     )
-    l.funcs.register("cos-of-triangle", "calculates cosine using adjacent and hypotenuse sides",
+    l.funcs.register("convert-profile", "Converts an image to another color profile and tags it with that profile, so it's embedded when saving. Images without a profile are treated as sRGB, colors outside of the target gamut are clipped.",
         []dslParamMeta{ 
             { 
-                name: "adjacent",
-                typ:  "float64", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The adjacent side length",
+                desc: "The image",
             },
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The hypotenuse length",
+                name: "profile",
+                typ:  "string", 
+                def:  "srgb", 
+                desc: "The target profile (srgb, display-p3, adobe-rgb, rec2020)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cosine value (adjacent/hypotenuse)",
+                desc: "- - - The converted image",
             },
         },
         func(a ...any) (any, error) {
-            return cosOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
+            return convertColorProfile(
+                a[0].(*image.NRGBA64),
+                a[1].(string), 
             )
         },
     )
-    l.funcs.register("tan-of-triangle", "calculates tangent using opposite and adjacent sides",
+    l.funcs.register("color-profile", "Returns the description of the color profile of an image, \"sRGB\" if it has none",
         []dslParamMeta{ 
             { 
-                name: "opposite",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The opposite side length",
-            },
-            { 
-                name: "adjacent",
-                typ:  "float64", 
+                name: "img",
+                typ:  "image.Image", 
                 def:  "-", 
-                desc: "The adjacent side length",
+                desc: "The image",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - tangent value (opposite/adjacent)",
+                desc: "- - - The profile description",
             },
         },
         func(a ...any) (any, error) {
-            return tanOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
+            return colorProfileName(
+                a[0].(image.Image), 
             )
         },
     )
-    l.funcs.register("sec-of-triangle", "calculates secant using hypotenuse and adjacent sides",
+    l.funcs.register("plot-data", "Renders a chart from CSV data by plotting selected columns with specified colors",
         []dslParamMeta{ 
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
+                name: "width",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The hypotenuse length",
+                desc: "Chart width in pixels",
             },
             { 
-                name: "adjacent",
-                typ:  "float64", 
+                name: "height",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The adjacent side length",
+                desc: "Chart height in pixels",
             },
-        },
-        []dslParamMeta{     
             { 
-                name: "result",
-                typ:  "error", 
-                desc: "- - - secant value (hypotenuse/adjacent)",
+                name: "data",
+                typ:  "[][]float64", 
+                def:  "-", 
+                desc: "2D array of data from CSV",
             },
-        },
-        func(a ...any) (any, error) {
-            return secOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
-            )
-        },
-    )
-    l.funcs.register("cosec-of-triangle", "calculates cosecant using hypotenuse and opposite sides",
-        []dslParamMeta{ 
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
+                name: "columns",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "The hypotenuse length",
+                desc: "Array of column indices to plot",
             },
             { 
-                name: "opposite",
-                typ:  "float64", 
+                name: "colors",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "Array of colors for each column",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cosecant value (hypotenuse/opposite)",
+                desc: "- - - The chart image",
             },
         },
         func(a ...any) (any, error) {
-            return cosecOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
+            return plotData(
+                a[0].(int),
+                a[1].(int),
+                a[2].([][]float64),
+                a[3].([]any),
+                a[4].([]any), 
             )
         },
     )
-    l.funcs.register("cot-of-triangle", "calculates cotangent using adjacent and opposite sides",
+    l.funcs.register("plot-data-compact", "Renders a chart from CSV data by plotting selected columns with specified colors. The series will be normalized to 0..1 based on their minium and maximimum value.",
         []dslParamMeta{ 
             { 
-                name: "adjacent",
-                typ:  "float64", 
+                name: "width",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The adjacent side length",
+                desc: "Chart width in pixels",
             },
             { 
-                name: "opposite",
-                typ:  "float64", 
+                name: "height",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "Chart height in pixels",
+            },
+            { 
+                name: "data",
+                typ:  "[][]float64", 
+                def:  "-", 
+                desc: "2D array of data from CSV",
+            },
+            { 
+                name: "columns",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "Array of column indices to plot",
+            },
+            { 
+                name: "colors",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "Array of colors for each column",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - cotangent value (adjacent/opposite)",
+                desc: "- - - The chart image",
             },
         },
         func(a ...any) (any, error) {
-            return cotOfTriangle(
-                a[0].(float64),
-                a[1].(float64), 
+            return plotDataCompact(
+                a[0].(int),
+                a[1].(int),
+                a[2].([][]float64),
+                a[3].([]any),
+                a[4].([]any), 
             )
         },
     )
-    l.funcs.register("radians-of-triangle", "calculates angle in radians using all three sides of a triangle",
+    l.funcs.register("plot-series", "Renders a series from CSV data by plotting a single column with colors determined by value using color stops",
         []dslParamMeta{ 
             { 
-                name: "adjacent",
-                typ:  "float64", 
+                name: "width",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The adjacent side length",
+                desc: "Chart width in pixels",
             },
             { 
-                name: "opposite",
-                typ:  "float64", 
+                name: "height",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The opposite side length",
+                desc: "Chart height in pixels",
             },
             { 
-                name: "hypotenuse",
-                typ:  "float64", 
+                name: "data",
+                typ:  "[][]float64", 
                 def:  "-", 
-                desc: "The hypotenuse length",
+                desc: "2D array of data from CSV",
             },
-        },
-        []dslParamMeta{     
             { 
-                name: "result",
-                typ:  "error", 
-                desc: "- - - angle in radians between adjacent and opposite sides",
+                name: "column",
+                typ:  "int", 
+                def:  "-", 
+                desc: "Column index to plot",
             },
-        },
-        func(a ...any) (any, error) {
-            return radiansOfTriangle(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
-            )
-        },
-    )
-    l.funcs.register("sin2", "calculates the square of sine (sin²(x))",
-        []dslParamMeta{ 
             { 
-                name: "x",
+                name: "min",
                 typ:  "float64", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "Minimum value for color mapping",
+            },
+            { 
+                name: "max",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "Maximum value for color mapping",
+            },
+            { 
+                name: "stops",
+                typ:  "[][]any", 
+                def:  "-", 
+                desc: "Color stops as [][]any where each stop is [threshold, hue, saturation, lightness, alpha]; additional fields are ignored",
+            },
+            { 
+                name: "invertY",
+                typ:  "bool", 
+                def:  false, 
+                desc: "Whether flip the y-axis when plotting",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - squared sine value (sin(x)²)",
+                desc: "- - - The chart image",
             },
         },
         func(a ...any) (any, error) {
-            return sin2(
-                a[0].(float64), 
+            return plotSeries(
+                a[0].(int),
+                a[1].(int),
+                a[2].([][]float64),
+                a[3].(int),
+                a[4].(float64),
+                a[5].(float64),
+                a[6].([][]any),
+                a[7].(bool), 
             )
         },
     )
-    l.funcs.register("cos2", "calculates the square of cosine (cos²(x))",
+    l.funcs.register("stitch", "Stitches overlapping images (e.g. 2-6 shots of a panorama, in any order) into a single image. Matching features are detected in the overlapping areas, the images are projected onto the plane of the image overlapping the others most and blended with soft seams. Works best with little parallax and a total field of view well below 180°.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "images",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "The overlapping images",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - squared cosine value (cos(x)²)",
+                desc: "- - - The stitched image",
             },
         },
         func(a ...any) (any, error) {
-            return cos2(
-                a[0].(float64), 
+            return stitch(
+                a[0].([]any), 
             )
         },
     )
-    l.funcs.register("tan2", "calculates the square of tangent (tan²(x))",
+    l.funcs.register("printf", "Prints formatted strings to the console",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "str",
+                typ:  "string", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "- - The format string",
+            },
+            { 
+                name: "a",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "A slice with the arguments",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - squared tangent value (tan(x)²)",
+                desc: "- - - Number of bytes written",
             },
         },
         func(a ...any) (any, error) {
-            return tan2(
-                a[0].(float64), 
+            return printf(
+                a[0].(string),
+                a[1].([]any), 
             )
         },
     )
-    l.funcs.register("sinc", "calculates the sinc function (sin(x)/x)",
+    l.funcs.register("printfln", "Prints formatted strings to the console",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "str",
+                typ:  "string", 
                 def:  "-", 
-                desc: "The input value",
+                desc: "- - The format string",
+            },
+            { 
+                name: "a",
+                typ:  "[]any", 
+                def:  "-", 
+                desc: "A slice with the arguments",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - sinc value (sin(x)/x, with sinc(0) = 1)",
+                desc: "- - - Number of bytes written",
             },
         },
         func(a ...any) (any, error) {
-            return sinc(
-                a[0].(float64), 
+            return printfln(
+                a[0].(string),
+                a[1].([]any), 
             )
         },
     )
-    l.funcs.register("lerp-angle", "linearly interpolates between two angles in radians",
+    l.funcs.register("sprintf", "Creates formatted strings",
         []dslParamMeta{ 
             { 
-                name: "angle1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first angle in radians",
-            },
-            { 
-                name: "angle2",
-                typ:  "float64", 
+                name: "str",
+                typ:  "string", 
                 def:  "-", 
-                desc: "The second angle in radians",
+                desc: "- - The format string",
             },
             { 
-                name: "t",
-                typ:  "float64", 
+                name: "a",
+                typ:  "[]any", 
                 def:  "-", 
-                desc: "The interpolation factor (0-1)",
+                desc: "A slice with the arguments",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - interpolated angle in radians",
+                desc: "- - - The formatted string",
             },
         },
         func(a ...any) (any, error) {
-            return lerpAngle(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+            return sprintf(
+                a[0].(string),
+                a[1].([]any), 
             )
         },
     )
-    l.funcs.register("lerp-angle-degrees", "linearly interpolates between two angles in degrees",
+    l.funcs.register("uppercase", "Uppercases a string",
         []dslParamMeta{ 
             { 
-                name: "angle1",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The first angle in degrees",
-            },
-            { 
-                name: "angle2",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The second angle in degrees",
-            },
-            { 
-                name: "t",
-                typ:  "float64", 
+                name: "str",
+                typ:  "string", 
                 def:  "-", 
-                desc: "The interpolation factor (0-1)",
+                desc: "- - The string to uppercase",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - interpolated angle in degrees",
+                desc: "- - - The uppercased string",
             },
         },
         func(a ...any) (any, error) {
-            return lerpAngleDegrees(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+            return uppercase(
+                a[0].(string), 
             )
         },
     )
-    l.funcs.register("len", "Returns the length of the given value.",
+    l.funcs.register("lowercase", "Lowercases a string",
         []dslParamMeta{ 
             { 
-                name: "v",
-                typ:  "any", 
+                name: "str",
+                typ:  "string", 
                 def:  "-", 
-                desc: "The value to get the length of",
+                desc: "- - The string to lowercase",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The length of v",
+                desc: "- - - The lowercased string",
             },
         },
         func(a ...any) (any, error) {
-            return len2(
-                a[0].(any), 
+            return lowercase(
+                a[0].(string), 
             )
         },
     )
-    l.funcs.register("plot-data", "Renders a chart from CSV data by plotting selected columns with specified colors",
+    l.funcs.register("now", "Returns the current time according to the given layout",
         []dslParamMeta{ 
             { 
-                name: "width",
-                typ:  "int", 
-                def:  "-", 
-                desc: "Chart width in pixels",
-            },
-            { 
-                name: "height",
+                name: "offset",
                 typ:  "int", 
-                def:  "-", 
-                desc: "Chart height in pixels",
-            },
-            { 
-                name: "data",
-                typ:  "[][]float64", 
-                def:  "-", 
-                desc: "2D array of data from CSV",
-            },
-            { 
-                name: "columns",
-                typ:  "[]any", 
-                def:  "-", 
-                desc: "Array of column indices to plot",
+                def:  0, 
+                desc: "The amount of seconds to offset the current time by",
             },
             { 
-                name: "colors",
-                typ:  "[]any", 
-                def:  "-", 
-                desc: "Array of colors for each column",
+                name: "layout",
+                typ:  "string", 
+                def:  "2006-01-02 15:04:05", 
+                desc: "The layout",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The chart image",
+                desc: "- - - The current time as string",
             },
         },
         func(a ...any) (any, error) {
-            return plotData(
+            return nowStr(
                 a[0].(int),
-                a[1].(int),
-                a[2].([][]float64),
-                a[3].([]any),
-                a[4].([]any), 
+                a[1].(string), 
             )
         },
     )
-    l.funcs.register("plot-data-compact", "Renders a chart from CSV data by plotting selected columns with specified colors. The series will be normalized to 0..1 based on their minium and maximimum value.",
+    l.funcs.register("P", "Creates a new point at P(x|y).",
         []dslParamMeta{ 
             { 
-                name: "width",
-                typ:  "int", 
-                def:  "-", 
-                desc: "Chart width in pixels",
-            },
-            { 
-                name: "height",
-                typ:  "int", 
-                def:  "-", 
-                desc: "Chart height in pixels",
-            },
-            { 
-                name: "data",
-                typ:  "[][]float64", 
-                def:  "-", 
-                desc: "2D array of data from CSV",
-            },
-            { 
-                name: "columns",
-                typ:  "[]any", 
-                def:  "-", 
-                desc: "Array of column indices to plot",
+                name: "x",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The start position on the x-axis",
             },
             { 
-                name: "colors",
-                typ:  "[]any", 
-                def:  "-", 
-                desc: "Array of colors for each column",
+                name: "y",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The start position on the y-axis",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The chart image",
+                desc: "- - - A new point",
             },
         },
         func(a ...any) (any, error) {
-            return plotDataCompact(
-                a[0].(int),
-                a[1].(int),
-                a[2].([][]float64),
-                a[3].([]any),
-                a[4].([]any), 
+            return makePoint(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("plot-series", "Renders a series from CSV data by plotting a single column with colors determined by value using color stops",
+    l.funcs.register("Px", "Returns the x-coordinate of a point.",
         []dslParamMeta{ 
             { 
-                name: "width",
-                typ:  "int", 
+                name: "p",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "Chart width in pixels",
+                desc: "The point to return the x-coordinate of",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "height",
-                typ:  "int", 
-                def:  "-", 
-                desc: "Chart height in pixels",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The x-coordinate of p",
             },
+        },
+        func(a ...any) (any, error) {
+            return pointX(
+                a[0].(Point), 
+            )
+        },
+    )
+    l.funcs.register("Py", "Returns the y-coordinate of a point.",
+        []dslParamMeta{ 
             { 
-                name: "data",
-                typ:  "[][]float64", 
+                name: "p",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "2D array of data from CSV",
+                desc: "The point to return the y-coordinate of",
             },
+        },
+        []dslParamMeta{     
             { 
-                name: "column",
-                typ:  "int", 
-                def:  "-", 
-                desc: "Column index to plot",
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The y-coordinate of p",
             },
+        },
+        func(a ...any) (any, error) {
+            return pointY(
+                a[0].(Point), 
+            )
+        },
+    )
+    l.funcs.register("V", "Creates a new Vector from x, y and z.",
+        []dslParamMeta{ 
             { 
-                name: "min",
+                name: "x",
                 typ:  "float64", 
-                def:  "-", 
-                desc: "Minimum value for color mapping",
+                def:  0, 
+                desc: "The x-component",
             },
             { 
-                name: "max",
+                name: "y",
                 typ:  "float64", 
-                def:  "-", 
-                desc: "Maximum value for color mapping",
-            },
-            { 
-                name: "stops",
-                typ:  "[][]any", 
-                def:  "-", 
-                desc: "Color stops as [][]any where each stop is [threshold, hue, saturation, lightness, alpha]; additional fields are ignored",
+                def:  0, 
+                desc: "The y-component",
             },
             { 
-                name: "invertY",
-                typ:  "bool", 
-                def:  false, 
-                desc: "Whether flip the y-axis when plotting",
+                name: "z",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The z-component",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The chart image",
+                desc: "- - - A new vector",
             },
         },
         func(a ...any) (any, error) {
-            return plotSeries(
-                a[0].(int),
-                a[1].(int),
-                a[2].([][]float64),
-                a[3].(int),
-                a[4].(float64),
-                a[5].(float64),
-                a[6].([][]any),
-                a[7].(bool), 
+            return makeVector(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("printf", "Prints formatted strings to the console",
+    l.funcs.register("Vx", "Returns the x-component of a vector.",
         []dslParamMeta{ 
             { 
-                name: "str",
-                typ:  "string", 
-                def:  "-", 
-                desc: "- - The format string",
-            },
-            { 
-                name: "a",
-                typ:  "[]any", 
+                name: "v",
+                typ:  "Vector", 
                 def:  "-", 
-                desc: "A slice with the arguments",
+                desc: "The vector to return the x-component of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - Number of bytes written",
+                desc: "- - - The x-component of v",
             },
         },
         func(a ...any) (any, error) {
-            return printf(
-                a[0].(string),
-                a[1].([]any), 
+            return vectorX(
+                a[0].(Vector), 
             )
         },
     )
-    l.funcs.register("printfln", "Prints formatted strings to the console",
+    l.funcs.register("Vy", "Returns the y-component of a vector.",
         []dslParamMeta{ 
             { 
-                name: "str",
-                typ:  "string", 
+                name: "v",
+                typ:  "Vector", 
                 def:  "-", 
-                desc: "- - The format string",
+                desc: "The vector to return the y-component of",
+            },
+        },
+        []dslParamMeta{     
+            { 
+                name: "result",
+                typ:  "error", 
+                desc: "- - - The y-component of vp",
             },
+        },
+        func(a ...any) (any, error) {
+            return vectorY(
+                a[0].(Vector), 
+            )
+        },
+    )
+    l.funcs.register("Vz", "Returns the z-component of a vector.",
+        []dslParamMeta{ 
             { 
-                name: "a",
-                typ:  "[]any", 
+                name: "v",
+                typ:  "Vector", 
                 def:  "-", 
-                desc: "A slice with the arguments",
+                desc: "The vector to return the z-component of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - Number of bytes written",
+                desc: "- - - The z-component of vp",
             },
         },
         func(a ...any) (any, error) {
-            return printfln(
-                a[0].(string),
-                a[1].([]any), 
+            return vectorZ(
+                a[0].(Vector), 
             )
         },
     )
-    l.funcs.register("sprintf", "Creates formatted strings",
+    l.funcs.register("R", "Creates a new rectangle with the given dimensions at P(x|y).",
         []dslParamMeta{ 
             { 
-                name: "str",
-                typ:  "string", 
+                name: "x",
+                typ:  "float64", 
                 def:  "-", 
-                desc: "- - The format string",
+                desc: "The upper-left corner of the rectangle",
             },
             { 
-                name: "a",
-                typ:  "[]any", 
+                name: "y",
+                typ:  "float64", 
                 def:  "-", 
-                desc: "A slice with the arguments",
+                desc: "The upper-left corner of the rectangle",
+            },
+            { 
+                name: "w",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The width of the rectangle",
+            },
+            { 
+                name: "h",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The width of the rectangle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The formatted string",
+                desc: "- - - A new rectangle",
             },
         },
         func(a ...any) (any, error) {
-            return sprintf(
-                a[0].(string),
-                a[1].([]any), 
+            return makeRect(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("uppercase", "Uppercases a string",
+    l.funcs.register("Rx", "Returns the x-coordinate of a rect.",
         []dslParamMeta{ 
             { 
-                name: "str",
-                typ:  "string", 
+                name: "r",
+                typ:  "Rect", 
                 def:  "-", 
-                desc: "- - The string to uppercase",
+                desc: "The rect to return the x-coordinate of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The uppercased string",
+                desc: "- - - The x-coordinate of r",
             },
         },
         func(a ...any) (any, error) {
-            return uppercase(
-                a[0].(string), 
+            return rectX(
+                a[0].(Rect), 
             )
         },
     )
-    l.funcs.register("lowercase", "Lowercases a string",
+    l.funcs.register("Ry", "Returns the y-coordinate of a rect.",
         []dslParamMeta{ 
             { 
-                name: "str",
-                typ:  "string", 
+                name: "r",
+                typ:  "Rect", 
                 def:  "-", 
-                desc: "- - The string to lowercase",
+                desc: "The rect to return the y-coordinate of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The lowercased string",
+                desc: "- - - The y-coordinate of r",
             },
         },
         func(a ...any) (any, error) {
-            return lowercase(
-                a[0].(string), 
+            return rectY(
+                a[0].(Rect), 
             )
         },
     )
-    l.funcs.register("now", "Returns the current time according to the given layout",
+    l.funcs.register("Rw", "Returns the width of a rect.",
         []dslParamMeta{ 
             { 
-                name: "offset",
-                typ:  "int", 
-                def:  0, 
-                desc: "The amount of seconds to offset the current time by",
-            },
-            { 
-                name: "layout",
-                typ:  "string", 
-                def:  "2006-01-02 15:04:05", 
-                desc: "The layout",
+                name: "r",
+                typ:  "Rect", 
+                def:  "-", 
+                desc: "The rect to return the width of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The current time as string",
+                desc: "- - - The width of r",
             },
         },
         func(a ...any) (any, error) {
-            return nowStr(
-                a[0].(int),
-                a[1].(string), 
+            return rectW(
+                a[0].(Rect), 
             )
         },
     )
-    l.funcs.register("P", "Creates a new point at P(x|y).",
+    l.funcs.register("Rh", "Returns the height of a rect.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The start position on the x-axis",
-            },
-            { 
-                name: "y",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The start position on the y-axis",
+                name: "r",
+                typ:  "Rect", 
+                def:  "-", 
+                desc: "The rect to return the height of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new point",
+                desc: "- - - The height of r",
             },
         },
         func(a ...any) (any, error) {
-            return makePoint(
-                a[0].(float64),
-                a[1].(float64), 
+            return rectH(
+                a[0].(Rect), 
             )
         },
     )
-    l.funcs.register("Px", "Returns the x-coordinate of a point.",
+    l.funcs.register("E", "Creates a new ellipse with the given radius at P(x|y).",
         []dslParamMeta{ 
             { 
-                name: "p",
-                typ:  "Point", 
+                name: "centerX",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The center of the ellipse on the x-axis",
+            },
+            { 
+                name: "centerY",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The center of the ellipse on the y-axis",
+            },
+            { 
+                name: "radiusX",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The radius of the ellipse on the x-axis",
+            },
+            { 
+                name: "radiusY",
+                typ:  "float64", 
                 def:  "-", 
-                desc: "The point to return the x-coordinate of",
+                desc: "The radius of the ellipse on the y-axis",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The x-coordinate of p",
+                desc: "- - - A new ellipse",
             },
         },
         func(a ...any) (any, error) {
-            return pointX(
-                a[0].(Point), 
+            return makeEllipse(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64), 
             )
         },
     )
-    l.funcs.register("Py", "Returns the y-coordinate of a point.",
+    l.funcs.register("Ex", "Returns the center x-coordinate of an ellipse.",
         []dslParamMeta{ 
             { 
-                name: "p",
-                typ:  "Point", 
+                name: "e",
+                typ:  "Ellipse", 
                 def:  "-", 
-                desc: "The point to return the y-coordinate of",
+                desc: "The ellipse to return the center x-coordinate of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The y-coordinate of p",
+                desc: "- - - the center x-coordinate of e",
             },
         },
         func(a ...any) (any, error) {
-            return pointY(
-                a[0].(Point), 
+            return ellipseX(
+                a[0].(Ellipse), 
             )
         },
     )
-    l.funcs.register("V", "Creates a new Vector from x, y and z.",
+    l.funcs.register("Ey", "Returns the center y-coordinate of an ellipse.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The x-component",
-            },
-            { 
-                name: "y",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The y-component",
-            },
-            { 
-                name: "z",
-                typ:  "float64", 
-                def:  0, 
-                desc: "The z-component",
+                name: "e",
+                typ:  "Ellipse", 
+                def:  "-", 
+                desc: "The ellipse to return the center y-coordinate of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new vector",
+                desc: "- - - the center y-coordinate of e",
             },
         },
         func(a ...any) (any, error) {
-            return makeVector(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+            return ellipseY(
+                a[0].(Ellipse), 
             )
         },
     )
-    l.funcs.register("Vx", "Returns the x-component of a vector.",
+    l.funcs.register("Erx", "Returns the x-component of the radius of an ellipse.",
         []dslParamMeta{ 
             { 
-                name: "v",
-                typ:  "Vector", 
+                name: "e",
+                typ:  "Ellipse", 
                 def:  "-", 
-                desc: "The vector to return the x-component of",
+                desc: "The ellipse to return the x-component of the radius of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The x-component of v",
+                desc: "- - - the x-component of the radius of e",
             },
         },
         func(a ...any) (any, error) {
-            return vectorX(
-                a[0].(Vector), 
+            return ellipseRadiusX(
+                a[0].(Ellipse), 
             )
         },
     )
-    l.funcs.register("Vy", "Returns the y-component of a vector.",
+    l.funcs.register("Ery", "Returns the y-component of the radius of an ellipse.",
         []dslParamMeta{ 
             { 
-                name: "v",
-                typ:  "Vector", 
+                name: "e",
+                typ:  "Ellipse", 
                 def:  "-", 
-                desc: "The vector to return the y-component of",
+                desc: "The ellipse to return the y-component of the radius of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The y-component of vp",
+                desc: "- - - the y-component of the radius of e",
             },
         },
         func(a ...any) (any, error) {
-            return vectorY(
-                a[0].(Vector), 
+            return ellipseRadiusY(
+                a[0].(Ellipse), 
             )
         },
     )
-    l.funcs.register("Vz", "Returns the z-component of a vector.",
+    l.funcs.register("C", "Creates a new circle with the given radius at P(x|y).",
         []dslParamMeta{ 
             { 
-                name: "v",
-                typ:  "Vector", 
+                name: "centerX",
+                typ:  "float64", 
                 def:  "-", 
-                desc: "The vector to return the z-component of",
+                desc: "The center of the circle on the x-axis",
+            },
+            { 
+                name: "centerY",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The center of the circle on the y-axis",
+            },
+            { 
+                name: "radius",
+                typ:  "float64", 
+                def:  "-", 
+                desc: "The radius of the circle",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The z-component of vp",
+                desc: "- - - A new circle",
             },
         },
         func(a ...any) (any, error) {
-            return vectorZ(
-                a[0].(Vector), 
+            return makeCircle(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64), 
             )
         },
     )
-    l.funcs.register("R", "Creates a new rectangle with the given dimensions at P(x|y).",
+    l.funcs.register("Q", "Creates a new quad from four corners, e.g. upper-left, upper-right, lower-right and lower-left.",
         []dslParamMeta{ 
             { 
-                name: "x",
-                typ:  "float64", 
+                name: "p1",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "The upper-left corner of the rectangle",
+                desc: "The first corner",
             },
             { 
-                name: "y",
-                typ:  "float64", 
+                name: "p2",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "The upper-left corner of the rectangle",
+                desc: "The second corner",
             },
             { 
-                name: "w",
-                typ:  "float64", 
+                name: "p3",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "The width of the rectangle",
+                desc: "The third corner",
             },
             { 
-                name: "h",
-                typ:  "float64", 
+                name: "p4",
+                typ:  "Point", 
                 def:  "-", 
-                desc: "The width of the rectangle",
+                desc: "The fourth corner",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new rectangle",
+                desc: "- - - A new quad",
             },
         },
         func(a ...any) (any, error) {
-            return makeRect(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+            return makeQuad(
+                a[0].(Point),
+                a[1].(Point),
+                a[2].(Point),
+                a[3].(Point), 
             )
         },
     )
-    l.funcs.register("Rx", "Returns the x-coordinate of a rect.",
+    l.funcs.register("Iw", "Returns the width of an image.",
         []dslParamMeta{ 
             { 
-                name: "r",
-                typ:  "Rect", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The rect to return the x-coordinate of",
+                desc: "The image to return the width of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The x-coordinate of r",
+                desc: "- - - The width of img",
             },
         },
         func(a ...any) (any, error) {
-            return rectX(
-                a[0].(Rect), 
+            return imageW(
+                a[0].(*image.NRGBA64), 
             )
         },
     )
-    l.funcs.register("Ry", "Returns the y-coordinate of a rect.",
+    l.funcs.register("Ih", "Returns the height of an image.",
         []dslParamMeta{ 
             { 
-                name: "r",
-                typ:  "Rect", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The rect to return the y-coordinate of",
+                desc: "The image to return the height of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The y-coordinate of r",
+                desc: "- - - The height of img",
             },
         },
         func(a ...any) (any, error) {
-            return rectY(
-                a[0].(Rect), 
+            return imageH(
+                a[0].(*image.NRGBA64), 
             )
         },
     )
-    l.funcs.register("Rw", "Returns the width of a rect.",
+    l.funcs.register("Ir", "Returns the aspect ratio of the given image",
         []dslParamMeta{ 
             { 
-                name: "r",
-                typ:  "Rect", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The rect to return the width of",
+                desc: "The image to return the aspect ratio of",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The width of r",
+                desc: "- - - Aspect ratio of the image",
             },
         },
         func(a ...any) (any, error) {
-            return rectW(
-                a[0].(Rect), 
+            return imageAspectRatio(
+                a[0].(*image.NRGBA64), 
             )
         },
     )
-    l.funcs.register("Rh", "Returns the height of a rect.",
+    l.funcs.register("IC", "Creates a new image with the given color.",
         []dslParamMeta{ 
             { 
-                name: "r",
-                typ:  "Rect", 
+                name: "w",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The rect to return the height of",
+                desc: "The width of the image",
+            },
+            { 
+                name: "h",
+                typ:  "int", 
+                def:  "-", 
+                desc: "The height of the image",
+            },
+            { 
+                name: "cFill",
+                typ:  "color.RGBA64", 
+                def:  "-", 
+                desc: "The fill color",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The height of r",
+                desc: "- - - The new image",
             },
         },
         func(a ...any) (any, error) {
-            return rectH(
-                a[0].(Rect), 
+            return makeImage(
+                a[0].(int),
+                a[1].(int),
+                a[2].(color.RGBA64), 
             )
         },
     )
-    l.funcs.register("E", "Creates a new ellipse with the given radius at P(x|y).",
+    l.funcs.register("I", "Creates a new transparent image.",
         []dslParamMeta{ 
             { 
-                name: "centerX",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The center of the ellipse on the x-axis",
-            },
-            { 
-                name: "centerY",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The center of the ellipse on the y-axis",
-            },
-            { 
-                name: "radiusX",
-                typ:  "float64", 
+                name: "w",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The radius of the ellipse on the x-axis",
+                desc: "The width of the image",
             },
             { 
-                name: "radiusY",
-                typ:  "float64", 
+                name: "h",
+                typ:  "int", 
                 def:  "-", 
-                desc: "The radius of the ellipse on the y-axis",
+                desc: "The height of the image",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new ellipse",
+                desc: "- - - The new image",
             },
         },
         func(a ...any) (any, error) {
-            return makeEllipse(
-                a[0].(float64),
-                a[1].(float64),
-                a[2].(float64),
-                a[3].(float64), 
+            return makeImageTransparent(
+                a[0].(int),
+                a[1].(int), 
             )
         },
     )
-    l.funcs.register("Ex", "Returns the center x-coordinate of an ellipse.",
+    l.funcs.register("SI", "Copies an area from a source image and returns it as a new image.",
         []dslParamMeta{ 
             { 
-                name: "e",
-                typ:  "Ellipse", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
+                def:  "-", 
+                desc: "The source image",
+            },
+            { 
+                name: "r",
+                typ:  "Rect", 
                 def:  "-", 
-                desc: "The ellipse to return the center x-coordinate of",
+                desc: "The selection to copy",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - the center x-coordinate of e",
+                desc: "- - - The new image",
             },
         },
         func(a ...any) (any, error) {
-            return ellipseX(
-                a[0].(Ellipse), 
+            return extractSubImage(
+                a[0].(*image.NRGBA64),
+                a[1].(Rect), 
             )
         },
     )
-    l.funcs.register("Ey", "Returns the center y-coordinate of an ellipse.",
+    l.funcs.register("LS", "Creates a new line style.",
         []dslParamMeta{ 
             { 
-                name: "e",
-                typ:  "Ellipse", 
+                name: "color",
+                typ:  "color.RGBA64", 
                 def:  "-", 
-                desc: "The ellipse to return the center y-coordinate of",
+                desc: "The line color",
+            },
+            { 
+                name: "thickness",
+                typ:  "float64", 
+                def:  1, 
+                unit: "1", 
+                desc: "The line thickness",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - the center y-coordinate of e",
+                desc: "- - - A new line style",
             },
         },
         func(a ...any) (any, error) {
-            return ellipseY(
-                a[0].(Ellipse), 
+            return makeLineStyle(
+                a[0].(color.RGBA64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("Erx", "Returns the x-component of the radius of an ellipse.",
+    l.funcs.register("FS", "Creates a new fill style.",
         []dslParamMeta{ 
             { 
-                name: "e",
-                typ:  "Ellipse", 
+                name: "color",
+                typ:  "color.RGBA64", 
                 def:  "-", 
-                desc: "The ellipse to return the x-component of the radius of",
+                desc: "The fill color",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - the x-component of the radius of e",
+                desc: "- - - A new fill style",
             },
         },
         func(a ...any) (any, error) {
-            return ellipseRadiusX(
-                a[0].(Ellipse), 
+            return makeFillStyle(
+                a[0].(color.RGBA64), 
             )
         },
     )
-    l.funcs.register("Ery", "Returns the y-component of the radius of an ellipse.",
+    l.funcs.register("matrix", "Creates an affine transformation matrix that maps (x, y) to (a*x + b*y + c, d*x + e*y + f). Coordinates are in pixels with the origin in the upper-left corner.",
         []dslParamMeta{ 
+            { 
+                name: "a",
+                typ:  "float64", 
+                def:  1, 
+                desc: "The horizontal scale",
+            },
+            { 
+                name: "b",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The horizontal shear",
+            },
+            { 
+                name: "c",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The horizontal translation",
+            },
+            { 
+                name: "d",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The vertical shear",
+            },
             { 
                 name: "e",
-                typ:  "Ellipse", 
-                def:  "-", 
-                desc: "The ellipse to return the y-component of the radius of",
+                typ:  "float64", 
+                def:  1, 
+                desc: "The vertical scale",
+            },
+            { 
+                name: "f",
+                typ:  "float64", 
+                def:  0, 
+                desc: "The vertical translation",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - the y-component of the radius of e",
+                desc: "- - - The matrix",
             },
         },
         func(a ...any) (any, error) {
-            return ellipseRadiusY(
-                a[0].(Ellipse), 
+            return makeMatrix(
+                a[0].(float64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(float64),
+                a[4].(float64),
+                a[5].(float64), 
             )
         },
     )
-    l.funcs.register("C", "Creates a new circle with the given radius at P(x|y).",
+    l.funcs.register("matrix-translate", "Creates a matrix that moves points",
         []dslParamMeta{ 
             { 
-                name: "centerX",
-                typ:  "float64", 
-                def:  "-", 
-                desc: "The center of the circle on the x-axis",
-            },
-            { 
-                name: "centerY",
+                name: "dx",
                 typ:  "float64", 
-                def:  "-", 
-                desc: "The center of the circle on the y-axis",
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The horizontal translation",
             },
             { 
-                name: "radius",
+                name: "dy",
                 typ:  "float64", 
-                def:  "-", 
-                desc: "The radius of the circle",
+                def:  0, 
+                unit: "\"px\"", 
+                desc: "The vertical translation",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new circle",
+                desc: "- - - The matrix",
             },
         },
         func(a ...any) (any, error) {
-            return makeCircle(
+            return matrixTranslate(
                 a[0].(float64),
-                a[1].(float64),
-                a[2].(float64), 
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("Iw", "Returns the width of an image.",
+    l.funcs.register("matrix-rotate", "Creates a matrix that rotates points around the origin",
         []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to return the width of",
+                name: "angle",
+                typ:  "float64", 
+                min:  -360, 
+                max:  360, 
+                def:  0, 
+                unit: "\"°\"", 
+                desc: "The rotation angle in degrees (positive = clockwise)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The width of img",
+                desc: "- - - The matrix",
             },
         },
         func(a ...any) (any, error) {
-            return imageW(
-                a[0].(*image.NRGBA64), 
+            return matrixRotate(
+                a[0].(float64), 
             )
         },
     )
-    l.funcs.register("Ih", "Returns the height of an image.",
+    l.funcs.register("matrix-scale", "Creates a matrix that scales points relative to the origin",
         []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to return the height of",
+                name: "sx",
+                typ:  "float64", 
+                def:  1, 
+                desc: "The horizontal scale factor (1 = unchanged)",
+            },
+            { 
+                name: "sy",
+                typ:  "float64", 
+                def:  1, 
+                desc: "The vertical scale factor (1 = unchanged)",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The height of img",
+                desc: "- - - The matrix",
             },
         },
         func(a ...any) (any, error) {
-            return imageH(
-                a[0].(*image.NRGBA64), 
+            return matrixScale(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("Ir", "Returns the aspect ratio of the given image",
+    l.funcs.register("matrix-skew", "Creates a matrix that skews (shears) points relative to the origin",
         []dslParamMeta{ 
             { 
-                name: "img",
-                typ:  "*image.NRGBA64", 
-                def:  "-", 
-                desc: "The image to return the aspect ratio of",
+                name: "ax",
+                typ:  "float64", 
+                min:  -89, 
+                max:  89, 
+                def:  0, 
+                unit: "\"°\"", 
+                desc: "The horizontal skew angle in degrees",
+            },
+            { 
+                name: "ay",
+                typ:  "float64", 
+                min:  -89, 
+                max:  89, 
+                def:  0, 
+                unit: "\"°\"", 
+                desc: "The vertical skew angle in degrees",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - Aspect ratio of the image",
+                desc: "- - - The matrix",
             },
         },
         func(a ...any) (any, error) {
-            return imageAspectRatio(
-                a[0].(*image.NRGBA64), 
+            return matrixSkew(
+                a[0].(float64),
+                a[1].(float64), 
             )
         },
     )
-    l.funcs.register("IC", "Creates a new image with the given color.",
+    l.funcs.register("matrix-compose", "Combines two transformations into one that applies a first and b second",
         []dslParamMeta{ 
             { 
-                name: "w",
-                typ:  "int", 
-                def:  "-", 
-                desc: "The width of the image",
-            },
-            { 
-                name: "h",
-                typ:  "int", 
+                name: "a",
+                typ:  "*Matrix", 
                 def:  "-", 
-                desc: "The height of the image",
+                desc: "The first transformation",
             },
             { 
-                name: "cFill",
-                typ:  "color.RGBA64", 
+                name: "b",
+                typ:  "*Matrix", 
                 def:  "-", 
-                desc: "The fill color",
+                desc: "The second transformation",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The new image",
+                desc: "- - - The combined matrix",
             },
         },
         func(a ...any) (any, error) {
-            return makeImage(
-                a[0].(int),
-                a[1].(int),
-                a[2].(color.RGBA64), 
+            return matrixCompose(
+                a[0].(*Matrix),
+                a[1].(*Matrix), 
             )
         },
     )
-    l.funcs.register("I", "Creates a new transparent image.",
+    l.funcs.register("matrix-invert", "Inverts a matrix, so the result undoes the transformation",
         []dslParamMeta{ 
             { 
-                name: "w",
-                typ:  "int", 
-                def:  "-", 
-                desc: "The width of the image",
-            },
-            { 
-                name: "h",
-                typ:  "int", 
+                name: "m",
+                typ:  "*Matrix", 
                 def:  "-", 
-                desc: "The height of the image",
+                desc: "The matrix to invert",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The new image",
+                desc: "- - - The inverted matrix",
             },
         },
         func(a ...any) (any, error) {
-            return makeImageTransparent(
-                a[0].(int),
-                a[1].(int), 
+            return matrixInvert(
+                a[0].(*Matrix), 
             )
         },
     )
-    l.funcs.register("SI", "Copies an area from a source image and returns it as a new image.",
+    l.funcs.register("warp-affine", "Transforms an image with a matrix, e.g. matrix-compose(matrix-rotate(10) matrix-translate(20 0)). The result has the size of the image, areas without source pixels are transparent.",
         []dslParamMeta{ 
             { 
                 name: "img",
                 typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The source image",
+                desc: "The image to warp",
             },
             { 
-                name: "r",
-                typ:  "Rect", 
+                name: "m",
+                typ:  "*Matrix", 
                 def:  "-", 
-                desc: "The selection to copy",
+                desc: "The transformation matrix, mapping source to result coordinates",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - The new image",
+                desc: "- - - The warped image",
             },
         },
         func(a ...any) (any, error) {
-            return extractSubImage(
+            return warpAffine(
                 a[0].(*image.NRGBA64),
-                a[1].(Rect), 
+                a[1].(*Matrix),
+                a[2].(string), 
             )
         },
     )
-    l.funcs.register("LS", "Creates a new line style.",
+    l.funcs.register("warp-perspective", "Applies a perspective transformation that moves the corners of srcQuad to the corners of dstQuad, e.g. to correct keystone distortion by mapping the corners of a tilted facade to a rectangle. The result has the size of the image, areas without source pixels are transparent.",
         []dslParamMeta{ 
             { 
-                name: "color",
-                typ:  "color.RGBA64", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The line color",
+                desc: "The image to warp",
             },
             { 
-                name: "thickness",
-                typ:  "float64", 
-                def:  1, 
-                unit: "1", 
-                desc: "The line thickness",
+                name: "srcQuad",
+                typ:  "Quad", 
+                def:  "-", 
+                desc: "The corners in the image",
+            },
+            { 
+                name: "dstQuad",
+                typ:  "Quad", 
+                def:  "-", 
+                desc: "Where the corners end up, in the same order",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new line style",
+                desc: "- - - The warped image",
             },
         },
         func(a ...any) (any, error) {
-            return makeLineStyle(
-                a[0].(color.RGBA64),
-                a[1].(float64), 
+            return warpPerspective(
+                a[0].(*image.NRGBA64),
+                a[1].(Quad),
+                a[2].(Quad),
+                a[3].(string), 
             )
         },
     )
-    l.funcs.register("FS", "Creates a new fill style.",
+    l.funcs.register("skew", "Skews (shears) an image. The result is enlarged to fit the skewed image.",
         []dslParamMeta{ 
             { 
-                name: "color",
-                typ:  "color.RGBA64", 
+                name: "img",
+                typ:  "*image.NRGBA64", 
                 def:  "-", 
-                desc: "The fill color",
+                desc: "The image to skew",
+            },
+            { 
+                name: "ax",
+                typ:  "float64", 
+                min:  -89, 
+                max:  89, 
+                def:  0, 
+                unit: "\"°\"", 
+                desc: "The horizontal skew angle in degrees, positive values shift the bottom to the right",
+            },
+            { 
+                name: "ay",
+                typ:  "float64", 
+                min:  -89, 
+                max:  89, 
+                def:  0, 
+                unit: "\"°\"", 
+                desc: "The vertical skew angle in degrees, positive values shift the right side down",
+            },
+            { 
+                name: "filter",
+                typ:  "string", 
+                def:  "bicubic", 
+                desc: "The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area",
             },
         },
         []dslParamMeta{     
             { 
                 name: "result",
                 typ:  "error", 
-                desc: "- - - A new fill style",
+                desc: "- - - The skewed image",
             },
         },
         func(a ...any) (any, error) {
-            return makeFillStyle(
-                a[0].(color.RGBA64), 
+            return skew(
+                a[0].(*image.NRGBA64),
+                a[1].(float64),
+                a[2].(float64),
+                a[3].(string), 
             )
         },
     )
@@ -0,0 +1,253 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+// defaultFrameDelay is used for frames of sources without timing information.
+const defaultFrameDelay = 100
+
+// DecodeFrames decodes all frames of an animation. Animated GIFs, PNGs
+// (APNG) and WebPs are composited frame by frame, multi-page TIFFs return one frame per
+// page and all other images are returned as a single frame.
+func DecodeFrames(data []byte) (*Frames, error) {
+	switch DetectImageType(data) {
+	case "gif":
+		return decodeGIFFrames(data)
+	case "png":
+		if frames, err := decodeAPNGFrames(data); err != nil || frames != nil {
+			return frames, err
+		}
+	case "webp":
+		if frames, err := decodeWebPFrames(data); err != nil || frames != nil {
+			return frames, err
+		}
+	}
+	imgs, err := DecodeImages(data)
+	if err != nil {
+		return nil, err
+	}
+	frames := NewFrames()
+	for _, img := range imgs {
+		frames.Add(imageToNRGBA64(img), defaultFrameDelay)
+	}
+	return frames, nil
+}
+
+func decodeGIFFrames(data []byte) (*Frames, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode GIF: %v", err)
+	}
+	canvas := IFromBounds(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	frames := NewFrames()
+	frames.LoopCount = g.LoopCount
+	for i, src := range g.Image {
+		disposal := DisposalNone
+		if i < len(g.Disposal) {
+			switch g.Disposal[i] {
+			case gif.DisposalBackground:
+				disposal = DisposalBackground
+			case gif.DisposalPrevious:
+				disposal = DisposalPrevious
+			}
+		}
+		var previous *image.NRGBA64
+		if disposal == DisposalPrevious {
+			previous = cloneNRGBA64(canvas)
+		}
+		draw.Draw(canvas, src.Bounds(), src, src.Bounds().Min, draw.Over)
+
+		delay := defaultFrameDelay
+		if i < len(g.Delay) && g.Delay[i] > 0 {
+			delay = g.Delay[i] * 10
+		}
+		frames.Add(cloneNRGBA64(canvas), delay)
+		frames.Disposal[i] = disposal
+
+		switch disposal {
+		case DisposalBackground:
+			draw.Draw(canvas, src.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, nil
+}
+
+// decodeAPNGFrames decodes an animated PNG. It returns nil frames without an
+// error if data is a regular PNG without animation control chunk.
+func decodeAPNGFrames(data []byte) (*Frames, error) {
+	chunks, err := pngChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ihdr     []byte
+		shared   [][]byte // ancillary chunks (PLTE, tRNS, gAMA, ...) shared by all frames
+		frames   *Frames
+		canvas   *image.NRGBA64
+		fctl     []byte
+		frame    []byte
+		seenIDAT bool
+	)
+
+	flush := func() error {
+		if fctl == nil {
+			return nil
+		}
+		w, h := int(binary.BigEndian.Uint32(fctl[4:])), int(binary.BigEndian.Uint32(fctl[8:]))
+		x, y := int(binary.BigEndian.Uint32(fctl[12:])), int(binary.BigEndian.Uint32(fctl[16:]))
+		delayNum, delayDen := int(binary.BigEndian.Uint16(fctl[20:])), int(binary.BigEndian.Uint16(fctl[22:]))
+		disposeOp, blendOp := fctl[24], fctl[25]
+		if delayDen == 0 {
+			delayDen = 100
+		}
+
+		hdr := bytes.Clone(ihdr)
+		binary.BigEndian.PutUint32(hdr[0:], uint32(w))
+		binary.BigEndian.PutUint32(hdr[4:], uint32(h))
+		var buf bytes.Buffer
+		buf.Write(pngSignature)
+		writePNGChunk(&buf, "IHDR", hdr)
+		for _, c := range shared {
+			buf.Write(c)
+		}
+		writePNGChunk(&buf, "IDAT", frame)
+		writePNGChunk(&buf, "IEND", nil)
+		src, err := png.Decode(&buf)
+		if err != nil {
+			return fmt.Errorf("failed to decode APNG frame %d: %v", frames.Len()+1, err)
+		}
+
+		rect := image.Rect(x, y, x+w, y+h)
+		var previous *image.NRGBA64
+		if disposeOp == 2 {
+			previous = cloneNRGBA64(canvas)
+		}
+		op := draw.Src
+		if blendOp == 1 {
+			op = draw.Over
+		}
+		draw.Draw(canvas, rect, src, src.Bounds().Min, op)
+		frames.Add(cloneNRGBA64(canvas), delayNum*1000/delayDen)
+		frames.Disposal[frames.Len()-1] = int(disposeOp)
+
+		switch disposeOp {
+		case 1:
+			draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+		case 2:
+			canvas = previous
+		}
+		fctl, frame = nil, nil
+		return nil
+	}
+
+	for _, c := range chunks {
+		typ, body := string(c[4:8]), c[8:len(c)-4]
+		switch typ {
+		case "IHDR":
+			if len(body) < 13 {
+				return nil, fmt.Errorf("invalid PNG header")
+			}
+			ihdr = bytes.Clone(body)
+		case "acTL":
+			if len(body) < 8 || ihdr == nil {
+				return nil, fmt.Errorf("invalid APNG animation control chunk")
+			}
+			frames = NewFrames()
+			// APNG counts plays, GIF-style loop counts count repetitions
+			if plays := int(binary.BigEndian.Uint32(body[4:])); plays > 0 {
+				frames.LoopCount = plays - 1
+				if plays == 1 {
+					frames.LoopCount = -1
+				}
+			}
+			canvas = IFromBounds(image.Rect(0, 0, int(binary.BigEndian.Uint32(ihdr[0:])), int(binary.BigEndian.Uint32(ihdr[4:]))))
+		case "fcTL":
+			if frames == nil {
+				continue
+			}
+			if len(body) < 26 {
+				return nil, fmt.Errorf("invalid APNG frame control chunk")
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			fctl = bytes.Clone(body)
+		case "IDAT":
+			seenIDAT = true
+			if fctl != nil {
+				frame = append(frame, body...)
+			}
+		case "fdAT":
+			if fctl != nil && len(body) > 4 {
+				frame = append(frame, body[4:]...)
+			}
+		case "IEND":
+		default:
+			if !seenIDAT {
+				shared = append(shared, c)
+			}
+		}
+	}
+	if frames == nil {
+		return nil, nil
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if frames.Len() == 0 {
+		return nil, fmt.Errorf("APNG contains no frames")
+	}
+	return frames, nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngChunks splits a PNG stream into its chunks (length, type, data and CRC).
+func pngChunks(data []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+	var chunks [][]byte
+	for pos := len(pngSignature); pos+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + n
+		if n < 0 || end > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk")
+		}
+		chunks = append(chunks, data[pos:end])
+		pos = end
+	}
+	return chunks, nil
+}
+
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(data)))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	chunk := append(append(append(b[:], typ...), data...), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(chunk[len(chunk)-4:], crc.Sum32())
+	_, err := w.Write(chunk)
+	return err
+}
+
+func cloneNRGBA64(img *image.NRGBA64) *image.NRGBA64 {
+	return &image.NRGBA64{
+		Pix:    bytes.Clone(img.Pix),
+		Stride: img.Stride,
+		Rect:   img.Rect,
+	}
+}
@@ -0,0 +1,462 @@
+package language
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"strings"
+
+	"github.com/toxyl/math"
+)
+
+const (
+	FormatAPNG = "apng"
+
+	PaletteLocal  = "local"  // One palette per frame
+	PaletteGlobal = "global" // One palette shared by all frames
+)
+
+// AnimationOptions configures how frames are encoded.
+// Zero values select the defaults (except for Dither).
+type AnimationOptions struct {
+	Format  string // gif, apng or webp; derived from the path if empty
+	Colors  int    // GIF palette size (2..256)
+	Dither  bool   // GIF Floyd-Steinberg dithering
+	Palette string // GIF palette mode (local, global)
+	Depth   int    // APNG bits per channel (8 or 16)
+}
+
+func DefaultAnimationOptions() AnimationOptions {
+	return AnimationOptions{
+		Colors:  256,
+		Dither:  true,
+		Palette: PaletteLocal,
+		Depth:   8,
+	}
+}
+
+// AnimationFormatFromPath returns the animation format matching the
+// extension of path or an empty string if the extension is unknown.
+func AnimationFormatFromPath(path string) string {
+	switch FormatFromPath(path) {
+	case FormatGIF:
+		return FormatGIF
+	case FormatPNG:
+		return FormatAPNG
+	case FormatWebP:
+		return FormatWebP
+	}
+	if strings.EqualFold(strings.TrimSpace(path[strings.LastIndex(path, ".")+1:]), "apng") {
+		return FormatAPNG
+	}
+	return ""
+}
+
+// EncodeAnimation writes frames to w using the format and options given in opts.
+// opts.Format must be set. Frames of different sizes are placed at the top
+// left of a canvas covering all frames.
+func EncodeAnimation(w io.Writer, frames *Frames, opts AnimationOptions) error {
+	if frames == nil || frames.Len() == 0 {
+		return fmt.Errorf("animation has no frames")
+	}
+	defaults := DefaultAnimationOptions()
+	if opts.Colors <= 0 {
+		opts.Colors = defaults.Colors
+	}
+	if opts.Palette == "" {
+		opts.Palette = defaults.Palette
+	}
+	if opts.Depth <= 0 {
+		opts.Depth = defaults.Depth
+	}
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case FormatGIF:
+		if opts.Palette != PaletteLocal && opts.Palette != PaletteGlobal {
+			return fmt.Errorf("unknown GIF palette mode: %s (supported: local, global)", opts.Palette)
+		}
+		return encodeGIFAnimation(w, frames, opts)
+	case FormatAPNG, FormatPNG:
+		if opts.Depth != 8 && opts.Depth != 16 {
+			return fmt.Errorf("unsupported bit depth: %d (supported: 8, 16)", opts.Depth)
+		}
+		return encodeAPNG(w, frames, opts.Depth)
+	case FormatWebP:
+		return encodeWebPAnimation(w, frames)
+	}
+	return fmt.Errorf("unsupported animation format: %s (supported: gif, apng, webp)", opts.Format)
+}
+
+// SaveAnimation encodes frames and stores them at path, creating parent
+// directories as needed. If opts.Format is empty, the format is derived from
+// the extension of path.
+func SaveAnimation(path string, frames *Frames, opts AnimationOptions) error {
+	if opts.Format == "" {
+		opts.Format = AnimationFormatFromPath(path)
+		if opts.Format == "" {
+			return fmt.Errorf("can't determine animation format from path: %s", path)
+		}
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return EncodeAnimation(w, frames, opts)
+	})
+}
+
+// canvasFrames draws all frames onto canvases of the given size.
+func canvasFrames(frames *Frames, canvas image.Rectangle) []*image.NRGBA64 {
+	res := make([]*image.NRGBA64, frames.Len())
+	for i, img := range frames.Images {
+		if img.Bounds() == canvas {
+			res[i] = img
+			continue
+		}
+		res[i] = IFromBounds(canvas)
+		draw.Draw(res[i], img.Bounds().Sub(img.Bounds().Min), img, img.Bounds().Min, draw.Src)
+	}
+	return res
+}
+
+// diffRect returns the smallest rectangle containing all pixels of a and b
+// that differ. Both images must have the same bounds and pixel layout.
+func diffRect(a, b []byte, stride, bpp int, bounds image.Rectangle) image.Rectangle {
+	res := image.Rectangle{}
+	for y := 0; y < bounds.Dy(); y++ {
+		row := y * stride
+		for x := 0; x < bounds.Dx(); x++ {
+			i := row + x*bpp
+			if !bytes.Equal(a[i:i+bpp], b[i:i+bpp]) {
+				res = res.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return res.Add(bounds.Min)
+}
+
+// gifFrame converts img to 8 bits per channel with binary alpha,
+// GIFs can't represent translucent pixels.
+func gifFrame(img *image.NRGBA64) *image.NRGBA {
+	res := ImageTo8Bit(img).(*image.NRGBA)
+	for i := 0; i < len(res.Pix); i += 4 {
+		if res.Pix[i+3] < 0x80 {
+			res.Pix[i], res.Pix[i+1], res.Pix[i+2], res.Pix[i+3] = 0, 0, 0, 0
+		} else {
+			res.Pix[i+3] = 0xFF
+		}
+	}
+	return res
+}
+
+// exactPalette returns the colors of img within r if there are at most
+// numColors of them, otherwise nil.
+func exactPalette(img *image.NRGBA, r image.Rectangle, numColors int) color.Palette {
+	seen := map[color.NRGBA]bool{}
+	var p color.Palette
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			if seen[c] {
+				continue
+			}
+			if len(p) == numColors {
+				return nil
+			}
+			seen[c] = true
+			p = append(p, c)
+		}
+	}
+	return p
+}
+
+// withTransparent makes sure p has a fully transparent entry.
+func withTransparent(p color.Palette, numColors int) color.Palette {
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			return p
+		}
+	}
+	if len(p) >= numColors {
+		p = p[:numColors-1]
+	}
+	return append(p, color.NRGBA{})
+}
+
+func encodeGIFAnimation(w io.Writer, frames *Frames, opts AnimationOptions) error {
+	canvas := frames.Bounds()
+	numColors := math.Clamp(opts.Colors, 2, 256)
+	srcs := canvasFrames(frames, canvas)
+	imgs := make([]*image.NRGBA, len(srcs))
+	parallelRows(0, len(srcs), func(i int) {
+		imgs[i] = gifFrame(srcs[i])
+	})
+
+	// A frame that turns opaque pixels transparent can't be drawn on top of
+	// its predecessor, which then has to be cleared to the background
+	needsClear := make([]bool, len(imgs)+1)
+	for i := 1; i < len(imgs); i++ {
+		prev, cur := imgs[i-1].Pix, imgs[i].Pix
+		for j := 3; j < len(cur); j += 4 {
+			if cur[j] == 0 && prev[j] != 0 {
+				needsClear[i] = true
+				break
+			}
+		}
+	}
+
+	var global color.Palette
+	if opts.Palette == PaletteGlobal {
+		global = globalPalette(imgs, numColors)
+	}
+
+	drawer := draw.Drawer(draw.Src)
+	if opts.Dither {
+		drawer = draw.FloydSteinberg
+	}
+
+	g := &gif.GIF{
+		LoopCount: frames.LoopCount,
+		Config:    image.Config{Width: canvas.Dx(), Height: canvas.Dy()},
+	}
+	if global != nil {
+		g.Config.ColorModel = global
+	}
+	for i, img := range imgs {
+		delay := (frames.Delay(i) + 5) / 10
+		region := canvas
+		if i > 0 && !needsClear[i] && !needsClear[i+1] {
+			region = diffRect(imgs[i-1].Pix, img.Pix, img.Stride, 4, canvas)
+			if region.Empty() {
+				// Nothing changed, extend the previous frame instead
+				g.Delay[len(g.Delay)-1] += delay
+				continue
+			}
+			// Pixels that didn't change are left transparent, that way they
+			// don't need palette entries and compress better
+			sub := image.NewNRGBA(region)
+			draw.Draw(sub, region, img, region.Min, draw.Src)
+			prev := imgs[i-1]
+			for y := region.Min.Y; y < region.Max.Y; y++ {
+				for x := region.Min.X; x < region.Max.X; x++ {
+					if prev.NRGBAAt(x, y) == sub.NRGBAAt(x, y) {
+						sub.SetNRGBA(x, y, color.NRGBA{})
+					}
+				}
+			}
+			img = sub
+		}
+
+		palette := global
+		d := drawer
+		if palette == nil {
+			if palette = exactPalette(img, region, numColors); palette != nil {
+				d = draw.Src
+			} else {
+				palette = medianCutQuantizer{numColors: numColors}.Quantize(make(color.Palette, 0, numColors), img.SubImage(region))
+			}
+		}
+		paletted := image.NewPaletted(region, palette)
+		d.Draw(paletted, region, img, region.Min)
+
+		disposal := byte(gif.DisposalNone)
+		if needsClear[i+1] {
+			disposal = gif.DisposalBackground
+		}
+		g.Image = append(g.Image, paletted)
+		g.Delay = append(g.Delay, delay)
+		g.Disposal = append(g.Disposal, disposal)
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// globalPalette builds a palette for all frames of an animation,
+// sampling the frames so the quantizer sees at most ~1M pixels.
+func globalPalette(imgs []*image.NRGBA, numColors int) color.Palette {
+	b := imgs[0].Bounds()
+	step := 1
+	for (b.Dx()/step)*(b.Dy()/step)*len(imgs) > 1<<20 {
+		step++
+	}
+	w, h := (b.Dx()+step-1)/step, (b.Dy()+step-1)/step
+	samples := image.NewNRGBA(image.Rect(0, 0, w, h*len(imgs)))
+	for i, img := range imgs {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				samples.SetNRGBA(x, i*h+y, img.NRGBAAt(b.Min.X+x*step, b.Min.Y+y*step))
+			}
+		}
+	}
+	if p := exactPalette(samples, samples.Bounds(), numColors-1); p != nil {
+		return withTransparent(p, numColors)
+	}
+	p := medianCutQuantizer{numColors: numColors - 1}.Quantize(make(color.Palette, 0, numColors), samples)
+	return withTransparent(p, numColors)
+}
+
+// encodeAPNG writes an animated PNG with RGBA pixels. Every frame after the
+// first only covers the area that changed and replaces it (blend op source),
+// so translucent pixels are represented exactly.
+func encodeAPNG(w io.Writer, frames *Frames, depth int) error {
+	canvas := frames.Bounds()
+	imgs := canvasFrames(frames, canvas)
+
+	type apngFrame struct {
+		img    *image.NRGBA64
+		region image.Rectangle
+		delay  int
+	}
+	var seq []*apngFrame
+	for i, img := range imgs {
+		region := canvas
+		if i > 0 {
+			region = diffRect(imgs[i-1].Pix, img.Pix, img.Stride, 8, canvas)
+			if region.Empty() {
+				seq[len(seq)-1].delay += frames.Delay(i)
+				continue
+			}
+		}
+		seq = append(seq, &apngFrame{img, region, frames.Delay(i)})
+	}
+
+	plays := 0
+	switch {
+	case frames.LoopCount < 0:
+		plays = 1
+	case frames.LoopCount > 0:
+		plays = frames.LoopCount + 1
+	}
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(canvas.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(canvas.Dy()))
+	ihdr[8], ihdr[9] = byte(depth), 6 // RGBA
+	if err := writePNGChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(seq)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(plays))
+	if err := writePNGChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	sequence := uint32(0)
+	for i, f := range seq {
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(f.region.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(f.region.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(f.region.Min.X-canvas.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(f.region.Min.Y-canvas.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(math.Clamp(f.delay, 0, 65535)))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		// fctl[24], fctl[25] = 0, 0: dispose op none, blend op source
+		if err := writePNGChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+		sequence++
+
+		data, err := apngImageData(f.img, f.region, depth)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			err = writePNGChunk(w, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			err = writePNGChunk(w, "fdAT", append(fdat, data...))
+			sequence++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writePNGChunk(w, "IEND", nil)
+}
+
+// apngImageData returns the compressed, filtered RGBA scanlines of img within r.
+// Each row uses the filter with the smallest sum of absolute values,
+// the heuristic recommended by the PNG specification.
+func apngImageData(img *image.NRGBA64, r image.Rectangle, depth int) ([]byte, error) {
+	bpp := depth / 2 // bytes per pixel: 4 channels at 1 or 2 bytes
+	rowLen := r.Dx() * bpp
+	prev := make([]byte, rowLen)
+	cur := make([]byte, rowLen)
+	filtered := make([][]byte, 5)
+	for i := range filtered {
+		filtered[i] = make([]byte, rowLen+1)
+		filtered[i][0] = byte(i)
+	}
+
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)]
+		if depth == 16 {
+			copy(cur, src)
+		} else {
+			for i := 0; i < len(src); i += 2 {
+				cur[i/2] = src[i]
+			}
+		}
+
+		best, bestSum := 0, -1
+		for ft := range filtered {
+			out := filtered[ft][1:]
+			sum := 0
+			for i := range cur {
+				var a, b, c byte
+				if i >= bpp {
+					a, c = cur[i-bpp], prev[i-bpp]
+				}
+				b = prev[i]
+				v := cur[i]
+				switch ft {
+				case 1:
+					v -= a
+				case 2:
+					v -= b
+				case 3:
+					v -= byte((int(a) + int(b)) / 2)
+				case 4:
+					v -= paeth(a, b, c)
+				}
+				out[i] = v
+				sum += math.Abs(int(int8(v)))
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = ft, sum
+			}
+		}
+		if _, err := zw.Write(filtered[best]); err != nil {
+			return nil, err
+		}
+		prev, cur = cur, prev
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := math.Abs(p-int(a)), math.Abs(p-int(b)), math.Abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
@@ -0,0 +1,109 @@
+package language
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testFrames returns an animation of moving squares with the given colors.
+func testFrames(colors ...color.NRGBA64) *Frames {
+	frames := NewFrames()
+	frames.LoopCount = 2
+	for i, c := range colors {
+		img := I(24, 16)
+		for y := 4; y < 12; y++ {
+			for x := 3 + i*5; x < 11+i*5; x++ {
+				img.SetNRGBA64(x, y, c)
+			}
+		}
+		frames.Add(img, 40*(i+1))
+	}
+	return frames
+}
+
+func encodeDecodeFrames(t *testing.T, frames *Frames, opts AnimationOptions) *Frames {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeAnimation(&buf, frames, opts); err != nil {
+		t.Fatalf("%s: %v", opts.Format, err)
+	}
+	res, err := DecodeFrames(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%s: %v", opts.Format, err)
+	}
+	return res
+}
+
+func assertSameFrames(t *testing.T, name string, want, got *Frames) {
+	t.Helper()
+	if got.Len() != want.Len() {
+		t.Fatalf("%s: expected %d frames, got %d", name, want.Len(), got.Len())
+	}
+	if got.LoopCount != want.LoopCount {
+		t.Errorf("%s: expected loop count %d, got %d", name, want.LoopCount, got.LoopCount)
+	}
+	for i := range want.Images {
+		if got.Delay(i) != want.Delay(i) {
+			t.Errorf("%s: frame %d: expected a delay of %d ms, got %d", name, i, want.Delay(i), got.Delay(i))
+		}
+		assertClose(t, name, ImageTo8Bit(want.Images[i]).(*image.NRGBA), got.Images[i], 0)
+	}
+}
+
+func TestAnimationRoundTrip(t *testing.T) {
+	// Translucent colors only survive APNG and WebP
+	frames := testFrames(
+		color.NRGBA64{0xFFFF, 0, 0, 0xFFFF},
+		color.NRGBA64{0, 0x8080, 0xFFFF, 0x8080},
+		color.NRGBA64{0x4040, 0xFFFF, 0, 0xFFFF},
+	)
+	for _, format := range []string{FormatAPNG, FormatWebP} {
+		assertSameFrames(t, format, frames, encodeDecodeFrames(t, frames, AnimationOptions{Format: format}))
+	}
+
+	opaque := testFrames(color.NRGBA64{0xFFFF, 0, 0, 0xFFFF}, color.NRGBA64{0, 0, 0xFFFF, 0xFFFF}, color.NRGBA64{0, 0xFFFF, 0, 0xFFFF})
+	for _, palette := range []string{PaletteLocal, PaletteGlobal} {
+		res := encodeDecodeFrames(t, opaque, AnimationOptions{Format: FormatGIF, Palette: palette})
+		assertSameFrames(t, "gif "+palette, opaque, res)
+	}
+}
+
+func TestAnimationMergesUnchangedFrames(t *testing.T) {
+	c := color.NRGBA64{0xFFFF, 0x8080, 0, 0xFFFF}
+	frames := testFrames(c, c)
+	frames.Add(frames.Images[1], 100)
+	for _, format := range []string{FormatGIF, FormatAPNG, FormatWebP} {
+		res := encodeDecodeFrames(t, frames, AnimationOptions{Format: format})
+		if res.Len() != 2 {
+			t.Fatalf("%s: expected 2 frames, got %d", format, res.Len())
+		}
+		if res.Delay(1) != frames.Delay(1)+frames.Delay(2) {
+			t.Errorf("%s: expected the delays to be merged, got %d", format, res.Delay(1))
+		}
+	}
+}
+
+func TestAnimationFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"a.gif": FormatGIF, "a.png": FormatAPNG, "a.apng": FormatAPNG, "a.webp": FormatWebP, "a.jpg": "",
+	} {
+		if got := AnimationFormatFromPath(path); got != want {
+			t.Errorf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestDecodeAnimatedWebPAsImage(t *testing.T) {
+	frames := testFrames(color.NRGBA64{0xFFFF, 0, 0, 0xFFFF}, color.NRGBA64{0, 0, 0xFFFF, 0xFFFF})
+	var buf bytes.Buffer
+	if err := EncodeAnimation(&buf, frames, AnimationOptions{Format: FormatWebP}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := DecodeImage(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertClose(t, "first frame", ImageTo8Bit(frames.Images[0]).(*image.NRGBA), imageToNRGBA64(img), 0)
+}
@@ -0,0 +1,233 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+
+	"github.com/toxyl/math"
+	"golang.org/x/image/webp"
+)
+
+// riffChunk is a chunk of a RIFF file.
+type riffChunk struct {
+	typ  string
+	data []byte
+}
+
+// webpChunks splits the body of a WebP file into its chunks.
+func webpChunks(data []byte) ([]riffChunk, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("not a WebP file")
+	}
+	return riffChunks(data[12:])
+}
+
+// riffChunks splits data into RIFF chunks.
+func riffChunks(data []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated RIFF chunk")
+		}
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size > len(data)-8 {
+			return nil, fmt.Errorf("truncated %s chunk", data[0:4])
+		}
+		chunks = append(chunks, riffChunk{string(data[0:4]), data[8 : 8+size]})
+		data = data[math.Min(8+size+size&1, len(data)):]
+	}
+	return chunks, nil
+}
+
+// uint24 reads a little-endian 24-bit value.
+func uint24(b []byte) int {
+	return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+}
+
+func putUint24(b []byte, v int) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+// decodeWebPFrames decodes an animated WebP. It returns nil frames without an
+// error if data is a WebP without animation chunk.
+func decodeWebPFrames(data []byte) (*Frames, error) {
+	chunks, err := webpChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		frames *Frames
+		canvas *image.NRGBA64
+	)
+	for _, c := range chunks {
+		switch c.typ {
+		case "VP8X":
+			if len(c.data) < 10 {
+				return nil, fmt.Errorf("invalid WebP header")
+			}
+			canvas = IFromBounds(image.Rect(0, 0, uint24(c.data[4:])+1, uint24(c.data[7:])+1))
+		case "ANIM":
+			if len(c.data) < 6 || canvas == nil {
+				return nil, fmt.Errorf("invalid WebP animation chunk")
+			}
+			frames = NewFrames()
+			// WebP counts plays, GIF-style loop counts count repetitions
+			if plays := int(binary.LittleEndian.Uint16(c.data[4:])); plays > 0 {
+				frames.LoopCount = plays - 1
+				if plays == 1 {
+					frames.LoopCount = -1
+				}
+			}
+		case "ANMF":
+			if frames == nil {
+				continue
+			}
+			if len(c.data) < 16 {
+				return nil, fmt.Errorf("invalid WebP frame chunk")
+			}
+			x, y := uint24(c.data[0:])*2, uint24(c.data[3:])*2
+			w, h := uint24(c.data[6:])+1, uint24(c.data[9:])+1
+			delay := uint24(c.data[12:])
+			noBlend, dispose := c.data[15]&0x02 != 0, c.data[15]&0x01 != 0
+
+			src, err := decodeWebPFrame(c.data[16:], w, h)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode WebP frame %d: %v", frames.Len()+1, err)
+			}
+			rect := image.Rect(x, y, x+w, y+h)
+			op := draw.Over
+			if noBlend {
+				op = draw.Src
+			}
+			draw.Draw(canvas, rect, src, src.Bounds().Min, op)
+			if delay <= 0 {
+				delay = defaultFrameDelay
+			}
+			frames.Add(cloneNRGBA64(canvas), delay)
+			if dispose {
+				frames.Disposal[frames.Len()-1] = DisposalBackground
+				draw.Draw(canvas, rect, image.Transparent, image.Point{}, draw.Src)
+			}
+		}
+	}
+	if frames == nil {
+		return nil, nil
+	}
+	if frames.Len() == 0 {
+		return nil, fmt.Errorf("WebP animation contains no frames")
+	}
+	return frames, nil
+}
+
+// decodeWebPFrame decodes the image chunks (ALPH, VP8 or VP8L) of an
+// animation frame of w x h pixels by wrapping them in a WebP file of their own.
+func decodeWebPFrame(data []byte, w, h int) (image.Image, error) {
+	chunks, err := riffChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	for _, c := range chunks {
+		if c.typ == "ALPH" {
+			// Lossy frames with alpha need an extended header
+			vp8x := make([]byte, 10)
+			vp8x[0] = 0x10 // alpha
+			putUint24(vp8x[4:], w-1)
+			putUint24(vp8x[7:], h-1)
+			writeRIFFChunk(&body, "VP8X", vp8x)
+			break
+		}
+	}
+	for _, c := range chunks {
+		switch c.typ {
+		case "ALPH", "VP8 ", "VP8L":
+			writeRIFFChunk(&body, c.typ, c.data)
+		}
+	}
+	file := append(riffHeader("WEBP", body.Len()), body.Bytes()...)
+	return webp.Decode(bytes.NewReader(file))
+}
+
+// encodeWebPAnimation writes a lossless animated WebP. Every frame after the
+// first only covers the area that changed and replaces it without blending,
+// so translucent pixels are represented exactly.
+func encodeWebPAnimation(w io.Writer, frames *Frames) error {
+	canvas := frames.Bounds()
+	if canvas.Dx() > 1<<14 || canvas.Dy() > 1<<14 {
+		return fmt.Errorf("WebP only supports animations up to 16384x16384 pixels, got %dx%d", canvas.Dx(), canvas.Dy())
+	}
+	srcs := canvasFrames(frames, canvas)
+	imgs := make([]*image.NRGBA, len(srcs))
+	parallelRows(0, len(srcs), func(i int) {
+		imgs[i] = ImageTo8Bit(srcs[i]).(*image.NRGBA)
+	})
+
+	type webpFrame struct {
+		img    *image.NRGBA
+		region image.Rectangle
+		delay  int
+	}
+	var seq []*webpFrame
+	for i, img := range imgs {
+		region := canvas
+		if i > 0 {
+			region = diffRect(imgs[i-1].Pix, img.Pix, img.Stride, 4, canvas)
+			if region.Empty() {
+				seq[len(seq)-1].delay += frames.Delay(i)
+				continue
+			}
+			// Frame offsets are stored in units of two pixels
+			region.Min.X -= region.Min.X & 1
+			region.Min.Y -= region.Min.Y & 1
+		}
+		seq = append(seq, &webpFrame{img, region, frames.Delay(i)})
+	}
+
+	plays := 0
+	switch {
+	case frames.LoopCount < 0:
+		plays = 1
+	case frames.LoopCount > 0:
+		plays = frames.LoopCount + 1
+	}
+
+	var body bytes.Buffer
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x10 | 0x02 // alpha, animation
+	putUint24(vp8x[4:], canvas.Dx()-1)
+	putUint24(vp8x[7:], canvas.Dy()-1)
+	writeRIFFChunk(&body, "VP8X", vp8x)
+	anim := make([]byte, 6)
+	binary.LittleEndian.PutUint32(anim[0:], 0) // transparent background
+	binary.LittleEndian.PutUint16(anim[4:], uint16(math.Clamp(plays, 0, 65535)))
+	writeRIFFChunk(&body, "ANIM", anim)
+
+	for _, f := range seq {
+		data, err := vp8lBitstream(f.img.SubImage(f.region))
+		if err != nil {
+			return err
+		}
+		var anmf bytes.Buffer
+		header := make([]byte, 16)
+		putUint24(header[0:], (f.region.Min.X-canvas.Min.X)/2)
+		putUint24(header[3:], (f.region.Min.Y-canvas.Min.Y)/2)
+		putUint24(header[6:], f.region.Dx()-1)
+		putUint24(header[9:], f.region.Dy()-1)
+		putUint24(header[12:], math.Clamp(f.delay, 0, 1<<24-1))
+		header[15] = 0x02 // don't blend, don't dispose
+		anmf.Write(header)
+		writeRIFFChunk(&anmf, "VP8L", data)
+		writeRIFFChunk(&body, "ANMF", anmf.Bytes())
+	}
+
+	if _, err := w.Write(riffHeader("WEBP", body.Len())); err != nil {
+		return err
+	}
+	_, err := w.Write(body.Bytes())
+	return err
+}
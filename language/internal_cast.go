@@ -0,0 +1,43 @@
+package language
+
+import "image"
+
+// castValue converts value to targetType like cast, including the types
+// of this package: linear-light images, masks, alignments, frames, layers
+// and matrices. Images are passed to image.Image parameters as they are.
+func (dsl *dslCollection) castValue(value any, targetType string) (any, error) {
+	switch v := value.(type) {
+	case *LinearImage:
+		switch {
+		case targetType == "image.Image" || isLinearImageType(targetType):
+			return v, nil
+		case isMaskType(targetType):
+			return maskFromImage(v), nil
+		}
+		return dsl.castValue(fromLinearImage(v), targetType)
+	case *image.NRGBA, *image.RGBA, *image.RGBA64, *image.NRGBA64:
+		switch {
+		case targetType == "image.Image":
+			return v, nil
+		case isMaskType(targetType):
+			return maskFromImage(v.(image.Image)), nil
+		case isLinearImageType(targetType):
+			return toLinearImage(imageToNRGBA64(v.(image.Image))), nil
+		}
+	case *Mask:
+		return dsl.castMask(v, targetType)
+	case *Alignment:
+		return dsl.castAlignment(v, targetType)
+	case *Frames:
+		return castSelfOnly(v, targetType, "Frames")
+	case *Layer:
+		return castSelfOnly(v, targetType, "Layer")
+	case *Matrix:
+		return castSelfOnly(v, targetType, "Matrix")
+	}
+	return dsl.cast(value, targetType)
+}
+
+func isLinearImageType(typ string) bool {
+	return typ == "*LinearImage" || typ == "*language.LinearImage"
+}
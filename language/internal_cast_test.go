@@ -0,0 +1,50 @@
+package language
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCastValuePackageTypes(t *testing.T) {
+	src := testImage(32, 24)
+	for _, script := range []string{
+		"m: select-brightness($1)\ninvert(m)",
+		"mask-invert($1)",
+		"apply-masked($1 invert($1) select-brightness($1))",
+		"flatten({layer($1) layer(invert($1) \"multiply\" 0.5)})",
+		"warp-affine($1 matrix-rotate(10))",
+		"a: align($1 $1)\ninvert(a)",
+		"warp-affine($1 align($1 $1))",
+		"to-srgb(invert(to-linear($1)))",
+		"set-exif(to-linear($1) \"Artist\" \"pxp\")",
+	} {
+		runImage(t, script, src)
+	}
+}
+
+func TestCastValueLinearImages(t *testing.T) {
+	src := testImage(8, 8)
+	lin, err := dsl.castValue(src, "*language.LinearImage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lin.(*LinearImage); !ok {
+		t.Fatalf("expected a *LinearImage, got %T", lin)
+	}
+	back, err := dsl.castValue(lin, "*image.NRGBA64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := back.(*image.NRGBA64)
+	for i := range src.Pix {
+		if d := int(src.Pix[i]) - int(img.Pix[i]); d < -1 || d > 1 {
+			t.Fatalf("linear round trip changed byte %d: %d vs %d", i, src.Pix[i], img.Pix[i])
+		}
+	}
+	if res, err := dsl.castValue(lin, "image.Image"); err != nil || res != lin {
+		t.Errorf("expected linear image to be passed to image.Image as it is, got %T, %v", res, err)
+	}
+	if _, err := dsl.castValue(NewFrames(), "*image.NRGBA64"); err == nil {
+		t.Error("expected frames not to convert to an image")
+	}
+}
@@ -0,0 +1,197 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/gen2brain/heic"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+// DecodeImage decodes the first image (page) contained in data.
+func DecodeImage(data []byte) (image.Image, error) {
+	imgs, err := DecodeImages(data)
+	if err != nil {
+		return nil, err
+	}
+	return imgs[0], nil
+}
+
+// DecodeImages decodes all images contained in data. All formats but TIFF
+// contain a single image, multi-page TIFFs return one image per page.
+// DNG raw images are developed with DefaultDNGOptions.
+// Decoders that support 16 bits per channel keep that precision.
+func DecodeImages(data []byte) ([]image.Image, error) {
+	imgType := DetectImageType(data)
+	if imgType == "" {
+		return nil, fmt.Errorf("unsupported image format or corrupted file")
+	}
+
+	var img image.Image
+	var err error
+	reader := bytes.NewReader(data)
+	switch imgType {
+	case "gif":
+		img, err = gif.Decode(reader)
+	case "png":
+		img, err = png.Decode(reader)
+	case "jpeg":
+		img, err = jpeg.Decode(reader)
+	case "webp":
+		var frames *Frames
+		if frames, err = decodeWebPFrames(data); err == nil && frames != nil {
+			// Animations return their first frame
+			img = frames.Images[0]
+		} else {
+			img, err = webp.Decode(reader)
+		}
+	case "bmp":
+		img, err = bmp.Decode(reader)
+	case "heic":
+		img, err = heic.Decode(reader)
+	case "avif":
+		// libheif decodes AVIF if it was built with an AV1 decoder,
+		// that is the case for most system installations but not the embedded fallback
+		img, err = heic.Decode(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %v (AVIF requires a system libheif with AV1 support)", err)
+		}
+	case "dng":
+		img, err = DecodeDNG(data, DefaultDNGOptions())
+	case "tiff":
+		pages := tiffPages(data)
+		imgs := make([]image.Image, 0, len(pages))
+		for i, page := range pages {
+			img, err := tiff.Decode(page)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode page %d: %v", i+1, err)
+			}
+			imgs = append(imgs, img)
+		}
+		return imgs, nil
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", imgType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+	return []image.Image{img}, nil
+}
+
+// DecodeLayers decodes the layers of an OpenRaster or Photoshop document, bottom layer first.
+func DecodeLayers(data []byte) ([]*Layer, error) {
+	switch {
+	case isORA(data):
+		return DecodeORA(data)
+	case isPSD(data):
+		return DecodePSD(data)
+	}
+	return nil, fmt.Errorf("unsupported layered document (only ORA and PSD are supported)")
+}
+
+// tiffPages returns one TIFF stream per image file directory (page) of data.
+// The streams read from data, only the offset of the first directory in the
+// header differs, so the standard decoder (which only reads the first
+// directory) decodes the corresponding page.
+func tiffPages(data []byte) []*io.SectionReader {
+	stream := func(header []byte) *io.SectionReader {
+		p := &tiffPage{data: data}
+		copy(p.header[:], header)
+		return io.NewSectionReader(p, 0, int64(len(data)))
+	}
+	if len(data) < 8 {
+		return []*io.SectionReader{stream(data)}
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+	var pages []*io.SectionReader
+	seen := map[uint32]bool{}
+	for offset := order.Uint32(data[4:8]); offset != 0 && !seen[offset]; {
+		seen[offset] = true
+		if int(offset)+2 > len(data) {
+			break
+		}
+		header := bytes.Clone(data[:8])
+		order.PutUint32(header[4:8], offset)
+		pages = append(pages, stream(header))
+
+		next := int(offset) + 2 + 12*int(order.Uint16(data[offset:]))
+		if next+4 > len(data) {
+			break
+		}
+		offset = order.Uint32(data[next:])
+	}
+	if len(pages) == 0 {
+		return []*io.SectionReader{stream(data)}
+	}
+	return pages
+}
+
+// tiffPage reads data with the first 8 bytes (the TIFF header) replaced.
+type tiffPage struct {
+	data   []byte
+	header [8]byte
+}
+
+func (p *tiffPage) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 || off >= int64(len(p.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, p.data[off:])
+	if off < int64(len(p.header)) {
+		copy(b[:n], p.header[off:])
+	}
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// imageToNRGBA64 converts a decoded image to NRGBA64, reading the pixel
+// buffers of the common image types directly so no precision is lost.
+func imageToNRGBA64(img image.Image) *image.NRGBA64 {
+	bounds := img.Bounds()
+	switch t := img.(type) {
+	case *image.NRGBA64:
+		return t
+	case *image.RGBA64:
+		return dsl.convertRGBA64ToNRGBA64(t)
+	case *LinearImage:
+		return fromLinearImage(t)
+	}
+
+	res := IFromBounds(bounds)
+	set := func(x, y int, r, g, b, a uint16) {
+		i := res.PixOffset(x, y)
+		s := res.Pix[i : i+8 : i+8]
+		s[0], s[1], s[2], s[3] = uint8(r>>8), uint8(r), uint8(g>>8), uint8(g)
+		s[4], s[5], s[6], s[7] = uint8(b>>8), uint8(b), uint8(a>>8), uint8(a)
+	}
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			switch t := img.(type) {
+			case *image.NRGBA:
+				c := t.NRGBAAt(x, y)
+				set(x, y, uint16(c.R)*0x101, uint16(c.G)*0x101, uint16(c.B)*0x101, uint16(c.A)*0x101)
+			case *image.Gray16:
+				v := t.Gray16At(x, y).Y
+				set(x, y, v, v, v, 0xFFFF)
+			default:
+				c := color.NRGBA64Model.Convert(img.At(x, y)).(color.NRGBA64)
+				set(x, y, c.R, c.G, c.B, c.A)
+			}
+		}
+	})
+	return res
+}
@@ -0,0 +1,157 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// multiPageTIFF returns an uncompressed little-endian RGB TIFF with one page per image.
+func multiPageTIFF(imgs ...*image.NRGBA) []byte {
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.Write([]byte{'I', 'I', 42, 0, 0, 0, 0, 0})
+	prevNext := 4 // where the offset of the next directory is stored
+	for _, img := range imgs {
+		w, h := img.Rect.Dx(), img.Rect.Dy()
+		stripOffset := buf.Len()
+		for y := range h {
+			for x := range w {
+				c := img.NRGBAAt(x, y)
+				buf.Write([]byte{c.R, c.G, c.B})
+			}
+		}
+		bitsOffset := buf.Len()
+		binary.Write(&buf, le, []uint16{8, 8, 8})
+		if buf.Len()%2 != 0 {
+			buf.WriteByte(0)
+		}
+		ifdOffset := buf.Len()
+		b := buf.Bytes()
+		le.PutUint32(b[prevNext:], uint32(ifdOffset))
+
+		entries := [][3]uint32{ // tag, type (3 = SHORT, 4 = LONG), count and value follow
+			{256, 4, uint32(w)}, {257, 4, uint32(h)}, {258, 3, uint32(bitsOffset)}, {259, 3, 1},
+			{262, 3, 2}, {273, 4, uint32(stripOffset)}, {277, 3, 3}, {278, 4, uint32(h)},
+			{279, 4, uint32(w * h * 3)},
+		}
+		binary.Write(&buf, le, uint16(len(entries)))
+		for _, e := range entries {
+			count := uint32(1)
+			if e[0] == 258 {
+				count = 3
+			}
+			binary.Write(&buf, le, uint16(e[0]))
+			binary.Write(&buf, le, uint16(e[1]))
+			binary.Write(&buf, le, count)
+			if e[1] == 3 && count == 1 {
+				binary.Write(&buf, le, []uint16{uint16(e[2]), 0})
+			} else {
+				binary.Write(&buf, le, e[2])
+			}
+		}
+		prevNext = buf.Len()
+		binary.Write(&buf, le, uint32(0))
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeMultiPageTIFF(t *testing.T) {
+	pages := []*image.NRGBA{opaqueImage(12, 8), opaqueImage(5, 9), opaqueImage(7, 7)}
+	for y := range 9 {
+		for x := range 5 {
+			pages[1].SetNRGBA(x, y, color.NRGBA{200, uint8(x * 40), uint8(y * 20), 255})
+		}
+	}
+	data := multiPageTIFF(pages...)
+	orig := bytes.Clone(data)
+	if typ := DetectImageType(data); typ != "tiff" {
+		t.Fatalf("detected as %q", typ)
+	}
+	imgs, err := DecodeImages(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imgs) != len(pages) {
+		t.Fatalf("expected %d pages, got %d", len(pages), len(imgs))
+	}
+	for i, img := range imgs {
+		assertClose(t, "page", pages[i], imageToNRGBA64(img), 0)
+	}
+	if !bytes.Equal(data, orig) {
+		t.Error("decoding modified the data")
+	}
+
+	res, err := New().Run("x: $1\ny: x[1]\ny", "", nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img, ok := res.Value().(image.Image); !ok || img.Bounds().Size() != pages[1].Rect.Size() {
+		t.Errorf("expected the second page, got %v", res.Value())
+	}
+}
+
+func TestDecodeFormats(t *testing.T) {
+	src := opaqueImage(23, 17)
+	for _, format := range []string{FormatPNG, FormatWebP, FormatTIFF, FormatBMP, FormatGIF, FormatJPEG} {
+		var buf bytes.Buffer
+		if err := EncodeImage(&buf, src, SaveOptions{Format: format, Quality: 100, Subsampling: JPEGSubsampling444}); err != nil {
+			t.Fatal(err)
+		}
+		imgs, meta, err := decodeImageData(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(imgs) != 1 || meta == nil {
+			t.Fatalf("%s: expected one image with metadata, got %d", format, len(imgs))
+		}
+		tolerance := 0
+		switch format {
+		case FormatGIF:
+			tolerance = 16
+		case FormatJPEG:
+			tolerance = 4
+		}
+		assertClose(t, format, src, imgs[0], tolerance)
+	}
+}
+
+func TestDecode16BitPNG(t *testing.T) {
+	src := testImage(9, 7)
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, src, SaveOptions{Format: FormatPNG}); err != nil {
+		t.Fatal(err)
+	}
+	imgs, _, err := decodeImageData(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(imgs[0].Pix, src.Pix) {
+		t.Error("16-bit PNG lost precision")
+	}
+}
+
+func TestDetectImageType(t *testing.T) {
+	ftyp := func(brands ...string) []byte {
+		data := []byte{0, 0, 0, byte(8 + 4*len(brands)), 'f', 't', 'y', 'p'}
+		for _, b := range brands {
+			data = append(data, b...)
+		}
+		return append(data, make([]byte, 16)...)
+	}
+	for want, data := range map[string][]byte{
+		"avif": ftyp("avif", "\x00\x00\x00\x00", "mif1"),
+		"heic": ftyp("heic", "\x00\x00\x00\x00", "mif1"),
+		"bmp":  append([]byte("BM"), make([]byte, 30)...),
+		"":     []byte("not an image"),
+	} {
+		if got := DetectImageType(data); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+	if got := DetectImageType(ftyp("mif1", "\x00\x00\x00\x00", "avif")); got != "avif" {
+		t.Errorf("expected AVIF with mif1 major brand to be detected, got %q", got)
+	}
+}
@@ -0,0 +1,248 @@
+package language
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/toxyl/math"
+)
+
+// reflectCoord mirrors coordinates outside of 0..n-1 back into the image,
+// keeping their parity so that CFA colors stay the same.
+func reflectCoord(v, n int) int {
+	if v < 0 {
+		v = -v
+	}
+	if v >= n {
+		v = 2*(n-1) - v
+	}
+	if v < 0 {
+		return 0
+	}
+	return v
+}
+
+// at returns the CFA sample at (x, y), coordinates outside of the image are mirrored.
+func (r *dngRaw) at(x, y int) float32 {
+	return r.data[reflectCoord(y, r.h)*r.w+reflectCoord(x, r.w)]
+}
+
+// demosaicBilinear interpolates the missing colors of each pixel from the
+// average of the neighboring samples of that color and passes each row
+// (as RGB triplets) to emit. Rows are processed in parallel.
+func demosaicBilinear(r *dngRaw, emit func(y int, rgb []float32)) {
+	parallelRows(0, r.h, func(y int) {
+		rgb := make([]float32, 3*r.w)
+		for x := range r.w {
+			var sum [3]float32
+			var n [3]int
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					c := r.color(x+dx, y+dy)
+					sum[c] += r.at(x+dx, y+dy)
+					n[c]++
+				}
+			}
+			own := r.color(x, y)
+			for c := range 3 {
+				if c == own {
+					rgb[3*x+c] = r.at(x, y)
+				} else if n[c] > 0 {
+					rgb[3*x+c] = sum[c] / float32(n[c])
+				}
+			}
+		}
+		emit(y, rgb)
+	})
+}
+
+// demosaicBand is the number of rows processed at once by demosaicAHD.
+const demosaicBand = 32
+
+// demosaicAHD implements adaptive homogeneity-directed demosaicing: green
+// is interpolated horizontally and vertically, red and blue follow from the
+// color differences, and for each pixel the direction whose result is more
+// homogeneous in CIELab is used. The image is processed in parallel bands.
+func demosaicAHD(r *dngRaw, emit func(y int, rgb []float32)) {
+	bands := make(chan int)
+	var wg sync.WaitGroup
+	for range runtime.NumCPU() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y0 := range bands {
+				y1 := y0 + demosaicBand
+				if y1 > r.h {
+					y1 = r.h
+				}
+				demosaicAHDBand(r, y0, y1, emit)
+			}
+		}()
+	}
+	for y0 := 0; y0 < r.h; y0 += demosaicBand {
+		bands <- y0
+	}
+	close(bands)
+	wg.Wait()
+}
+
+func demosaicAHDBand(r *dngRaw, y0, y1 int, emit func(y int, rgb []float32)) {
+	const margin = 3
+	w, top := r.w, y0-margin
+	rows := y1 - y0 + 2*margin
+	idx := func(x, y int) int { return (y-top)*w + x }
+
+	// Green interpolated horizontally (0) and vertically (1)
+	var green [2][]float32
+	for d := range 2 {
+		green[d] = make([]float32, rows*w)
+	}
+	for y := top; y < y1+margin; y++ {
+		for x := range w {
+			i := idx(x, y)
+			if r.color(x, y) == 1 {
+				green[0][i], green[1][i] = r.at(x, y), r.at(x, y)
+				continue
+			}
+			c := r.at(x, y)
+			for d := range 2 {
+				dx, dy := 1-d, d
+				g1, g2 := r.at(x-dx, y-dy), r.at(x+dx, y+dy)
+				g := (g1+g2)/2 + (2*c-r.at(x-2*dx, y-2*dy)-r.at(x+2*dx, y+2*dy))/4
+				lo, hi := g1, g2
+				if lo > hi {
+					lo, hi = hi, lo
+				}
+				green[d][i] = math.Clamp(g, lo, hi)
+			}
+		}
+	}
+	greenAt := func(d, x, y int) float32 {
+		return green[d][idx(reflectCoord(x, w), y)]
+	}
+
+	// Full colors and their CIELab values for both directions
+	var rgb, lab [2][]float32
+	for d := range 2 {
+		rgb[d] = make([]float32, 3*rows*w)
+		lab[d] = make([]float32, 3*rows*w)
+		for y := top + 1; y < y1+margin-1; y++ {
+			for x := range w {
+				i := idx(x, y)
+				own := r.color(x, y)
+				g := green[d][i]
+				var diff [3]float32
+				var n [3]int
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						c := r.color(x+dx, y+dy)
+						if c != 1 {
+							diff[c] += r.at(x+dx, y+dy) - greenAt(d, x+dx, y+dy)
+							n[c]++
+						}
+					}
+				}
+				px := rgb[d][3*i : 3*i+3]
+				px[1] = g
+				for _, c := range []int{0, 2} {
+					switch {
+					case c == own:
+						px[c] = r.at(x, y)
+					case n[c] > 0:
+						px[c] = math.Clamp(g+diff[c]/float32(n[c]), 0, 1)
+					}
+				}
+				l, a, b := demosaicLab(px[0], px[1], px[2])
+				lab[d][3*i], lab[d][3*i+1], lab[d][3*i+2] = l, a, b
+			}
+		}
+	}
+
+	// Homogeneity: the number of neighbors that are within the smaller of
+	// the luminance and chrominance differences of both directions
+	homo := [2][]uint8{make([]uint8, rows*w), make([]uint8, rows*w)}
+	neighbors := [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	for y := top + 2; y < y1+margin-2; y++ {
+		for x := range w {
+			i := idx(x, y)
+			var ldiff, abdiff [2][4]float32
+			for d := range 2 {
+				p := lab[d][3*i : 3*i+3]
+				for k, nb := range neighbors {
+					q := lab[d][3*idx(reflectCoord(x+nb[0], w), y+nb[1]):]
+					ldiff[d][k] = math.Abs(p[0] - q[0])
+					da, db := p[1]-q[1], p[2]-q[2]
+					abdiff[d][k] = da*da + db*db
+				}
+			}
+			leps := math.Min(math.Max(ldiff[0][0], ldiff[0][1]), math.Max(ldiff[1][2], ldiff[1][3]))
+			abeps := math.Min(math.Max(abdiff[0][0], abdiff[0][1]), math.Max(abdiff[1][2], abdiff[1][3]))
+			for d := range 2 {
+				for k := range 4 {
+					if ldiff[d][k] <= leps && abdiff[d][k] <= abeps {
+						homo[d][i]++
+					}
+				}
+			}
+		}
+	}
+
+	out := make([]float32, 3*w)
+	for y := y0; y < y1; y++ {
+		for x := range w {
+			var hm [2]int
+			for d := range 2 {
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						hm[d] += int(homo[d][idx(reflectCoord(x+dx, w), y+dy)])
+					}
+				}
+			}
+			i := 3 * idx(x, y)
+			for c := range 3 {
+				switch {
+				case hm[0] > hm[1]:
+					out[3*x+c] = rgb[0][i+c]
+				case hm[0] < hm[1]:
+					out[3*x+c] = rgb[1][i+c]
+				default:
+					out[3*x+c] = (rgb[0][i+c] + rgb[1][i+c]) / 2
+				}
+			}
+		}
+		emit(y, out)
+	}
+}
+
+// demosaicLab converts camera colors (treated as linear sRGB) to CIELab.
+func demosaicLab(r, g, b float32) (float32, float32, float32) {
+	x := (0.412453*r + 0.357580*g + 0.180423*b) / 0.950456
+	y := 0.212671*r + 0.715160*g + 0.072169*b
+	z := (0.019334*r + 0.119193*g + 0.950227*b) / 1.088754
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+var (
+	labFLUT     []float32
+	labFLUTOnce sync.Once
+)
+
+const labFLUTMax = 1.25 // white balanced colors slightly exceed 1 after the XYZ conversion
+
+// labF is the CIELab companding function, looked up in a table since
+// demosaicAHD evaluates it for every pixel in two directions.
+func labF(t float32) float32 {
+	labFLUTOnce.Do(func() {
+		labFLUT = make([]float32, 0x10000)
+		for i := range labFLUT {
+			v := float32(i) / 0xFFFF * labFLUTMax
+			if v > 0.008856 {
+				labFLUT[i] = math.Cbrt(v)
+			} else {
+				labFLUT[i] = 7.787*v + 16.0/116
+			}
+		}
+	})
+	return labFLUT[int(math.Clamp(t/labFLUTMax, 0, 1)*0xFFFF)]
+}
@@ -0,0 +1,620 @@
+package language
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	DemosaicBilinear = "bilinear"
+	DemosaicAHD      = "ahd"
+)
+
+// DNGOptions configure how raw DNG data is developed.
+type DNGOptions struct {
+	Demosaic string // The demosaicing algorithm (bilinear, ahd)
+}
+
+// DefaultDNGOptions returns the options load uses for DNG files.
+func DefaultDNGOptions() DNGOptions {
+	return DNGOptions{Demosaic: DemosaicAHD}
+}
+
+// TIFF and DNG tags read by the DNG decoder
+const (
+	tagNewSubFileType         = 0x00FE
+	tagImageWidth             = 0x0100
+	tagImageLength            = 0x0101
+	tagBitsPerSample          = 0x0102
+	tagCompression            = 0x0103
+	tagPhotometric            = 0x0106
+	tagStripOffsets           = 0x0111
+	tagSamplesPerPixel        = 0x0115
+	tagRowsPerStrip           = 0x0116
+	tagStripByteCounts        = 0x0117
+	tagPlanarConfiguration    = 0x011C
+	tagPredictor              = 0x013D
+	tagTileWidth              = 0x0142
+	tagTileLength             = 0x0143
+	tagTileOffsets            = 0x0144
+	tagTileByteCounts         = 0x0145
+	tagSubIFDs                = 0x014A
+	tagCFARepeatPatternDim    = 0x828D
+	tagCFAPattern             = 0x828E
+	tagDNGVersion             = 0xC612
+	tagLinearizationTable     = 0xC618
+	tagBlackLevelRepeatDim    = 0xC619
+	tagBlackLevel             = 0xC61A
+	tagBlackLevelDeltaH       = 0xC61B
+	tagBlackLevelDeltaV       = 0xC61C
+	tagWhiteLevel             = 0xC61D
+	tagDefaultCropOrigin      = 0xC61F
+	tagDefaultCropSize        = 0xC620
+	tagColorMatrix1           = 0xC621
+	tagColorMatrix2           = 0xC622
+	tagCameraCalibration1     = 0xC623
+	tagCameraCalibration2     = 0xC624
+	tagAnalogBalance          = 0xC627
+	tagAsShotNeutral          = 0xC628
+	tagBaselineExposure       = 0xC62A
+	tagCalibrationIlluminant1 = 0xC65A
+	tagCalibrationIlluminant2 = 0xC65B
+	tagActiveArea             = 0xC68D
+	tagForwardMatrix1         = 0xC714
+	tagForwardMatrix2         = 0xC715
+
+	photometricCFA       = 32803
+	photometricLinearRaw = 34892
+
+	illuminantD65 = 21
+)
+
+// tiffField is a raw TIFF directory entry.
+type tiffField struct {
+	typ   uint16
+	count int
+	data  []byte
+}
+
+type tiffIFD struct {
+	order  binary.ByteOrder
+	fields map[uint16]tiffField
+}
+
+// readTIFFIFD reads the image file directory at offset.
+func readTIFFIFD(data []byte, order binary.ByteOrder, offset int) (*tiffIFD, error) {
+	if offset < 8 || offset+2 > len(data) {
+		return nil, fmt.Errorf("invalid TIFF directory offset")
+	}
+	n := int(order.Uint16(data[offset:]))
+	ifd := &tiffIFD{order: order, fields: map[uint16]tiffField{}}
+	for i := range n {
+		pos := offset + 2 + 12*i
+		if pos+12 > len(data) {
+			return nil, fmt.Errorf("truncated TIFF directory")
+		}
+		typ, count := order.Uint16(data[pos+2:]), int(order.Uint32(data[pos+4:]))
+		if int(typ) >= len(exifTypeSize) || exifTypeSize[typ] == 0 || count < 0 || count > len(data) {
+			continue
+		}
+		size := exifTypeSize[typ] * count
+		value := data[pos+8 : pos+12]
+		if size > 4 {
+			start := int(order.Uint32(value))
+			if start < 0 || start+size > len(data) {
+				continue
+			}
+			value = data[start : start+size]
+		}
+		ifd.fields[order.Uint16(data[pos:])] = tiffField{typ: typ, count: count, data: value[:size]}
+	}
+	return ifd, nil
+}
+
+func (d *tiffIFD) has(tag uint16) bool {
+	_, ok := d.fields[tag]
+	return ok
+}
+
+// floats returns the values of a numeric field, nil if it doesn't exist.
+func (d *tiffIFD) floats(tag uint16) []float64 {
+	f, ok := d.fields[tag]
+	if !ok {
+		return nil
+	}
+	res := make([]float64, f.count)
+	for i := range res {
+		b := f.data[exifTypeSize[f.typ]*i:]
+		switch f.typ {
+		case exifByte, exifUndefined:
+			res[i] = float64(b[0])
+		case exifSByte:
+			res[i] = float64(int8(b[0]))
+		case exifShort:
+			res[i] = float64(d.order.Uint16(b))
+		case exifSShort:
+			res[i] = float64(int16(d.order.Uint16(b)))
+		case exifLong:
+			res[i] = float64(d.order.Uint32(b))
+		case exifSLong:
+			res[i] = float64(int32(d.order.Uint32(b)))
+		case exifRational:
+			res[i] = float64(d.order.Uint32(b)) / float64(d.order.Uint32(b[4:]))
+		case exifSRational:
+			res[i] = float64(int32(d.order.Uint32(b))) / float64(int32(d.order.Uint32(b[4:])))
+		case exifFloat:
+			res[i] = float64(math.Float32frombits(d.order.Uint32(b)))
+		case exifDouble:
+			res[i] = math.Float64frombits(d.order.Uint64(b))
+		}
+	}
+	return res
+}
+
+// ints returns the values of an integer field, nil if it doesn't exist.
+func (d *tiffIFD) ints(tag uint16) []int {
+	v := d.floats(tag)
+	if v == nil {
+		return nil
+	}
+	res := make([]int, len(v))
+	for i, f := range v {
+		res[i] = int(f)
+	}
+	return res
+}
+
+// int returns the first value of an integer field or def if it doesn't exist.
+func (d *tiffIFD) int(tag uint16, def int) int {
+	if v := d.ints(tag); len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
+
+// matrix returns a 3x3 matrix field, false if it doesn't exist or has a different size.
+func (d *tiffIFD) matrix(tag uint16) (mat3, bool) {
+	v := d.floats(tag)
+	if len(v) != 9 {
+		return mat3{}, false
+	}
+	return mat3{{v[0], v[1], v[2]}, {v[3], v[4], v[5]}, {v[6], v[7], v[8]}}, true
+}
+
+// dngIFDs returns the byte order and all directories of a TIFF file,
+// including the sub-directories DNG files store their raw data in.
+func dngIFDs(data []byte) (binary.ByteOrder, []*tiffIFD, error) {
+	if len(data) < 8 {
+		return nil, nil, fmt.Errorf("not a TIFF file")
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, fmt.Errorf("not a TIFF file")
+	}
+	var ifds []*tiffIFD
+	seen := map[int]bool{}
+	var walk func(offset int, depth int) error
+	walk = func(offset int, depth int) error {
+		for offset != 0 && !seen[offset] && depth < 4 {
+			seen[offset] = true
+			ifd, err := readTIFFIFD(data, order, offset)
+			if err != nil {
+				return err
+			}
+			ifds = append(ifds, ifd)
+			for _, sub := range ifd.ints(tagSubIFDs) {
+				if err := walk(sub, depth+1); err != nil {
+					return err
+				}
+			}
+			next := offset + 2 + 12*int(order.Uint16(data[offset:]))
+			if next+4 > len(data) {
+				return nil
+			}
+			offset = int(order.Uint32(data[next:]))
+		}
+		return nil
+	}
+	if err := walk(int(order.Uint32(data[4:])), 0); err != nil {
+		return nil, nil, err
+	}
+	return order, ifds, nil
+}
+
+// isDNG reports whether data is a TIFF file with a DNGVersion tag.
+func isDNG(data []byte) bool {
+	_, ifds, err := dngIFDs(data)
+	return err == nil && len(ifds) > 0 && ifds[0].has(tagDNGVersion)
+}
+
+// DecodeDNG develops the raw image of a DNG file: the sensor data is
+// linearized, black and white levels are applied, CFA data is white
+// balanced (as-shot neutral) and demosaiced, and the camera colors are
+// converted to sRGB using the color or forward matrices.
+func DecodeDNG(data []byte, opts DNGOptions) (*image.NRGBA64, error) {
+	_, ifds, err := dngIFDs(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(ifds) == 0 || !ifds[0].has(tagDNGVersion) {
+		return nil, fmt.Errorf("not a DNG file")
+	}
+	main := ifds[0]
+	var raw *tiffIFD
+	for _, ifd := range ifds {
+		photometric := ifd.int(tagPhotometric, 0)
+		if ifd.int(tagNewSubFileType, 0) == 0 && (photometric == photometricCFA || photometric == photometricLinearRaw) {
+			raw = ifd
+			break
+		}
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("DNG file contains no raw image")
+	}
+
+	samples, err := readDNGSamples(data, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Camera metadata is usually stored in the main directory, but may be
+	// overridden by the raw directory.
+	field := func(tag uint16) *tiffIFD {
+		if raw.has(tag) {
+			return raw
+		}
+		return main
+	}
+
+	neutral := field(tagAsShotNeutral).floats(tagAsShotNeutral)
+	if len(neutral) < 3 {
+		neutral = []float64{1, 1, 1}
+	}
+	wb := [3]float64{}
+	for c := range 3 {
+		wb[c] = 1 / math.Max(neutral[c], 1e-6)
+	}
+	wbMin := math.Min(wb[0], math.Min(wb[1], wb[2]))
+	for c := range 3 {
+		wb[c] /= wbMin
+	}
+
+	img, err := newDNGRaw(raw, samples)
+	if err != nil {
+		return nil, err
+	}
+	img.normalize(raw, wb)
+
+	transform := dngCameraToSRGB(field, neutral)
+	exposure := 1.0
+	if v := field(tagBaselineExposure).floats(tagBaselineExposure); len(v) > 0 {
+		exposure = math.Pow(2, v[0])
+	}
+	for i := range 3 {
+		for j := range 3 {
+			transform[i][j] *= exposure
+		}
+	}
+
+	// The default crop is relative to the active area
+	crop := image.Rect(0, 0, img.w, img.h)
+	if o, s := raw.floats(tagDefaultCropOrigin), raw.floats(tagDefaultCropSize); len(o) == 2 && len(s) == 2 {
+		r := image.Rect(int(o[0]), int(o[1]), int(o[0]+s[0]), int(o[1]+s[1]))
+		if r.In(crop) && !r.Empty() {
+			crop = r
+		}
+	}
+
+	srgb := builtinProfiles[ProfileSRGB]
+	encoder := newProfileConversion(srgb, srgb)
+	res := image.NewNRGBA64(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	emit := func(y int, rgb []float32) {
+		if y < crop.Min.Y || y >= crop.Max.Y {
+			return
+		}
+		row := res.Pix[(y-crop.Min.Y)*res.Stride:]
+		for x := crop.Min.X; x < crop.Max.X; x++ {
+			v := transform.apply([3]float64{float64(rgb[3*x]), float64(rgb[3*x+1]), float64(rgb[3*x+2])})
+			d := row[(x-crop.Min.X)*8:]
+			for c := range 3 {
+				e := encoder.encodeChannel(c, v[c])
+				d[2*c], d[2*c+1] = uint8(e>>8), uint8(e)
+			}
+			d[6], d[7] = 0xFF, 0xFF
+		}
+	}
+	if img.cfa {
+		switch strings.ToLower(opts.Demosaic) {
+		case DemosaicBilinear:
+			demosaicBilinear(img, emit)
+		case DemosaicAHD, "":
+			demosaicAHD(img, emit)
+		default:
+			return nil, fmt.Errorf("unknown demosaicing algorithm: %s (supported: bilinear, ahd)", opts.Demosaic)
+		}
+	} else {
+		parallelRows(0, img.h, func(y int) {
+			emit(y, img.data[3*y*img.w:3*(y+1)*img.w])
+		})
+	}
+	return res, nil
+}
+
+// dngCameraToSRGB returns the matrix converting white balanced camera
+// colors to linear sRGB. Forward matrices are preferred, otherwise the
+// color matrix (XYZ to camera) is inverted. Of two calibrations the D65
+// one is used.
+func dngCameraToSRGB(field func(tag uint16) *tiffIFD, neutral []float64) mat3 {
+	pick := func(tag1, tag2 uint16) (mat3, bool) {
+		m1, ok1 := field(tag1).matrix(tag1)
+		m2, ok2 := field(tag2).matrix(tag2)
+		if ok2 && (!ok1 || field(tagCalibrationIlluminant2).int(tagCalibrationIlluminant2, 0) == illuminantD65) {
+			return m2, true
+		}
+		return m1, ok1
+	}
+	srgbD50 := builtinProfiles[ProfileSRGB].toXYZ
+	if fm, ok := pick(tagForwardMatrix1, tagForwardMatrix2); ok {
+		// Forward matrices map white balanced camera colors to XYZ (D50)
+		return srgbD50.inverse().mul(fm)
+	}
+	cm, ok := pick(tagColorMatrix1, tagColorMatrix2)
+	if !ok {
+		return mat3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+	if cc, ok := pick(tagCameraCalibration1, tagCameraCalibration2); ok {
+		cm = cc.mul(cm)
+	}
+	if ab := field(tagAnalogBalance).floats(tagAnalogBalance); len(ab) == 3 {
+		cm = mat3{{ab[0], 0, 0}, {0, ab[1], 0}, {0, 0, ab[2]}}.mul(cm)
+	}
+	// Camera colors of sRGB primaries, normalized so that white balanced
+	// neutral camera colors map to neutral sRGB colors
+	camRGB := cm.mul(bradfordD65ToD50.inverse().mul(srgbD50))
+	for i := range 3 {
+		sum := camRGB[i][0] + camRGB[i][1] + camRGB[i][2]
+		if sum != 0 {
+			for j := range 3 {
+				camRGB[i][j] /= sum
+			}
+		}
+	}
+	return camRGB.inverse()
+}
+
+// readDNGSamples reads the (decompressed) samples of the raw image in raster order.
+func readDNGSamples(data []byte, ifd *tiffIFD) ([]uint16, error) {
+	width, height := ifd.int(tagImageWidth, 0), ifd.int(tagImageLength, 0)
+	spp := ifd.int(tagSamplesPerPixel, 1)
+	bps := ifd.int(tagBitsPerSample, 16)
+	compression := ifd.int(tagCompression, 1)
+	if width <= 0 || height <= 0 || spp < 1 || spp > 4 || bps < 1 || bps > 16 {
+		return nil, fmt.Errorf("unsupported DNG raw image (%dx%d, %d samples of %d bits)", width, height, spp, bps)
+	}
+	if ifd.int(tagPlanarConfiguration, 1) != 1 {
+		return nil, fmt.Errorf("unsupported DNG planar configuration")
+	}
+
+	// Strips are tiles spanning the whole width
+	tileW, tileH := ifd.int(tagTileWidth, 0), ifd.int(tagTileLength, 0)
+	offsets, counts := ifd.ints(tagTileOffsets), ifd.ints(tagTileByteCounts)
+	if tileW == 0 || tileH == 0 {
+		tileW, tileH = width, ifd.int(tagRowsPerStrip, height)
+		offsets, counts = ifd.ints(tagStripOffsets), ifd.ints(tagStripByteCounts)
+	}
+	tilesX, tilesY := (width+tileW-1)/tileW, (height+tileH-1)/tileH
+	if len(offsets) < tilesX*tilesY || len(counts) < len(offsets) {
+		return nil, fmt.Errorf("missing DNG raw data")
+	}
+
+	res := make([]uint16, width*height*spp)
+	for t := range tilesX * tilesY {
+		start, size := offsets[t], counts[t]
+		if start < 0 || size < 0 || start+size > len(data) {
+			return nil, fmt.Errorf("truncated DNG raw data")
+		}
+		tile, err := decodeDNGTile(data[start:start+size], ifd, compression, tileW, tileH, spp, bps)
+		if err != nil {
+			return nil, err
+		}
+		x0, y0 := (t%tilesX)*tileW, (t/tilesX)*tileH
+		n := tileW
+		if x0+n > width {
+			n = width - x0
+		}
+		for y := 0; y < tileH && y0+y < height; y++ {
+			copy(res[((y0+y)*width+x0)*spp:], tile[y*tileW*spp:(y*tileW+n)*spp])
+		}
+	}
+	return res, nil
+}
+
+// decodeDNGTile returns the samples of a tile (or strip) with tileW*tileH*spp entries.
+func decodeDNGTile(data []byte, ifd *tiffIFD, compression, tileW, tileH, spp, bps int) ([]uint16, error) {
+	res := make([]uint16, tileW*tileH*spp)
+	switch compression {
+	case 1: // uncompressed
+		rowSamples := tileW * spp
+		switch bps {
+		case 8:
+			for i := 0; i < len(res) && i < len(data); i++ {
+				res[i] = uint16(data[i])
+			}
+		case 16:
+			for i := 0; i < len(res) && 2*i+1 < len(data); i++ {
+				res[i] = ifd.order.Uint16(data[2*i:])
+			}
+		default:
+			// Packed samples, most significant bit first, rows start at byte boundaries
+			rowBytes := (rowSamples*bps + 7) / 8
+			for y := range tileH {
+				if y*rowBytes >= len(data) {
+					break
+				}
+				row := data[y*rowBytes:]
+				var acc uint32
+				var n, pos int
+				for x := range rowSamples {
+					for n < bps && pos < len(row) {
+						acc = acc<<8 | uint32(row[pos])
+						pos++
+						n += 8
+					}
+					if n < bps {
+						break
+					}
+					n -= bps
+					res[y*rowSamples+x] = uint16(acc >> n & (1<<bps - 1))
+				}
+			}
+		}
+	case 7: // lossless JPEG
+		samples, _, _, _, err := decodeLosslessJPEG(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode DNG tile: %v", err)
+		}
+		// Encoders may split rows into multiple components, so the samples are copied in raster order
+		copy(res, samples)
+	case 8: // deflate
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if bps != 16 || len(buf) < 2*len(res) {
+			return nil, fmt.Errorf("unsupported deflate-compressed DNG data (%d bits)", bps)
+		}
+		for i := range res {
+			res[i] = ifd.order.Uint16(buf[2*i:])
+		}
+		if ifd.int(tagPredictor, 1) == 2 {
+			for y := range tileH {
+				row := res[y*tileW*spp : (y+1)*tileW*spp]
+				for i := spp; i < len(row); i++ {
+					row[i] += row[i-spp]
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DNG compression: %d", compression)
+	}
+	return res, nil
+}
+
+// dngRaw is the normalized (0..1) sensor data of the active area. CFA data
+// has one sample per pixel, linear raw data three.
+type dngRaw struct {
+	w, h    int
+	cfa     bool
+	pattern [2][2]int // color (0 = red, 1 = green, 2 = blue) of each position of the 2x2 CFA pattern
+	data    []float32
+	samples []uint16
+	spp     int
+	width   int // of the full raw image
+	area    image.Rectangle
+}
+
+func newDNGRaw(ifd *tiffIFD, samples []uint16) (*dngRaw, error) {
+	width, height := ifd.int(tagImageWidth, 0), ifd.int(tagImageLength, 0)
+	r := &dngRaw{
+		samples: samples,
+		spp:     ifd.int(tagSamplesPerPixel, 1),
+		width:   width,
+		area:    image.Rect(0, 0, width, height),
+		cfa:     ifd.int(tagPhotometric, 0) == photometricCFA,
+	}
+	if a := ifd.ints(tagActiveArea); len(a) == 4 {
+		area := image.Rect(a[1], a[0], a[3], a[2])
+		if area.In(r.area) && !area.Empty() {
+			r.area = area
+		}
+	}
+	r.w, r.h = r.area.Dx(), r.area.Dy()
+
+	if r.cfa {
+		if r.spp != 1 {
+			return nil, fmt.Errorf("unsupported CFA data with %d samples per pixel", r.spp)
+		}
+		dim := ifd.ints(tagCFARepeatPatternDim)
+		pattern := ifd.ints(tagCFAPattern)
+		if len(dim) != 2 || dim[0] != 2 || dim[1] != 2 || len(pattern) != 4 {
+			return nil, fmt.Errorf("unsupported CFA pattern (only 2x2 Bayer patterns are supported)")
+		}
+		for i, c := range pattern {
+			if c > 2 {
+				return nil, fmt.Errorf("unsupported CFA colors (only RGB is supported)")
+			}
+			// The pattern is relative to the active area
+			r.pattern[i/2][i%2] = c
+		}
+	} else if r.spp != 3 {
+		return nil, fmt.Errorf("unsupported linear raw data with %d samples per pixel", r.spp)
+	}
+	return r, nil
+}
+
+// color returns the CFA color at (x, y).
+func (r *dngRaw) color(x, y int) int {
+	return r.pattern[y&1][x&1]
+}
+
+// normalize linearizes the samples, applies black and white levels and
+// the white balance multipliers wb. The results are clipped to 0..1.
+func (r *dngRaw) normalize(ifd *tiffIFD, wb [3]float64) {
+	spp := r.spp
+	linearization := ifd.ints(tagLinearizationTable)
+
+	dim := ifd.ints(tagBlackLevelRepeatDim)
+	if len(dim) != 2 || dim[0] < 1 || dim[1] < 1 {
+		dim = []int{1, 1}
+	}
+	black := ifd.floats(tagBlackLevel)
+	if len(black) < dim[0]*dim[1]*spp {
+		black = make([]float64, dim[0]*dim[1]*spp)
+	}
+	deltaH, deltaV := ifd.floats(tagBlackLevelDeltaH), ifd.floats(tagBlackLevelDeltaV)
+	white := ifd.floats(tagWhiteLevel)
+	for len(white) < spp {
+		white = append(white, float64(int(1)<<ifd.int(tagBitsPerSample, 16)-1))
+	}
+
+	r.data = make([]float32, r.w*r.h*spp)
+	parallelRows(0, r.h, func(y int) {
+		for x := range r.w {
+			for s := range spp {
+				v := float64(r.samples[((r.area.Min.Y+y)*r.width+r.area.Min.X+x)*spp+s])
+				if len(linearization) > 0 {
+					v = float64(linearization[int(math.Min(v, float64(len(linearization)-1)))])
+				}
+				b := black[((y%dim[0])*dim[1]+x%dim[1])*spp+s]
+				if x < len(deltaH) {
+					b += deltaH[x]
+				}
+				if y < len(deltaV) {
+					b += deltaV[y]
+				}
+				v = (v - b) / (white[s] - b)
+				c := s
+				if r.cfa {
+					c = r.color(x, y)
+				}
+				r.data[(y*r.w+x)*spp+s] = float32(math.Max(0, math.Min(1, v*wb[c])))
+			}
+		}
+	})
+	r.samples = nil
+}
@@ -0,0 +1,291 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// tiffEntry is a directory entry of a test TIFF, types are 1 (BYTE),
+// 3 (SHORT) or 4 (LONG).
+type tiffEntry struct {
+	tag, typ uint16
+	values   []uint32
+}
+
+// dngFile returns a little-endian TIFF with a single directory holding the
+// entries and a single strip (or tile) holding data. The strip offset and
+// byte count entries are added.
+func dngFile(data []byte, entries ...tiffEntry) []byte {
+	le := binary.LittleEndian
+	entries = append(entries,
+		tiffEntry{tagStripOffsets, 4, []uint32{0}},
+		tiffEntry{tagStripByteCounts, 4, []uint32{uint32(len(data))}},
+		tiffEntry{tagDNGVersion, 1, []uint32{1, 4, 0, 0}},
+	)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	ifdSize := 2 + 12*len(entries) + 4
+	values := []byte{}
+	dataOffset := 8 + ifdSize
+	for _, e := range entries {
+		if size := exifTypeSize[e.typ] * len(e.values); size > 4 {
+			dataOffset += size + size&1
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'I', 42, 0, 8, 0, 0, 0})
+	binary.Write(&buf, le, uint16(len(entries)))
+	for _, e := range entries {
+		if e.tag == tagStripOffsets {
+			e.values = []uint32{uint32(dataOffset)}
+		}
+		var v bytes.Buffer
+		for _, x := range e.values {
+			switch e.typ {
+			case 1:
+				v.WriteByte(byte(x))
+			case 3:
+				binary.Write(&v, le, uint16(x))
+			default:
+				binary.Write(&v, le, x)
+			}
+		}
+		binary.Write(&buf, le, e.tag)
+		binary.Write(&buf, le, e.typ)
+		binary.Write(&buf, le, uint32(len(e.values)))
+		if v.Len() > 4 {
+			binary.Write(&buf, le, uint32(8+ifdSize+len(values)))
+			values = append(values, v.Bytes()...)
+			if v.Len()&1 != 0 {
+				values = append(values, 0)
+			}
+		} else {
+			buf.Write(append(v.Bytes(), make([]byte, 4-v.Len())...))
+		}
+	}
+	binary.Write(&buf, le, uint32(0))
+	buf.Write(values)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// rawDNG returns a DNG with 16-bit samples (spp per pixel, CFA data with
+// an RGGB pattern if spp is 1), compressed as lossless JPEG if ljpeg is set.
+func rawDNG(w, h, spp int, samples []uint16, ljpeg bool) []byte {
+	photometric, compression := uint32(photometricLinearRaw), uint32(1)
+	if spp == 1 {
+		photometric = photometricCFA
+	}
+	var data []byte
+	if ljpeg {
+		compression = 7
+		data = encodeLosslessJPEG(samples, w, h, spp, 16)
+	} else {
+		data = make([]byte, 2*len(samples))
+		for i, s := range samples {
+			binary.LittleEndian.PutUint16(data[2*i:], s)
+		}
+	}
+	entries := []tiffEntry{
+		{tagImageWidth, 4, []uint32{uint32(w)}},
+		{tagImageLength, 4, []uint32{uint32(h)}},
+		{tagBitsPerSample, 3, []uint32{16}},
+		{tagCompression, 3, []uint32{compression}},
+		{tagPhotometric, 3, []uint32{photometric}},
+		{tagSamplesPerPixel, 3, []uint32{uint32(spp)}},
+		{tagRowsPerStrip, 4, []uint32{uint32(h)}},
+	}
+	if spp == 1 {
+		entries = append(entries,
+			tiffEntry{tagCFARepeatPatternDim, 3, []uint32{2, 2}},
+			tiffEntry{tagCFAPattern, 1, []uint32{0, 1, 1, 2}},
+		)
+	}
+	return dngFile(data, entries...)
+}
+
+// encodeLosslessJPEG encodes samples as a lossless JPEG with predictor 1
+// and a single Huffman table assigning 5-bit codes to all categories.
+func encodeLosslessJPEG(samples []uint16, w, h, components, precision int) []byte {
+	var buf bytes.Buffer
+	segment := func(marker byte, data ...byte) {
+		buf.Write([]byte{0xFF, marker})
+		binary.Write(&buf, binary.BigEndian, uint16(len(data)+2))
+		buf.Write(data)
+	}
+	buf.Write([]byte{0xFF, 0xD8})
+	sof := []byte{byte(precision), byte(h >> 8), byte(h), byte(w >> 8), byte(w), byte(components)}
+	sos := []byte{byte(components)}
+	for c := range components {
+		sof = append(sof, byte(c+1), 0x11, 0)
+		sos = append(sos, byte(c+1), 0)
+	}
+	segment(0xC3, sof...)
+	dht := append([]byte{0, 0, 0, 0, 0, 17}, make([]byte, 11)...)
+	for v := range 17 {
+		dht = append(dht, byte(v))
+	}
+	segment(0xC4, dht...)
+	segment(0xDA, append(sos, 1, 0, 0)...)
+
+	var acc uint64
+	var n uint
+	put := func(v uint32, size uint) {
+		acc = acc<<size | uint64(v)&(1<<size-1)
+		n += size
+		for n >= 8 {
+			b := byte(acc >> (n - 8))
+			buf.WriteByte(b)
+			if b == 0xFF {
+				buf.WriteByte(0)
+			}
+			n -= 8
+		}
+	}
+	stride := w * components
+	for i, s := range samples {
+		var pred uint16
+		switch {
+		case i < components:
+			pred = 1 << (precision - 1)
+		case i < stride:
+			pred = samples[i-components]
+		case i%stride < components:
+			pred = samples[i-stride]
+		default:
+			pred = samples[i-components]
+		}
+		diff := int32(int16(s - pred))
+		abs := diff
+		if abs < 0 {
+			abs = -abs
+		}
+		ssss := uint(bits.Len32(uint32(abs)))
+		put(uint32(ssss), 5)
+		switch {
+		case ssss == 16:
+		case diff > 0:
+			put(uint32(diff), ssss)
+		case diff < 0:
+			put(uint32(diff+1<<ssss-1), ssss)
+		}
+	}
+	if n > 0 {
+		put(1<<(8-n)-1, 8-n)
+	}
+	buf.Write([]byte{0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func randomSamples(n int) []uint16 {
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]uint16, n)
+	for i := range samples {
+		samples[i] = uint16(rng.Intn(1 << 16))
+	}
+	samples[0], samples[1] = 0, 0xFFFF
+	return samples
+}
+
+func TestDecodeLosslessJPEG(t *testing.T) {
+	for _, components := range []int{1, 2} {
+		w, h := 7, 5
+		samples := randomSamples(w * h * components)
+		res, rw, rh, rc, err := decodeLosslessJPEG(encodeLosslessJPEG(samples, w, h, components, 16))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rw != w || rh != h || rc != components {
+			t.Fatalf("expected %dx%d with %d components, got %dx%d with %d", w, h, components, rw, rh, rc)
+		}
+		for i := range samples {
+			if res[i] != samples[i] {
+				t.Fatalf("%d components: sample %d differs: %d vs %d", components, i, res[i], samples[i])
+			}
+		}
+	}
+}
+
+func TestDecodeDNGLinearRaw(t *testing.T) {
+	samples := []uint16{
+		0xFFFF, 0xFFFF, 0xFFFF, 0, 0, 0,
+		0xFFFF, 0, 0, 0, 0, 0xFFFF,
+	}
+	img, err := DecodeDNG(rawDNG(2, 2, 3, samples, false), DefaultDNGOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Rect != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("expected 2x2 pixels, got %v", img.Rect)
+	}
+	for i, v := range samples {
+		c := img.NRGBA64At(i/3%2, i/6)
+		got := [3]uint16{c.R, c.G, c.B}[i%3]
+		if got != v || c.A != 0xFFFF {
+			t.Errorf("sample %d: expected %d, got %d (alpha %d)", i, v, got, c.A)
+		}
+	}
+}
+
+func TestDecodeDNGDemosaicsNeutralCFA(t *testing.T) {
+	w, h := 8, 6
+	samples := make([]uint16, w*h)
+	for i := range samples {
+		samples[i] = 0x8000
+	}
+	data := rawDNG(w, h, 1, samples, false)
+	for _, demosaic := range []string{DemosaicBilinear, DemosaicAHD} {
+		img, err := DecodeDNG(data, DNGOptions{Demosaic: demosaic})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := img.NRGBA64At(0, 0)
+		if want.R != want.G || want.G != want.B || want.R == 0 || want.R == 0xFFFF {
+			t.Fatalf("%s: expected a mid gray, got %v", demosaic, want)
+		}
+		for y := range h {
+			for x := range w {
+				if c := img.NRGBA64At(x, y); c != want {
+					t.Fatalf("%s: expected %v at %d,%d, got %v", demosaic, want, x, y, c)
+				}
+			}
+		}
+	}
+	if _, err := DecodeDNG(data, DNGOptions{Demosaic: "foo"}); err == nil {
+		t.Error("expected an error for an unknown demosaicing algorithm")
+	}
+}
+
+func TestDecodeDNGLosslessJPEG(t *testing.T) {
+	for _, spp := range []int{1, 3} {
+		w, h := 10, 6
+		samples := randomSamples(w * h * spp)
+		plain, err := DecodeDNG(rawDNG(w, h, spp, samples, false), DefaultDNGOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressed, err := DecodeDNG(rawDNG(w, h, spp, samples, true), DefaultDNGOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plain.Pix, compressed.Pix) {
+			t.Errorf("%d samples per pixel: lossless JPEG data decodes differently", spp)
+		}
+	}
+}
+
+func TestIsDNG(t *testing.T) {
+	data := rawDNG(2, 2, 3, make([]uint16, 12), false)
+	if !isDNG(data) {
+		t.Fatal("expected the file to be detected as DNG")
+	}
+	if isDNG(multiPageTIFF(opaqueImage(2, 2))) {
+		t.Error("expected a plain TIFF not to be detected as DNG")
+	}
+}
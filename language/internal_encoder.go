@@ -0,0 +1,237 @@
+package language
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/toxyl/flo"
+	"github.com/toxyl/math"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatTIFF = "tiff"
+	FormatGIF  = "gif"
+	FormatBMP  = "bmp"
+)
+
+// SaveOptions configures how an image is encoded.
+// Options that do not apply to the chosen format are ignored,
+// zero values select the defaults (except for Dither).
+type SaveOptions struct {
+	Format      string    // png, jpeg, webp, tiff, gif or bmp; derived from the path if empty
+	Quality     int       // JPEG quality (1..100)
+	Subsampling string    // JPEG chroma subsampling (444, 422, 420)
+	Compression string    // PNG: none, fast, default, best; TIFF: none, default (deflate)
+	Colors      int       // GIF palette size (2..256)
+	Dither      bool      // GIF Floyd-Steinberg dithering
+	Depth       int       // PNG and TIFF bits per channel (8, 16 or 0 to keep the image's depth)
+	Metadata    *Metadata // EXIF, XMP and ICC data to embed into JPEGs and PNGs
+}
+
+func DefaultSaveOptions() SaveOptions {
+	return SaveOptions{
+		Quality:     90,
+		Subsampling: JPEGSubsampling420,
+		Compression: "default",
+		Colors:      256,
+		Dither:      true,
+	}
+}
+
+// FormatFromPath returns the format matching the extension of path
+// or an empty string if the extension is unknown.
+func FormatFromPath(path string) string {
+	return normalizeFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+func normalizeFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "png":
+		return FormatPNG
+	case "jpg", "jpeg", "jpe":
+		return FormatJPEG
+	case "webp":
+		return FormatWebP
+	case "tif", "tiff":
+		return FormatTIFF
+	case "gif":
+		return FormatGIF
+	case "bmp":
+		return FormatBMP
+	}
+	return ""
+}
+
+// EncodeImage writes img to w using the format and options given in opts.
+// opts.Format must be set.
+func EncodeImage(w io.Writer, img image.Image, opts SaveOptions) error {
+	defaults := DefaultSaveOptions()
+	if opts.Quality <= 0 {
+		opts.Quality = defaults.Quality
+	}
+	if opts.Colors <= 0 {
+		opts.Colors = defaults.Colors
+	}
+	format := normalizeFormat(opts.Format)
+	if !opts.Metadata.Empty() && (format == FormatJPEG || format == FormatPNG) {
+		var buf bytes.Buffer
+		m := opts.Metadata
+		opts.Metadata = nil
+		if err := EncodeImage(&buf, img, opts); err != nil {
+			return err
+		}
+		data, err := embedMetadata(buf.Bytes(), format, m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	switch format {
+	case FormatPNG:
+		enc := &png.Encoder{}
+		switch opts.Compression {
+		case "none":
+			enc.CompressionLevel = png.NoCompression
+		case "fast":
+			enc.CompressionLevel = png.BestSpeed
+		case "best":
+			enc.CompressionLevel = png.BestCompression
+		case "", "default":
+			enc.CompressionLevel = png.DefaultCompression
+		default:
+			return fmt.Errorf("unknown PNG compression: %s (supported: none, fast, default, best)", opts.Compression)
+		}
+		img, err := imageWithDepth(img, opts.Depth)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(w, img)
+	case FormatJPEG:
+		switch opts.Subsampling {
+		case "", JPEGSubsampling420, JPEGSubsampling422, JPEGSubsampling444:
+		default:
+			return fmt.Errorf("unknown JPEG subsampling: %s (supported: 444, 422, 420)", opts.Subsampling)
+		}
+		return encodeJPEG(w, img, opts.Quality, opts.Subsampling)
+	case FormatWebP:
+		return encodeWebP(w, img)
+	case FormatTIFF:
+		to := &tiff.Options{Compression: tiff.Deflate, Predictor: true}
+		switch opts.Compression {
+		case "none":
+			to = &tiff.Options{Compression: tiff.Uncompressed}
+		case "", "default", "deflate":
+		default:
+			return fmt.Errorf("unknown TIFF compression: %s (supported: none, deflate)", opts.Compression)
+		}
+		img, err := imageWithDepth(img, opts.Depth)
+		if err != nil {
+			return err
+		}
+		return tiff.Encode(w, img, to)
+	case FormatGIF:
+		o := &gif.Options{
+			NumColors: math.Clamp(opts.Colors, 2, 256),
+			Drawer:    draw.Src,
+		}
+		o.Quantizer = medianCutQuantizer{numColors: o.NumColors}
+		if opts.Dither {
+			o.Drawer = draw.FloydSteinberg
+		}
+		return gif.Encode(w, img, o)
+	case FormatBMP:
+		return bmp.Encode(w, ImageTo8Bit(img))
+	}
+	return fmt.Errorf("unsupported image format: %s (supported: png, jpeg, webp, tiff, gif, bmp)", opts.Format)
+}
+
+// SaveImage encodes img and stores it at path, creating parent directories
+// as needed. Readers never see partially written images.
+// If opts.Format is empty, the format is derived from the extension of path.
+func SaveImage(path string, img image.Image, opts SaveOptions) error {
+	if opts.Format == "" {
+		opts.Format = FormatFromPath(path)
+		if opts.Format == "" {
+			return fmt.Errorf("can't determine image format from path: %s", path)
+		}
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return EncodeImage(w, img, opts)
+	})
+}
+
+// writeFileAtomic creates the parent directories of path and writes the file
+// using write. The data goes to a uniquely named temporary file in the same
+// directory first which is then renamed, so readers never see partially
+// written files and concurrent writers don't interfere.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f := flo.File(path)
+	if err := f.Mkparent(0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	err = write(tmp)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// imageWithDepth converts img to 8 or 16 bits per channel.
+// A depth of 0 returns the image unchanged.
+func imageWithDepth(img image.Image, depth int) (image.Image, error) {
+	switch depth {
+	case 0:
+		if l, ok := img.(*LinearImage); ok {
+			return fromLinearImage(l), nil
+		}
+		return img, nil
+	case 8:
+		switch img.(type) {
+		case *image.NRGBA, *image.RGBA, *image.Gray, *image.Paletted:
+			return img, nil
+		case *image.NRGBA64, *image.RGBA64, *LinearImage:
+			return ImageTo8Bit(img), nil
+		}
+		dst := image.NewNRGBA(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), img, dst.Bounds().Min, draw.Src)
+		return dst, nil
+	case 16:
+		switch t := img.(type) {
+		case *image.NRGBA64, *image.RGBA64:
+			return img, nil
+		case *LinearImage:
+			return fromLinearImage(t), nil
+		}
+		dst := IFromBounds(img.Bounds())
+		draw.Draw(dst, dst.Bounds(), img, dst.Bounds().Min, draw.Src)
+		return dst, nil
+	}
+	return nil, fmt.Errorf("unsupported bit depth: %d (supported: 8, 16)", depth)
+}
@@ -0,0 +1,390 @@
+package language
+
+import (
+	"bufio"
+	"image"
+	"image/jpeg"
+	"io"
+
+	"github.com/toxyl/math"
+)
+
+// The standard library only writes 4:2:0 subsampled JPEGs, so 4:4:4 and
+// 4:2:2 are written by the baseline encoder below. It uses the example
+// quantization and Huffman tables of the JPEG specification (Annex K).
+
+const (
+	JPEGSubsampling444 = "444"
+	JPEGSubsampling422 = "422"
+	JPEGSubsampling420 = "420"
+)
+
+// jpegUnzig maps the zig-zag index of a coefficient to its natural index.
+var jpegUnzig = [64]int{
+	0, 1, 8, 16, 9, 2, 3, 10,
+	17, 24, 32, 25, 18, 11, 4, 5,
+	12, 19, 26, 33, 40, 48, 41, 34,
+	27, 20, 13, 6, 7, 14, 21, 28,
+	35, 42, 49, 56, 57, 50, 43, 36,
+	29, 22, 15, 23, 30, 37, 44, 51,
+	58, 59, 52, 45, 38, 31, 39, 46,
+	53, 60, 61, 54, 47, 55, 62, 63,
+}
+
+// jpegQuant holds the luminance and chrominance quantization tables in zig-zag order.
+var jpegQuant = [2][64]byte{
+	{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	},
+	{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	},
+}
+
+type jpegHuffmanSpec struct {
+	count [16]byte // count[i] is the number of codes of length i+1
+	value []byte
+}
+
+// jpegHuffmanSpecs holds the luminance DC, luminance AC, chrominance DC and
+// chrominance AC tables, in that order.
+var jpegHuffmanSpecs = [4]jpegHuffmanSpec{
+	{
+		[16]byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 125},
+		[]byte{
+			0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+			0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+			0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+			0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+			0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+			0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+			0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+			0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+			0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+			0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+			0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+			0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+			0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+			0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+			0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+			0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+			0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+			0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+			0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+			0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+	{
+		[16]byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0},
+		[]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+	},
+	{
+		[16]byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 119},
+		[]byte{
+			0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+			0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+			0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+			0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+			0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+			0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+			0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+			0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+			0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+			0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+			0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+			0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+			0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+			0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+			0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+			0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+			0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+			0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+			0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+			0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+			0xf9, 0xfa,
+		},
+	},
+}
+
+// jpegHuffmanCode is a codeword and its length in bits.
+type jpegHuffmanCode struct {
+	code uint32
+	size uint32
+}
+
+func (s jpegHuffmanSpec) codes() [256]jpegHuffmanCode {
+	var codes [256]jpegHuffmanCode
+	code, k := uint32(0), 0
+	for i, n := range s.count {
+		for range n {
+			codes[s.value[k]] = jpegHuffmanCode{code, uint32(i + 1)}
+			code++
+			k++
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// jpegDCTCos[x][u] = C(u) * cos((2x+1)uπ/16) / 2
+var jpegDCTCos = func() (c [8][8]float64) {
+	for x := range 8 {
+		for u := range 8 {
+			cu := 1.0
+			if u == 0 {
+				cu = 1 / math.Sqrt(2.0)
+			}
+			c[x][u] = cu * math.Cos(float64(2*x+1)*float64(u)*math.Pi/16) / 2
+		}
+	}
+	return
+}()
+
+// fdct transforms the level-shifted samples of a block in place.
+func fdct(b *[64]float64) {
+	var tmp [64]float64
+	for y := range 8 {
+		for u := range 8 {
+			sum := 0.0
+			for x := range 8 {
+				sum += b[y*8+x] * jpegDCTCos[x][u]
+			}
+			tmp[y*8+u] = sum
+		}
+	}
+	for u := range 8 {
+		for v := range 8 {
+			sum := 0.0
+			for y := range 8 {
+				sum += tmp[y*8+u] * jpegDCTCos[y][v]
+			}
+			b[v*8+u] = sum
+		}
+	}
+}
+
+type jpegEncoder struct {
+	w      *bufio.Writer
+	err    error
+	bits   uint32
+	nBits  uint32
+	quant  [2][64]byte
+	codes  [4][256]jpegHuffmanCode
+	prevDC [3]int32
+}
+
+func (e *jpegEncoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *jpegEncoder) writeByte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+func (e *jpegEncoder) writeMarker(marker byte, length int) {
+	e.write([]byte{0xFF, marker, byte((length + 2) >> 8), byte(length + 2)})
+}
+
+// emit writes the lowest nBits of bits, stuffing a zero byte after every 0xFF.
+func (e *jpegEncoder) emit(bits, nBits uint32) {
+	nBits += e.nBits
+	bits <<= 32 - nBits
+	bits |= e.bits
+	for nBits >= 8 {
+		b := byte(bits >> 24)
+		e.writeByte(b)
+		if b == 0xFF {
+			e.writeByte(0x00)
+		}
+		bits <<= 8
+		nBits -= 8
+	}
+	e.bits, e.nBits = bits, nBits
+}
+
+func (e *jpegEncoder) emitHuff(table int, value byte) {
+	c := e.codes[table][value]
+	e.emit(c.code, c.size)
+}
+
+// emitValue writes value using the JPEG magnitude category encoding,
+// prefixed by the Huffman code of runLength|category.
+func (e *jpegEncoder) emitValue(table int, runLength, value int32) {
+	a, b := value, value
+	if a < 0 {
+		a, b = -value, value-1
+	}
+	nBits := uint32(0)
+	for a > 0 {
+		nBits++
+		a >>= 1
+	}
+	e.emitHuff(table, byte(uint32(runLength<<4)|nBits))
+	if nBits > 0 {
+		e.emit(uint32(b)&(1<<nBits-1), nBits)
+	}
+}
+
+func (e *jpegEncoder) writeBlock(b *[64]float64, component int) {
+	q := 0
+	if component > 0 {
+		q = 1
+	}
+	fdct(b)
+	dc := int32(math.Round(b[0] / float64(e.quant[q][0])))
+	e.emitValue(2*q, 0, dc-e.prevDC[component])
+	e.prevDC[component] = dc
+
+	runLength := int32(0)
+	for k := 1; k < 64; k++ {
+		ac := int32(math.Round(b[jpegUnzig[k]] / float64(e.quant[q][k])))
+		if ac == 0 {
+			runLength++
+			continue
+		}
+		for runLength > 15 {
+			e.emitHuff(2*q+1, 0xF0)
+			runLength -= 16
+		}
+		e.emitValue(2*q+1, runLength, ac)
+		runLength = 0
+	}
+	if runLength > 0 {
+		e.emitHuff(2*q+1, 0x00)
+	}
+}
+
+// encodeJPEG writes img as baseline JPEG with the given quality (1..100)
+// and chroma subsampling (444, 422 or 420).
+func encodeJPEG(w io.Writer, img image.Image, quality int, subsampling string) error {
+	quality = math.Clamp(quality, 1, 100)
+	var h, v int
+	switch subsampling {
+	case JPEGSubsampling444:
+		h, v = 1, 1
+	case JPEGSubsampling422:
+		h, v = 2, 1
+	default:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	}
+
+	e := &jpegEncoder{w: bufio.NewWriter(w)}
+	scale := 200 - 2*quality
+	if quality < 50 {
+		scale = 5000 / quality
+	}
+	for i := range e.quant {
+		for j := range e.quant[i] {
+			e.quant[i][j] = byte(math.Clamp((int(jpegQuant[i][j])*scale+50)/100, 1, 255))
+		}
+	}
+	for i, spec := range jpegHuffmanSpecs {
+		e.codes[i] = spec.codes()
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	e.write([]byte{0xFF, 0xD8}) // SOI
+
+	e.writeMarker(0xDB, 2*65) // DQT
+	for i := range e.quant {
+		e.writeByte(byte(i))
+		e.write(e.quant[i][:])
+	}
+
+	e.writeMarker(0xC0, 6+3*3) // SOF0
+	e.write([]byte{8, byte(height >> 8), byte(height), byte(width >> 8), byte(width), 3})
+	e.write([]byte{1, byte(h<<4 | v), 0, 2, 0x11, 1, 3, 0x11, 1})
+
+	dhtLen := 0
+	for _, spec := range jpegHuffmanSpecs {
+		dhtLen += 1 + 16 + len(spec.value)
+	}
+	e.writeMarker(0xC4, dhtLen) // DHT
+	for i, spec := range jpegHuffmanSpecs {
+		e.writeByte(byte((i&1)<<4 | i>>1))
+		e.write(spec.count[:])
+		e.write(spec.value)
+	}
+
+	e.writeMarker(0xDA, 4+2*3) // SOS
+	e.write([]byte{3, 1, 0x00, 2, 0x11, 3, 0x11, 0, 63, 0})
+
+	// Convert to YCbCr once, replicating edge pixels to fill partial blocks
+	mcuW, mcuH := 8*h, 8*v
+	cols, rows := (width+mcuW-1)/mcuW, (height+mcuH-1)/mcuH
+	ycc := make([][3]float64, width*height)
+	parallelRows(0, height, func(y int) {
+		for x := range width {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			fr, fg, fb := float64(r>>8), float64(g>>8), float64(b>>8)
+			ycc[y*width+x] = [3]float64{
+				0.299*fr + 0.587*fg + 0.114*fb - 128,
+				-0.168736*fr - 0.331264*fg + 0.5*fb,
+				0.5*fr - 0.418688*fg - 0.081312*fb,
+			}
+		}
+	})
+	at := func(x, y int) [3]float64 {
+		return ycc[math.Min(y, height-1)*width+math.Min(x, width-1)]
+	}
+
+	var block [64]float64
+	for my := range rows {
+		for mx := range cols {
+			x0, y0 := mx*mcuW, my*mcuH
+			for by := range v {
+				for bx := range h {
+					for i := range 64 {
+						block[i] = at(x0+bx*8+i%8, y0+by*8+i/8)[0]
+					}
+					e.writeBlock(&block, 0)
+				}
+			}
+			for c := 1; c < 3; c++ {
+				for i := range 64 {
+					sum := 0.0
+					for sy := range v {
+						for sx := range h {
+							sum += at(x0+(i%8)*h+sx, y0+(i/8)*v+sy)[c]
+						}
+					}
+					block[i] = sum / float64(h*v)
+				}
+				e.writeBlock(&block, c)
+			}
+		}
+	}
+
+	// Pad the last byte with 1s and finish with EOI
+	e.emit(0x7F, 7)
+	e.write([]byte{0xFF, 0xD9})
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
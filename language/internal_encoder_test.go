@@ -0,0 +1,143 @@
+package language
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// opaqueImage returns an opaque 8-bit gradient, w and h have to be at least 2.
+func opaqueImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 255 / (w - 1)), uint8(y * 255 / (h - 1)), 128, 255})
+		}
+	}
+	return img
+}
+
+// encodeDecode encodes img with opts and decodes the result.
+func encodeDecode(t *testing.T, img image.Image, opts SaveOptions) *image.NRGBA64 {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeImage(&buf, img, opts); err != nil {
+		t.Fatalf("%s: %v", opts.Format, err)
+	}
+	if typ := DetectImageType(buf.Bytes()); typ != opts.Format {
+		t.Fatalf("%s: detected as %q", opts.Format, typ)
+	}
+	res, err := DecodeImage(buf.Bytes())
+	if err != nil {
+		t.Fatalf("%s: %v", opts.Format, err)
+	}
+	return imageToNRGBA64(res)
+}
+
+// assertClose fails if a channel of a and b differs by more than tolerance.
+func assertClose(t *testing.T, name string, a *image.NRGBA, b *image.NRGBA64, tolerance int) {
+	t.Helper()
+	if a.Bounds().Size() != b.Rect.Size() {
+		t.Fatalf("%s: size differs: %v vs %v", name, a.Bounds().Size(), b.Rect.Size())
+	}
+	for y := range a.Rect.Dy() {
+		for x := range a.Rect.Dx() {
+			c1 := a.NRGBAAt(a.Rect.Min.X+x, a.Rect.Min.Y+y)
+			c2 := b.NRGBA64At(b.Rect.Min.X+x, b.Rect.Min.Y+y)
+			for i, d := range []int{
+				int(c1.R) - int(c2.R>>8), int(c1.G) - int(c2.G>>8),
+				int(c1.B) - int(c2.B>>8), int(c1.A) - int(c2.A>>8),
+			} {
+				if d < -tolerance || d > tolerance {
+					t.Fatalf("%s: channel %d at (%d, %d) differs: %v vs %v", name, i, x, y, c1, c2)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeLosslessRoundTrip(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i*7 + i/5)
+	}
+	for _, format := range []string{FormatPNG, FormatWebP, FormatTIFF} {
+		opts := DefaultSaveOptions()
+		opts.Format = format
+		assertClose(t, format, src, encodeDecode(t, src, opts), 0)
+	}
+	assertClose(t, FormatBMP, opaqueImage(37, 23), encodeDecode(t, opaqueImage(37, 23), SaveOptions{Format: FormatBMP}), 0)
+}
+
+func TestEncodeKeeps16Bits(t *testing.T) {
+	src := testImage(16, 8)
+	for _, format := range []string{FormatPNG, FormatTIFF} {
+		opts := DefaultSaveOptions()
+		opts.Format = format
+		res := encodeDecode(t, src, opts)
+		for i := range src.Pix {
+			if src.Pix[i] != res.Pix[i] {
+				t.Fatalf("%s: byte %d differs: %d vs %d", format, i, src.Pix[i], res.Pix[i])
+			}
+		}
+		opts.Depth = 8
+		if res := encodeDecode(t, src, opts); res.Pix[1] != res.Pix[0] {
+			t.Errorf("%s: expected 8 bits per channel with depth 8", format)
+		}
+	}
+}
+
+func TestEncodeJPEG(t *testing.T) {
+	src := opaqueImage(120, 72)
+	for _, subsampling := range []string{JPEGSubsampling444, JPEGSubsampling422, JPEGSubsampling420} {
+		res := encodeDecode(t, src, SaveOptions{Format: FormatJPEG, Quality: 95, Subsampling: subsampling})
+		assertClose(t, "jpeg "+subsampling, src, res, 8)
+	}
+	var low, high bytes.Buffer
+	if err := EncodeImage(&low, src, SaveOptions{Format: FormatJPEG, Quality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeImage(&high, src, SaveOptions{Format: FormatJPEG, Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if low.Len() >= high.Len() {
+		t.Errorf("expected quality 10 to be smaller than quality 100, got %d and %d bytes", low.Len(), high.Len())
+	}
+	if err := EncodeImage(&low, src, SaveOptions{Format: FormatJPEG, Subsampling: "411"}); err == nil {
+		t.Error("expected an error for an unknown subsampling")
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+	// Without dithering an image with few colors keeps them exactly
+	src := image.NewNRGBA(image.Rect(0, 0, 20, 20))
+	colors := []color.NRGBA{{255, 0, 0, 255}, {0, 200, 0, 255}, {10, 20, 250, 255}, {240, 240, 0, 255}}
+	for y := range 20 {
+		for x := range 20 {
+			src.SetNRGBA(x, y, colors[(x/5+y/5)%len(colors)])
+		}
+	}
+	assertClose(t, "gif", src, encodeDecode(t, src, SaveOptions{Format: FormatGIF, Colors: 4}), 0)
+
+	// The median-cut quantizer limits the palette to the requested size
+	grad := opaqueImage(64, 64)
+	for _, n := range []int{2, 16, 256} {
+		res := encodeDecode(t, grad, SaveOptions{Format: FormatGIF, Colors: n})
+		seen := map[color.NRGBA64]bool{}
+		for y := range 64 {
+			for x := range 64 {
+				seen[res.NRGBA64At(x, y)] = true
+			}
+		}
+		if len(seen) > n {
+			t.Errorf("expected at most %d colors, got %d", n, len(seen))
+		}
+		if n == 256 {
+			assertClose(t, "gif 256", grad, res, 16)
+		}
+	}
+	if p := (medianCutQuantizer{numColors: 8}).Quantize(nil, grad); len(p) != 8 {
+		t.Errorf("expected a palette of 8 colors, got %d", len(p))
+	}
+}
@@ -0,0 +1,553 @@
+package language
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"slices"
+
+	"github.com/toxyl/math"
+)
+
+// encodeWebP writes img as lossless WebP (VP8L).
+func encodeWebP(w io.Writer, img image.Image) error {
+	data, err := vp8lBitstream(img)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(riffHeader("WEBP", 8+len(data)+len(data)&1)); err != nil {
+		return err
+	}
+	return writeRIFFChunk(w, "VP8L", data)
+}
+
+// vp8lBitstream returns the VP8L bitstream of img. The encoder applies the
+// subtract-green and predictor transforms and LZ77 backward references,
+// then entropy codes the result with one set of prefix codes for the whole image.
+func vp8lBitstream(img image.Image) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return nil, fmt.Errorf("WebP only supports images from 1x1 to 16384x16384 pixels, got %dx%d", width, height)
+	}
+
+	argb := make([]uint32, width*height)
+	parallelRows(0, height, func(y int) {
+		for x := range width {
+			c := nrgbaAt(img, bounds.Min.X+x, bounds.Min.Y+y)
+			r, g, b, a := uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+			r, b = (r-g)&0xFF, (b-g)&0xFF // subtract-green transform
+			argb[y*width+x] = a<<24 | r<<16 | g<<8 | b
+		}
+	})
+	hasAlpha := false
+	for _, p := range argb {
+		if p>>24 != 0xFF {
+			hasAlpha = true
+			break
+		}
+	}
+
+	bw := &vp8lBitWriter{}
+	bw.write(0x2F, 8) // signature
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+
+	bw.write(1, 1) // transform present
+	bw.write(2, 2) // subtract-green
+
+	bw.write(1, 1) // transform present
+	bw.write(0, 2) // predictor
+	bw.write(vp8lPredictorBits-2, 3)
+	residuals, modes := vp8lPredict(argb, width, height)
+	bw.writeImageData(modes, false)
+
+	bw.write(0, 1) // no more transforms
+	bw.writeImageData(residuals, true)
+	return bw.bytes(), nil
+}
+
+// riffHeader returns the header of a RIFF file of the given form type whose
+// chunks take size bytes.
+func riffHeader(form string, size int) []byte {
+	header := make([]byte, 12)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+size))
+	copy(header[8:], form)
+	return header
+}
+
+// writeRIFFChunk writes a RIFF chunk, padded to an even size.
+func writeRIFFChunk(w io.Writer, typ string, data []byte) error {
+	header := make([]byte, 8)
+	copy(header, typ)
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if len(data)&1 == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// nrgbaAt returns the non-premultiplied 8-bit color at (x, y). Unlike
+// color.NRGBAModel it does not lose precision for translucent NRGBA pixels.
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	switch t := img.(type) {
+	case *image.NRGBA:
+		return t.NRGBAAt(x, y)
+	case *image.NRGBA64:
+		c := t.NRGBA64At(x, y)
+		return color.NRGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), uint8(c.A >> 8)}
+	}
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+// vp8lPredictorBits is the log2 of the tile size of the predictor transform.
+const vp8lPredictorBits = 5
+
+// vp8lPredictors are the predictor modes the encoder chooses from, per tile.
+var vp8lPredictors = map[uint32]func(l, t, tl uint32) uint32{
+	1: func(l, t, tl uint32) uint32 { return l },
+	2: func(l, t, tl uint32) uint32 { return t },
+	7: func(l, t, tl uint32) uint32 { return vp8lAverage2(l, t) },
+	11: func(l, t, tl uint32) uint32 {
+		pl, pt := 0, 0 // distances of the estimate l+t-tl to l and t
+		for shift := 0; shift < 32; shift += 8 {
+			cl, ct, ctl := int(l>>shift&0xFF), int(t>>shift&0xFF), int(tl>>shift&0xFF)
+			pl += math.Abs(ct - ctl)
+			pt += math.Abs(cl - ctl)
+		}
+		if pl < pt {
+			return l
+		}
+		return t
+	},
+	12: func(l, t, tl uint32) uint32 {
+		var p uint32
+		for shift := 0; shift < 32; shift += 8 {
+			c := math.Clamp(int(l>>shift&0xFF)+int(t>>shift&0xFF)-int(tl>>shift&0xFF), 0, 255)
+			p |= uint32(c) << shift
+		}
+		return p
+	},
+}
+
+func vp8lAverage2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xFEFEFEFE) >> 1) + (a & b)
+}
+
+func vp8lSub(a, b uint32) uint32 {
+	var d uint32
+	for shift := 0; shift < 32; shift += 8 {
+		d |= ((a>>shift - b>>shift) & 0xFF) << shift
+	}
+	return d
+}
+
+// vp8lPredict picks the predictor with the smallest residuals for every tile
+// and returns the residuals and the predictor mode image.
+func vp8lPredict(argb []uint32, width, height int) (residuals, modes []uint32) {
+	predict := func(mode uint32, x, y int) uint32 {
+		switch {
+		case x == 0 && y == 0:
+			return 0xFF000000
+		case y == 0:
+			return argb[x-1]
+		case x == 0:
+			return argb[(y-1)*width]
+		}
+		i := y*width + x
+		return vp8lPredictors[mode](argb[i-1], argb[i-width], argb[i-width-1])
+	}
+	tile := 1 << vp8lPredictorBits
+	tilesX, tilesY := (width+tile-1)/tile, (height+tile-1)/tile
+	residuals = make([]uint32, len(argb))
+	modes = make([]uint32, tilesX*tilesY)
+	parallelRows(0, tilesY, func(ty int) {
+		for tx := range tilesX {
+			x0, y0 := tx*tile, ty*tile
+			x1, y1 := math.Min(x0+tile, width), math.Min(y0+tile, height)
+			bestMode, bestCost := uint32(1), -1
+			for mode := range vp8lPredictors {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						d := vp8lSub(argb[y*width+x], predict(mode, x, y))
+						for shift := 0; shift < 32; shift += 8 {
+							cost += math.Abs(int(int8(d >> shift)))
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost || cost == bestCost && mode < bestMode {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xFF000000 | bestMode<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					residuals[y*width+x] = vp8lSub(argb[y*width+x], predict(bestMode, x, y))
+				}
+			}
+		}
+	})
+	return residuals, modes
+}
+
+// writeImageData entropy codes argb without a color cache. Only the main
+// image may use meta prefix codes, which this encoder never does.
+func (bw *vp8lBitWriter) writeImageData(argb []uint32, isMain bool) {
+	bw.write(0, 1) // no color cache
+	if isMain {
+		bw.write(0, 1) // no meta prefix codes
+	}
+
+	tokens := vp8lBackwardRefs(argb)
+	var histos [5][]int
+	for i, n := range []int{256 + 24, 256, 256, 256, 40} {
+		histos[i] = make([]int, n)
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			histos[0][t.argb>>8&0xFF]++
+			histos[1][t.argb>>16&0xFF]++
+			histos[2][t.argb&0xFF]++
+			histos[3][t.argb>>24]++
+			continue
+		}
+		code, _, _ := vp8lPrefixEncode(t.length)
+		histos[0][256+code]++
+		code, _, _ = vp8lPrefixEncode(t.dist + 120)
+		histos[4][code]++
+	}
+	var codes [5][]vp8lCode
+	for i, h := range histos {
+		codes[i] = bw.writePrefixCode(h)
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			bw.writeCode(codes[0][t.argb>>8&0xFF])
+			bw.writeCode(codes[1][t.argb>>16&0xFF])
+			bw.writeCode(codes[2][t.argb&0xFF])
+			bw.writeCode(codes[3][t.argb>>24])
+			continue
+		}
+		code, extraBits, extra := vp8lPrefixEncode(t.length)
+		bw.writeCode(codes[0][256+code])
+		bw.write(extra, extraBits)
+		code, extraBits, extra = vp8lPrefixEncode(t.dist + 120)
+		bw.writeCode(codes[4][code])
+		bw.write(extra, extraBits)
+	}
+}
+
+// vp8lToken is either a literal pixel (length 0) or a backward reference.
+type vp8lToken struct {
+	argb   uint32
+	length int
+	dist   int
+}
+
+// vp8lBackwardRefs finds repeated runs of pixels using a hash of two
+// consecutive pixels, always also trying the previous pixel (runs).
+func vp8lBackwardRefs(argb []uint32) []vp8lToken {
+	const (
+		hashBits  = 16
+		minLength = 3
+		maxLength = 4096
+		maxDist   = 1<<20 - 120
+	)
+	hash := func(i int) uint32 {
+		return (argb[i]*0x1E35A7BD + argb[i+1]*0x9E3779B1) >> (32 - hashBits)
+	}
+	matchLength := func(i, j int) int {
+		n := 0
+		for i+n < len(argb) && n < maxLength && argb[i+n] == argb[j+n] {
+			n++
+		}
+		return n
+	}
+	last := make([]int, 1<<hashBits)
+	for i := range last {
+		last[i] = -1
+	}
+	tokens := make([]vp8lToken, 0, len(argb)/2)
+	for i := 0; i < len(argb); {
+		bestLen, bestDist := 0, 0
+		if i > 0 {
+			bestLen, bestDist = matchLength(i, i-1), 1
+		}
+		if i+1 < len(argb) {
+			h := hash(i)
+			if j := last[h]; j >= 0 && i-j <= maxDist {
+				if n := matchLength(i, j); n > bestLen {
+					bestLen, bestDist = n, i-j
+				}
+			}
+			last[h] = i
+		}
+		if bestLen < minLength {
+			tokens = append(tokens, vp8lToken{argb: argb[i]})
+			i++
+			continue
+		}
+		tokens = append(tokens, vp8lToken{length: bestLen, dist: bestDist})
+		for k := i + 1; k < i+bestLen && k+1 < len(argb); k++ {
+			last[hash(k)] = k
+		}
+		i += bestLen
+	}
+	return tokens
+}
+
+// vp8lPrefixEncode splits a length or distance code into its prefix symbol and extra bits.
+func vp8lPrefixEncode(v int) (code int, extraBits uint32, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := 0
+	for d>>(h+1) != 0 {
+		h++
+	}
+	second := (d >> (h - 1)) & 1
+	extraBits = uint32(h - 1)
+	return 2*h + second, extraBits, uint32(d) & (1<<extraBits - 1)
+}
+
+type vp8lCode struct {
+	bits   uint32 // already bit-reversed for LSB-first writing
+	length uint32
+}
+
+type vp8lBitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint32
+}
+
+func (bw *vp8lBitWriter) write(v uint32, n uint32) {
+	bw.acc |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *vp8lBitWriter) writeCode(c vp8lCode) { bw.write(c.bits, c.length) }
+
+func (bw *vp8lBitWriter) bytes() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.acc))
+		bw.acc, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
+
+// writePrefixCode writes the prefix code for the given histogram and returns the codes.
+func (bw *vp8lBitWriter) writePrefixCode(histo []int) []vp8lCode {
+	var used []int
+	for s, n := range histo {
+		if n > 0 {
+			used = append(used, s)
+		}
+	}
+	codes := make([]vp8lCode, len(histo))
+	if len(used) <= 2 && (len(used) == 0 || used[len(used)-1] < 256) {
+		// Simple code: one or two symbols with a code length of 0 or 1
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] > 1 {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		} else {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			codes[used[1]] = vp8lCode{1, 1}
+			codes[used[0]] = vp8lCode{0, 1}
+		}
+		return codes
+	}
+
+	lengths := huffmanCodeLengths(histo, 15)
+	codes = canonicalCodes(lengths)
+
+	// Run-length encode the code lengths with the code length alphabet
+	type rle struct{ sym, extra, extraBits int }
+	var seq []rle
+	for i := 0; i < len(lengths); {
+		l := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == l {
+			run++
+		}
+		i += run
+		if l == 0 {
+			for run >= 11 {
+				n := math.Min(run, 138)
+				seq = append(seq, rle{18, n - 11, 7})
+				run -= n
+			}
+			if run >= 3 {
+				seq = append(seq, rle{17, run - 3, 3})
+				run = 0
+			}
+		} else {
+			seq = append(seq, rle{l, 0, 0})
+			run--
+			for run >= 3 {
+				n := math.Min(run, 6)
+				seq = append(seq, rle{16, n - 3, 2})
+				run -= n
+			}
+		}
+		for ; run > 0; run-- {
+			seq = append(seq, rle{l, 0, 0})
+		}
+	}
+
+	clHisto := make([]int, 19)
+	for _, s := range seq {
+		clHisto[s.sym]++
+	}
+	clLengths := huffmanCodeLengths(clHisto, 7)
+	clCodes := canonicalCodes(clLengths)
+	order := [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	num := 19
+	for num > 4 && clLengths[order[num-1]] == 0 {
+		num--
+	}
+	bw.write(0, 1) // normal code
+	bw.write(uint32(num-4), 4)
+	for _, s := range order[:num] {
+		bw.write(uint32(clLengths[s]), 3)
+	}
+	bw.write(0, 1) // code lengths for the whole alphabet follow
+	for _, s := range seq {
+		bw.writeCode(clCodes[s.sym])
+		bw.write(uint32(s.extra), uint32(s.extraBits))
+	}
+	return codes
+}
+
+type huffmanNode struct {
+	weight int
+	symbol int // -1 for internal nodes
+	left   *huffmanNode
+	right  *huffmanNode
+}
+
+type huffmanHeap []*huffmanNode
+
+func (h huffmanHeap) Len() int           { return len(h) }
+func (h huffmanHeap) Less(i, j int) bool { return h[i].weight < h[j].weight }
+func (h huffmanHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *huffmanHeap) Push(x any)        { *h = append(*h, x.(*huffmanNode)) }
+func (h *huffmanHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// huffmanCodeLengths computes Huffman code lengths for the histogram,
+// flattening the histogram until no code is longer than maxLength.
+// Symbols with a count of zero get a length of zero.
+func huffmanCodeLengths(histo []int, maxLength int) []int {
+	lengths := make([]int, len(histo))
+	counts := slices.Clone(histo)
+	for {
+		h := &huffmanHeap{}
+		for s, n := range counts {
+			if n > 0 {
+				*h = append(*h, &huffmanNode{weight: n, symbol: s})
+			}
+		}
+		if h.Len() == 1 {
+			lengths[(*h)[0].symbol] = 1
+			return lengths
+		}
+		heap.Init(h)
+		for h.Len() > 1 {
+			a, b := heap.Pop(h).(*huffmanNode), heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{weight: a.weight + b.weight, symbol: -1, left: a, right: b})
+		}
+		tooLong := false
+		var walk func(n *huffmanNode, depth int)
+		walk = func(n *huffmanNode, depth int) {
+			if n.symbol >= 0 {
+				lengths[n.symbol] = depth
+				tooLong = tooLong || depth > maxLength
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk((*h)[0], 0)
+		if !tooLong {
+			return lengths
+		}
+		for s, n := range counts {
+			if n > 0 {
+				counts[s] = n>>1 | 1
+			}
+		}
+	}
+}
+
+// canonicalCodes assigns canonical prefix codes to the given code lengths.
+// A code with a single symbol is decoded without reading any bits, so that
+// symbol gets an empty code.
+func canonicalCodes(lengths []int) []vp8lCode {
+	codes := make([]vp8lCode, len(lengths))
+	var count [16]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	if len(lengths)-count[0] == 1 {
+		return codes
+	}
+	count[0] = 0
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l < 16; l++ {
+		code = (code + uint32(count[l-1])) << 1
+		next[l] = code
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		rev := uint32(0)
+		for i := 0; i < l; i++ {
+			rev = rev<<1 | (c>>i)&1
+		}
+		codes[s] = vp8lCode{rev, uint32(l)}
+	}
+	return codes
+}
@@ -0,0 +1,222 @@
+package language
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/toxyl/math"
+)
+
+// solveLinear solves a*x = b with Gaussian elimination and partial pivoting.
+// a and b are modified.
+func solveLinear(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for c := range n {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[p][c]) {
+				p = r
+			}
+		}
+		if math.Abs(a[p][c]) < 1e-12 {
+			return nil, fmt.Errorf("the system of equations is singular")
+		}
+		a[c], a[p] = a[p], a[c]
+		b[c], b[p] = b[p], b[c]
+		for r := c + 1; r < n; r++ {
+			f := a[r][c] / a[c][c]
+			for k := c; k < n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		v := b[r]
+		for k := r + 1; k < n; k++ {
+			v -= a[r][k] * x[k]
+		}
+		x[r] = v / a[r][r]
+	}
+	return x, nil
+}
+
+// leastSquares returns the x minimizing |a*x - b| by solving the normal equations.
+func leastSquares(rows [][]float64, b []float64) ([]float64, error) {
+	n := len(rows[0])
+	ata := make([][]float64, n)
+	atb := make([]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n)
+	}
+	for r, row := range rows {
+		for i := range n {
+			for j := range n {
+				ata[i][j] += row[i] * row[j]
+			}
+			atb[i] += row[i] * b[r]
+		}
+	}
+	return solveLinear(ata, atb)
+}
+
+// normalizePoints returns the matrix that moves the centroid of points to
+// the origin and scales their average distance from it to sqrt(2), which
+// keeps the equations of the model fits well-conditioned.
+func normalizePoints(points []Point) *Matrix {
+	var cx, cy float64
+	for _, p := range points {
+		cx += p.X
+		cy += p.Y
+	}
+	cx /= float64(len(points))
+	cy /= float64(len(points))
+	d := 0.0
+	for _, p := range points {
+		d += math.Sqrt((p.X-cx)*(p.X-cx) + (p.Y-cy)*(p.Y-cy))
+	}
+	s := 1.0
+	if d > 0 {
+		s = math.Sqrt(2.0) * float64(len(points)) / d
+	}
+	return NewAffineMatrix(s, 0, -s*cx, 0, s, -s*cy)
+}
+
+func applyAll(m *Matrix, points []Point) []Point {
+	res := make([]Point, len(points))
+	for i, p := range points {
+		res[i].X, res[i].Y = m.Apply(p.X, p.Y)
+	}
+	return res
+}
+
+// fitModel fits a transformation mapping src to dst in normalized
+// coordinates. equations returns the rows and the right-hand sides
+// contributed by one correspondence, build turns the solution into a matrix.
+func fitModel(src, dst []Point, equations func(s, d Point) ([][]float64, []float64), build func(x []float64) *Matrix) (*Matrix, error) {
+	ns, nd := normalizePoints(src), normalizePoints(dst)
+	s, d := applyAll(ns, src), applyAll(nd, dst)
+	var rows [][]float64
+	var b []float64
+	for i := range s {
+		r, v := equations(s[i], d[i])
+		rows = append(rows, r...)
+		b = append(b, v...)
+	}
+	x, err := leastSquares(rows, b)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := nd.Invert()
+	if err != nil {
+		return nil, err
+	}
+	m := inv.Mul(build(x)).Mul(ns)
+	if m[8] != 0 {
+		for i := range m {
+			m[i] /= m[8]
+		}
+	}
+	return m, nil
+}
+
+// fitTranslation returns the translation that maps src to dst best (at least 1 point).
+func fitTranslation(src, dst []Point) (*Matrix, error) {
+	var dx, dy float64
+	for i := range src {
+		dx += dst[i].X - src[i].X
+		dy += dst[i].Y - src[i].Y
+	}
+	n := float64(len(src))
+	return NewAffineMatrix(1, 0, dx/n, 0, 1, dy/n), nil
+}
+
+// fitSimilarity returns the rotation, uniform scale and translation that maps src to dst best (at least 2 points).
+func fitSimilarity(src, dst []Point) (*Matrix, error) {
+	return fitModel(src, dst, func(s, d Point) ([][]float64, []float64) {
+		return [][]float64{{s.X, -s.Y, 1, 0}, {s.Y, s.X, 0, 1}}, []float64{d.X, d.Y}
+	}, func(x []float64) *Matrix {
+		return NewAffineMatrix(x[0], -x[1], x[2], x[1], x[0], x[3])
+	})
+}
+
+// fitAffine returns the affine transformation that maps src to dst best (at least 3 points).
+func fitAffine(src, dst []Point) (*Matrix, error) {
+	return fitModel(src, dst, func(s, d Point) ([][]float64, []float64) {
+		return [][]float64{{s.X, s.Y, 1, 0, 0, 0}, {0, 0, 0, s.X, s.Y, 1}}, []float64{d.X, d.Y}
+	}, func(x []float64) *Matrix {
+		return NewAffineMatrix(x[0], x[1], x[2], x[3], x[4], x[5])
+	})
+}
+
+// fitHomography returns the perspective transformation that maps src to dst best (at least 4 points).
+func fitHomography(src, dst []Point) (*Matrix, error) {
+	return fitModel(src, dst, func(s, d Point) ([][]float64, []float64) {
+		return [][]float64{
+			{s.X, s.Y, 1, 0, 0, 0, -d.X * s.X, -d.X * s.Y},
+			{0, 0, 0, s.X, s.Y, 1, -d.Y * s.X, -d.Y * s.Y},
+		}, []float64{d.X, d.Y}
+	}, func(x []float64) *Matrix {
+		return &Matrix{x[0], x[1], x[2], x[3], x[4], x[5], x[6], x[7], 1}
+	})
+}
+
+// ransac robustly fits a model mapping src to dst: it repeatedly fits the
+// model to a random minimal sample, keeps the fit most other correspondences
+// agree with (within threshold pixels) and refits it to all of them.
+// The random sequence is fixed, so results are reproducible.
+func ransac(src, dst []Point, samples int, fit func(src, dst []Point) (*Matrix, error), threshold float64, iterations int) (*Matrix, []int, error) {
+	if len(src) < samples {
+		return nil, nil, fmt.Errorf("not enough matching points (%d, need at least %d)", len(src), samples)
+	}
+	inliers := func(m *Matrix) []int {
+		var res []int
+		for i := range src {
+			x, y := m.Apply(src[i].X, src[i].Y)
+			if (x-dst[i].X)*(x-dst[i].X)+(y-dst[i].Y)*(y-dst[i].Y) <= threshold*threshold {
+				res = append(res, i)
+			}
+		}
+		return res
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	var best []int
+	s, d := make([]Point, samples), make([]Point, samples)
+	for range iterations {
+		for i, j := range r.Perm(len(src))[:samples] {
+			s[i], d[i] = src[j], dst[j]
+		}
+		m, err := fit(s, d)
+		if err != nil {
+			continue
+		}
+		if in := inliers(m); len(in) > len(best) {
+			best = in
+		}
+	}
+	if len(best) < samples {
+		return nil, nil, fmt.Errorf("no consistent transformation found")
+	}
+	// Refit to all inliers, which may add a few more
+	fitInliers := func() (*Matrix, error) {
+		s, d := make([]Point, len(best)), make([]Point, len(best))
+		for i, j := range best {
+			s[i], d[i] = src[j], dst[j]
+		}
+		return fit(s, d)
+	}
+	m, err := fitInliers()
+	for range 2 {
+		if err != nil {
+			break
+		}
+		in := inliers(m)
+		if len(in) <= len(best) {
+			break
+		}
+		best = in
+		m, err = fitInliers()
+	}
+	return m, best, err
+}
@@ -0,0 +1,80 @@
+package language
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/toxyl/math"
+)
+
+func gridPoints() []Point {
+	var points []Point
+	for y := range 5 {
+		for x := range 6 {
+			points = append(points, Point{float64(x*37 + y*3), float64(y*29 + x)})
+		}
+	}
+	return points
+}
+
+func assertMapsPoints(t *testing.T, name string, m, want *Matrix, points []Point, tolerance float64) {
+	t.Helper()
+	for _, p := range points {
+		x, y := m.Apply(p.X, p.Y)
+		wx, wy := want.Apply(p.X, p.Y)
+		if math.Abs(x-wx) > tolerance || math.Abs(y-wy) > tolerance {
+			t.Fatalf("%s: expected %v to map to (%v, %v), got (%v, %v)", name, p, wx, wy, x, y)
+		}
+	}
+}
+
+func TestFitModels(t *testing.T) {
+	homography := &Matrix{1.1, 0.05, 12, -0.03, 0.95, -7, 0.0004, -0.0002, 1}
+	for _, c := range []struct {
+		name string
+		m    *Matrix
+		fit  func(src, dst []Point) (*Matrix, error)
+	}{
+		{"translation", NewAffineMatrix(1, 0, 5, 0, 1, -3), fitTranslation},
+		{"similarity", NewAffineMatrix(0.9, -0.2, 4, 0.2, 0.9, 8), fitSimilarity},
+		{"affine", NewAffineMatrix(1.2, 0.1, -6, -0.15, 0.8, 3), fitAffine},
+		{"homography", homography, fitHomography},
+	} {
+		src := gridPoints()
+		m, err := c.fit(src, applyAll(c.m, src))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		assertMapsPoints(t, c.name, m, c.m, src, 1e-6)
+	}
+}
+
+func TestRansacIgnoresOutliers(t *testing.T) {
+	h := &Matrix{1.1, 0.05, 12, -0.03, 0.95, -7, 0.0004, -0.0002, 1}
+	src := gridPoints()
+	dst := applyAll(h, src)
+	inliers := len(src)
+	r := rand.New(rand.NewPCG(3, 4))
+	for range 15 {
+		src = append(src, Point{r.Float64() * 200, r.Float64() * 150})
+		dst = append(dst, Point{r.Float64() * 200, r.Float64() * 150})
+	}
+
+	m, in, err := ransac(src, dst, 4, fitHomography, 1, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in) != inliers {
+		t.Errorf("expected %d inliers, got %d", inliers, len(in))
+	}
+	for _, i := range in {
+		if i >= inliers {
+			t.Errorf("expected outlier %d to be rejected", i)
+		}
+	}
+	assertMapsPoints(t, "ransac", m, h, src[:inliers], 1e-3)
+
+	if _, _, err := ransac(src[:3], dst[:3], 4, fitHomography, 1, 200); err == nil {
+		t.Error("expected an error with fewer points than samples")
+	}
+}
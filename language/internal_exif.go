@@ -0,0 +1,520 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EXIF value types as defined by TIFF 6.0.
+const (
+	exifByte      = 1
+	exifASCII     = 2
+	exifShort     = 3
+	exifLong      = 4
+	exifRational  = 5
+	exifSByte     = 6
+	exifUndefined = 7
+	exifSShort    = 8
+	exifSLong     = 9
+	exifSRational = 10
+	exifFloat     = 11
+	exifDouble    = 12
+)
+
+var exifTypeSize = map[uint16]int{
+	exifByte: 1, exifASCII: 1, exifShort: 2, exifLong: 4, exifRational: 8, exifSByte: 1,
+	exifUndefined: 1, exifSShort: 2, exifSLong: 4, exifSRational: 8, exifFloat: 4, exifDouble: 8,
+}
+
+// Image file directories that can hold EXIF tags.
+const (
+	exifIFD0    = "ifd0"
+	exifIFDExif = "exif"
+	exifIFDGPS  = "gps"
+	exifIFDIop  = "interop"
+)
+
+// Tags pointing to sub-directories.
+var exifSubIFDs = map[uint16]string{
+	0x8769: exifIFDExif,
+	0x8825: exifIFDGPS,
+	0xA005: exifIFDIop,
+}
+
+type exifTag struct {
+	id   uint16
+	ifd  string
+	name string
+	typ  uint16
+}
+
+var exifTags = []exifTag{
+	{0x010E, exifIFD0, "ImageDescription", exifASCII},
+	{0x010F, exifIFD0, "Make", exifASCII},
+	{0x0110, exifIFD0, "Model", exifASCII},
+	{0x0112, exifIFD0, "Orientation", exifShort},
+	{0x011A, exifIFD0, "XResolution", exifRational},
+	{0x011B, exifIFD0, "YResolution", exifRational},
+	{0x0128, exifIFD0, "ResolutionUnit", exifShort},
+	{0x0131, exifIFD0, "Software", exifASCII},
+	{0x0132, exifIFD0, "DateTime", exifASCII},
+	{0x013B, exifIFD0, "Artist", exifASCII},
+	{0x013E, exifIFD0, "WhitePoint", exifRational},
+	{0x013F, exifIFD0, "PrimaryChromaticities", exifRational},
+	{0x0211, exifIFD0, "YCbCrCoefficients", exifRational},
+	{0x0213, exifIFD0, "YCbCrPositioning", exifShort},
+	{0x0214, exifIFD0, "ReferenceBlackWhite", exifRational},
+	{0x8298, exifIFD0, "Copyright", exifASCII},
+	{0x829A, exifIFDExif, "ExposureTime", exifRational},
+	{0x829D, exifIFDExif, "FNumber", exifRational},
+	{0x8822, exifIFDExif, "ExposureProgram", exifShort},
+	{0x8824, exifIFDExif, "SpectralSensitivity", exifASCII},
+	{0x8827, exifIFDExif, "ISOSpeedRatings", exifShort},
+	{0x8830, exifIFDExif, "SensitivityType", exifShort},
+	{0x9000, exifIFDExif, "ExifVersion", exifUndefined},
+	{0x9003, exifIFDExif, "DateTimeOriginal", exifASCII},
+	{0x9004, exifIFDExif, "DateTimeDigitized", exifASCII},
+	{0x9010, exifIFDExif, "OffsetTime", exifASCII},
+	{0x9011, exifIFDExif, "OffsetTimeOriginal", exifASCII},
+	{0x9012, exifIFDExif, "OffsetTimeDigitized", exifASCII},
+	{0x9101, exifIFDExif, "ComponentsConfiguration", exifUndefined},
+	{0x9102, exifIFDExif, "CompressedBitsPerPixel", exifRational},
+	{0x9201, exifIFDExif, "ShutterSpeedValue", exifSRational},
+	{0x9202, exifIFDExif, "ApertureValue", exifRational},
+	{0x9203, exifIFDExif, "BrightnessValue", exifSRational},
+	{0x9204, exifIFDExif, "ExposureBiasValue", exifSRational},
+	{0x9205, exifIFDExif, "MaxApertureValue", exifRational},
+	{0x9206, exifIFDExif, "SubjectDistance", exifRational},
+	{0x9207, exifIFDExif, "MeteringMode", exifShort},
+	{0x9208, exifIFDExif, "LightSource", exifShort},
+	{0x9209, exifIFDExif, "Flash", exifShort},
+	{0x920A, exifIFDExif, "FocalLength", exifRational},
+	{0x9214, exifIFDExif, "SubjectArea", exifShort},
+	{0x927C, exifIFDExif, "MakerNote", exifUndefined},
+	{0x9286, exifIFDExif, "UserComment", exifUndefined},
+	{0x9290, exifIFDExif, "SubSecTime", exifASCII},
+	{0x9291, exifIFDExif, "SubSecTimeOriginal", exifASCII},
+	{0x9292, exifIFDExif, "SubSecTimeDigitized", exifASCII},
+	{0xA000, exifIFDExif, "FlashpixVersion", exifUndefined},
+	{0xA001, exifIFDExif, "ColorSpace", exifShort},
+	{0xA002, exifIFDExif, "PixelXDimension", exifLong},
+	{0xA003, exifIFDExif, "PixelYDimension", exifLong},
+	{0xA004, exifIFDExif, "RelatedSoundFile", exifASCII},
+	{0xA20E, exifIFDExif, "FocalPlaneXResolution", exifRational},
+	{0xA20F, exifIFDExif, "FocalPlaneYResolution", exifRational},
+	{0xA210, exifIFDExif, "FocalPlaneResolutionUnit", exifShort},
+	{0xA215, exifIFDExif, "ExposureIndex", exifRational},
+	{0xA217, exifIFDExif, "SensingMethod", exifShort},
+	{0xA300, exifIFDExif, "FileSource", exifUndefined},
+	{0xA301, exifIFDExif, "SceneType", exifUndefined},
+	{0xA401, exifIFDExif, "CustomRendered", exifShort},
+	{0xA402, exifIFDExif, "ExposureMode", exifShort},
+	{0xA403, exifIFDExif, "WhiteBalance", exifShort},
+	{0xA404, exifIFDExif, "DigitalZoomRatio", exifRational},
+	{0xA405, exifIFDExif, "FocalLengthIn35mmFilm", exifShort},
+	{0xA406, exifIFDExif, "SceneCaptureType", exifShort},
+	{0xA407, exifIFDExif, "GainControl", exifShort},
+	{0xA408, exifIFDExif, "Contrast", exifShort},
+	{0xA409, exifIFDExif, "Saturation", exifShort},
+	{0xA40A, exifIFDExif, "Sharpness", exifShort},
+	{0xA40C, exifIFDExif, "SubjectDistanceRange", exifShort},
+	{0xA420, exifIFDExif, "ImageUniqueID", exifASCII},
+	{0xA430, exifIFDExif, "CameraOwnerName", exifASCII},
+	{0xA431, exifIFDExif, "BodySerialNumber", exifASCII},
+	{0xA432, exifIFDExif, "LensSpecification", exifRational},
+	{0xA433, exifIFDExif, "LensMake", exifASCII},
+	{0xA434, exifIFDExif, "LensModel", exifASCII},
+	{0xA435, exifIFDExif, "LensSerialNumber", exifASCII},
+	{0x0000, exifIFDGPS, "GPSVersionID", exifByte},
+	{0x0001, exifIFDGPS, "GPSLatitudeRef", exifASCII},
+	{0x0002, exifIFDGPS, "GPSLatitude", exifRational},
+	{0x0003, exifIFDGPS, "GPSLongitudeRef", exifASCII},
+	{0x0004, exifIFDGPS, "GPSLongitude", exifRational},
+	{0x0005, exifIFDGPS, "GPSAltitudeRef", exifByte},
+	{0x0006, exifIFDGPS, "GPSAltitude", exifRational},
+	{0x0007, exifIFDGPS, "GPSTimeStamp", exifRational},
+	{0x0008, exifIFDGPS, "GPSSatellites", exifASCII},
+	{0x0009, exifIFDGPS, "GPSStatus", exifASCII},
+	{0x000A, exifIFDGPS, "GPSMeasureMode", exifASCII},
+	{0x000B, exifIFDGPS, "GPSDOP", exifRational},
+	{0x000C, exifIFDGPS, "GPSSpeedRef", exifASCII},
+	{0x000D, exifIFDGPS, "GPSSpeed", exifRational},
+	{0x000E, exifIFDGPS, "GPSTrackRef", exifASCII},
+	{0x000F, exifIFDGPS, "GPSTrack", exifRational},
+	{0x0010, exifIFDGPS, "GPSImgDirectionRef", exifASCII},
+	{0x0011, exifIFDGPS, "GPSImgDirection", exifRational},
+	{0x0012, exifIFDGPS, "GPSMapDatum", exifASCII},
+	{0x0017, exifIFDGPS, "GPSDestBearingRef", exifASCII},
+	{0x0018, exifIFDGPS, "GPSDestBearing", exifRational},
+	{0x001D, exifIFDGPS, "GPSDateStamp", exifASCII},
+	{0x001F, exifIFDGPS, "GPSHPositioningError", exifRational},
+	{0x0001, exifIFDIop, "InteroperabilityIndex", exifASCII},
+}
+
+// exifTagByName returns the definition of the tag with the given name
+// (case-insensitive).
+func exifTagByName(name string) (exifTag, bool) {
+	for _, t := range exifTags {
+		if strings.EqualFold(t.name, name) {
+			return t, true
+		}
+	}
+	return exifTag{}, false
+}
+
+func exifTagByID(ifd string, id uint16) (exifTag, bool) {
+	for _, t := range exifTags {
+		if t.ifd == ifd && t.id == id {
+			return t, true
+		}
+	}
+	return exifTag{}, false
+}
+
+// exifTagName returns the name of a tag, unknown tags are named by
+// directory and ID, e.g. "exif.0xA460".
+func exifTagName(ifd string, id uint16) string {
+	if t, ok := exifTagByID(ifd, id); ok {
+		return t.name
+	}
+	return fmt.Sprintf("%s.0x%04X", ifd, id)
+}
+
+type exifEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte    // raw value in the byte order of the EXIF block
+	sub   *exifData // sub-directory for the pointer tags in exifSubIFDs
+}
+
+// exifData is an image file directory with all its entries.
+// Only the primary image directory and its sub-directories are kept,
+// the thumbnail directory (IFD1) is dropped since it no longer matches
+// the edited image.
+type exifData struct {
+	order   binary.ByteOrder
+	ifd     string
+	entries []*exifEntry
+}
+
+// parseEXIF parses a raw EXIF block, with or without the "Exif\0\0" prefix.
+func parseEXIF(raw []byte) (*exifData, error) {
+	raw = bytes.TrimPrefix(raw, []byte("Exif\x00\x00"))
+	if len(raw) < 8 {
+		return nil, fmt.Errorf("EXIF data too short")
+	}
+	var order binary.ByteOrder
+	switch string(raw[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid EXIF header")
+	}
+	return parseEXIFDir(raw, order, order.Uint32(raw[4:]), exifIFD0, map[uint32]bool{})
+}
+
+func parseEXIFDir(raw []byte, order binary.ByteOrder, offset uint32, ifd string, seen map[uint32]bool) (*exifData, error) {
+	if seen[offset] || int(offset)+2 > len(raw) {
+		return nil, fmt.Errorf("invalid EXIF directory offset")
+	}
+	seen[offset] = true
+	d := &exifData{order: order, ifd: ifd}
+	n := int(order.Uint16(raw[offset:]))
+	for i := range n {
+		pos := int(offset) + 2 + 12*i
+		if pos+12 > len(raw) {
+			break
+		}
+		e := &exifEntry{
+			tag:   order.Uint16(raw[pos:]),
+			typ:   order.Uint16(raw[pos+2:]),
+			count: order.Uint32(raw[pos+4:]),
+		}
+		size, ok := exifTypeSize[e.typ]
+		if !ok {
+			continue
+		}
+		length := size * int(e.count)
+		if length < 0 || length > len(raw) {
+			continue
+		}
+		value := pos + 8
+		if length > 4 {
+			value = int(order.Uint32(raw[pos+8:]))
+		}
+		if value+length > len(raw) {
+			continue
+		}
+		e.data = bytes.Clone(raw[value : value+length])
+		if sub, ok := exifSubIFDs[e.tag]; ok {
+			sd, err := parseEXIFDir(raw, order, order.Uint32(e.data), sub, seen)
+			if err != nil {
+				continue
+			}
+			e.sub = sd
+		}
+		d.entries = append(d.entries, e)
+	}
+	return d, nil
+}
+
+// bytes serializes the directory (and its sub-directories) into a complete
+// EXIF block without the "Exif\0\0" prefix.
+func (d *exifData) bytes() []byte {
+	buf := []byte("II*\x00\x00\x00\x00\x00")
+	if d.order == binary.BigEndian {
+		buf = []byte("MM\x00*\x00\x00\x00\x00")
+	}
+	var write func(d *exifData) uint32
+	write = func(d *exifData) uint32 {
+		if len(buf)%2 == 1 {
+			buf = append(buf, 0)
+		}
+		offset := len(buf)
+		buf = append(buf, make([]byte, 2+12*len(d.entries)+4)...)
+		d.order.PutUint16(buf[offset:], uint16(len(d.entries)))
+		type pending struct {
+			pos int
+			sub *exifData
+		}
+		var subs []pending
+		for i, e := range d.entries {
+			pos := offset + 2 + 12*i
+			d.order.PutUint16(buf[pos:], e.tag)
+			d.order.PutUint16(buf[pos+2:], e.typ)
+			d.order.PutUint32(buf[pos+4:], e.count)
+			switch {
+			case e.sub != nil:
+				subs = append(subs, pending{pos + 8, e.sub})
+			case len(e.data) <= 4:
+				copy(buf[pos+8:pos+12], e.data)
+			default:
+				if len(buf)%2 == 1 {
+					buf = append(buf, 0)
+				}
+				d.order.PutUint32(buf[pos+8:], uint32(len(buf)))
+				buf = append(buf, e.data...)
+			}
+		}
+		for _, s := range subs {
+			sub := write(s.sub)
+			d.order.PutUint32(buf[s.pos:], sub)
+		}
+		return uint32(offset)
+	}
+	offset := write(d)
+	d.order.PutUint32(buf[4:], offset)
+	return buf
+}
+
+func (d *exifData) clone() *exifData {
+	res := &exifData{order: d.order, ifd: d.ifd}
+	for _, e := range d.entries {
+		c := *e
+		c.data = bytes.Clone(e.data)
+		if e.sub != nil {
+			c.sub = e.sub.clone()
+		}
+		res.entries = append(res.entries, &c)
+	}
+	return res
+}
+
+// dir returns the directory with the given name, creating it if create is set.
+func (d *exifData) dir(ifd string, create bool) *exifData {
+	if d.ifd == ifd {
+		return d
+	}
+	for _, e := range d.entries {
+		if e.sub != nil {
+			if res := e.sub.dir(ifd, false); res != nil {
+				return res
+			}
+		}
+	}
+	if !create {
+		return nil
+	}
+	parent := d
+	if ifd == exifIFDIop {
+		parent = d.dir(exifIFDExif, true)
+	}
+	for id, name := range exifSubIFDs {
+		if name == ifd {
+			sub := &exifData{order: d.order, ifd: ifd}
+			parent.set(&exifEntry{tag: id, typ: exifLong, count: 1, data: make([]byte, 4), sub: sub})
+			return sub
+		}
+	}
+	return nil
+}
+
+// set adds e to the directory, replacing an entry with the same tag.
+// Entries are kept sorted by tag as required by TIFF.
+func (d *exifData) set(e *exifEntry) {
+	for i, o := range d.entries {
+		if o.tag == e.tag {
+			d.entries[i] = e
+			return
+		}
+		if o.tag > e.tag {
+			d.entries = append(d.entries[:i], append([]*exifEntry{e}, d.entries[i:]...)...)
+			return
+		}
+	}
+	d.entries = append(d.entries, e)
+}
+
+func (d *exifData) get(ifd string, tag uint16) *exifEntry {
+	dir := d.dir(ifd, false)
+	if dir == nil {
+		return nil
+	}
+	for _, e := range dir.entries {
+		if e.tag == tag && e.sub == nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// tags returns all tags (of all directories) with their decoded values.
+func (d *exifData) tags() map[string]any {
+	res := map[string]any{}
+	var walk func(d *exifData)
+	walk = func(d *exifData) {
+		for _, e := range d.entries {
+			if e.sub != nil {
+				walk(e.sub)
+				continue
+			}
+			res[exifTagName(d.ifd, e.tag)] = e.value(d.order)
+		}
+	}
+	walk(d)
+	return res
+}
+
+// value decodes the entry: strings for ASCII (and printable undefined) data,
+// float64 for single numbers (rationals are divided) and []any of float64 for
+// multiple numbers.
+func (e *exifEntry) value(order binary.ByteOrder) any {
+	switch e.typ {
+	case exifASCII:
+		return strings.TrimRight(string(e.data), "\x00 ")
+	case exifUndefined:
+		s := strings.TrimRight(string(e.data), "\x00")
+		for _, r := range s {
+			if !unicode.IsPrint(r) {
+				return hex.EncodeToString(e.data)
+			}
+		}
+		return s
+	}
+	size := exifTypeSize[e.typ]
+	values := make([]any, 0, e.count)
+	for i := 0; i+size <= len(e.data); i += size {
+		b := e.data[i:]
+		var v float64
+		switch e.typ {
+		case exifByte:
+			v = float64(b[0])
+		case exifSByte:
+			v = float64(int8(b[0]))
+		case exifShort:
+			v = float64(order.Uint16(b))
+		case exifSShort:
+			v = float64(int16(order.Uint16(b)))
+		case exifLong:
+			v = float64(order.Uint32(b))
+		case exifSLong:
+			v = float64(int32(order.Uint32(b)))
+		case exifRational:
+			if den := order.Uint32(b[4:]); den != 0 {
+				v = float64(order.Uint32(b)) / float64(den)
+			}
+		case exifSRational:
+			if den := int32(order.Uint32(b[4:])); den != 0 {
+				v = float64(int32(order.Uint32(b))) / float64(den)
+			}
+		case exifFloat:
+			v = float64(math.Float32frombits(order.Uint32(b)))
+		case exifDouble:
+			v = math.Float64frombits(order.Uint64(b))
+		}
+		values = append(values, v)
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// setTag sets the tag with the given name, value is parsed according to the
+// type of the tag: text for ASCII and undefined tags, space-separated numbers
+// for all other types.
+func (d *exifData) setTag(name, value string) error {
+	t, ok := exifTagByName(name)
+	if !ok {
+		return fmt.Errorf("unknown EXIF tag: %s", name)
+	}
+	e := &exifEntry{tag: t.id, typ: t.typ}
+	switch t.typ {
+	case exifASCII:
+		e.data = append([]byte(value), 0)
+		e.count = uint32(len(e.data))
+	case exifUndefined:
+		e.data = []byte(value)
+		e.count = uint32(len(e.data))
+	default:
+		for _, f := range strings.Fields(value) {
+			v, err := strconv.ParseFloat(f, 64)
+			if err != nil {
+				return fmt.Errorf("invalid value for EXIF tag %s: %s", t.name, f)
+			}
+			b := make([]byte, exifTypeSize[t.typ])
+			switch t.typ {
+			case exifByte:
+				b[0] = byte(v)
+			case exifShort:
+				d.order.PutUint16(b, uint16(v))
+			case exifLong:
+				d.order.PutUint32(b, uint32(v))
+			case exifRational, exifSRational:
+				num, den := exifRationalOf(v)
+				d.order.PutUint32(b, uint32(num))
+				d.order.PutUint32(b[4:], uint32(den))
+			}
+			e.data = append(e.data, b...)
+			e.count++
+		}
+		if e.count == 0 {
+			return fmt.Errorf("missing value for EXIF tag %s", t.name)
+		}
+	}
+	d.dir(t.ifd, true).set(e)
+	return nil
+}
+
+// exifRationalOf returns a fraction for v using the smallest power of ten
+// as denominator that represents v exactly (up to 1/1000000).
+func exifRationalOf(v float64) (num, den int64) {
+	for den = 1; den < 1000000; den *= 10 {
+		if n := math.Round(v * float64(den)); math.Abs(n/float64(den)-v) < 1e-9 {
+			break
+		}
+	}
+	for math.Abs(v*float64(den)) > math.MaxInt32 && den > 1 {
+		den /= 10
+	}
+	return int64(math.Round(v * float64(den))), den
+}
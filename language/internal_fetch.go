@@ -0,0 +1,270 @@
+package language
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fetcher downloads the remote files (http and https URLs) scripts load.
+// Responses are cached on disk and revalidated with ETag and Last-Modified,
+// so unchanged files are only downloaded once.
+type Fetcher struct {
+	Client       *http.Client  // The client to use, a client with Timeout if nil
+	Timeout      time.Duration // The timeout of a request including reading the body
+	MaxSize      int64         // The maximum size of a response body in bytes, 0 for no limit
+	ContentTypes []string      // The allowed content types (prefixes like "image/" match all subtypes), empty to allow all
+	CacheDir     string        // The cache directory, empty to disable caching
+	MaxCacheSize int64         // The maximum total size of the cache in bytes, 0 for no limit
+	MaxCacheAge  time.Duration // How long unused files are kept in the cache, 0 to keep them forever
+	StaleIfError bool          // Whether to use the cached copy of a file if the server can't be reached
+}
+
+// DefaultFetcher returns the fetcher used unless a Language is given its own.
+// It caches in os.TempDir()/pxp.
+func DefaultFetcher() *Fetcher {
+	return &Fetcher{
+		Timeout:      30 * time.Second,
+		MaxSize:      256 << 20,
+		ContentTypes: []string{"image/", "text/", "application/octet-stream"},
+		CacheDir:     filepath.Join(os.TempDir(), "pxp"),
+		MaxCacheSize: 1 << 30,
+		MaxCacheAge:  7 * 24 * time.Hour,
+	}
+}
+
+// isRemotePath reports whether path is a URL that has to be fetched.
+func isRemotePath(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// fetchCacheEntry describes a cached response, it's stored next to the body.
+type fetchCacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	ContentType  string `json:"contentType,omitempty"`
+	SHA256       string `json:"sha256"` // The hash of the body the entry belongs to
+}
+
+const (
+	fetchCacheBody    = "body"
+	fetchCacheMeta    = "meta.json"
+	fetchCacheEvicted = ".evicted" // touched whenever the cache is evicted
+
+	// fetchEvictInterval is how often the cache is evicted at most, so that
+	// downloading many files doesn't scan the cache for every one of them.
+	fetchEvictInterval = time.Minute
+)
+
+// Fetch downloads url and returns the response body and the path of the
+// cached copy (empty if caching is disabled). Cached copies are revalidated
+// with the server. If the server can't be reached, the cached copy is only
+// used with StaleIfError, otherwise the error is returned.
+func (f *Fetcher) Fetch(url string) (data []byte, cachePath string, err error) {
+	var dir string
+	var cached *fetchCacheEntry
+	var cachedData []byte
+	if f.CacheDir != "" {
+		dir = filepath.Join(f.CacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(url))))
+		cached, cachedData = f.cacheEntry(dir, url)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download file '%s': %s", url, err.Error())
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		if cached != nil && f.StaleIfError {
+			return cachedData, f.cacheHit(dir), nil
+		}
+		return nil, "", fmt.Errorf("failed to download file '%s': %s", url, err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return cachedData, f.cacheHit(dir), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download file: status %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !f.allowsContentType(contentType) {
+		return nil, "", fmt.Errorf("failed to download file '%s': content type %q is not allowed", url, contentType)
+	}
+	if f.MaxSize > 0 && resp.ContentLength > f.MaxSize {
+		return nil, "", fmt.Errorf("failed to download file '%s': size of %d bytes exceeds the limit of %d bytes", url, resp.ContentLength, f.MaxSize)
+	}
+
+	body := io.Reader(resp.Body)
+	if f.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, f.MaxSize+1)
+	}
+	if data, err = io.ReadAll(body); err != nil {
+		return nil, "", fmt.Errorf("failed to read body: %s", err.Error())
+	}
+	if f.MaxSize > 0 && int64(len(data)) > f.MaxSize {
+		return nil, "", fmt.Errorf("failed to download file '%s': size exceeds the limit of %d bytes", url, f.MaxSize)
+	}
+
+	if dir == "" {
+		return data, "", nil
+	}
+	entry := &fetchCacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  contentType,
+		SHA256:       fmt.Sprintf("%x", sha256.Sum256(data)),
+	}
+	if cachePath, err = f.store(dir, entry, data); err != nil {
+		return nil, "", fmt.Errorf("could not store downloaded file: %s", err.Error())
+	}
+	f.evict()
+	return data, cachePath, nil
+}
+
+func (f *Fetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return &http.Client{Timeout: f.Timeout}
+}
+
+func (f *Fetcher) allowsContentType(contentType string) bool {
+	if len(f.ContentTypes) == 0 || contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range f.ContentTypes {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheEntry returns the cache entry of url and the cached body, nil if
+// there is none or if the body isn't the one the entry was written for
+// (e.g. because another download of url replaced it in the meantime).
+func (f *Fetcher) cacheEntry(dir, url string) (*fetchCacheEntry, []byte) {
+	meta, err := os.ReadFile(filepath.Join(dir, fetchCacheMeta))
+	if err != nil {
+		return nil, nil
+	}
+	entry := &fetchCacheEntry{}
+	if json.Unmarshal(meta, entry) != nil || entry.URL != url {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, fetchCacheBody))
+	if err != nil || fmt.Sprintf("%x", sha256.Sum256(data)) != entry.SHA256 {
+		return nil, nil
+	}
+	return entry, data
+}
+
+// cacheHit marks the cached body as recently used and returns its path.
+func (f *Fetcher) cacheHit(dir string) string {
+	path := filepath.Join(dir, fetchCacheBody)
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return path
+}
+
+// store writes the body and then its cache entry. The entry holds the hash
+// of the body, so an entry whose body was replaced by a concurrent download
+// is ignored instead of being served with the wrong body.
+func (f *Fetcher) store(dir string, entry *fetchCacheEntry, data []byte) (string, error) {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fetchCacheBody)
+	if err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return path, writeFileAtomic(filepath.Join(dir, fetchCacheMeta), func(w io.Writer) error {
+		_, err := w.Write(meta)
+		return err
+	})
+}
+
+// evict removes cached files that haven't been used for MaxCacheAge and,
+// if the cache is still larger than MaxCacheSize, the least recently used ones.
+// It does nothing if the cache was evicted less than fetchEvictInterval ago.
+func (f *Fetcher) evict() {
+	if f.MaxCacheAge <= 0 && f.MaxCacheSize <= 0 {
+		return
+	}
+	marker := filepath.Join(f.CacheDir, fetchCacheEvicted)
+	if info, err := os.Stat(marker); err == nil && time.Since(info.ModTime()) < fetchEvictInterval {
+		return
+	}
+	now := time.Now()
+	if os.Chtimes(marker, now, now) != nil && os.WriteFile(marker, nil, 0o644) != nil {
+		return
+	}
+	dirs, err := os.ReadDir(f.CacheDir)
+	if err != nil {
+		return
+	}
+	type cached struct {
+		dir  string
+		size int64
+		used time.Time
+	}
+	var entries []cached
+	var total int64
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(f.CacheDir, d.Name())
+		info, err := os.Stat(filepath.Join(dir, fetchCacheBody))
+		if err != nil {
+			continue
+		}
+		if f.MaxCacheAge > 0 && time.Since(info.ModTime()) > f.MaxCacheAge {
+			_ = os.RemoveAll(dir)
+			continue
+		}
+		entries = append(entries, cached{dir: dir, size: info.Size(), used: info.ModTime()})
+		total += info.Size()
+	}
+	if f.MaxCacheSize <= 0 || total <= f.MaxCacheSize {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= f.MaxCacheSize {
+			break
+		}
+		if os.RemoveAll(e.dir) == nil {
+			total -= e.size
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package language
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testServer serves body with an ETag and counts the full responses.
+func testServer(t *testing.T, body *[]byte, full *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + string(*body) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		*full++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", etag)
+		w.Write(*body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchRevalidatesCachedFiles(t *testing.T) {
+	body, full := []byte("one"), 0
+	srv := testServer(t, &body, &full)
+	f := &Fetcher{CacheDir: t.TempDir()}
+
+	for range 2 {
+		data, path, err := f.Fetch(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, body) || path == "" {
+			t.Fatalf("expected %q from the cache, got %q from %q", body, data, path)
+		}
+	}
+	if full != 1 {
+		t.Errorf("expected 1 download, got %d", full)
+	}
+
+	body = []byte("two")
+	if data, _, err := f.Fetch(srv.URL); err != nil || string(data) != "two" {
+		t.Fatalf("expected the changed file, got %q (%v)", data, err)
+	}
+}
+
+func TestFetchIgnoresEntriesOfOtherBodies(t *testing.T) {
+	body, full := []byte("one"), 0
+	srv := testServer(t, &body, &full)
+	f := &Fetcher{CacheDir: t.TempDir()}
+	_, path, err := f.Fetch(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent download replaced the body but not (yet) its entry
+	if err := os.WriteFile(path, []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, _, err := f.Fetch(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "one" || full != 2 {
+		t.Errorf("expected a new download of %q, got %q after %d downloads", "one", data, full)
+	}
+}
+
+func TestFetchUsesStaleFilesOnlyIfAllowed(t *testing.T) {
+	body, full := []byte("one"), 0
+	srv := testServer(t, &body, &full)
+	f := &Fetcher{CacheDir: t.TempDir()}
+	if _, _, err := f.Fetch(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	if _, _, err := f.Fetch(srv.URL); err == nil {
+		t.Error("expected an error if the server can't be reached")
+	}
+	f.StaleIfError = true
+	if data, _, err := f.Fetch(srv.URL); err != nil || string(data) != "one" {
+		t.Errorf("expected the stale copy, got %q (%v)", data, err)
+	}
+}
+
+func TestFetchThrottlesEviction(t *testing.T) {
+	body, full := []byte("one"), 0
+	srv := testServer(t, &body, &full)
+	dir := t.TempDir()
+	f := &Fetcher{CacheDir: dir, MaxCacheAge: time.Hour}
+	if _, _, err := f.Fetch(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	old := filepath.Join(dir, "old")
+	if err := os.MkdirAll(old, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(old, fetchCacheBody), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(old, fetchCacheBody), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	body = []byte("two")
+	if _, _, err := f.Fetch(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Fatal("expected no eviction within the interval")
+	}
+
+	marker := filepath.Join(dir, fetchCacheEvicted)
+	if err := os.Chtimes(marker, past, past); err != nil {
+		t.Fatal(err)
+	}
+	body = []byte("three")
+	if _, _, err := f.Fetch(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("expected the unused file to be evicted")
+	}
+}
@@ -0,0 +1,68 @@
+package language
+
+import (
+	"github.com/toxyl/math"
+)
+
+// fft transforms a in place with the iterative radix-2 Cooley-Tukey
+// algorithm, len(a) must be a power of 2. The inverse transform is scaled by 1/len(a).
+func fft(a []complex128, inverse bool) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		angle := sign * 2 * math.Pi / float64(size)
+		step := complex(math.Cos(angle), math.Sin(angle))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := range size / 2 {
+				u, v := a[start+k], a[start+k+size/2]*w
+				a[start+k], a[start+k+size/2] = u+v, u-v
+				w *= step
+			}
+		}
+	}
+	if inverse {
+		for i := range a {
+			a[i] /= complex(float64(n), 0)
+		}
+	}
+}
+
+// fft2 transforms the w x h values of a (row by row) in place, w and h must be powers of 2.
+func fft2(a []complex128, w, h int, inverse bool) {
+	parallelRows(0, h, func(y int) {
+		fft(a[y*w:(y+1)*w], inverse)
+	})
+	parallelRows(0, w, func(x int) {
+		col := make([]complex128, h)
+		for y := range h {
+			col[y] = a[y*w+x]
+		}
+		fft(col, inverse)
+		for y := range h {
+			a[y*w+x] = col[y]
+		}
+	})
+}
+
+// nextPowerOf2 returns the smallest power of 2 >= n.
+func nextPowerOf2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
@@ -0,0 +1,356 @@
+package language
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSystem resolves the files scripts load, save and include.
+// Names are the paths as written in scripts. Only includes are resolved
+// against the script's base directory, all other functions pass relative
+// paths as they are, so the OS file system resolves them against the
+// working directory.
+type FileSystem interface {
+	fs.FS
+
+	// WriteFile creates or replaces the file name with the data written by write.
+	// Read-only file systems return an error wrapping fs.ErrPermission.
+	WriteFile(name string, write func(w io.Writer) error) error
+}
+
+// readFile reads a file from the file system of the run.
+func (r *scriptRun) readFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.fs, name)
+}
+
+// writeFile writes a file to the file system of the run.
+func (r *scriptRun) writeFile(name string, write func(w io.Writer) error) error {
+	return r.fs.WriteFile(name, write)
+}
+
+// loadFile returns the data of a file of the run and the path it was read
+// from. Remote files are downloaded (and cached), which bypasses the file
+// system, so unless the run was given a fetcher, they are only allowed with
+// the OS file system.
+func (r *scriptRun) loadFile(filePath string) (string, []byte, error) {
+	filePath = strings.TrimSpace(filePath)
+	if isRemotePath(filePath) {
+		fetcher := r.fetcher
+		if fetcher == nil {
+			if !isPlainOSFileSystem(r.fs) {
+				return "", nil, fmt.Errorf("can't load remote files with a custom file system: %s", filePath)
+			}
+			fetcher = DefaultFetcher()
+		}
+		data, cachePath, err := fetcher.Fetch(filePath)
+		if err != nil {
+			return "", nil, err
+		}
+		return cachePath, data, nil
+	}
+
+	data, err := r.readFile(filePath)
+	if err != nil {
+		return "", nil, err
+	}
+	return filePath, data, nil
+}
+
+// expandIncludes replaces the include directives of script with the
+// scripts they include, read from the file system of the run.
+func (r *scriptRun) expandIncludes(script string, baseDir string, stack map[string]struct{}) (string, error) {
+	if stack == nil {
+		stack = make(map[string]struct{})
+	}
+	var builder strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		rawLine := scanner.Text()
+
+		includePath, ok := r.dsl.parseIncludeLine(rawLine)
+		if !ok {
+			builder.WriteString(rawLine)
+			builder.WriteByte('\n')
+			continue
+		}
+
+		resolvedPath, err := r.resolveIncludePath(includePath, baseDir)
+		if err != nil {
+			return "", fmt.Errorf("include %q (line %d): %w", includePath, lineNo, err)
+		}
+		if _, seen := stack[resolvedPath]; seen {
+			return "", fmt.Errorf("include cycle detected at %s", resolvedPath)
+		}
+
+		stack[resolvedPath] = struct{}{}
+		content, err := r.readFile(resolvedPath)
+		if err != nil {
+			return "", fmt.Errorf("include %q (line %d): %w", includePath, lineNo, err)
+		}
+		expanded, err := r.expandIncludes(string(content), filepath.Dir(resolvedPath), stack)
+		delete(stack, resolvedPath)
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString("# include \"")
+		builder.WriteString(resolvedPath)
+		builder.WriteString("\" #\n")
+		builder.WriteString(expanded)
+		if !strings.HasSuffix(expanded, "\n") {
+			builder.WriteByte('\n')
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// resolveIncludePath normalizes an include path, resolving relatives against
+// baseDir. Without a base directory, paths are relative to the working
+// directory for the OS file system and to the root of other file systems.
+func (r *scriptRun) resolveIncludePath(path, baseDir string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	if baseDir == "" && isPlainOSFileSystem(r.fs) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		baseDir = cwd
+	}
+	return filepath.Clean(filepath.Join(baseDir, path)), nil
+}
+
+// cleanName converts a script path to a name valid for io/fs: slash-separated,
+// unrooted and without "." or ".." elements. Names escaping the root are invalid.
+func cleanName(op, name string) (string, error) {
+	clean := path.Clean("/" + filepath.ToSlash(name))[1:]
+	if clean == "" {
+		clean = "."
+	}
+	if rel := path.Clean(filepath.ToSlash(name)); rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return clean, nil
+}
+
+// osFileSystem accesses the OS file system. Without a root, paths are
+// used as they are, otherwise they are confined to the root directory.
+type osFileSystem struct {
+	root string
+}
+
+// NewOSFileSystem returns a file system backed by the OS. If root is not
+// empty, all paths (including absolute ones) are resolved within root.
+func NewOSFileSystem(root string) FileSystem {
+	return &osFileSystem{root: root}
+}
+
+func (o *osFileSystem) path(op, name string) (string, error) {
+	if o.root == "" {
+		return name, nil
+	}
+	clean, err := cleanName(op, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(o.root, filepath.FromSlash(clean)), nil
+}
+
+func (o *osFileSystem) Open(name string) (fs.File, error) {
+	p, err := o.path("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (o *osFileSystem) WriteFile(name string, write func(w io.Writer) error) error {
+	p, err := o.path("write", name)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p, write)
+}
+
+// isPlainOSFileSystem reports whether fsys uses OS paths as they are.
+func isPlainOSFileSystem(fsys FileSystem) bool {
+	o, ok := fsys.(*osFileSystem)
+	return ok && o.root == ""
+}
+
+// MemoryFileSystem keeps files in memory, e.g. for tests or sandboxed scripts.
+type MemoryFileSystem struct {
+	mu    sync.RWMutex
+	files map[string]*memoryFile
+}
+
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// NewMemoryFileSystem returns an in-memory file system with the given files.
+func NewMemoryFileSystem(files map[string][]byte) *MemoryFileSystem {
+	m := &MemoryFileSystem{files: map[string]*memoryFile{}}
+	for name, data := range files {
+		_ = m.WriteFile(name, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+	}
+	return m
+}
+
+func (m *MemoryFileSystem) Open(name string) (fs.File, error) {
+	clean, err := cleanName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if f, ok := m.files[clean]; ok {
+		return &openMemoryFile{Reader: bytes.NewReader(f.data), info: memoryFileInfo{name: path.Base(clean), size: int64(len(f.data)), modTime: f.modTime}}, nil
+	}
+	// Directories exist implicitly as prefixes of file names
+	prefix := clean + "/"
+	for n := range m.files {
+		if clean == "." || strings.HasPrefix(n, prefix) {
+			return &openMemoryFile{Reader: bytes.NewReader(nil), info: memoryFileInfo{name: path.Base(clean), dir: true}}, nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *MemoryFileSystem) WriteFile(name string, write func(w io.Writer) error) error {
+	clean, err := cleanName("write", name)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[clean] = &memoryFile{data: buf.Bytes(), modTime: time.Now()}
+	return nil
+}
+
+// Files returns the names of all files, e.g. to inspect what a script wrote.
+func (m *MemoryFileSystem) Files() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	return names
+}
+
+type openMemoryFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+func (f *openMemoryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *openMemoryFile) Close() error               { return nil }
+
+type memoryFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) ModTime() time.Time { return i.modTime }
+func (i memoryFileInfo) IsDir() bool        { return i.dir }
+func (i memoryFileInfo) Sys() any           { return nil }
+func (i memoryFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// readOnlyFileSystem serves files from any fs.FS and rejects writes.
+type readOnlyFileSystem struct {
+	fsys fs.FS
+}
+
+// NewReadOnlyFileSystem returns a file system reading from fsys (e.g. an
+// embed.FS) that rejects all writes.
+func NewReadOnlyFileSystem(fsys fs.FS) FileSystem {
+	return &readOnlyFileSystem{fsys: fsys}
+}
+
+// NewZipFileSystem returns a read-only file system serving the files of a zip archive.
+func NewZipFileSystem(r io.ReaderAt, size int64) (FileSystem, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	return NewReadOnlyFileSystem(zr), nil
+}
+
+func (r *readOnlyFileSystem) Open(name string) (fs.File, error) {
+	clean, err := cleanName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	return r.fsys.Open(clean)
+}
+
+func (r *readOnlyFileSystem) WriteFile(name string, write func(w io.Writer) error) error {
+	return &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+}
+
+// overlayFileSystem reads from upper first and falls back to the read-only
+// lower file system, writes only go to upper.
+type overlayFileSystem struct {
+	upper FileSystem
+	lower fs.FS
+}
+
+// NewOverlayFileSystem returns a file system that reads from upper and,
+// for files upper doesn't have, from lower. Writes go to upper, so lower
+// is never modified.
+func NewOverlayFileSystem(upper FileSystem, lower fs.FS) FileSystem {
+	return &overlayFileSystem{upper: upper, lower: lower}
+}
+
+func (o *overlayFileSystem) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	if _, ok := o.lower.(FileSystem); !ok {
+		if name, err = cleanName("open", name); err != nil {
+			return nil, err
+		}
+	}
+	return o.lower.Open(name)
+}
+
+func (o *overlayFileSystem) WriteFile(name string, write func(w io.Writer) error) error {
+	return o.upper.WriteFile(name, write)
+}
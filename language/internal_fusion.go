@@ -0,0 +1,46 @@
+package language
+
+import (
+	"image/color"
+)
+
+// pixelKernelFactory creates the per-pixel kernel of an operation from its
+// arguments. The image argument is not included, the remaining arguments
+// have already been cast to the types the operation declares.
+type pixelKernelFactory func(args []any) dslPixelProcessor
+
+// pixelKernels lists all pure per-pixel operations, i.e. operations whose
+// result for a pixel only depends on that pixel's color and the arguments.
+// Chains of these operations are fused into a single pass by the optimizer.
+var pixelKernels = map[string]pixelKernelFactory{
+	"invert":     func(a []any) dslPixelProcessor { return colorInvertKernel() },
+	"grayscale":  func(a []any) dslPixelProcessor { return colorGrayscaleKernel() },
+	"sepia":      func(a []any) dslPixelProcessor { return colorSepiaKernel() },
+	"brightness": func(a []any) dslPixelProcessor { return colorBrightnessKernel(a[0].(float64)) },
+	"colorize":   func(a []any) dslPixelProcessor { return colorColorizeKernel(a[0].(color.RGBA64)) },
+	"contrast":   func(a []any) dslPixelProcessor { return colorContrastKernel(a[0].(float64)) },
+	"saturation": func(a []any) dslPixelProcessor { return colorSaturationKernel(a[0].(float64)) },
+	"opacity":    func(a []any) dslPixelProcessor { return colorOpacityKernel(a[0].(float64)) },
+	"hue-rotate": func(a []any) dslPixelProcessor { return colorHueRotateKernel(a[0].(float64)) },
+	"color-balance": func(a []any) dslPixelProcessor {
+		return colorBalanceKernel(a[0].(float64), a[1].(float64), a[2].(float64))
+	},
+	"posterize": func(a []any) dslPixelProcessor { return colorPosterizeKernel(a[0].(int)) },
+	"threshold": func(a []any) dslPixelProcessor { return colorThresholdKernel(a[0].(float64)) },
+	"vibrance":  func(a []any) dslPixelProcessor { return colorVibranceKernel(a[0].(float64)) },
+	"exposure":  func(a []any) dslPixelProcessor { return colorExposureKernel(a[0].(float64)) },
+}
+
+// fuseKernels chains the given kernels into one.
+// Between two kernels the channels are truncated to 16 bits, exactly like
+// storing the intermediate result in an NRGBA64 image would do, so the fused
+// kernel produces the same output as running the kernels one after another.
+func fuseKernels(kernels []dslPixelProcessor) dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+		r, g, b, a = r1, g1, b1, a1
+		for _, k := range kernels {
+			r, g, b, a = k(r&0xFFFF, g&0xFFFF, b&0xFFFF, a&0xFFFF)
+		}
+		return
+	}
+}
@@ -0,0 +1,148 @@
+package language
+
+import (
+	"image"
+
+	"github.com/toxyl/math"
+)
+
+// houghMaxSize is the size images are reduced to before lines are detected,
+// which keeps the number of edge points manageable without changing angles.
+const houghMaxSize = 1024
+
+// edgePoints returns the strongest edges of img (the top 10% of the
+// edge-detect magnitudes), relative to the upper-left corner of img.
+func edgePoints(img *image.NRGBA64) []image.Point {
+	if w, h := img.Rect.Dx(), img.Rect.Dy(); w > houghMaxSize || h > houghMaxSize {
+		s := float64(houghMaxSize) / float64(math.Max(w, h))
+		img = resample(img, math.Max(int(float64(w)*s), 1), math.Max(int(float64(h)*s), 1), resampleFilters["area"])
+	}
+	edges, err := colorEdgeDetect(img)
+	if err != nil {
+		return nil
+	}
+	b := edges.Bounds()
+	mags := make([]uint16, b.Dx()*b.Dy())
+	var hist [0x100]int
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := edges.NRGBA64At(x, y)
+			m := uint16(uint32(c.R) * uint32(c.A) / 0xffff)
+			mags[(y-b.Min.Y)*b.Dx()+x-b.Min.X] = m
+			hist[m>>8]++
+		}
+	}
+	// Find the magnitude of the strongest 10%, flat areas never count as edges
+	threshold, n := 0x100, 0
+	for threshold > 0 && n < len(mags)/10 {
+		threshold--
+		n += hist[threshold]
+	}
+	minMag := math.Max(uint16(threshold)<<8, 0x800)
+
+	var points []image.Point
+	for i, m := range mags {
+		if m >= minMag {
+			points = append(points, image.Pt(i%b.Dx(), i/b.Dx()))
+		}
+	}
+	return points
+}
+
+// houghScore measures how well the points line up along lines tilted by
+// angle degrees (clockwise) from the horizontal and the vertical. The points
+// are projected onto the normals of both directions; the sum of the squared
+// counts per distance is large when many points fall onto the same lines.
+func houghScore(points []image.Point, angle float64, size int) float64 {
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	// Distances are within -size..size, so they are offset by size
+	horizontal := make([]int, 2*size+1)
+	vertical := make([]int, 2*size+1)
+	for _, p := range points {
+		x, y := float64(p.X), float64(p.Y)
+		horizontal[int(math.Round(y*cos-x*sin))+size]++
+		vertical[int(math.Round(x*cos+y*sin))+size]++
+	}
+	score := 0.0
+	for i := range horizontal {
+		score += float64(horizontal[i]*horizontal[i] + vertical[i]*vertical[i])
+	}
+	return score
+}
+
+// skewMinPeakRatio is how much higher than the average score over all
+// orientations the best angle has to score for the edges to count as lines.
+// Fewer than skewMinPoints edge points are too few to tell lines from noise.
+const (
+	skewMinPeakRatio = 1.3
+	skewMinPoints    = 50
+)
+
+// detectSkewAngle returns the tilt (in degrees, clockwise, within
+// -maxAngle..maxAngle) of the dominant lines in img, 0 if it has no lines.
+// The angles are searched coarsely first and then refined around the best match.
+func detectSkewAngle(img *image.NRGBA64, maxAngle float64) float64 {
+	points := edgePoints(img)
+	maxAngle = math.Clamp(math.Abs(maxAngle), 0, 45)
+	if len(points) < skewMinPoints || maxAngle == 0 {
+		return 0
+	}
+	size := 0
+	for _, p := range points {
+		size = math.Max(size, p.X+p.Y+1)
+	}
+	scores := func(from, to, step float64) []float64 {
+		res := make([]float64, int(math.Round((to-from)/step))+1)
+		parallelRows(0, len(res), func(i int) {
+			res[i] = houghScore(points, from+float64(i)*step, size)
+		})
+		return res
+	}
+	search := func(from, to, step float64) (float64, float64) {
+		best := 0
+		s := scores(from, to, step)
+		for i := range s {
+			// Prefer the smaller correction when scores are equal
+			if s[i] > s[best] || (s[i] == s[best] && math.Abs(from+float64(i)*step) < math.Abs(from+float64(best)*step)) {
+				best = i
+			}
+		}
+		return from + float64(best)*step, s[best]
+	}
+
+	// Scores are periodic in 90°, so this covers all orientations. Without
+	// lines, all of them score about the same.
+	mean := 0.0
+	all := scores(-45, 44, 1)
+	for _, s := range all {
+		mean += s / float64(len(all))
+	}
+	angle, score := search(-maxAngle, maxAngle, math.Min(0.5, maxAngle))
+	if score < skewMinPeakRatio*mean {
+		return 0
+	}
+	angle, _ = search(math.Max(angle-0.5, -maxAngle), math.Min(angle+0.5, maxAngle), 0.02)
+	return math.Round(angle*100) / 100
+}
+
+// innerRect returns the size of the largest axis-aligned rectangle that fits
+// into a w x h rectangle rotated by angle degrees.
+func innerRect(w, h, angle float64) (float64, float64) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	rad := angle * math.Pi / 180
+	sin, cos := math.Abs(math.Sin(rad)), math.Abs(math.Cos(rad))
+	long, short := math.Max(w, h), math.Min(w, h)
+	if short <= 2*sin*cos*long || math.Abs(sin-cos) < 1e-10 {
+		// The rectangle touches the long sides only
+		x := 0.5 * short
+		if w >= h {
+			return x / sin, x / cos
+		}
+		return x / cos, x / sin
+	}
+	cos2 := cos*cos - sin*sin
+	return (w*cos - h*sin) / cos2, (h*cos - w*sin) / cos2
+}
@@ -0,0 +1,75 @@
+package language
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/toxyl/math"
+)
+
+// linesImage returns white lines on black, tilted by angle degrees.
+func linesImage(angle float64) *image.NRGBA64 {
+	img := IFromBounds(image.Rect(0, 0, 200, 150))
+	for y := range 150 {
+		for x := range 200 {
+			v := uint16(0)
+			if y%12 < 2 && x > 20 && x < 180 {
+				v = 0xffff
+			}
+			img.SetNRGBA64(x, y, color.NRGBA64{v, v, v, 0xffff})
+		}
+	}
+	return rotatePremul(toPremulImage(img), angle, resampleFilters["bilinear"]).toNRGBA64()
+}
+
+func TestDetectSkewFindsLines(t *testing.T) {
+	for _, angle := range []float64{-7, 0, 3, 9} {
+		if got := detectSkewAngle(linesImage(angle), 10); math.Abs(got-angle) > 0.2 {
+			t.Errorf("expected a tilt of %v°, got %v°", angle, got)
+		}
+	}
+}
+
+func TestDetectSkewWithoutLines(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := IFromBounds(image.Rect(0, 0, 200, 150))
+	for i := range noise.Pix {
+		noise.Pix[i] = byte(rng.Intn(256))
+	}
+	dots := opaqueImage(200, 150)
+	for i := range dots.Pix {
+		if i%4 < 3 {
+			dots.Pix[i] = 0
+		}
+	}
+	for range 40 {
+		dots.Set(rng.Intn(200), rng.Intn(150), color.White)
+	}
+	circles := IFromBounds(image.Rect(0, 0, 200, 150))
+	for y := range 150 {
+		for x := range 200 {
+			v := uint16(0)
+			if int(math.Hypot(float64(x-100), float64(y-75)))%20 < 3 {
+				v = 0xffff
+			}
+			circles.SetNRGBA64(x, y, color.NRGBA64{v, v, v, 0xffff})
+		}
+	}
+	for name, img := range map[string]*image.NRGBA64{
+		"blank":   IFromBounds(image.Rect(0, 0, 200, 150)),
+		"noise":   noise,
+		"dots":    imageToNRGBA64(dots),
+		"circles": circles,
+	} {
+		for _, maxAngle := range []float64{10, 45} {
+			if got := detectSkewAngle(img, maxAngle); got != 0 {
+				t.Errorf("%s: expected no tilt within %v°, got %v°", name, maxAngle, got)
+			}
+		}
+	}
+	if got := detectSkewAngle(linesImage(9), 1); got != 0 {
+		t.Errorf("expected no tilt for lines outside the range, got %v°", got)
+	}
+}
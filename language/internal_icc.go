@@ -0,0 +1,616 @@
+package language
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	ProfileSRGB      = "srgb"
+	ProfileDisplayP3 = "display-p3"
+	ProfileAdobeRGB  = "adobe-rgb"
+	ProfileRec2020   = "rec2020"
+)
+
+type mat3 [3][3]float64
+
+func (m mat3) mul(n mat3) mat3 {
+	var res mat3
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				res[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+	return res
+}
+
+func (m mat3) apply(v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0][0]*v[0] + m[0][1]*v[1] + m[0][2]*v[2],
+		m[1][0]*v[0] + m[1][1]*v[1] + m[1][2]*v[2],
+		m[2][0]*v[0] + m[2][1]*v[1] + m[2][2]*v[2],
+	}
+}
+
+func (m mat3) inverse() mat3 {
+	a, b, c := m[0][0], m[0][1], m[0][2]
+	d, e, f := m[1][0], m[1][1], m[1][2]
+	g, h, i := m[2][0], m[2][1], m[2][2]
+	det := a*(e*i-f*h) - b*(d*i-f*g) + c*(d*h-e*g)
+	if det == 0 {
+		return mat3{}
+	}
+	return mat3{
+		{(e*i - f*h) / det, (c*h - b*i) / det, (b*f - c*e) / det},
+		{(f*g - d*i) / det, (a*i - c*g) / det, (c*d - a*f) / det},
+		{(d*h - e*g) / det, (b*g - a*h) / det, (a*e - b*d) / det},
+	}
+}
+
+// toneCurve maps encoded values (0..1) to linear light.
+type toneCurve interface {
+	eval(x float64) float64
+}
+
+type gammaCurve float64
+
+func (g gammaCurve) eval(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return math.Pow(x, float64(g))
+}
+
+// parametricCurve is an ICC parametric curve, params holds g, a, b, c, d, e, f.
+type parametricCurve struct {
+	typ    int
+	params [7]float64
+}
+
+func (p parametricCurve) eval(x float64) float64 {
+	g, a, b, c, d, e, f := p.params[0], p.params[1], p.params[2], p.params[3], p.params[4], p.params[5], p.params[6]
+	pow := func(v float64) float64 {
+		if v <= 0 {
+			return 0
+		}
+		return math.Pow(v, g)
+	}
+	switch p.typ {
+	case 0:
+		return pow(x)
+	case 1:
+		if x >= -b/a {
+			return pow(a*x + b)
+		}
+		return 0
+	case 2:
+		if x >= -b/a {
+			return pow(a*x+b) + c
+		}
+		return c
+	case 3:
+		if x >= d {
+			return pow(a*x + b)
+		}
+		return c * x
+	case 4:
+		if x >= d {
+			return pow(a*x+b) + e
+		}
+		return c*x + f
+	}
+	return x
+}
+
+type tableCurve []float64
+
+func (t tableCurve) eval(x float64) float64 {
+	if len(t) == 0 {
+		return x
+	}
+	pos := math.Max(0, math.Min(1, x)) * float64(len(t)-1)
+	i := int(pos)
+	if i >= len(t)-1 {
+		return t[len(t)-1]
+	}
+	frac := pos - float64(i)
+	return t[i]*(1-frac) + t[i+1]*frac
+}
+
+// srgbCurve is the sRGB transfer function (IEC 61966-2-1).
+var srgbCurve = parametricCurve{3, [7]float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045}}
+
+// rec709Curve is the transfer function of Rec.709 and Rec.2020.
+var rec709Curve = parametricCurve{3, [7]float64{1 / 0.45, 1 / 1.099, 0.099 / 1.099, 1 / 4.5, 0.081}}
+
+// colorProfile is an RGB matrix/TRC profile: toXYZ converts linear RGB to
+// PCS XYZ (D50), trc linearizes the encoded channels.
+type colorProfile struct {
+	name  string
+	toXYZ mat3
+	trc   [3]toneCurve
+}
+
+var (
+	whiteD65 = [3]float64{0.95047, 1, 1.08883}
+	whiteD50 = [3]float64{0.96422, 1, 0.82521}
+)
+
+// bradfordD65ToD50 adapts XYZ values from D65 to the D50 PCS illuminant.
+var bradfordD65ToD50 = func() mat3 {
+	b := mat3{
+		{0.8951, 0.2664, -0.1614},
+		{-0.7502, 1.7135, 0.0367},
+		{0.0389, -0.0685, 1.0296},
+	}
+	src, dst := b.apply(whiteD65), b.apply(whiteD50)
+	scale := mat3{{dst[0] / src[0], 0, 0}, {0, dst[1] / src[1], 0}, {0, 0, dst[2] / src[2]}}
+	return b.inverse().mul(scale).mul(b)
+}()
+
+// primariesToXYZ returns the D50-adapted RGB to XYZ matrix of a D65 color
+// space with the given xy chromaticities of red, green and blue.
+func primariesToXYZ(rx, ry, gx, gy, bx, by float64) mat3 {
+	xyz := func(x, y float64) [3]float64 { return [3]float64{x / y, 1, (1 - x - y) / y} }
+	r, g, b := xyz(rx, ry), xyz(gx, gy), xyz(bx, by)
+	m := mat3{{r[0], g[0], b[0]}, {r[1], g[1], b[1]}, {r[2], g[2], b[2]}}
+	s := m.inverse().apply(whiteD65)
+	for i := range 3 {
+		for j := range 3 {
+			m[i][j] *= s[j]
+		}
+	}
+	return bradfordD65ToD50.mul(m)
+}
+
+var builtinProfiles = map[string]*colorProfile{
+	ProfileSRGB: {
+		name:  "sRGB",
+		toXYZ: primariesToXYZ(0.64, 0.33, 0.30, 0.60, 0.15, 0.06),
+		trc:   [3]toneCurve{srgbCurve, srgbCurve, srgbCurve},
+	},
+	ProfileDisplayP3: {
+		name:  "Display P3",
+		toXYZ: primariesToXYZ(0.680, 0.320, 0.265, 0.690, 0.150, 0.060),
+		trc:   [3]toneCurve{srgbCurve, srgbCurve, srgbCurve},
+	},
+	ProfileAdobeRGB: {
+		name:  "Adobe RGB (1998)",
+		toXYZ: primariesToXYZ(0.64, 0.33, 0.21, 0.71, 0.15, 0.06),
+		trc:   [3]toneCurve{gammaCurve(563.0 / 256), gammaCurve(563.0 / 256), gammaCurve(563.0 / 256)},
+	},
+	ProfileRec2020: {
+		name:  "Rec. 2020",
+		toXYZ: primariesToXYZ(0.708, 0.292, 0.170, 0.797, 0.131, 0.046),
+		trc:   [3]toneCurve{rec709Curve, rec709Curve, rec709Curve},
+	},
+}
+
+// builtinProfile returns the built-in profile with the given name.
+func builtinProfile(name string) (*colorProfile, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "srgb", "":
+		return builtinProfiles[ProfileSRGB], nil
+	case "display-p3", "p3", "displayp3":
+		return builtinProfiles[ProfileDisplayP3], nil
+	case "adobe-rgb", "adobergb", "adobe-rgb-1998":
+		return builtinProfiles[ProfileAdobeRGB], nil
+	case "rec2020", "rec-2020", "bt2020":
+		return builtinProfiles[ProfileRec2020], nil
+	}
+	return nil, fmt.Errorf("unknown color profile: %s (supported: srgb, display-p3, adobe-rgb, rec2020)", name)
+}
+
+// isSRGB reports whether p describes (almost) the same colors as sRGB,
+// in that case no conversion is necessary.
+func (p *colorProfile) isSRGB() bool {
+	srgb := builtinProfiles[ProfileSRGB]
+	for i := range 3 {
+		for j := range 3 {
+			if math.Abs(p.toXYZ[i][j]-srgb.toXYZ[i][j]) > 0.003 {
+				return false
+			}
+		}
+		for x := 0.0; x <= 1; x += 0.125 {
+			if math.Abs(p.trc[i].eval(x)-srgbCurve.eval(x)) > 0.003 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// parseICC parses an RGB or gray matrix/TRC ICC profile.
+// LUT-based profiles are not supported.
+func parseICC(data []byte) (*colorProfile, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("invalid ICC profile")
+	}
+	space := string(data[16:20])
+	if space != "RGB " && space != "GRAY" {
+		return nil, fmt.Errorf("unsupported ICC color space: %q", space)
+	}
+	tags := map[string][]byte{}
+	n := int(binary.BigEndian.Uint32(data[128:]))
+	for i := range n {
+		pos := 132 + 12*i
+		if pos+12 > len(data) {
+			break
+		}
+		offset, size := int(binary.BigEndian.Uint32(data[pos+4:])), int(binary.BigEndian.Uint32(data[pos+8:]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			continue
+		}
+		tags[string(data[pos:pos+4])] = data[offset : offset+size]
+	}
+
+	p := &colorProfile{name: iccDescription(tags["desc"])}
+	if space == "GRAY" {
+		trc, err := parseICCCurve(tags["kTRC"])
+		if err != nil {
+			return nil, err
+		}
+		// Gray values map to the PCS white point, each channel contributes a third
+		for i := range 3 {
+			for j := range 3 {
+				p.toXYZ[i][j] = whiteD50[i] / 3
+			}
+			p.trc[i] = trc
+		}
+		return p, nil
+	}
+
+	for j, name := range []string{"r", "g", "b"} {
+		xyz := tags[name+"XYZ"]
+		if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, fmt.Errorf("unsupported ICC profile: missing %sXYZ tag (only matrix/TRC profiles are supported)", name)
+		}
+		for i := range 3 {
+			p.toXYZ[i][j] = s15Fixed16(xyz[8+4*i:])
+		}
+		trc, err := parseICCCurve(tags[name+"TRC"])
+		if err != nil {
+			return nil, err
+		}
+		p.trc[j] = trc
+	}
+	return p, nil
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func parseICCCurve(data []byte) (toneCurve, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("missing ICC tone curve")
+	}
+	switch string(data[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if len(data) < 12+2*n {
+			return nil, fmt.Errorf("truncated ICC tone curve")
+		}
+		switch n {
+		case 0:
+			return gammaCurve(1), nil
+		case 1:
+			return gammaCurve(float64(binary.BigEndian.Uint16(data[12:])) / 256), nil
+		}
+		t := make(tableCurve, n)
+		for i := range t {
+			t[i] = float64(binary.BigEndian.Uint16(data[12+2*i:])) / 65535
+		}
+		return t, nil
+	case "para":
+		typ := int(binary.BigEndian.Uint16(data[8:]))
+		count := []int{1, 3, 4, 5, 7}
+		if typ >= len(count) || len(data) < 12+4*count[typ] {
+			return nil, fmt.Errorf("unsupported ICC parametric curve type %d", typ)
+		}
+		c := parametricCurve{typ: typ}
+		for i := range count[typ] {
+			c.params[i] = s15Fixed16(data[12+4*i:])
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unsupported ICC tone curve type %q", data[:4])
+}
+
+// iccDescription decodes a v2 'desc' or v4 'mluc' description tag.
+func iccDescription(data []byte) string {
+	if len(data) < 12 {
+		return ""
+	}
+	switch string(data[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(data[8:]))
+		if 12+n <= len(data) {
+			return strings.TrimRight(string(data[12:12+n]), "\x00")
+		}
+	case "mluc":
+		if len(data) < 28 {
+			return ""
+		}
+		length, offset := int(binary.BigEndian.Uint32(data[20:])), int(binary.BigEndian.Uint32(data[24:]))
+		if offset+length > len(data) {
+			return ""
+		}
+		u := make([]uint16, length/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(data[offset+2*i:])
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	}
+	return ""
+}
+
+// bytes returns p as an ICC v2 display profile.
+func (p *colorProfile) bytes() []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	be := binary.BigEndian
+	xyz := func(v [3]float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		for i := range 3 {
+			be.PutUint32(b[8+4*i:], uint32(int32(math.Round(v[i]*65536))))
+		}
+		return b
+	}
+	text := func(s string) []byte {
+		return append(append([]byte("text\x00\x00\x00\x00"), s...), 0)
+	}
+	desc := func(s string) []byte {
+		b := []byte("desc\x00\x00\x00\x00")
+		b = be.AppendUint32(b, uint32(len(s)+1))
+		b = append(append(b, s...), 0)
+		return append(b, make([]byte, 4+4+2+1+67)...) // empty Unicode and ScriptCode descriptions
+	}
+	curve := func(c toneCurve) []byte {
+		b := []byte("curv\x00\x00\x00\x00")
+		if g, ok := c.(gammaCurve); ok {
+			b = be.AppendUint32(b, 1)
+			return be.AppendUint16(b, uint16(math.Round(float64(g)*256)))
+		}
+		const n = 1024
+		b = be.AppendUint32(b, n)
+		for i := range n {
+			v := math.Max(0, math.Min(1, c.eval(float64(i)/(n-1))))
+			b = be.AppendUint16(b, uint16(math.Round(v*65535)))
+		}
+		return b
+	}
+	column := func(j int) [3]float64 { return [3]float64{p.toXYZ[0][j], p.toXYZ[1][j], p.toXYZ[2][j]} }
+
+	trc := curve(p.trc[0])
+	tags := []tag{
+		{"desc", desc(p.name)},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(whiteD50)},
+		{"rXYZ", xyz(column(0))},
+		{"gXYZ", xyz(column(1))},
+		{"bXYZ", xyz(column(2))},
+		{"rTRC", trc},
+		{"gTRC", trc}, // identical curves share their data
+		{"bTRC", trc},
+	}
+
+	header := make([]byte, 128)
+	be.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntrRGB XYZ ")
+	copy(header[36:], "acsp")
+	for i, v := range whiteD50 {
+		be.PutUint32(header[68+4*i:], uint32(int32(math.Round(v*65536))))
+	}
+
+	table := be.AppendUint32(nil, uint32(len(tags)))
+	var data []byte
+	offset := 128 + 4 + 12*len(tags)
+	offsets := map[*byte]int{}
+	for _, t := range tags {
+		pos, ok := offsets[&t.data[0]]
+		if !ok {
+			pos = offset + len(data)
+			offsets[&t.data[0]] = pos
+			data = append(data, t.data...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		table = append(table, t.sig...)
+		table = be.AppendUint32(table, uint32(pos))
+		table = be.AppendUint32(table, uint32(len(t.data)))
+	}
+	res := append(append(header, table...), data...)
+	be.PutUint32(res, uint32(len(res)))
+	return res
+}
+
+// sourceProfile returns the profile the pixels described by m have to be
+// converted from to end up in sRGB, nil if no conversion is needed, and the
+// metadata of the converted pixels. Unsupported RGB profiles are kept as
+// they are, other unsupported profiles (e.g. CMYK) are dropped because
+// they no longer describe the decoded pixels.
+func (m *Metadata) sourceProfile() (*colorProfile, *Metadata) {
+	if m == nil || len(m.ICC) == 0 {
+		return nil, m
+	}
+	p, err := parseICC(m.ICC)
+	if err != nil {
+		if len(m.ICC) >= 20 && string(m.ICC[16:20]) == "RGB " {
+			return nil, m
+		}
+		res := m.Clone()
+		res.ICC = nil
+		return nil, res
+	}
+	if p.isSRGB() {
+		return nil, m
+	}
+	res := m.Clone()
+	res.ICC = builtinProfiles[ProfileSRGB].bytes()
+	return p, res
+}
+
+// profile returns the color profile of the pixels described by m,
+// images without a (supported) profile are sRGB.
+func (m *Metadata) profile() *colorProfile {
+	if m != nil && len(m.ICC) > 0 {
+		if p, err := parseICC(m.ICC); err == nil {
+			return p
+		}
+	}
+	return builtinProfiles[ProfileSRGB]
+}
+
+// profileConversion converts pixels from src to dst.
+type profileConversion struct {
+	toLinear [3][]float32 // source TRCs for all 16-bit values
+	matrix   mat3
+	encode   [3][]float32 // inverse destination TRCs, sampled over 0..1
+}
+
+const profileEncodeSteps = 4096
+
+func newProfileConversion(src, dst *colorProfile) *profileConversion {
+	c := &profileConversion{matrix: dst.toXYZ.inverse().mul(src.toXYZ)}
+	for ch := range 3 {
+		c.toLinear[ch] = make([]float32, 65536)
+		for i := range c.toLinear[ch] {
+			c.toLinear[ch][i] = float32(src.trc[ch].eval(float64(i) / 65535))
+		}
+		// Invert the (monotonic) destination curve by bisection
+		c.encode[ch] = make([]float32, profileEncodeSteps+1)
+		for i := range c.encode[ch] {
+			y := float64(i) / profileEncodeSteps
+			lo, hi := 0.0, 1.0
+			for range 24 {
+				mid := (lo + hi) / 2
+				if dst.trc[ch].eval(mid) < y {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			c.encode[ch][i] = float32((lo + hi) / 2)
+		}
+	}
+	return c
+}
+
+func (c *profileConversion) linear(r, g, b uint16) [3]float64 {
+	return c.matrix.apply([3]float64{
+		float64(c.toLinear[0][r]),
+		float64(c.toLinear[1][g]),
+		float64(c.toLinear[2][b]),
+	})
+}
+
+func (c *profileConversion) encodeChannel(ch int, v float64) uint16 {
+	pos := math.Max(0, math.Min(1, v)) * profileEncodeSteps
+	i := int(pos)
+	if i >= profileEncodeSteps {
+		return uint16(math.Round(float64(c.encode[ch][profileEncodeSteps]) * 65535))
+	}
+	frac := pos - float64(i)
+	e := float64(c.encode[ch][i])*(1-frac) + float64(c.encode[ch][i+1])*frac
+	return uint16(math.Round(e * 65535))
+}
+
+// convertProfile converts the pixels of img from src to dst, colors outside
+// of the destination gamut are clipped. Alpha is left unchanged.
+func convertProfile(img *image.NRGBA64, src, dst *colorProfile) *image.NRGBA64 {
+	c := newProfileConversion(src, dst)
+	bounds := img.Bounds()
+	res := IFromBounds(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			s := img.Pix[i : i+8 : i+8]
+			d := res.Pix[i : i+8 : i+8]
+			v := c.linear(be16(s[0:]), be16(s[2:]), be16(s[4:]))
+			for ch := range 3 {
+				e := c.encodeChannel(ch, v[ch])
+				d[2*ch], d[2*ch+1] = uint8(e>>8), uint8(e)
+			}
+			d[6], d[7] = s[6], s[7]
+		}
+	})
+	return res
+}
+
+// convertProfileToLinear converts the pixels of img from src to linear-light
+// sRGB without clipping, so colors outside of the sRGB gamut are preserved.
+func convertProfileToLinear(img *image.NRGBA64, src *colorProfile) *LinearImage {
+	c := newProfileConversion(src, builtinProfiles[ProfileSRGB])
+	bounds := img.Bounds()
+	res := NewLinearImage(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			s := img.Pix[img.PixOffset(x, y):]
+			v := c.linear(be16(s[0:]), be16(s[2:]), be16(s[4:]))
+			res.SetLinear(x, y, float32(v[0]), float32(v[1]), float32(v[2]), float32(be16(s[6:]))/0xFFFF)
+		}
+	})
+	return res
+}
+
+func be16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+// heifColorProfile returns the ICC profile of a HEIF/AVIF file, either
+// embedded ('prof') or derived from the color primaries and transfer
+// characteristics ('nclx').
+func heifColorProfile(data []byte) []byte {
+	i := bytes.Index(data, []byte("colr"))
+	for ; i >= 4; i = nextIndex(data, i, "colr") {
+		size := int(binary.BigEndian.Uint32(data[i-4:]))
+		end := i - 4 + size
+		if size < 12 || end > len(data) {
+			continue
+		}
+		switch string(data[i+4 : i+8]) {
+		case "prof", "rICC":
+			return bytes.Clone(data[i+8 : end])
+		case "nclx":
+			if end < i+12 {
+				continue
+			}
+			primaries := binary.BigEndian.Uint16(data[i+8:])
+			var name string
+			switch primaries {
+			case 9:
+				name = ProfileRec2020
+			case 12:
+				name = ProfileDisplayP3
+			default:
+				return nil
+			}
+			p := *builtinProfiles[name]
+			if transfer := binary.BigEndian.Uint16(data[i+10:]); transfer == 13 {
+				p.trc = [3]toneCurve{srgbCurve, srgbCurve, srgbCurve}
+			}
+			return p.bytes()
+		}
+	}
+	return nil
+}
+
+func nextIndex(data []byte, after int, s string) int {
+	j := bytes.Index(data[after+1:], []byte(s))
+	if j < 0 {
+		return -1
+	}
+	return after + 1 + j
+}
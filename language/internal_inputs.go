@@ -0,0 +1,64 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// decodeInput converts an in-memory input into a value scripts can use.
+// Encoded images ([]byte or io.Reader) are decoded like files passed to
+// load, including their metadata and color profile, image.Image values
+// are converted to NRGBA64, keeping the metadata of images returned by an
+// earlier run. Inputs are decoded before the script runs and can't see its
+// working space, so they are sRGB unless they already are linear images.
+// Other values are returned as they are.
+func (r *scriptRun) decodeInput(input any) (any, error) {
+	switch t := input.(type) {
+	case []byte:
+		imgs, meta, err := decodeImageData(t)
+		if err != nil {
+			return nil, err
+		}
+		return r.imagesToWorkingSpace(imgs, meta), nil
+	case io.Reader:
+		data, err := io.ReadAll(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		return r.decodeInput(data)
+	case *LinearImage:
+		return t, nil
+	case image.Image:
+		res := imageToNRGBA64(t)
+		// Results of earlier runs keep their metadata
+		if m, ok := resultMetadata.get(t); ok {
+			r.metadata.set(res, m)
+		}
+		return res, nil
+	}
+	return input, nil
+}
+
+// decodeInputs decodes the script arguments and named inputs of a run,
+// named inputs are stored as variables.
+func (r *scriptRun) decodeInputs(args []any, inputs map[string]any) ([]any, error) {
+	res := make([]any, len(args))
+	for i, arg := range args {
+		v, err := r.decodeInput(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode argument $%d: %w", i+1, err)
+		}
+		res[i] = v
+	}
+	for name, input := range inputs {
+		v, err := r.decodeInput(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode input %s: %w", name, err)
+		}
+		if err := r.dsl.vars.set(name, v); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,173 @@
+package language
+
+import (
+	"image"
+	"sync"
+
+	"github.com/toxyl/math"
+)
+
+const (
+	WorkingSpaceSRGB   = "srgb"
+	WorkingSpaceLinear = "linear"
+)
+
+var (
+	srgbToLinearLUT     []float32
+	srgbToLinearLUTOnce sync.Once
+)
+
+// workingSpace returns the working space of the run.
+func (r *scriptRun) workingSpace() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.space
+}
+
+func (r *scriptRun) setWorkingSpace(space string) (previous string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, r.space = r.space, space
+	return
+}
+
+// srgbToLinear16 decodes a 16-bit sRGB channel value to linear light.
+// The decoding uses a lookup table since there are only 65536 possible inputs.
+func srgbToLinear16(v uint32) float32 {
+	srgbToLinearLUTOnce.Do(func() {
+		srgbToLinearLUT = make([]float32, 0x10000)
+		for i := range srgbToLinearLUT {
+			srgbToLinearLUT[i] = float32(srgbToLinear(float64(i) / 0xFFFF))
+		}
+	})
+	return srgbToLinearLUT[v&0xFFFF]
+}
+
+// linearToSRGB16 encodes a linear-light channel value as 16-bit sRGB,
+// clipping values outside of 0..1.
+func linearToSRGB16(v float32) uint16 {
+	return unitToU16(float32(linearToSRGB(float64(v))))
+}
+
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// unitToU16 maps 0..1 to 0..65535, clipping values outside of that range.
+func unitToU16(v float32) uint16 {
+	return uint16(math.Clamp(float64(v)*0xFFFF+0.5, 0, 0xFFFF))
+}
+
+// parallelRows calls fn for every row in [minY, maxY), spreading the rows
+// over NumColorConversionWorkers goroutines.
+func parallelRows(minY, maxY int, fn func(y int)) {
+	numWorkers := NumColorConversionWorkers
+	height := maxY - minY
+	if height <= 0 {
+		return
+	}
+	rowsPerWorker := (height + numWorkers - 1) / numWorkers
+
+	var wg sync.WaitGroup
+	for i := range numWorkers {
+		startY := minY + i*rowsPerWorker
+		endY := math.Min(startY+rowsPerWorker, maxY)
+		if startY >= endY {
+			continue
+		}
+		wg.Add(1)
+		go func(startY, endY int) {
+			defer wg.Done()
+			for y := startY; y < endY; y++ {
+				fn(y)
+			}
+		}(startY, endY)
+	}
+	wg.Wait()
+}
+
+// toLinearImage decodes an sRGB image to linear light.
+func toLinearImage(img *image.NRGBA64) *LinearImage {
+	bounds := img.Bounds()
+	result := NewLinearImage(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := dsl.getColor(img, x, y)
+			result.SetLinear(x, y, srgbToLinear16(r), srgbToLinear16(g), srgbToLinear16(b), float32(a)/0xFFFF)
+		}
+	})
+	return result
+}
+
+// fromLinearImage encodes a linear-light image as sRGB, clipping values
+// outside of the displayable range.
+func fromLinearImage(img *LinearImage) *image.NRGBA64 {
+	bounds := img.Bounds()
+	result := IFromBounds(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.LinearAt(x, y)
+			dsl.setColor(result, x, y,
+				uint32(linearToSRGB16(r)),
+				uint32(linearToSRGB16(g)),
+				uint32(linearToSRGB16(b)),
+				uint32(unitToU16(a)),
+			)
+		}
+	})
+	return result
+}
+
+// linearToPremul premultiplies the colors of a linear-light image for
+// resampling, values outside of 0..1 are kept.
+func linearToPremul(img *LinearImage) *premulImage {
+	bounds := img.Bounds()
+	p := newPremulImage(bounds.Dx(), bounds.Dy())
+	parallelRows(0, p.h, func(y int) {
+		for x := range p.w {
+			r, g, b, a := img.LinearAt(bounds.Min.X+x, bounds.Min.Y+y)
+			i := (y*p.w + x) * 4
+			p.pix[i], p.pix[i+1], p.pix[i+2], p.pix[i+3] = r*a, g*a, b*a, a
+		}
+	})
+	return p
+}
+
+// toLinearImage returns the colors of p as a linear-light image. Only the
+// alpha is clamped, colors keep the values the filters produced.
+func (p *premulImage) toLinearImage() *LinearImage {
+	img := NewLinearImage(image.Rect(0, 0, p.w, p.h))
+	parallelRows(0, p.h, func(y int) {
+		for x := range p.w {
+			i := (y*p.w + x) * 4
+			a := p.pix[i+3]
+			if a <= 0 {
+				continue
+			}
+			a = math.Min(a, 1)
+			img.SetLinear(x, y, p.pix[i]/a, p.pix[i+1]/a, p.pix[i+2]/a, a)
+		}
+	})
+	return img
+}
+
+// linearCrop returns the part r of img, moved to the origin.
+func linearCrop(img *LinearImage, r image.Rectangle) *LinearImage {
+	r = r.Intersect(img.Rect)
+	result := NewLinearImage(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := img.PixOffset(r.Min.X, y)
+		copy(result.Pix[(y-r.Min.Y)*result.Stride:], img.Pix[i:i+4*r.Dx()])
+	}
+	return result
+}
@@ -0,0 +1,376 @@
+package language
+
+import (
+	"fmt"
+
+	"github.com/toxyl/math"
+)
+
+type linearBlendFunc func(c1, c2 float32) float32
+
+// linearBlendModes are the blend modes that are well-defined for
+// unbounded linear-light values.
+var linearBlendModes = map[string]linearBlendFunc{
+	NORMAL:     func(c1, c2 float32) float32 { return c2 },
+	MULTIPLY:   func(c1, c2 float32) float32 { return c1 * c2 },
+	SCREEN:     func(c1, c2 float32) float32 { return c1 + c2 - c1*c2 },
+	DIFFERENCE: func(c1, c2 float32) float32 { return math.Abs(c1 - c2) },
+	SUBTRACT:   func(c1, c2 float32) float32 { return math.Max(c1-c2, 0) },
+	AVERAGE:    func(c1, c2 float32) float32 { return (c1 + c2) / 2 },
+	DARKEN:     func(c1, c2 float32) float32 { return math.Min(c1, c2) },
+	LIGHTEN:    func(c1, c2 float32) float32 { return math.Max(c1, c2) },
+}
+
+// linearOps lists the operations that have a linear-light implementation.
+// When any image argument of a call is a *LinearImage, the call is routed
+// to the implementation listed here and all image arguments are passed as
+// *LinearImage. Operations not listed here receive sRGB-encoded images.
+var linearOps = map[string]func(a []any) (any, error){
+	"exposure": func(a []any) (any, error) {
+		return linearScale(a[0].(*LinearImage), float32(math.Pow(2, a[1].(float64)))), nil
+	},
+	"brightness": func(a []any) (any, error) {
+		return linearScale(a[0].(*LinearImage), float32(a[1].(float64))), nil
+	},
+	"contrast": func(a []any) (any, error) {
+		return linearContrast(a[0].(*LinearImage), a[1].(float64)), nil
+	},
+	"grayscale": func(a []any) (any, error) {
+		return linearGrayscale(a[0].(*LinearImage)), nil
+	},
+	"opacity": func(a []any) (any, error) {
+		return linearOpacity(a[0].(*LinearImage), float32(a[1].(float64))), nil
+	},
+	"blur-gaussian": func(a []any) (any, error) {
+		r := a[1].(float64)
+		if r <= 0 {
+			return linearScale(a[0].(*LinearImage), 1), nil
+		}
+		return linearConvolve(a[0].(*LinearImage), makeKernelGaussian1D(int(r*2+1), r, int(r))), nil
+	},
+	"blur-box": func(a []any) (any, error) {
+		size := a[1].(int)*2 + 1
+		kernel := make([]float64, size)
+		for i := range kernel {
+			kernel[i] = 1 / float64(size)
+		}
+		return linearConvolve(a[0].(*LinearImage), kernel), nil
+	},
+	"scale": func(a []any) (any, error) {
+		return linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			w, h := scaleSize(a[0].(*LinearImage).Rect, a[1].(float64), a[2].(float64))
+			return src.resample(w, h, f), nil
+		})
+	},
+	"resize": func(a []any) (any, error) {
+		return linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			w, h, err := resizeSize(a[0].(*LinearImage).Rect, a[1].(int), a[2].(int))
+			if err != nil {
+				return nil, err
+			}
+			return src.resample(w, h, f), nil
+		})
+	},
+	"resize-fit": func(a []any) (any, error) {
+		w, h, ok := fitSize(a[0].(*LinearImage).Rect, a[1].(int), a[2].(int))
+		if !ok {
+			return a[0], nil
+		}
+		return linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			return src.resample(w, h, f), nil
+		})
+	},
+	"resize-max-mp": func(a []any) (any, error) {
+		w, h, ok := maxMPSize(a[0].(*LinearImage).Rect, a[1].(float64))
+		if !ok {
+			return a[0], nil
+		}
+		return linearResample(a[0], a[2], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			return src.resample(w, h, f), nil
+		})
+	},
+	"resize-fill": func(a []any) (any, error) {
+		w, h := a[1].(int), a[2].(int)
+		scaled, err := linearResample(a[0], a[4], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			nw, nh, err := coverSize(a[0].(*LinearImage).Rect, w, h)
+			if err != nil {
+				return nil, err
+			}
+			return src.resample(nw, nh, f), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		r, err := fillRect(scaled.(*LinearImage).Rect, w, h, a[3].(string))
+		if err != nil {
+			return nil, err
+		}
+		return linearCrop(scaled.(*LinearImage), r), nil
+	},
+	"smart-crop": func(a []any) (any, error) {
+		w, h := a[1].(int), a[2].(int)
+		scaled, err := linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			nw, nh, err := coverSize(a[0].(*LinearImage).Rect, w, h)
+			if err != nil {
+				return nil, err
+			}
+			return src.resample(nw, nh, f), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// The detail is measured on the sRGB image, like edge-detect sees it
+		r, err := smartCropRect(fromLinearImage(scaled.(*LinearImage)), w, h)
+		if err != nil {
+			return nil, err
+		}
+		return linearCrop(scaled.(*LinearImage), r), nil
+	},
+	"rotate": func(a []any) (any, error) {
+		return linearResample(a[0], a[2], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			return rotatePremul(src, a[1].(float64), f), nil
+		})
+	},
+	"transform": func(a []any) (any, error) {
+		return linearResample(a[0], a[6], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			return transformPremul(src, a[1].(float64), a[2].(float64), a[3].(float64), a[4].(float64), a[5].(float64), f), nil
+		})
+	},
+	"warp-affine": func(a []any) (any, error) {
+		return linearResample(a[0], a[2], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			inv, err := a[1].(*Matrix).Invert()
+			if err != nil {
+				return nil, err
+			}
+			return src.remap(src.w, src.h, f, inv.Apply), nil
+		})
+	},
+	"warp-perspective": func(a []any) (any, error) {
+		return linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			inv, err := perspectiveInverse(a[1].(Quad), a[2].(Quad))
+			if err != nil {
+				return nil, err
+			}
+			return src.remap(src.w, src.h, f, inv.Apply), nil
+		})
+	},
+	"skew": func(a []any) (any, error) {
+		return linearResample(a[0], a[3], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			inv, w, h, err := skewInverse(a[0].(*LinearImage).Rect, a[1].(float64), a[2].(float64))
+			if err != nil {
+				return nil, err
+			}
+			return src.remap(w, h, f, inv.Apply), nil
+		})
+	},
+	"lens-correct": func(a []any) (any, error) {
+		return linearResample(a[0], a[8], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			g := newLensGeometry(a[0].(*LinearImage).Rect, a[6].(float64), a[7].(float64))
+			return src.remap(src.w, src.h, f, g.correction(a[1].(float64), a[2].(float64), a[3].(float64), a[4].(float64), a[5].(float64))), nil
+		})
+	},
+	"lens-distort": func(a []any) (any, error) {
+		return linearResample(a[0], a[8], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			g := newLensGeometry(a[0].(*LinearImage).Rect, a[6].(float64), a[7].(float64))
+			return src.remap(src.w, src.h, f, g.distortion(a[1].(float64), a[2].(float64), a[3].(float64), a[4].(float64), a[5].(float64))), nil
+		})
+	},
+	"tca-correct": func(a []any) (any, error) {
+		return linearResample(a[0], a[5], func(src *premulImage, f *resampleFilter) (*premulImage, error) {
+			return tcaCorrectPremul(src, a[1].(float64), a[2].(float64), a[3].(float64), a[4].(float64), f), nil
+		})
+	},
+	"blend": func(a []any) (any, error) {
+		return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), a[2].(string))
+	},
+	"blend-normal":     func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), NORMAL) },
+	"blend-multiply":   func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), MULTIPLY) },
+	"blend-screen":     func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), SCREEN) },
+	"blend-difference": func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), DIFFERENCE) },
+	"blend-subtract":   func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), SUBTRACT) },
+	"blend-average":    func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), AVERAGE) },
+	"blend-darken":     func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), DARKEN) },
+	"blend-lighten":    func(a []any) (any, error) { return linearBlend(a[0].(*LinearImage), a[1].(*LinearImage), LIGHTEN) },
+}
+
+// callLinear calls the linear-light implementation of fn if there is one and
+// at least one of the image arguments is a *LinearImage.
+// The returned bool reports whether the call was handled.
+func (r *scriptRun) callLinear(fn *dslFnType, args []any) (any, bool, error) {
+	op, ok := linearOps[fn.meta.name]
+	if !ok {
+		return nil, false, nil
+	}
+	hasLinear := false
+	for i, arg := range args {
+		if typ := fn.meta.params[i].typ; typ == "*image.NRGBA64" {
+			_, ok := r.resolveArg(arg, typ).(*LinearImage)
+			hasLinear = hasLinear || ok
+		}
+	}
+	if !hasLinear {
+		return nil, false, nil
+	}
+	resolved := make([]any, len(args))
+	for i, param := range fn.meta.params {
+		typ := param.typ
+		if typ == "*image.NRGBA64" {
+			typ = "*language.LinearImage"
+		}
+		arg, err := r.castArg(args[i], typ)
+		if err != nil {
+			return nil, true, err
+		}
+		resolved[i] = arg
+	}
+	if err := fn.validate(resolved...); err != nil {
+		return nil, true, err
+	}
+	res, err := op(resolved)
+	return r.metadata.inherit(res, resolved...), true, err
+}
+
+func linearMap(img *LinearImage, fn func(r, g, b, a float32) (float32, float32, float32, float32)) *LinearImage {
+	bounds := img.Bounds()
+	result := NewLinearImage(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := fn(img.LinearAt(x, y))
+			result.SetLinear(x, y, r, g, b, a)
+		}
+	})
+	return result
+}
+
+func linearScale(img *LinearImage, factor float32) *LinearImage {
+	return linearMap(img, func(r, g, b, a float32) (float32, float32, float32, float32) {
+		return r * factor, g * factor, b * factor, a
+	})
+}
+
+// linearContrast scales the distance to middle gray in log space,
+// which keeps values positive and works for any dynamic range.
+func linearContrast(img *LinearImage, factor float64) *LinearImage {
+	const mid = 0.18
+	f := func(v float32) float32 {
+		if v <= 0 {
+			return 0
+		}
+		return float32(mid * math.Pow(float64(v)/mid, factor))
+	}
+	return linearMap(img, func(r, g, b, a float32) (float32, float32, float32, float32) {
+		return f(r), f(g), f(b), a
+	})
+}
+
+func linearGrayscale(img *LinearImage) *LinearImage {
+	return linearMap(img, func(r, g, b, a float32) (float32, float32, float32, float32) {
+		l := 0.2126*r + 0.7152*g + 0.0722*b // Rec. 709 luminance
+		return l, l, l, a
+	})
+}
+
+func linearOpacity(img *LinearImage, amount float32) *LinearImage {
+	return linearMap(img, func(r, g, b, a float32) (float32, float32, float32, float32) {
+		return r, g, b, a * amount
+	})
+}
+
+// linearConvolve applies the given 1D kernel horizontally and vertically.
+// Colors are premultiplied while convolving so transparent pixels do not
+// bleed their color into opaque neighbors.
+func linearConvolve(img *LinearImage, kernel []float64) *LinearImage {
+	bounds := img.Bounds()
+	half := len(kernel) / 2
+	tmp := NewLinearImage(bounds)
+	result := NewLinearImage(bounds)
+
+	pass := func(src, dst *LinearImage, dx, dy int, premultiply bool) {
+		parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				var r, g, b, a, wsum float64
+				for k, w := range kernel {
+					px, py := x+(k-half)*dx, y+(k-half)*dy
+					if px < bounds.Min.X || px >= bounds.Max.X || py < bounds.Min.Y || py >= bounds.Max.Y {
+						continue
+					}
+					cr, cg, cb, ca := src.LinearAt(px, py)
+					pa := float64(ca)
+					if !premultiply {
+						pa = 1
+					}
+					r += float64(cr) * pa * w
+					g += float64(cg) * pa * w
+					b += float64(cb) * pa * w
+					a += float64(ca) * w
+					wsum += w
+				}
+				if wsum > 0 {
+					r, g, b, a = r/wsum, g/wsum, b/wsum, a/wsum
+				}
+				dst.SetLinear(x, y, float32(r), float32(g), float32(b), float32(a))
+			}
+		})
+	}
+	pass(img, tmp, 1, 0, true)
+	pass(tmp, result, 0, 1, false)
+
+	// Undo the premultiplication
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := result.LinearAt(x, y)
+			if a > 0 {
+				result.SetLinear(x, y, r/a, g/a, b/a, a)
+			}
+		}
+	})
+	return result
+}
+
+// linearResample runs fn on the premultiplied colors of the linear-light
+// image img with the resampling filter named filter. The same resampling code
+// serves sRGB images, so linear-light values are never clipped.
+func linearResample(img, filter any, fn func(src *premulImage, f *resampleFilter) (*premulImage, error)) (any, error) {
+	f, err := getResampleFilter(filter.(string))
+	if err != nil {
+		return nil, err
+	}
+	res, err := fn(linearToPremul(img.(*LinearImage)), f)
+	if err != nil {
+		return nil, err
+	}
+	return res.toLinearImage(), nil
+}
+
+// linearBlend composites imgB over imgA using the given blend mode.
+// Modes without a linear-light definition are rendered in sRGB and decoded again.
+func linearBlend(imgA, imgB *LinearImage, mode string) (*LinearImage, error) {
+	fn, ok := linearBlendModes[mode]
+	if !ok {
+		if blenders.get(mode) == nil {
+			return nil, fmt.Errorf("unknown blend mode: %s", mode)
+		}
+		return toLinearImage(blenders.BlendImages(mode, fromLinearImage(imgA), fromLinearImage(imgB))), nil
+	}
+	bounds := imgA.Bounds()
+	result := NewLinearImage(bounds)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := imgA.LinearAt(x, y)
+			r2, g2, b2, a2 := imgB.LinearAt(x, y)
+			// Blend the colors where both layers overlap, then composite (Porter-Duff over)
+			br := fn(r1, r2)*a1 + r2*(1-a1)
+			bg := fn(g1, g2)*a1 + g2*(1-a1)
+			bb := fn(b1, b2)*a1 + b2*(1-a1)
+			a := a2 + a1*(1-a2)
+			if a <= 0 {
+				continue
+			}
+			r := (br*a2 + r1*a1*(1-a2)) / a
+			g := (bg*a2 + g1*a1*(1-a2)) / a
+			b := (bb*a2 + b1*a1*(1-a2)) / a
+			result.SetLinear(x, y, r, g, b, a)
+		}
+	})
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package language
+
+import (
+	"image"
+	"testing"
+
+	"github.com/toxyl/math"
+)
+
+// TestLinearResamplingKeepsHDR checks that the resampling functions don't clip
+// linear-light values above 1.
+func TestLinearResamplingKeepsHDR(t *testing.T) {
+	img := NewLinearImage(image.Rect(0, 0, 40, 30))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 4, 2, 0.5, 1
+	}
+	quad := func(x0, y0, x1, y1 float64) Quad {
+		return Quad{&Point{x0, y0}, &Point{x1, y0}, &Point{x1, y1}, &Point{x0, y1}}
+	}
+	for op, args := range map[string][]any{
+		"scale":            {img, -0.5, 0.5, "lanczos3"},
+		"resize":           {img, 20, 0, "bicubic"},
+		"resize-fit":       {img, 10, 10, "area"},
+		"resize-max-mp":    {img, 0.0003, "mitchell"},
+		"resize-fill":      {img, 10, 20, "C", "lanczos3"},
+		"smart-crop":       {img, 10, 20, "bilinear"},
+		"rotate":           {img, 30.0, "bicubic"},
+		"transform":        {img, 0.0, 0.0, 10.0, -0.2, -0.2, "bicubic"},
+		"warp-affine":      {img, NewAffineMatrix(1, 0.1, 0, 0, 1, 0), "lanczos3"},
+		"warp-perspective": {img, quad(0, 0, 40, 30), quad(2, 1, 38, 29), "bicubic"},
+		"skew":             {img, 10.0, 0.0, "bicubic"},
+		"lens-correct":     {img, -0.1, 0.0, 0.0, 0.0, 0.0, 0.5, 0.5, "bicubic"},
+		"lens-distort":     {img, -0.1, 0.0, 0.0, 0.0, 0.0, 0.5, 0.5, "bicubic"},
+		"tca-correct":      {img, 1.002, 0.998, 0.5, 0.5, "bicubic"},
+	} {
+		res, err := linearOps[op](args)
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+		l, ok := res.(*LinearImage)
+		if !ok {
+			t.Fatalf("%s: expected a *LinearImage, got %T", op, res)
+		}
+		c := l.Rect.Min.Add(l.Rect.Size().Div(2))
+		r, g, b, a := l.LinearAt(c.X, c.Y)
+		if math.Abs(r-4) > 1e-3 || math.Abs(g-2) > 1e-3 || math.Abs(b-0.5) > 1e-3 || math.Abs(a-1) > 1e-3 {
+			t.Errorf("%s: expected (4, 2, 0.5, 1) in the center, got (%v, %v, %v, %v)", op, r, g, b, a)
+		}
+	}
+}
+
+func TestLinearResamplingScripts(t *testing.T) {
+	src := testImage(32, 24)
+	for _, script := range []string{
+		"to-srgb(resize(exposure(to-linear($1) 1) 16 0 \"bicubic\"))",
+		"to-srgb(rotate(to-linear($1) 10))",
+		"to-srgb(warp-affine(to-linear($1) matrix-rotate(10)))",
+		"to-srgb(resize-fill(to-linear($1) 10 10 \"TL\"))",
+	} {
+		runImage(t, script, src)
+	}
+}
+
+func TestLinearScaleUsesFilter(t *testing.T) {
+	img := NewLinearImage(image.Rect(0, 0, 4, 1))
+	for x := range 4 {
+		img.SetLinear(x, 0, float32(x), float32(x), float32(x), 1)
+	}
+	nearest, err := linearOps["scale"]([]any{img, 1.0, 0.0, "nearest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bilinear, err := linearOps["scale"]([]any{img, 1.0, 0.0, "bilinear"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _, _, _ := nearest.(*LinearImage).LinearAt(1, 0)
+	b, _, _, _ := bilinear.(*LinearImage).LinearAt(1, 0)
+	if n != 0 || b == 0 {
+		t.Errorf("expected the filter to be used, got %v with nearest and %v with bilinear", n, b)
+	}
+	if _, err := linearOps["scale"]([]any{img, 1.0, 0.0, "foo"}); err == nil {
+		t.Error("expected an error for an unknown filter")
+	}
+}
+
+func TestResizeMaxMPUsesMegapixels(t *testing.T) {
+	src := testImage(40, 30)
+	for _, script := range []string{
+		"resize-max-mp($1 0.0003)",
+		"to-srgb(resize-max-mp(to-linear($1) 0.0003))",
+	} {
+		img := runImage(t, script, src)
+		if n := img.Rect.Dx() * img.Rect.Dy(); n > 300 || n < 250 {
+			t.Errorf("%q: expected at most 300 pixels, got %v", script, img.Rect)
+		}
+	}
+	if img := runImage(t, "resize-max-mp($1 1)", src); img.Rect != src.Rect {
+		t.Errorf("expected an image below the limit to keep its size, got %v", img.Rect)
+	}
+}
@@ -0,0 +1,270 @@
+package language
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ljpegHuffman is a Huffman table of a lossless JPEG, decoded canonically
+// by code length.
+type ljpegHuffman struct {
+	maxCode [18]int32 // largest code of each length, -1 if there is none
+	valPtr  [17]int32 // index of the first value of each length
+	minCode [17]int32
+	values  []uint8
+}
+
+func newLJPEGHuffman(counts []uint8, values []uint8) *ljpegHuffman {
+	h := &ljpegHuffman{values: values}
+	code, k := int32(0), int32(0)
+	for l := 1; l <= 16; l++ {
+		n := int32(counts[l-1])
+		h.valPtr[l] = k
+		h.minCode[l] = code
+		code += n
+		k += n
+		if n > 0 {
+			h.maxCode[l] = code - 1
+		} else {
+			h.maxCode[l] = -1
+		}
+		code <<= 1
+	}
+	h.maxCode[17] = 0x7FFFFFFF
+	return h
+}
+
+// ljpegBits reads the entropy-coded segment of a JPEG bit by bit, removing
+// stuffed zero bytes. Markers end the bit stream, missing bits are zero.
+type ljpegBits struct {
+	data   []byte
+	pos    int
+	acc    uint32
+	n      uint
+	marker bool
+}
+
+func (b *ljpegBits) fill() {
+	for b.n <= 24 {
+		var c byte
+		if !b.marker && b.pos < len(b.data) {
+			c = b.data[b.pos]
+			if c == 0xFF {
+				if b.pos+1 < len(b.data) && b.data[b.pos+1] == 0x00 {
+					b.pos += 2
+				} else {
+					b.marker = true
+					c = 0
+				}
+			} else {
+				b.pos++
+			}
+		}
+		b.acc |= uint32(c) << (24 - b.n)
+		b.n += 8
+	}
+}
+
+func (b *ljpegBits) bits(n uint) int32 {
+	if n == 0 {
+		return 0
+	}
+	b.fill()
+	v := b.acc >> (32 - n)
+	b.acc <<= n
+	b.n -= n
+	return int32(v)
+}
+
+func (b *ljpegBits) decode(h *ljpegHuffman) (int, error) {
+	code := b.bits(1)
+	l := 1
+	for ; l <= 16 && code > h.maxCode[l]; l++ {
+		code = code<<1 | b.bits(1)
+	}
+	if l > 16 {
+		return 0, fmt.Errorf("invalid Huffman code")
+	}
+	return int(h.values[h.valPtr[l]+code-h.minCode[l]]), nil
+}
+
+// restart skips to the data after the next restart marker.
+func (b *ljpegBits) restart() {
+	for b.pos+1 < len(b.data) && !(b.data[b.pos] == 0xFF && b.data[b.pos+1] >= 0xD0 && b.data[b.pos+1] <= 0xD7) {
+		b.pos++
+	}
+	b.pos += 2
+	b.acc, b.n, b.marker = 0, 0, false
+}
+
+// decodeLosslessJPEG decodes a lossless (SOF3) JPEG as used by DNG files.
+// It returns the samples in raster order with the components of each
+// pixel interleaved, along with the width, height and number of components.
+func decodeLosslessJPEG(data []byte) (samples []uint16, width, height, components int, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, 0, 0, fmt.Errorf("not a JPEG stream")
+	}
+	var (
+		precision  int
+		compIDs    []int
+		tables     [4]*ljpegHuffman
+		scanTables []*ljpegHuffman
+		predictor  int
+		transform  uint
+		restarts   int
+	)
+	pos := 2
+	for {
+		for pos < len(data) && data[pos] != 0xFF {
+			pos++
+		}
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos+2 >= len(data) {
+			return nil, 0, 0, 0, fmt.Errorf("missing scan in lossless JPEG")
+		}
+		marker := data[pos]
+		length := int(binary.BigEndian.Uint16(data[pos+1:]))
+		end := pos + 1 + length
+		if end > len(data) || length < 2 {
+			return nil, 0, 0, 0, fmt.Errorf("truncated JPEG segment")
+		}
+		seg := data[pos+3 : end]
+		pos = end
+		switch marker {
+		case 0xC3: // SOF3
+			if len(seg) < 6 {
+				return nil, 0, 0, 0, fmt.Errorf("invalid SOF3 segment")
+			}
+			precision = int(seg[0])
+			height = int(binary.BigEndian.Uint16(seg[1:]))
+			width = int(binary.BigEndian.Uint16(seg[3:]))
+			components = int(seg[5])
+			for i := range components {
+				if 6+3*i+2 >= len(seg) {
+					return nil, 0, 0, 0, fmt.Errorf("invalid SOF3 segment")
+				}
+				compIDs = append(compIDs, int(seg[6+3*i]))
+			}
+		case 0xC0, 0xC1, 0xC2, 0xC5, 0xC6, 0xC7, 0xC9, 0xCA, 0xCB, 0xCD, 0xCE, 0xCF:
+			return nil, 0, 0, 0, fmt.Errorf("unsupported JPEG process (only lossless JPEG is supported)")
+		case 0xC4: // DHT
+			for p := 0; p+17 <= len(seg); {
+				id := int(seg[p] & 0x0F)
+				counts := seg[p+1 : p+17]
+				n := 0
+				for _, c := range counts {
+					n += int(c)
+				}
+				if id > 3 || p+17+n > len(seg) {
+					return nil, 0, 0, 0, fmt.Errorf("invalid Huffman table")
+				}
+				tables[id] = newLJPEGHuffman(counts, seg[p+17:p+17+n])
+				p += 17 + n
+			}
+		case 0xDD: // DRI
+			if len(seg) >= 2 {
+				restarts = int(binary.BigEndian.Uint16(seg))
+			}
+		case 0xDA: // SOS
+			if components == 0 || len(seg) < 1+2*components+3 {
+				return nil, 0, 0, 0, fmt.Errorf("invalid scan header")
+			}
+			n := int(seg[0])
+			scanTables = make([]*ljpegHuffman, components)
+			for i := range n {
+				id, table := int(seg[1+2*i]), seg[2+2*i]>>4
+				for c, cid := range compIDs {
+					if cid == id && table < 4 {
+						scanTables[c] = tables[table]
+					}
+				}
+			}
+			for c, t := range scanTables {
+				if t == nil {
+					return nil, 0, 0, 0, fmt.Errorf("missing Huffman table for component %d", c)
+				}
+			}
+			predictor = int(seg[1+2*n])
+			transform = uint(seg[3+2*n] & 0x0F)
+			samples, err = decodeLosslessScan(data[pos:], width, height, components, precision, predictor, transform, restarts, scanTables)
+			return samples, width, height, components, err
+		case 0xD9:
+			return nil, 0, 0, 0, fmt.Errorf("missing scan in lossless JPEG")
+		}
+	}
+}
+
+func decodeLosslessScan(data []byte, width, height, components, precision, predictor int, transform uint, restarts int, tables []*ljpegHuffman) ([]uint16, error) {
+	if predictor < 1 || predictor > 7 {
+		return nil, fmt.Errorf("unsupported lossless JPEG predictor %d", predictor)
+	}
+	stride := width * components
+	res := make([]uint16, stride*height)
+	bits := &ljpegBits{data: data}
+	initial := int32(1) << (precision - int(transform) - 1)
+	mcu, firstRow, firstX := 0, 0, 0 // position of the first pixel after the last restart
+	for y := range height {
+		row := res[y*stride : (y+1)*stride]
+		for x := range width {
+			if restarts > 0 && mcu > 0 && mcu%restarts == 0 {
+				bits.restart()
+				firstRow, firstX = y, x
+			}
+			mcu++
+			for c := range components {
+				ssss, err := bits.decode(tables[c])
+				if err != nil {
+					return nil, err
+				}
+				var diff int32
+				switch {
+				case ssss == 16:
+					diff = 32768
+				case ssss > 0:
+					diff = bits.bits(uint(ssss))
+					if diff < 1<<(ssss-1) {
+						diff -= 1<<ssss - 1
+					}
+				}
+
+				i := x*components + c
+				var pred int32
+				switch {
+				case y == firstRow && x == firstX:
+					pred = initial
+				case y == firstRow && x > firstX:
+					pred = int32(row[i-components])
+				case x == 0:
+					pred = int32(res[(y-1)*stride+i])
+				default:
+					ra, rb, rc := int32(row[i-components]), int32(res[(y-1)*stride+i]), int32(res[(y-1)*stride+i-components])
+					switch predictor {
+					case 1:
+						pred = ra
+					case 2:
+						pred = rb
+					case 3:
+						pred = rc
+					case 4:
+						pred = ra + rb - rc
+					case 5:
+						pred = ra + (rb-rc)>>1
+					case 6:
+						pred = rb + (ra-rc)>>1
+					case 7:
+						pred = (ra + rb) >> 1
+					}
+				}
+				row[i] = uint16(pred + diff)
+			}
+		}
+	}
+	if transform > 0 {
+		for i, v := range res {
+			res[i] = v << transform
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,499 @@
+package language
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"weak"
+)
+
+// Metadata holds the non-pixel data of an image: EXIF tags,
+// the XMP packet and the ICC color profile.
+type Metadata struct {
+	EXIF []byte // EXIF block starting with the TIFF header (without "Exif\0\0")
+	XMP  []byte // XMP packet (XML)
+	ICC  []byte // ICC profile
+}
+
+func (m *Metadata) Empty() bool {
+	return m == nil || (len(m.EXIF) == 0 && len(m.XMP) == 0 && len(m.ICC) == 0)
+}
+
+func (m *Metadata) Clone() *Metadata {
+	if m == nil {
+		return &Metadata{}
+	}
+	return &Metadata{
+		EXIF: bytes.Clone(m.EXIF),
+		XMP:  bytes.Clone(m.XMP),
+		ICC:  bytes.Clone(m.ICC),
+	}
+}
+
+// metadataRegistry maps images to their metadata. Images are immutable
+// values in scripts, so the pointer identifies an image throughout a run.
+// Images are referenced weakly, entries are removed once their image has
+// been garbage collected. Each run has its own registry.
+type metadataRegistry struct {
+	mu   sync.Mutex
+	data map[any]*Metadata
+}
+
+func newMetadataRegistry() *metadataRegistry {
+	return &metadataRegistry{data: map[any]*Metadata{}}
+}
+
+// resultMetadata holds the metadata of the images returned by Language.Run.
+var resultMetadata = newMetadataRegistry()
+
+func (r *metadataRegistry) remove(key any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.data, key)
+}
+
+func (r *metadataRegistry) get(img any) (*Metadata, bool) {
+	key := metadataKey(img)
+	if key == nil {
+		return nil, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.data[key]
+	return m, ok
+}
+
+func (r *metadataRegistry) set(img any, m *Metadata) {
+	switch t := img.(type) {
+	case *image.NRGBA64:
+		setMetadata(r, t, m)
+	case *image.RGBA64:
+		setMetadata(r, t, m)
+	case *image.NRGBA:
+		setMetadata(r, t, m)
+	case *image.RGBA:
+		setMetadata(r, t, m)
+	case *LinearImage:
+		setMetadata(r, t, m)
+	}
+}
+
+func setMetadata[T any](r *metadataRegistry, img *T, m *Metadata) {
+	if img == nil {
+		return
+	}
+	key := weak.Make(img)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.data[key]; !ok {
+		runtime.AddCleanup(img, r.remove, any(key))
+	}
+	r.data[key] = m
+}
+
+// metadataKey returns the registry key of img or nil if img is not an image.
+func metadataKey(img any) any {
+	switch t := img.(type) {
+	case *image.NRGBA64:
+		return weak.Make(t)
+	case *image.RGBA64:
+		return weak.Make(t)
+	case *image.NRGBA:
+		return weak.Make(t)
+	case *image.RGBA:
+		return weak.Make(t)
+	case *LinearImage:
+		return weak.Make(t)
+	}
+	return nil
+}
+
+// MetadataOf returns the metadata of an image returned by Language.Run
+// or nil if there is none.
+func MetadataOf(img image.Image) *Metadata {
+	m, _ := resultMetadata.get(img)
+	return m
+}
+
+// inherit attaches the metadata of the first source image that has
+// metadata to res, unless res already has metadata of its own (which may be
+// explicitly empty, e.g. after strip-metadata). Masks and alignments pass on
+// the metadata of the image they were created from. It returns res.
+func (r *metadataRegistry) inherit(res any, sources ...any) any {
+	if metadataKey(res) == nil {
+		return res
+	}
+	if _, ok := r.get(res); ok {
+		return res
+	}
+	for _, src := range sources {
+		switch t := src.(type) {
+		case *Mask:
+			src = t.Source
+		case *Alignment:
+			src = t.Image
+		}
+		if m, ok := r.get(src); ok {
+			r.set(res, m)
+			break
+		}
+	}
+	return res
+}
+
+// shallowCopyImage returns a new image header sharing the pixels of img,
+// so metadata can be changed without affecting other references to img.
+func shallowCopyImage(img image.Image) image.Image {
+	switch t := img.(type) {
+	case *image.NRGBA64:
+		c := *t
+		return &c
+	case *image.RGBA64:
+		c := *t
+		return &c
+	case *image.NRGBA:
+		c := *t
+		return &c
+	case *image.RGBA:
+		c := *t
+		return &c
+	case *LinearImage:
+		c := *t
+		return &c
+	}
+	return img
+}
+
+const (
+	jpegEXIFPrefix = "Exif\x00\x00"
+	jpegXMPPrefix  = "http://ns.adobe.com/xap/1.0/\x00"
+	jpegICCPrefix  = "ICC_PROFILE\x00"
+	pngXMPKeyword  = "XML:com.adobe.xmp"
+)
+
+// ReadMetadata extracts EXIF, XMP and ICC data from an encoded JPEG, PNG,
+// WebP or TIFF image. Other formats return empty metadata.
+func ReadMetadata(data []byte) *Metadata {
+	m := &Metadata{}
+	switch DetectImageType(data) {
+	case "jpeg":
+		readJPEGMetadata(data, m)
+	case "png":
+		readPNGMetadata(data, m)
+	case "webp":
+		readWebPMetadata(data, m)
+	case "tiff", "dng":
+		readTIFFMetadata(data, m)
+	case "heic", "avif":
+		m.ICC = heifColorProfile(data)
+	}
+	return m
+}
+
+// readTIFFMetadata reads the metadata of the first page of a TIFF file.
+// Only known EXIF tags are kept, the tags describing the pixel data of the
+// file don't apply to other files.
+func readTIFFMetadata(data []byte, m *Metadata) {
+	d, err := parseEXIF(data)
+	if err != nil {
+		return
+	}
+	exif := &exifData{order: d.order, ifd: d.ifd}
+	for _, e := range d.entries {
+		switch {
+		case e.tag == 0x8773: // InterColorProfile
+			m.ICC = e.data
+		case e.tag == 0x02BC: // XMP
+			m.XMP = e.data
+		case e.sub != nil:
+			exif.entries = append(exif.entries, e)
+		default:
+			if _, ok := exifTagByID(d.ifd, e.tag); ok {
+				exif.entries = append(exif.entries, e)
+			}
+		}
+	}
+	if len(exif.entries) > 0 {
+		m.EXIF = exif.bytes()
+	}
+}
+
+func readJPEGMetadata(data []byte, m *Metadata) {
+	var icc [][]byte
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			break
+		}
+		marker := data[pos+1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			pos++
+			if marker != 0xFF {
+				pos++
+			}
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // start of scan, image data follows
+			break
+		}
+		n := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + n
+		if n < 2 || end > len(data) {
+			break
+		}
+		seg := data[pos+4 : end]
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(seg, []byte(jpegEXIFPrefix)):
+			m.EXIF = bytes.Clone(seg[len(jpegEXIFPrefix):])
+		case marker == 0xE1 && bytes.HasPrefix(seg, []byte(jpegXMPPrefix)):
+			m.XMP = bytes.Clone(seg[len(jpegXMPPrefix):])
+		case marker == 0xE2 && bytes.HasPrefix(seg, []byte(jpegICCPrefix)) && len(seg) > len(jpegICCPrefix)+2:
+			// ICC profiles can span multiple segments: sequence number (1-based) and count
+			seq, count := int(seg[len(jpegICCPrefix)]), int(seg[len(jpegICCPrefix)+1])
+			if icc == nil && count > 0 {
+				icc = make([][]byte, count)
+			}
+			if seq >= 1 && seq <= len(icc) {
+				icc[seq-1] = seg[len(jpegICCPrefix)+2:]
+			}
+		}
+		pos = end
+	}
+	if icc != nil {
+		m.ICC = bytes.Join(icc, nil)
+	}
+}
+
+func readPNGMetadata(data []byte, m *Metadata) {
+	chunks, err := pngChunks(data)
+	if err != nil {
+		return
+	}
+	for _, c := range chunks {
+		body := c[8 : len(c)-4]
+		switch string(c[4:8]) {
+		case "eXIf":
+			m.EXIF = bytes.Clone(body)
+		case "iCCP":
+			// profile name, null separator, compression method, zlib data
+			if i := bytes.IndexByte(body, 0); i >= 0 && i+2 <= len(body) {
+				if r, err := zlib.NewReader(bytes.NewReader(body[i+2:])); err == nil {
+					if icc, err := io.ReadAll(r); err == nil {
+						m.ICC = icc
+					}
+				}
+			}
+		case "iTXt":
+			// keyword, null, compression flag, compression method, language, null, translated keyword, null, text
+			if !bytes.HasPrefix(body, []byte(pngXMPKeyword+"\x00")) {
+				continue
+			}
+			rest := body[len(pngXMPKeyword)+1:]
+			if len(rest) < 2 {
+				continue
+			}
+			compressed := rest[0] == 1
+			parts := bytes.SplitN(rest[2:], []byte{0}, 3)
+			if len(parts) != 3 {
+				continue
+			}
+			text := parts[2]
+			if compressed {
+				r, err := zlib.NewReader(bytes.NewReader(text))
+				if err != nil {
+					continue
+				}
+				if text, err = io.ReadAll(r); err != nil {
+					continue
+				}
+			}
+			m.XMP = bytes.Clone(text)
+		}
+	}
+}
+
+func readWebPMetadata(data []byte, m *Metadata) {
+	for pos := 12; pos+8 <= len(data); {
+		n := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + n
+		if n < 0 || end > len(data) {
+			break
+		}
+		body := data[pos+8 : end]
+		switch string(data[pos : pos+4]) {
+		case "EXIF":
+			m.EXIF = bytes.Clone(bytes.TrimPrefix(body, []byte(jpegEXIFPrefix)))
+		case "XMP ":
+			m.XMP = bytes.Clone(body)
+		case "ICCP":
+			m.ICC = bytes.Clone(body)
+		}
+		pos = end + n%2
+	}
+}
+
+// embedMetadata inserts the metadata into an encoded JPEG or PNG image.
+// Other formats are returned unchanged.
+func embedMetadata(data []byte, format string, m *Metadata) ([]byte, error) {
+	if m.Empty() {
+		return data, nil
+	}
+	switch format {
+	case FormatJPEG:
+		return embedJPEGMetadata(data, m)
+	case FormatPNG:
+		return embedPNGMetadata(data, m)
+	}
+	return data, nil
+}
+
+func embedJPEGMetadata(data []byte, m *Metadata) ([]byte, error) {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("invalid JPEG data")
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:2])
+	segment := func(marker byte, parts ...[]byte) error {
+		n := 2
+		for _, p := range parts {
+			n += len(p)
+		}
+		if n > 0xFFFF {
+			return fmt.Errorf("metadata segment too large (%d bytes)", n)
+		}
+		buf.Write([]byte{0xFF, marker, byte(n >> 8), byte(n)})
+		for _, p := range parts {
+			buf.Write(p)
+		}
+		return nil
+	}
+
+	// Keep the JFIF header (APP0) in front of the metadata
+	rest := data[2:]
+	if len(rest) > 4 && rest[0] == 0xFF && rest[1] == 0xE0 {
+		n := int(binary.BigEndian.Uint16(rest[2:]))
+		buf.Write(rest[:2+n])
+		rest = rest[2+n:]
+	}
+	if len(m.EXIF) > 0 {
+		if err := segment(0xE1, []byte(jpegEXIFPrefix), m.EXIF); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.XMP) > 0 {
+		if err := segment(0xE1, []byte(jpegXMPPrefix), m.XMP); err != nil {
+			return nil, err
+		}
+	}
+	if len(m.ICC) > 0 {
+		const chunkSize = 0xFFFF - 2 - len(jpegICCPrefix) - 2
+		count := (len(m.ICC) + chunkSize - 1) / chunkSize
+		if count > 255 {
+			return nil, fmt.Errorf("ICC profile too large (%d bytes)", len(m.ICC))
+		}
+		for i := range count {
+			chunk := m.ICC[i*chunkSize:]
+			if len(chunk) > chunkSize {
+				chunk = chunk[:chunkSize]
+			}
+			if err := segment(0xE2, []byte(jpegICCPrefix), []byte{byte(i + 1), byte(count)}, chunk); err != nil {
+				return nil, err
+			}
+		}
+	}
+	buf.Write(rest)
+	return buf.Bytes(), nil
+}
+
+func embedPNGMetadata(data []byte, m *Metadata) ([]byte, error) {
+	chunks, err := pngChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	for _, c := range chunks {
+		buf.Write(c)
+		if string(c[4:8]) != "IHDR" {
+			continue
+		}
+		// iCCP must precede PLTE and IDAT, so all metadata goes right after the header
+		if len(m.ICC) > 0 {
+			var z bytes.Buffer
+			zw := zlib.NewWriter(&z)
+			zw.Write(m.ICC)
+			zw.Close()
+			writePNGChunk(&buf, "iCCP", append([]byte("ICC Profile\x00\x00"), z.Bytes()...))
+		}
+		if len(m.EXIF) > 0 {
+			writePNGChunk(&buf, "eXIf", m.EXIF)
+		}
+		if len(m.XMP) > 0 {
+			writePNGChunk(&buf, "iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), m.XMP...))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// exifTags returns the EXIF tags of m, an empty map if there are none.
+func (m *Metadata) exifTags() map[string]any {
+	if m == nil || len(m.EXIF) == 0 {
+		return map[string]any{}
+	}
+	d, err := parseEXIF(m.EXIF)
+	if err != nil {
+		return map[string]any{}
+	}
+	return d.tags()
+}
+
+// orientation returns the EXIF orientation (1..8), 1 if there is none.
+func (m *Metadata) orientation() int {
+	if m == nil || len(m.EXIF) == 0 {
+		return 1
+	}
+	d, err := parseEXIF(m.EXIF)
+	if err != nil {
+		return 1
+	}
+	if e := d.get(exifIFD0, 0x0112); e != nil && len(e.data) >= 2 {
+		return int(d.order.Uint16(e.data))
+	}
+	return 1
+}
+
+// withTag returns a copy of m with the EXIF tag set to value.
+func (m *Metadata) withTag(name, value string) (*Metadata, error) {
+	res := m.Clone()
+	d := &exifData{order: binary.BigEndian, ifd: exifIFD0}
+	if len(res.EXIF) > 0 {
+		var err error
+		if d, err = parseEXIF(res.EXIF); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.setTag(name, value); err != nil {
+		return nil, err
+	}
+	res.EXIF = d.bytes()
+	return res, nil
+}
+
+func (m *Metadata) String() string {
+	var parts []string
+	if len(m.EXIF) > 0 {
+		parts = append(parts, fmt.Sprintf("EXIF %d bytes", len(m.EXIF)))
+	}
+	if len(m.XMP) > 0 {
+		parts = append(parts, fmt.Sprintf("XMP %d bytes", len(m.XMP)))
+	}
+	if len(m.ICC) > 0 {
+		parts = append(parts, fmt.Sprintf("ICC %d bytes", len(m.ICC)))
+	}
+	return "Metadata(" + strings.Join(parts, ", ") + ")"
+}
@@ -0,0 +1,202 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+)
+
+// fuse replaces a chain of two or more directly nested per-pixel operations
+// ending in node, e.g. `contrast(brightness(invert(img) 1.2) 1.1)`, with a
+// single call that is evaluated in one parallel pass with one allocation.
+// The fused call takes the source image followed by the arguments of all
+// operations, passed by name, so each operation gets its own defaults.
+// It reports whether node was replaced.
+func (r *scriptRun) fuse(node *dslNode) bool {
+	chain := fusableChain(node)
+	if len(chain) < 2 {
+		return false
+	}
+	source := chain[0].children[0]
+	children := []*dslNode{source}
+	fns := make([]*dslFnType, len(chain))
+	for k, call := range chain {
+		fns[k] = dsl.funcs.get(call.data)
+		args, ok := stageArgs(k, fns[k], call.children[1:])
+		if !ok {
+			return false
+		}
+		children = append(children, args...)
+	}
+	node.data = r.fusedAlias(fns)
+	node.children = children
+	for _, child := range children {
+		r.bindNode(child)
+	}
+	return true
+}
+
+// fusableChain returns the chain of directly nested per-pixel operations
+// ending in node, innermost first.
+func fusableChain(node *dslNode) []*dslNode {
+	var chain []*dslNode
+	for isFusable(node) {
+		chain = append([]*dslNode{node}, chain...)
+		node = node.children[0]
+	}
+	return chain
+}
+
+// isFusable reports whether node is a call of a per-pixel operation
+// that receives its image as first positional argument.
+func isFusable(node *dslNode) bool {
+	if node == nil || node.kind != nodes.call || len(node.children) == 0 || node.children[0].named {
+		return false
+	}
+	if _, ok := pixelKernels[node.data]; !ok {
+		return false
+	}
+	return dsl.funcs.get(node.data) != nil
+}
+
+// stageParam returns the name of parameter name of stage k in a fused call.
+func stageParam(k int, name string) string {
+	return fmt.Sprintf("%d:%s", k, name)
+}
+
+// stageArgs returns the arguments of stage k of a fused chain (without the
+// image) as named arguments of the fused call. It reports false if they
+// don't match the parameters of fn, the chain is then not fused, so the
+// call fails with the usual error.
+func stageArgs(k int, fn *dslFnType, args []*dslNode) ([]*dslNode, bool) {
+	params := fn.meta.params[1:]
+	res := make([]*dslNode, 0, len(args))
+	named := false
+	for i, arg := range args {
+		if arg.named {
+			named = true
+			if !slices.ContainsFunc(params, func(p dslParamMeta) bool { return p.name == arg.argName }) {
+				return nil, false
+			}
+			c := *arg
+			c.argName = stageParam(k, arg.argName)
+			res = append(res, &c)
+			continue
+		}
+		if named || i >= len(params) {
+			return nil, false
+		}
+		res = append(res, &dslNode{
+			kind:     nodes.arg,
+			children: []*dslNode{arg},
+			named:    true,
+			argName:  stageParam(k, params[i].name),
+			Line:     arg.Line,
+			Column:   arg.Column,
+		})
+	}
+	return res, true
+}
+
+// fusedAlias returns the alias of the fused call of fns.
+func (r *scriptRun) fusedAlias(fns []*dslFnType) string {
+	names := make([]string, len(fns))
+	params := []dslParamMeta{fns[0].meta.params[0]}
+	for k, fn := range fns {
+		names[k] = fn.meta.name
+		for _, p := range fn.meta.params[1:] {
+			p.name = stageParam(k, p.name)
+			params = append(params, p)
+		}
+	}
+	desc := strings.Join(names, " > ")
+	return r.register("fused "+desc, desc, params, fns[len(fns)-1].meta.returns, func(args ...any) (any, error) {
+		return r.callFused(fns, args)
+	})
+}
+
+// callFused evaluates a fused call. args are the source image followed by
+// the arguments of each operation, which are cast and validated like the
+// arguments of a regular call.
+func (r *scriptRun) callFused(fns []*dslFnType, args []any) (any, error) {
+	src := args[0]
+	if str, ok := src.(string); ok && r.dsl.vars.has(str) {
+		if v := r.dsl.vars.get(str); v != nil {
+			src = v.get()
+		}
+	}
+	stages := make([][]any, len(fns))
+	for k, pos := 0, 1; k < len(fns); k++ {
+		n := len(fns[k].meta.params) - 1
+		stages[k] = args[pos : pos+n]
+		pos += n
+	}
+	if lin, ok := src.(*LinearImage); ok {
+		res, err := r.callStagesLinear(lin, fns, stages)
+		return r.metadata.inherit(res, src), err
+	}
+	img, ok := src.(*image.NRGBA64)
+	if !ok {
+		converted, err := dsl.castValue(src, "*image.NRGBA64")
+		if err != nil {
+			return nil, err
+		}
+		img = converted.(*image.NRGBA64)
+	}
+
+	kernels := make([]dslPixelProcessor, len(fns))
+	for k, fn := range fns {
+		args, err := r.stageArgs(fn, img, stages[k])
+		if err != nil {
+			return nil, err
+		}
+		kernels[k] = pixelKernels[fn.meta.name](args[1:])
+	}
+	return r.metadata.inherit(dsl.parallelProcessNRGBA64(img, fuseKernels(kernels), NumColorConversionWorkers), src), nil
+}
+
+// stageArgs returns the image followed by the cast and validated
+// arguments of a fused operation.
+func (r *scriptRun) stageArgs(fn *dslFnType, img any, args []any) ([]any, error) {
+	res := make([]any, 1, len(args)+1)
+	res[0] = img
+	for i, arg := range args {
+		v, err := r.castArg(arg, fn.meta.params[i+1].typ)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	if err := fn.validate(res...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// callStagesLinear evaluates the fused operations one after another on a
+// linear-light image. Operations without a linear-light implementation are
+// applied to the sRGB-encoded image and decoded again.
+func (r *scriptRun) callStagesLinear(img *LinearImage, fns []*dslFnType, stages [][]any) (any, error) {
+	for k, fn := range fns {
+		if op, ok := linearOps[fn.meta.name]; ok {
+			args, err := r.stageArgs(fn, img, stages[k])
+			if err != nil {
+				return nil, err
+			}
+			res, err := op(args)
+			if err != nil {
+				return nil, err
+			}
+			img = res.(*LinearImage)
+			continue
+		}
+		srgb := fromLinearImage(img)
+		args, err := r.stageArgs(fn, srgb, stages[k])
+		if err != nil {
+			return nil, err
+		}
+		img = toLinearImage(dsl.parallelProcessNRGBA64(srgb, pixelKernels[fn.meta.name](args[1:]), NumColorConversionWorkers))
+	}
+	return img, nil
+}
@@ -0,0 +1,97 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testImage returns a gradient with all channels in use and partial alpha,
+// w and h have to be at least 2.
+func testImage(w, h int) *image.NRGBA64 {
+	img := image.NewNRGBA64(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(x * 0xFFFF / (w - 1)),
+				G: uint16(y * 0xFFFF / (h - 1)),
+				B: uint16((x + y) * 0x7FFF / (w + h - 2)),
+				A: uint16(0xFFFF - x*0x3FFF/(w-1)),
+			})
+		}
+	}
+	return img
+}
+
+// runImage runs script with the arguments and returns the resulting image.
+func runImage(t *testing.T, script string, args ...any) *image.NRGBA {
+	t.Helper()
+	res, err := New().Run(script, "", nil, args...)
+	if err != nil {
+		t.Fatalf("%q: %v", script, err)
+	}
+	img, ok := res.Value().(*image.NRGBA)
+	if !ok {
+		t.Fatalf("%q: expected an image, got %T", script, res.Value())
+	}
+	return img
+}
+
+func assertSamePixels(t *testing.T, name string, a, b *image.NRGBA) {
+	t.Helper()
+	if a.Rect != b.Rect {
+		t.Fatalf("%s: bounds differ: %v vs %v", name, a.Rect, b.Rect)
+	}
+	for i := range a.Pix {
+		if a.Pix[i] != b.Pix[i] {
+			t.Fatalf("%s: pixels differ at byte %d: %d vs %d", name, i, a.Pix[i], b.Pix[i])
+		}
+	}
+}
+
+func TestFusedChainsMatchUnfused(t *testing.T) {
+	src := testImage(64, 48)
+	ops := map[string]string{
+		"invert":        "",
+		"grayscale":     "",
+		"sepia":         "",
+		"brightness":    " 1.2",
+		"colorize":      " rgba(200 100 50 0.5)",
+		"contrast":      " 1.4",
+		"saturation":    " 0.6",
+		"opacity":       " 0.7",
+		"hue-rotate":    " 90",
+		"color-balance": " 1.1 0.9 1.2",
+		"posterize":     " 5",
+		"threshold":     " 0.4",
+		"vibrance":      " 0.5",
+		"exposure":      " 0.5",
+	}
+	for op, args := range ops {
+		fused := fmt.Sprintf("sepia(%s(invert($1)%s))", op, args)
+		unfused := fmt.Sprintf("a: invert($1)\nb: %s(a%s)\nsepia(b)", op, args)
+		assertSamePixels(t, op, runImage(t, fused, src), runImage(t, unfused, src))
+	}
+
+	for _, c := range []struct{ name, fused, unfused string }{
+		{"named", "contrast(brightness(invert($1) factor=1.2) 1.1)", "a: invert($1)\nb: brightness(a 1.2)\ncontrast(b 1.1)"},
+		{"defaults", "posterize(invert($1))", "a: invert($1)\nposterize(a)"},
+		{"linear", "to-srgb(contrast(brightness(to-linear($1) 1.2) 1.1))", "a: to-linear($1)\nb: brightness(a 1.2)\nc: contrast(b 1.1)\nto-srgb(c)"},
+		{"mixed linear", "to-srgb(sepia(exposure(to-linear($1) 0.5)))", "a: to-linear($1)\nb: exposure(a 0.5)\nc: sepia(b)\nto-srgb(c)"},
+	} {
+		assertSamePixels(t, c.name, runImage(t, c.fused, src), runImage(t, c.unfused, src))
+	}
+}
+
+func TestFusedChainsValidateArguments(t *testing.T) {
+	for _, script := range []string{
+		"posterize(invert($1) 1)",
+		"brightness(invert($1) foo=2)",
+		"brightness(invert($1) 1 2)",
+	} {
+		if _, err := New().Run(script, "", nil, testImage(8, 8)); err == nil {
+			t.Errorf("%q: expected an error", script)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package language
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/toxyl/math"
+	xdraw "golang.org/x/image/draw"
+)
+
+const oraMimeType = "image/openraster"
+
+// oraCompositeOps maps the composite operations of OpenRaster to blend modes.
+// Blend modes without an equivalent are written as "pxp:<mode>".
+var oraCompositeOps = map[string]string{
+	"svg:src-over":    NORMAL,
+	"svg:multiply":    MULTIPLY,
+	"svg:screen":      SCREEN,
+	"svg:overlay":     OVERLAY,
+	"svg:darken":      DARKEN,
+	"svg:lighten":     LIGHTEN,
+	"svg:color-dodge": COLOR_DODGE,
+	"svg:color-burn":  COLOR_BURN,
+	"svg:hard-light":  HARD_LIGHT,
+	"svg:soft-light":  SOFT_LIGHT,
+	"svg:difference":  DIFFERENCE,
+	"svg:exclusion":   EXCLUSION,
+	"svg:hue":         HUE,
+	"svg:saturation":  SATURATION,
+	"svg:color":       COLOR,
+	"svg:luminosity":  LUMINOSITY,
+	"svg:dst-out":     ERASE,
+}
+
+func oraBlendMode(op string) string {
+	if mode, ok := oraCompositeOps[op]; ok {
+		return mode
+	}
+	if mode, ok := strings.CutPrefix(op, "pxp:"); ok && blenders.get(mode) != nil {
+		return mode
+	}
+	return NORMAL
+}
+
+func oraCompositeOp(mode string) string {
+	for op, m := range oraCompositeOps {
+		if m == mode {
+			return op
+		}
+	}
+	return "pxp:" + mode
+}
+
+// isORA reports whether data is an OpenRaster document.
+func isORA(data []byte) bool {
+	return len(data) > 54 && bytes.Equal(data[:4], []byte("PK\x03\x04")) && bytes.Equal(data[30:38], []byte("mimetype")) && bytes.Equal(data[38:54], []byte(oraMimeType))
+}
+
+type oraImage struct {
+	XMLName xml.Name `xml:"image"`
+	W       int      `xml:"w,attr"`
+	H       int      `xml:"h,attr"`
+	Version string   `xml:"version,attr,omitempty"`
+	Stack   oraStack `xml:"stack"`
+}
+
+type oraStack struct {
+	Name       string       `xml:"name,attr,omitempty"`
+	X          int          `xml:"x,attr,omitempty"`
+	Y          int          `xml:"y,attr,omitempty"`
+	Visibility string       `xml:"visibility,attr,omitempty"`
+	Items      []oraElement `xml:",any"`
+}
+
+// oraElement is a layer or a nested stack.
+type oraElement struct {
+	XMLName     xml.Name
+	Src         string       `xml:"src,attr,omitempty"`
+	Name        string       `xml:"name,attr,omitempty"`
+	X           int          `xml:"x,attr"`
+	Y           int          `xml:"y,attr"`
+	Opacity     string       `xml:"opacity,attr,omitempty"`
+	Visibility  string       `xml:"visibility,attr,omitempty"`
+	CompositeOp string       `xml:"composite-op,attr,omitempty"`
+	Items       []oraElement `xml:",any"`
+}
+
+// DecodeORA decodes the layers of an OpenRaster document, bottom layer first.
+// Nested stacks are flattened into the list, their offset and visibility
+// apply to the layers they contain.
+func DecodeORA(data []byte) ([]*Layer, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ORA document: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	read := func(name string) ([]byte, error) {
+		f, ok := files[path.Clean(name)]
+		if !ok {
+			return nil, fmt.Errorf("missing file in ORA document: %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	stackXML, err := read("stack.xml")
+	if err != nil {
+		return nil, err
+	}
+	var doc oraImage
+	if err := xml.Unmarshal(stackXML, &doc); err != nil {
+		return nil, fmt.Errorf("invalid ORA stack: %w", err)
+	}
+
+	var layers []*Layer
+	var walk func(items []oraElement, dx, dy int, visible bool) error
+	walk = func(items []oraElement, dx, dy int, visible bool) error {
+		// Stacks list the topmost element first
+		for i := len(items) - 1; i >= 0; i-- {
+			e := items[i]
+			v := visible && e.Visibility != "hidden"
+			switch e.XMLName.Local {
+			case "stack":
+				if err := walk(e.Items, dx+e.X, dy+e.Y, v); err != nil {
+					return err
+				}
+			case "layer":
+				raw, err := read(e.Src)
+				if err != nil {
+					return err
+				}
+				img, err := png.Decode(bytes.NewReader(raw))
+				if err != nil {
+					return fmt.Errorf("failed to decode ORA layer %s: %w", e.Src, err)
+				}
+				l := NewLayer(imageToNRGBA64(img))
+				l.Name = e.Name
+				l.X, l.Y = dx+e.X, dy+e.Y
+				l.BlendMode = oraBlendMode(e.CompositeOp)
+				l.Visible = v
+				if e.Opacity != "" {
+					if o, err := strconv.ParseFloat(e.Opacity, 64); err == nil {
+						l.Opacity = o
+					}
+				}
+				layers = append(layers, l)
+			}
+		}
+		return nil
+	}
+	if err := walk(doc.Stack.Items, 0, 0, doc.Stack.Visibility != "hidden"); err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// EncodeORA writes layers (bottom layer first) as OpenRaster document.
+// The canvas covers all layers, masks are applied to the alpha of their layer.
+// The merged image and the thumbnail are created with flatten.
+func EncodeORA(w io.Writer, layers []*Layer) error {
+	if len(layers) == 0 {
+		return fmt.Errorf("no layers to save")
+	}
+	var canvas image.Rectangle
+	stack := make([]any, len(layers))
+	for i, l := range layers {
+		canvas = canvas.Union(image.Rect(0, 0, math.Max(0, l.Bounds().Max.X), math.Max(0, l.Bounds().Max.Y)))
+		stack[i] = l
+	}
+	doc := oraImage{W: canvas.Dx(), H: canvas.Dy(), Version: "0.0.6"}
+
+	zw := zip.NewWriter(w)
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mt, oraMimeType); err != nil {
+		return err
+	}
+	writePNG := func(name string, img image.Image) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store}) // PNG data is already compressed
+		if err != nil {
+			return err
+		}
+		return png.Encode(f, img)
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		l := layers[i]
+		src := fmt.Sprintf("data/layer%03d.png", i)
+		img := l.Image
+		if l.Mask != nil {
+			m := *l.Mask
+			m.Source = l.Image
+			img = m.Image()
+		}
+		if err := writePNG(src, img); err != nil {
+			return err
+		}
+		visibility := "visible"
+		if !l.Visible {
+			visibility = "hidden"
+		}
+		doc.Stack.Items = append(doc.Stack.Items, oraElement{
+			XMLName:     xml.Name{Local: "layer"},
+			Src:         src,
+			Name:        l.Name,
+			X:           l.X,
+			Y:           l.Y,
+			Opacity:     strconv.FormatFloat(l.Opacity, 'f', 3, 64),
+			Visibility:  visibility,
+			CompositeOp: oraCompositeOp(l.BlendMode),
+		})
+	}
+
+	stackXML, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create("stack.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header+string(stackXML)); err != nil {
+		return err
+	}
+
+	merged, err := flatten(stack)
+	if err != nil {
+		merged = I(canvas.Dx(), canvas.Dy()) // all layers are hidden
+	}
+	if err := writePNG("mergedimage.png", merged); err != nil {
+		return err
+	}
+	tw, th := merged.Rect.Dx(), merged.Rect.Dy()
+	if tw > 256 || th > 256 {
+		s := 256 / float64(math.Max(tw, th))
+		tw, th = math.Max(int(float64(tw)*s), 1), math.Max(int(float64(th)*s), 1)
+	}
+	thumb := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	xdraw.CatmullRom.Scale(thumb, thumb.Rect, merged, merged.Rect, xdraw.Src, nil)
+	if err := writePNG("Thumbnails/thumbnail.png", thumb); err != nil {
+		return err
+	}
+	return zw.Close()
+}
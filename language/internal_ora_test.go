@@ -0,0 +1,77 @@
+package language
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestORARoundTrip(t *testing.T) {
+	base := NewLayer(testImage(6, 4))
+	base.Name = "base"
+
+	top := NewLayer(testImage(3, 3))
+	top.Name = "top"
+	top.X, top.Y = 2, 1
+	top.BlendMode = MULTIPLY
+	top.Opacity = 0.5
+	top.Visible = false
+	top.Mask = NewMask(3, 3)
+	for i := range top.Mask.Values {
+		if i%3 > 0 {
+			top.Mask.Values[i] = 1
+		}
+	}
+
+	custom := NewLayer(testImage(2, 2))
+	custom.Name = "custom"
+	custom.X = 1
+	custom.BlendMode = VIVID_LIGHT
+
+	in := []*Layer{base, top, custom}
+	var buf bytes.Buffer
+	if err := EncodeORA(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	if !isORA(buf.Bytes()) {
+		t.Fatal("expected the document to be detected as ORA")
+	}
+	out, err := DecodeLayers(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("expected %d layers, got %d", len(in), len(out))
+	}
+
+	for i, l := range in {
+		o := out[i]
+		if o.Name != l.Name || o.X != l.X || o.Y != l.Y || o.BlendMode != l.BlendMode || o.Opacity != l.Opacity || o.Visible != l.Visible {
+			t.Errorf("layer %d: expected %s at %d,%d (%s, %.2f, visible %v), got %s at %d,%d (%s, %.2f, visible %v)",
+				i, l.Name, l.X, l.Y, l.BlendMode, l.Opacity, l.Visible, o.Name, o.X, o.Y, o.BlendMode, o.Opacity, o.Visible)
+		}
+		if o.Image.Rect != l.Image.Rect {
+			t.Fatalf("layer %d: expected bounds %v, got %v", i, l.Image.Rect, o.Image.Rect)
+		}
+		for y := range l.Image.Rect.Dy() {
+			for x := range l.Image.Rect.Dx() {
+				want, got := l.Image.NRGBA64At(x, y), o.Image.NRGBA64At(x, y)
+				if l.Mask != nil && l.Mask.At(x, y) == 0 {
+					// Masks are applied to the alpha of their layer
+					if got.A != 0 {
+						t.Errorf("layer %d: expected %d,%d to be masked, got alpha %d", i, x, y, got.A)
+					}
+					continue
+				}
+				if got != want {
+					t.Fatalf("layer %d: expected %v at %d,%d, got %v", i, want, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeORARequiresLayers(t *testing.T) {
+	if err := EncodeORA(&bytes.Buffer{}, nil); err == nil {
+		t.Error("expected an error without layers")
+	}
+}
@@ -0,0 +1,288 @@
+package language
+
+import (
+	"image"
+	"math/bits"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/toxyl/math"
+)
+
+const (
+	orbPatchRadius = 15  // radius of the patch the orientation and descriptor are computed from
+	orbBorder      = 18  // keypoints closer to the border have incomplete patches
+	orbMaxDistance = 64  // the largest Hamming distance of a match (out of 256 bits)
+	orbMatchRatio  = 0.8 // the best match must be this much closer than the second best
+)
+
+// grayImage holds the luminance (0..255) of an image, transparent pixels are black.
+type grayImage struct {
+	w, h int
+	pix  []float32
+}
+
+func toGrayImage(img *image.NRGBA64) *grayImage {
+	b := img.Bounds()
+	g := &grayImage{w: b.Dx(), h: b.Dy(), pix: make([]float32, b.Dx()*b.Dy())}
+	parallelRows(0, g.h, func(y int) {
+		for x := range g.w {
+			c := img.NRGBA64At(b.Min.X+x, b.Min.Y+y)
+			l := (0.2126*float32(c.R) + 0.7152*float32(c.G) + 0.0722*float32(c.B)) * float32(c.A) / 0xffff
+			g.pix[y*g.w+x] = l / 0x101
+		}
+	})
+	return g
+}
+
+func (g *grayImage) at(x, y int) float32 {
+	return g.pix[math.Clamp(y, 0, g.h-1)*g.w+math.Clamp(x, 0, g.w-1)]
+}
+
+// blur returns a copy of g smoothed with a separable Gaussian.
+func (g *grayImage) blur(sigma float64) *grayImage {
+	n := int(math.Ceil(sigma * 2))
+	kernel := make([]float32, 2*n+1)
+	sum := float32(0)
+	for i := -n; i <= n; i++ {
+		kernel[i+n] = float32(math.Exp(-float64(i*i) / (2 * sigma * sigma)))
+		sum += kernel[i+n]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	tmp := &grayImage{w: g.w, h: g.h, pix: make([]float32, len(g.pix))}
+	res := &grayImage{w: g.w, h: g.h, pix: make([]float32, len(g.pix))}
+	parallelRows(0, g.h, func(y int) {
+		for x := range g.w {
+			v := float32(0)
+			for i, k := range kernel {
+				v += k * g.at(x+i-n, y)
+			}
+			tmp.pix[y*g.w+x] = v
+		}
+	})
+	parallelRows(0, g.h, func(y int) {
+		for x := range g.w {
+			v := float32(0)
+			for i, k := range kernel {
+				v += k * tmp.at(x, y+i-n)
+			}
+			res.pix[y*g.w+x] = v
+		}
+	})
+	return res
+}
+
+// keypoint is a detected feature with its ORB descriptor.
+type keypoint struct {
+	X, Y  float64
+	score float64
+	angle float64
+	desc  [4]uint64
+}
+
+// fastCircle lists the 16 pixels on a circle of radius 3 used by FAST.
+var fastCircle = [16][2]int{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// isFASTCorner reports whether at least 9 contiguous pixels on the circle
+// around (x, y) are all brighter or all darker than the center by threshold.
+func (g *grayImage) isFASTCorner(x, y int, threshold float32) bool {
+	p := g.pix[y*g.w+x]
+	var state [16]int8
+	for i, o := range fastCircle {
+		v := g.pix[(y+o[1])*g.w+x+o[0]]
+		switch {
+		case v > p+threshold:
+			state[i] = 1
+		case v < p-threshold:
+			state[i] = -1
+		}
+	}
+	// Walk the circle twice so runs wrapping around are found
+	run, last := 0, int8(0)
+	for i := range 32 {
+		s := state[i%16]
+		if s != 0 && s == last {
+			run++
+		} else if s != 0 {
+			run = 1
+		} else {
+			run = 0
+		}
+		last = s
+		if run >= 9 {
+			return true
+		}
+	}
+	return false
+}
+
+// harrisScore returns the Harris corner response in a 7x7 window around (x, y).
+func (g *grayImage) harrisScore(x, y int) float64 {
+	var sxx, syy, sxy float64
+	for dy := -3; dy <= 3; dy++ {
+		for dx := -3; dx <= 3; dx++ {
+			px, py := x+dx, y+dy
+			ix := float64(g.at(px+1, py-1) + 2*g.at(px+1, py) + g.at(px+1, py+1) - g.at(px-1, py-1) - 2*g.at(px-1, py) - g.at(px-1, py+1))
+			iy := float64(g.at(px-1, py+1) + 2*g.at(px, py+1) + g.at(px+1, py+1) - g.at(px-1, py-1) - 2*g.at(px, py-1) - g.at(px+1, py-1))
+			sxx += ix * ix
+			syy += iy * iy
+			sxy += ix * iy
+		}
+	}
+	return sxx*syy - sxy*sxy - 0.04*(sxx+syy)*(sxx+syy)
+}
+
+// orbPairs are the point pairs compared by the descriptor, drawn once from
+// a Gaussian distribution within the patch radius.
+var orbPairs = func() [256][4]float64 {
+	var pairs [256][4]float64
+	r := rand.New(rand.NewPCG(31, 5))
+	sigma := float64(2*orbPatchRadius+1) / 5
+	point := func() (float64, float64) {
+		for {
+			x, y := math.Round(r.NormFloat64()*sigma), math.Round(r.NormFloat64()*sigma)
+			if x*x+y*y <= orbPatchRadius*orbPatchRadius {
+				return x, y
+			}
+		}
+	}
+	for i := range pairs {
+		pairs[i][0], pairs[i][1] = point()
+		pairs[i][2], pairs[i][3] = point()
+	}
+	return pairs
+}()
+
+// detectORB finds up to maxPoints FAST corners, ranked by their Harris score
+// and spread over the image, and computes their oriented BRIEF descriptors.
+func detectORB(img *image.NRGBA64, maxPoints int) []keypoint {
+	g := toGrayImage(img)
+	if g.w <= 2*orbBorder || g.h <= 2*orbBorder {
+		return nil
+	}
+	const threshold = 20
+
+	// Find the corners and keep the ones stronger than their neighbours
+	scores := make([]float64, g.w*g.h)
+	parallelRows(orbBorder, g.h-orbBorder, func(y int) {
+		for x := orbBorder; x < g.w-orbBorder; x++ {
+			if g.isFASTCorner(x, y, threshold) {
+				scores[y*g.w+x] = g.harrisScore(x, y)
+			}
+		}
+	})
+	var points []keypoint
+	for y := orbBorder; y < g.h-orbBorder; y++ {
+		for x := orbBorder; x < g.w-orbBorder; x++ {
+			s := scores[y*g.w+x]
+			if s <= 0 {
+				continue
+			}
+			isMax := true
+			for dy := -1; dy <= 1 && isMax; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					if (dx != 0 || dy != 0) && scores[(y+dy)*g.w+x+dx] > s {
+						isMax = false
+						break
+					}
+				}
+			}
+			if isMax {
+				points = append(points, keypoint{X: float64(x), Y: float64(y), score: s})
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].score > points[j].score })
+
+	// Limit the points per cell of an 8x8 grid, so a single detailed area
+	// doesn't take up all of them
+	const grid = 8
+	var perCell [grid * grid]int
+	cellCap := math.Max(2*maxPoints/(grid*grid), 1)
+	selected := make([]keypoint, 0, maxPoints)
+	for _, p := range points {
+		if len(selected) >= maxPoints {
+			break
+		}
+		cell := int(p.Y)*grid/g.h*grid + int(p.X)*grid/g.w
+		if perCell[cell] < cellCap {
+			perCell[cell]++
+			selected = append(selected, p)
+		}
+	}
+
+	smooth := g.blur(2)
+	parallelRows(0, len(selected), func(i int) {
+		p := &selected[i]
+		x, y := int(p.X), int(p.Y)
+		// The orientation points from the center to the intensity centroid of the patch
+		var m01, m10 float64
+		for dy := -orbPatchRadius; dy <= orbPatchRadius; dy++ {
+			for dx := -orbPatchRadius; dx <= orbPatchRadius; dx++ {
+				if dx*dx+dy*dy > orbPatchRadius*orbPatchRadius {
+					continue
+				}
+				v := float64(g.pix[(y+dy)*g.w+x+dx])
+				m10 += float64(dx) * v
+				m01 += float64(dy) * v
+			}
+		}
+		p.angle = math.Atan2(m01, m10)
+		sin, cos := math.Sin(p.angle), math.Cos(p.angle)
+		sample := func(px, py float64) float32 {
+			rx := int(math.Round(cos*px - sin*py))
+			ry := int(math.Round(sin*px + cos*py))
+			return smooth.pix[(y+ry)*g.w+x+rx]
+		}
+		for j, pair := range orbPairs {
+			if sample(pair[0], pair[1]) < sample(pair[2], pair[3]) {
+				p.desc[j/64] |= 1 << (j % 64)
+			}
+		}
+	})
+	return selected
+}
+
+func hamming(a, b [4]uint64) int {
+	return bits.OnesCount64(a[0]^b[0]) + bits.OnesCount64(a[1]^b[1]) + bits.OnesCount64(a[2]^b[2]) + bits.OnesCount64(a[3]^b[3])
+}
+
+// bestMatches returns, for each keypoint of a, the index of the closest keypoint of b
+// and whether it is clearly closer than the second closest.
+func bestMatches(a, b []keypoint) ([]int, []bool) {
+	idx := make([]int, len(a))
+	ok := make([]bool, len(a))
+	parallelRows(0, len(a), func(i int) {
+		best, second, bestJ := 257, 257, -1
+		for j := range b {
+			d := hamming(a[i].desc, b[j].desc)
+			if d < best {
+				best, second, bestJ = d, best, j
+			} else if d < second {
+				second = d
+			}
+		}
+		idx[i] = bestJ
+		ok[i] = bestJ >= 0 && best <= orbMaxDistance && float64(best) < orbMatchRatio*float64(second)
+	})
+	return idx, ok
+}
+
+// matchKeypoints returns the positions of the keypoints of a and b that
+// match each other, i.e. each is the other's closest and distinct match.
+func matchKeypoints(a, b []keypoint) (pa, pb []Point) {
+	ab, okA := bestMatches(a, b)
+	ba, okB := bestMatches(b, a)
+	for i, j := range ab {
+		if okA[i] && okB[j] && ba[j] == i {
+			pa = append(pa, Point{X: a[i].X, Y: a[i].Y})
+			pb = append(pb, Point{X: b[j].X, Y: b[j].Y})
+		}
+	}
+	return pa, pb
+}
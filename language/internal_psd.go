@@ -0,0 +1,369 @@
+package language
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"unicode/utf16"
+)
+
+// psdBlendModes maps the blend mode keys of PSD layers to blend modes.
+// Keys without an equivalent (e.g. dissolve or linear burn) are composited normally.
+var psdBlendModes = map[string]string{
+	"norm": NORMAL,
+	"mul ": MULTIPLY,
+	"scrn": SCREEN,
+	"over": OVERLAY,
+	"dark": DARKEN,
+	"lite": LIGHTEN,
+	"dkCl": DARKER_COLOR,
+	"lgCl": LIGHTER_COLOR,
+	"div ": COLOR_DODGE,
+	"idiv": COLOR_BURN,
+	"hLit": HARD_LIGHT,
+	"sLit": SOFT_LIGHT,
+	"vLit": VIVID_LIGHT,
+	"lLit": LINEAR_LIGHT,
+	"pLit": PIN_LIGHT,
+	"hMix": HARD_MIX,
+	"diff": DIFFERENCE,
+	"smud": EXCLUSION,
+	"fsub": SUBTRACT,
+	"fdiv": DIVIDE,
+	"hue ": HUE,
+	"sat ": SATURATION,
+	"colr": COLOR,
+	"lum ": LUMINOSITY,
+}
+
+// isPSD reports whether data is a Photoshop document.
+func isPSD(data []byte) bool {
+	return len(data) >= 26 && bytes.Equal(data[:4], []byte("8BPS"))
+}
+
+// psdReader reads the big-endian values of a PSD file.
+type psdReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *psdReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("truncated PSD file")
+		}
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *psdReader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *psdReader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *psdReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *psdReader) i32() int32 { return int32(r.u32()) }
+
+// section returns a reader for the next section, prefixed by its 32-bit length.
+func (r *psdReader) section() *psdReader {
+	n := int(r.u32())
+	return &psdReader{data: r.bytes(n), err: r.err}
+}
+
+type psdChannel struct {
+	id     int16
+	length int
+}
+
+type psdLayer struct {
+	rect     image.Rectangle
+	channels []psdChannel
+	blendKey string
+	opacity  uint8
+	hidden   bool
+	name     string
+	mask     image.Rectangle
+	maskDef  uint8
+	maskOff  bool
+}
+
+// DecodePSD decodes the raster layers of an RGB Photoshop document with 8 or
+// 16 bits per channel, bottom layer first. Layer names, opacity, visibility,
+// offsets, blend modes and layer masks are kept, groups and adjustment layers
+// are ignored.
+func DecodePSD(data []byte) ([]*Layer, error) {
+	if !isPSD(data) {
+		return nil, fmt.Errorf("not a PSD file")
+	}
+	r := &psdReader{data: data, pos: 4}
+	if v := r.u16(); v != 1 {
+		return nil, fmt.Errorf("unsupported PSD version %d (PSB files are not supported)", v)
+	}
+	r.bytes(6)
+	r.u16() // number of channels of the merged image
+	r.u32() // height
+	r.u32() // width
+	depth := int(r.u16())
+	mode := r.u16()
+	if mode != 3 {
+		return nil, fmt.Errorf("unsupported PSD color mode %d (only RGB is supported)", mode)
+	}
+	if depth != 8 && depth != 16 {
+		return nil, fmt.Errorf("unsupported PSD bit depth %d (only 8 and 16 bits are supported)", depth)
+	}
+	r.section() // color mode data
+	r.section() // image resources
+	layerAndMask := r.section()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	info := layerAndMask.section()
+	if len(info.data) == 0 {
+		// 16-bit documents store their layers in the additional layer information
+		layerAndMask.section() // global layer mask
+		for layerAndMask.err == nil && layerAndMask.pos+12 <= len(layerAndMask.data) {
+			layerAndMask.bytes(4) // signature
+			key := string(layerAndMask.bytes(4))
+			block := layerAndMask.section()
+			if key == "Lr16" {
+				info = block
+				break
+			}
+			layerAndMask.pos += (4 - len(block.data)%4) % 4
+		}
+	}
+	if len(info.data) == 0 {
+		return nil, nil
+	}
+	return decodePSDLayers(info, depth)
+}
+
+func decodePSDLayers(r *psdReader, depth int) ([]*Layer, error) {
+	count := int(int16(r.u16()))
+	if count < 0 {
+		count = -count // the first alpha channel holds the transparency of the merged image
+	}
+	records := make([]*psdLayer, count)
+	for i := range records {
+		l := &psdLayer{}
+		top, left, bottom, right := r.i32(), r.i32(), r.i32(), r.i32()
+		l.rect = image.Rect(int(left), int(top), int(right), int(bottom))
+		n := int(r.u16())
+		for range n {
+			l.channels = append(l.channels, psdChannel{id: int16(r.u16()), length: int(r.u32())})
+		}
+		if sig := string(r.bytes(4)); sig != "8BIM" && r.err == nil {
+			return nil, fmt.Errorf("invalid PSD layer record")
+		}
+		l.blendKey = string(r.bytes(4))
+		l.opacity = r.u8()
+		r.u8() // clipping
+		l.hidden = r.u8()&0x02 != 0
+		r.u8()
+		extra := r.section()
+		if mask := extra.section(); len(mask.data) >= 18 {
+			top, left, bottom, right := mask.i32(), mask.i32(), mask.i32(), mask.i32()
+			l.mask = image.Rect(int(left), int(top), int(right), int(bottom))
+			l.maskDef = mask.u8()
+			l.maskOff = mask.u8()&0x02 != 0
+		}
+		extra.section() // blending ranges
+		nameLen := int(extra.u8())
+		l.name = string(extra.bytes(nameLen))
+		extra.bytes((4 - (nameLen+1)%4) % 4)
+		for extra.err == nil && extra.pos+12 <= len(extra.data) {
+			extra.bytes(4)
+			key := string(extra.bytes(4))
+			block := extra.section()
+			if key == "luni" && len(block.data) >= 4 {
+				n := int(block.u32())
+				if n > len(block.data)/2 {
+					n = len(block.data) / 2
+				}
+				chars := make([]uint16, n)
+				for j := range chars {
+					chars[j] = block.u16()
+				}
+				if block.err == nil {
+					l.name = string(utf16.Decode(chars))
+				}
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		records[i] = l
+	}
+
+	var layers []*Layer
+	for i, rec := range records {
+		channels := map[int16][]byte{}
+		for _, ch := range rec.channels {
+			raw := r.bytes(ch.length)
+			if r.err != nil {
+				return nil, r.err
+			}
+			rect := rec.rect
+			if ch.id == -2 {
+				rect = rec.mask
+			}
+			if ch.length < 2 || rect.Empty() {
+				continue
+			}
+			plane, err := decodePSDChannel(raw, rect.Dx(), rect.Dy(), depth)
+			if err != nil {
+				return nil, fmt.Errorf("layer %d: %w", i, err)
+			}
+			channels[ch.id] = plane
+		}
+		if rec.rect.Empty() {
+			continue // groups and adjustment layers have no pixels
+		}
+
+		w, h := rec.rect.Dx(), rec.rect.Dy()
+		img := I(w, h)
+		bps := depth / 8
+		sample := func(plane []byte, i int) uint32 {
+			if plane == nil {
+				return 0xffff
+			}
+			if bps == 2 {
+				return uint32(plane[2*i])<<8 | uint32(plane[2*i+1])
+			}
+			return uint32(plane[i]) * 0x101
+		}
+		parallelRows(0, h, func(y int) {
+			for x := range w {
+				p := y*w + x
+				dsl.setColor(img, x, y, sample(channels[0], p), sample(channels[1], p), sample(channels[2], p), sample(channels[-1], p))
+			}
+		})
+
+		l := NewLayer(img)
+		l.Name = rec.name
+		l.X, l.Y = rec.rect.Min.X, rec.rect.Min.Y
+		l.Opacity = float64(rec.opacity) / 255
+		l.Visible = !rec.hidden
+		if mode, ok := psdBlendModes[rec.blendKey]; ok {
+			l.BlendMode = mode
+		}
+		if plane := channels[-2]; plane != nil && !rec.maskOff {
+			// Layer masks have their own bounds, outside of them the default color applies
+			l.Mask = NewMask(w, h)
+			mw := rec.mask.Dx()
+			for y := range h {
+				for x := range w {
+					v := float32(rec.maskDef) / 255
+					if p := image.Pt(x+l.X, y+l.Y); p.In(rec.mask) {
+						v = float32(sample(plane, (p.Y-rec.mask.Min.Y)*mw+p.X-rec.mask.Min.X)) / 0xffff
+					}
+					l.Mask.Values[y*w+x] = v
+				}
+			}
+		}
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+// decodePSDChannel decodes the pixels of a layer channel, prefixed by the compression method.
+func decodePSDChannel(data []byte, w, h, depth int) ([]byte, error) {
+	rowBytes := w * depth / 8
+	out := make([]byte, rowBytes*h)
+	compression := binary.BigEndian.Uint16(data)
+	data = data[2:]
+	switch compression {
+	case 0: // raw
+		copy(out, data)
+	case 1: // PackBits, preceded by the byte count of each row
+		if len(data) < 2*h {
+			return nil, fmt.Errorf("truncated PSD channel")
+		}
+		src := data[2*h:]
+		pos := 0
+		for y := range h {
+			n := int(binary.BigEndian.Uint16(data[2*y:]))
+			if pos+n > len(src) {
+				return nil, fmt.Errorf("truncated PSD channel")
+			}
+			unpackBits(src[pos:pos+n], out[y*rowBytes:(y+1)*rowBytes])
+			pos += n
+		}
+	case 2, 3: // zip without and with prediction
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PSD channel: %w", err)
+		}
+		if _, err := io.ReadFull(zr, out); err != nil {
+			return nil, fmt.Errorf("invalid PSD channel: %w", err)
+		}
+		if compression == 3 {
+			for y := range h {
+				row := out[y*rowBytes : (y+1)*rowBytes]
+				if depth == 16 {
+					for x := 1; x < w; x++ {
+						v := binary.BigEndian.Uint16(row[2*x:]) + binary.BigEndian.Uint16(row[2*x-2:])
+						binary.BigEndian.PutUint16(row[2*x:], v)
+					}
+				} else {
+					for x := 1; x < w; x++ {
+						row[x] += row[x-1]
+					}
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PSD compression %d", compression)
+	}
+	return out, nil
+}
+
+// unpackBits decodes PackBits run-length encoded data into dst.
+func unpackBits(src, dst []byte) {
+	for i, o := 0, 0; i < len(src) && o < len(dst); {
+		n := int(int8(src[i]))
+		i++
+		switch {
+		case n >= 0:
+			end := i + n + 1
+			if end > len(src) {
+				end = len(src)
+			}
+			o += copy(dst[o:], src[i:end])
+			i += n + 1
+		case n > -128:
+			if i < len(src) {
+				for range 1 - n {
+					if o < len(dst) {
+						dst[o] = src[i]
+						o++
+					}
+				}
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package language
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"testing"
+	"unicode/utf16"
+)
+
+// psdTestLayer describes a layer of a test PSD. Channels hold the planes
+// (-1 = alpha, -2 = mask) with depth/8 bytes per sample.
+type psdTestLayer struct {
+	name     string
+	rect     image.Rectangle
+	blendKey string
+	opacity  uint8
+	hidden   bool
+	channels map[int16][]byte
+	mask     image.Rectangle
+	maskDef  uint8
+}
+
+// psdFile returns an RGB Photoshop document with the layers. Channels are
+// compressed with all methods, depending on their ID, and 16-bit documents
+// store the layers in an Lr16 block like Photoshop does.
+func psdFile(depth int, layers []psdTestLayer) []byte {
+	be := binary.BigEndian
+	var info bytes.Buffer
+	binary.Write(&info, be, int16(len(layers)))
+	var data bytes.Buffer
+	for _, l := range layers {
+		binary.Write(&info, be, []int32{int32(l.rect.Min.Y), int32(l.rect.Min.X), int32(l.rect.Max.Y), int32(l.rect.Max.X)})
+		binary.Write(&info, be, uint16(len(l.channels)))
+		for _, id := range []int16{-1, 0, 1, 2, -2} {
+			plane, ok := l.channels[id]
+			if !ok {
+				continue
+			}
+			w := l.rect.Dx()
+			if id == -2 {
+				w = l.mask.Dx()
+			}
+			ch := psdChannelData(plane, w*depth/8, depth, id)
+			binary.Write(&info, be, id)
+			binary.Write(&info, be, uint32(len(ch)))
+			data.Write(ch)
+		}
+		info.WriteString("8BIM")
+		info.WriteString(l.blendKey)
+		flags := byte(0)
+		if l.hidden {
+			flags = 0x02
+		}
+		info.Write([]byte{l.opacity, 0, flags, 0})
+
+		var extra bytes.Buffer
+		if l.mask.Empty() {
+			binary.Write(&extra, be, uint32(0))
+		} else {
+			binary.Write(&extra, be, uint32(20))
+			binary.Write(&extra, be, []int32{int32(l.mask.Min.Y), int32(l.mask.Min.X), int32(l.mask.Max.Y), int32(l.mask.Max.X)})
+			extra.Write([]byte{l.maskDef, 0, 0, 0})
+		}
+		binary.Write(&extra, be, uint32(0)) // blending ranges
+		// The Pascal name is limited to ASCII, the full name is stored as luni block
+		extra.WriteByte(byte(len("layer")))
+		extra.WriteString("layer")
+		extra.Write(make([]byte, (4-(len("layer")+1)%4)%4))
+		name := utf16.Encode([]rune(l.name))
+		extra.WriteString("8BIMluni")
+		binary.Write(&extra, be, uint32(4+2*len(name)))
+		binary.Write(&extra, be, uint32(len(name)))
+		binary.Write(&extra, be, name)
+		binary.Write(&info, be, uint32(extra.Len()))
+		info.Write(extra.Bytes())
+	}
+	info.Write(data.Bytes())
+
+	var layerAndMask bytes.Buffer
+	if depth == 16 {
+		binary.Write(&layerAndMask, be, uint32(0)) // layer info
+		binary.Write(&layerAndMask, be, uint32(0)) // global layer mask
+		layerAndMask.WriteString("8BIMLr16")
+		binary.Write(&layerAndMask, be, uint32(info.Len()))
+		layerAndMask.Write(info.Bytes())
+	} else {
+		binary.Write(&layerAndMask, be, uint32(info.Len()))
+		layerAndMask.Write(info.Bytes())
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("8BPS")
+	binary.Write(&buf, be, uint16(1))
+	buf.Write(make([]byte, 6))
+	binary.Write(&buf, be, []uint16{3})
+	binary.Write(&buf, be, []uint32{16, 16})
+	binary.Write(&buf, be, []uint16{uint16(depth), 3})
+	binary.Write(&buf, be, []uint32{0, 0}) // color mode data, image resources
+	binary.Write(&buf, be, uint32(layerAndMask.Len()))
+	buf.Write(layerAndMask.Bytes())
+	return buf.Bytes()
+}
+
+// psdChannelData compresses a plane with rows of rowBytes, the method
+// depends on the channel ID: raw, PackBits, zip and zip with prediction.
+func psdChannelData(plane []byte, rowBytes, depth int, id int16) []byte {
+	var buf bytes.Buffer
+	h := len(plane) / rowBytes
+	switch id {
+	case 0, -2:
+		binary.Write(&buf, binary.BigEndian, uint16(0))
+		buf.Write(plane)
+	case 1:
+		// Every row is a single literal run
+		binary.Write(&buf, binary.BigEndian, uint16(1))
+		for range h {
+			binary.Write(&buf, binary.BigEndian, uint16(rowBytes+1))
+		}
+		for y := range h {
+			buf.WriteByte(byte(rowBytes - 1))
+			buf.Write(plane[y*rowBytes : (y+1)*rowBytes])
+		}
+	default:
+		compression := uint16(2)
+		src := plane
+		if id == 2 {
+			compression = 3
+			src = bytes.Clone(plane)
+			for y := range h {
+				row := src[y*rowBytes : (y+1)*rowBytes]
+				orig := plane[y*rowBytes : (y+1)*rowBytes]
+				if depth == 16 {
+					for x := 2; x < rowBytes; x += 2 {
+						binary.BigEndian.PutUint16(row[x:], binary.BigEndian.Uint16(orig[x:])-binary.BigEndian.Uint16(orig[x-2:]))
+					}
+				} else {
+					for x := 1; x < rowBytes; x++ {
+						row[x] = orig[x] - orig[x-1]
+					}
+				}
+			}
+		}
+		binary.Write(&buf, binary.BigEndian, compression)
+		zw := zlib.NewWriter(&buf)
+		zw.Write(src)
+		zw.Close()
+	}
+	return buf.Bytes()
+}
+
+// psdPlanes splits img into planes with depth/8 bytes per sample.
+func psdPlanes(img *image.NRGBA64, depth int) map[int16][]byte {
+	planes := map[int16][]byte{}
+	b := img.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.NRGBA64At(x, y)
+			for id, v := range map[int16]uint16{0: c.R, 1: c.G, 2: c.B, -1: c.A} {
+				if depth == 16 {
+					planes[id] = binary.BigEndian.AppendUint16(planes[id], v)
+				} else {
+					planes[id] = append(planes[id], byte(v>>8))
+				}
+			}
+		}
+	}
+	return planes
+}
+
+func TestDecodePSD(t *testing.T) {
+	for _, depth := range []int{8, 16} {
+		src := testImage(5, 3)
+		if depth == 8 {
+			src = imageToNRGBA64(ImageTo8Bit(src))
+		}
+		background := psdPlanes(src, depth)
+		masked := psdPlanes(src, depth)
+		masked[-2] = make([]byte, 2*3*depth/8) // a 2x3 mask hiding the left of the layer
+		data := psdFile(depth, []psdTestLayer{
+			{name: "Hintergrund", rect: image.Rect(0, 0, 5, 3), blendKey: "norm", opacity: 255, channels: background},
+			{name: "Ebene 1", rect: image.Rect(2, 1, 7, 4), blendKey: "mul ", opacity: 128, hidden: true, channels: masked, mask: image.Rect(2, 1, 4, 4), maskDef: 255},
+		})
+		if !isPSD(data) {
+			t.Fatal("expected the document to be detected as PSD")
+		}
+		layers, err := DecodeLayers(data)
+		if err != nil {
+			t.Fatalf("%d bits: %v", depth, err)
+		}
+		if len(layers) != 2 {
+			t.Fatalf("%d bits: expected 2 layers, got %d", depth, len(layers))
+		}
+
+		bg, l := layers[0], layers[1]
+		if bg.Name != "Hintergrund" || bg.BlendMode != NORMAL || bg.Opacity != 1 || !bg.Visible || bg.Mask != nil {
+			t.Errorf("%d bits: unexpected background layer %+v", depth, bg)
+		}
+		if l.Name != "Ebene 1" || l.X != 2 || l.Y != 1 || l.BlendMode != MULTIPLY || l.Opacity != 128.0/255 || l.Visible {
+			t.Errorf("%d bits: unexpected layer %+v", depth, l)
+		}
+		for _, layer := range layers {
+			if layer.Image.Rect != src.Rect {
+				t.Fatalf("%d bits: expected bounds %v, got %v", depth, src.Rect, layer.Image.Rect)
+			}
+			for y := range 3 {
+				for x := range 5 {
+					if want, got := src.NRGBA64At(x, y), layer.Image.NRGBA64At(x, y); got != want {
+						t.Fatalf("%d bits: expected %v at %d,%d, got %v", depth, want, x, y, got)
+					}
+				}
+			}
+		}
+
+		if l.Mask == nil {
+			t.Fatalf("%d bits: expected a layer mask", depth)
+		}
+		for y := range 3 {
+			for x := range 5 {
+				// Outside of its bounds the mask has its default color
+				want := float32(1)
+				if x < 2 {
+					want = 0
+				}
+				if got := l.Mask.At(x, y); got != want {
+					t.Errorf("%d bits: expected a mask value of %.0f at %d,%d, got %f", depth, want, x, y, got)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodePSDRejectsUnsupportedDocuments(t *testing.T) {
+	data := psdFile(8, []psdTestLayer{{name: "a", rect: image.Rect(0, 0, 1, 1), blendKey: "norm", opacity: 255, channels: psdPlanes(image.NewNRGBA64(image.Rect(0, 0, 1, 1)), 8)}})
+	cmyk := bytes.Clone(data)
+	binary.BigEndian.PutUint16(cmyk[24:], 4)
+	if _, err := DecodePSD(cmyk); err == nil {
+		t.Error("expected an error for a CMYK document")
+	}
+	if _, err := DecodePSD(data[:len(data)-10]); err == nil {
+		t.Error("expected an error for a truncated document")
+	}
+	if _, err := DecodePSD([]byte("8BPS")); err == nil {
+		t.Error("expected an error for a document without header")
+	}
+}
@@ -0,0 +1,227 @@
+package language
+
+import (
+	"github.com/toxyl/math"
+)
+
+// floatImage is an image with c float32 channels per pixel, used for
+// weight maps and the levels of image pyramids.
+type floatImage struct {
+	w, h, c int
+	pix     []float32
+}
+
+func newFloatImage(w, h, c int) *floatImage {
+	return &floatImage{w: w, h: h, c: c, pix: make([]float32, w*h*c)}
+}
+
+// floatImageFromPremul returns the premultiplied channels of p (sharing its pixels).
+func floatImageFromPremul(p *premulImage) *floatImage {
+	return &floatImage{w: p.w, h: p.h, c: 4, pix: p.pix}
+}
+
+// premul returns f (4 channels) as premultiplied image (sharing its pixels).
+func (f *floatImage) premul() *premulImage {
+	return &premulImage{w: f.w, h: f.h, pix: f.pix}
+}
+
+// pyramidKernel is the binomial 5-tap kernel used to reduce and expand pyramid levels.
+var pyramidKernel = [5]float32{1.0 / 16, 4.0 / 16, 6.0 / 16, 4.0 / 16, 1.0 / 16}
+
+// reduce blurs f and halves its size.
+func (f *floatImage) reduce() *floatImage {
+	w, h := (f.w+1)/2, (f.h+1)/2
+	// Horizontal pass at full height, then the vertical pass
+	tmp := newFloatImage(w, f.h, f.c)
+	parallelRows(0, f.h, func(y int) {
+		for x := range w {
+			for k, kw := range pyramidKernel {
+				sx := math.Clamp(2*x+k-2, 0, f.w-1)
+				for ch := range f.c {
+					tmp.pix[(y*w+x)*f.c+ch] += kw * f.pix[(y*f.w+sx)*f.c+ch]
+				}
+			}
+		}
+	})
+	res := newFloatImage(w, h, f.c)
+	parallelRows(0, h, func(y int) {
+		for k, kw := range pyramidKernel {
+			sy := math.Clamp(2*y+k-2, 0, f.h-1)
+			for i := range w * f.c {
+				res.pix[y*w*f.c+i] += kw * tmp.pix[sy*w*f.c+i]
+			}
+		}
+	})
+	return res
+}
+
+// expand doubles the size of f (cropped to w x h) and interpolates the
+// missing pixels with the pyramid kernel.
+func (f *floatImage) expand(w, h int) *floatImage {
+	// Only every other tap hits a source pixel, so the weights are doubled
+	tmp := newFloatImage(w, f.h, f.c)
+	parallelRows(0, f.h, func(y int) {
+		for x := range w {
+			for k, kw := range pyramidKernel {
+				if (x+k-2)%2 != 0 {
+					continue
+				}
+				sx := math.Clamp((x+k-2)/2, 0, f.w-1)
+				for ch := range f.c {
+					tmp.pix[(y*w+x)*f.c+ch] += 2 * kw * f.pix[(y*f.w+sx)*f.c+ch]
+				}
+			}
+		}
+	})
+	res := newFloatImage(w, h, f.c)
+	parallelRows(0, h, func(y int) {
+		for k, kw := range pyramidKernel {
+			if (y+k-2)%2 != 0 {
+				continue
+			}
+			sy := math.Clamp((y+k-2)/2, 0, f.h-1)
+			for i := range w * f.c {
+				res.pix[y*w*f.c+i] += 2 * kw * tmp.pix[sy*w*f.c+i]
+			}
+		}
+	})
+	return res
+}
+
+// pyramidLevels returns the number of levels of a pyramid for a w x h
+// image, the smallest level is at least 8 pixels wide and high.
+func pyramidLevels(w, h int) int {
+	n := 1
+	for s := math.Min(w, h); s >= 16; s /= 2 {
+		n++
+	}
+	return n
+}
+
+// gaussianPyramid returns f followed by levels-1 successively reduced versions of it.
+func gaussianPyramid(f *floatImage, levels int) []*floatImage {
+	res := []*floatImage{f}
+	for len(res) < levels {
+		res = append(res, res[len(res)-1].reduce())
+	}
+	return res
+}
+
+// laplacianPyramid returns the details lost between the levels of the
+// Gaussian pyramid of f, the last level is the smallest Gaussian level.
+func laplacianPyramid(f *floatImage, levels int) []*floatImage {
+	g := gaussianPyramid(f, levels)
+	for i := 0; i < len(g)-1; i++ {
+		up := g[i+1].expand(g[i].w, g[i].h)
+		l := newFloatImage(g[i].w, g[i].h, g[i].c)
+		for j := range l.pix {
+			l.pix[j] = g[i].pix[j] - up.pix[j]
+		}
+		g[i] = l
+	}
+	return g
+}
+
+// collapsePyramid reconstructs the image from its Laplacian pyramid.
+func collapsePyramid(pyr []*floatImage) *floatImage {
+	res := pyr[len(pyr)-1]
+	for i := len(pyr) - 2; i >= 0; i-- {
+		up := res.expand(pyr[i].w, pyr[i].h)
+		for j := range up.pix {
+			up.pix[j] += pyr[i].pix[j]
+		}
+		res = up
+	}
+	return res
+}
+
+// normalizeWeights scales the single-channel weight maps so they sum to 1
+// at every pixel, pixels without any weight are weighted equally.
+func normalizeWeights(weights []*floatImage) {
+	width := weights[0].w
+	parallelRows(0, weights[0].h, func(y int) {
+		for i := y * width; i < (y+1)*width; i++ {
+			var sum float32
+			for _, w := range weights {
+				sum += w.pix[i]
+			}
+			for _, w := range weights {
+				if sum > 1e-12 {
+					w.pix[i] /= sum
+				} else {
+					w.pix[i] = 1 / float32(len(weights))
+				}
+			}
+		}
+	})
+}
+
+// blendPyramids blends the images with the given normalized weight maps
+// level by level in their Laplacian pyramids, so the weights are smooth at
+// coarse scales and precise at fine scales, without visible seams.
+func blendPyramids(imgs []*floatImage, weights []*floatImage) *floatImage {
+	levels := pyramidLevels(imgs[0].w, imgs[0].h)
+	var res []*floatImage
+	for i, img := range imgs {
+		lp := laplacianPyramid(img, levels)
+		gw := gaussianPyramid(weights[i], levels)
+		if res == nil {
+			res = make([]*floatImage, levels)
+			for l := range res {
+				res[l] = newFloatImage(lp[l].w, lp[l].h, lp[l].c)
+			}
+		}
+		for l := range levels {
+			dst, src, w, c := res[l], lp[l], gw[l], lp[l].c
+			parallelRows(0, dst.h, func(y int) {
+				for p := y * dst.w; p < (y+1)*dst.w; p++ {
+					for ch := range c {
+						dst.pix[p*c+ch] += w.pix[p] * src.pix[p*c+ch]
+					}
+				}
+			})
+		}
+	}
+	return collapsePyramid(res)
+}
+
+// grayFloatImage returns the (unpremultiplied) gray values of p as single-channel image.
+func grayFloatImage(p *premulImage) *floatImage {
+	res := newFloatImage(p.w, p.h, 1)
+	parallelRows(0, p.h, func(y int) {
+		for i := y * p.w; i < (y+1)*p.w; i++ {
+			if a := p.pix[i*4+3]; a > 0 {
+				res.pix[i] = (0.299*p.pix[i*4] + 0.587*p.pix[i*4+1] + 0.114*p.pix[i*4+2]) / a
+			}
+		}
+	})
+	return res
+}
+
+// blur convolves the single-channel image f with the separable Gaussian
+// kernel of the given radius, the kernel is renormalized at the edges.
+func (f *floatImage) blur(radius float64) *floatImage {
+	if radius <= 0 {
+		return f
+	}
+	sigma, kernelSize, halfSize := calcKernelSize(radius)
+	kernel := makeKernelGaussian1D(kernelSize, sigma, halfSize)
+	pass := func(src *floatImage, dx, dy int) *floatImage {
+		res := newFloatImage(src.w, src.h, 1)
+		parallelRows(0, src.h, func(y int) {
+			for x := range src.w {
+				var sum, total float64
+				for i := -halfSize; i <= halfSize; i++ {
+					sx, sy := x+i*dx, y+i*dy
+					if sx >= 0 && sx < src.w && sy >= 0 && sy < src.h {
+						sum += float64(src.pix[sy*src.w+sx]) * kernel[i+halfSize]
+						total += kernel[i+halfSize]
+					}
+				}
+				res.pix[y*src.w+x] = float32(sum / total)
+			}
+		})
+		return res
+	}
+	return pass(pass(f, 1, 0), 0, 1)
+}
@@ -0,0 +1,100 @@
+package language
+
+import (
+	"image"
+	"image/color"
+	"slices"
+)
+
+// medianCutQuantizer builds a palette by repeatedly splitting the box of
+// colors with the largest range at its median. It implements draw.Quantizer.
+type medianCutQuantizer struct {
+	numColors int
+}
+
+func (q medianCutQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	bounds := m.Bounds()
+	numColors := q.numColors - len(p)
+	if numColors <= 0 {
+		return p
+	}
+
+	// Sample at most ~256k pixels, that is plenty to find the dominant colors
+	step := 1
+	for (bounds.Dx()/step)*(bounds.Dy()/step) > 1<<18 {
+		step++
+	}
+	var pixels [][4]uint8
+	hasTransparency := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				hasTransparency = true
+				continue
+			}
+			pixels = append(pixels, [4]uint8{c.R, c.G, c.B, c.A})
+		}
+	}
+	if hasTransparency {
+		p = append(p, color.NRGBA{})
+		numColors--
+	}
+
+	boxes := [][][4]uint8{pixels}
+	for len(boxes) < numColors {
+		// Find the box with the widest channel range
+		best, bestCh, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for ch := range 3 {
+				lo, hi := uint8(255), uint8(0)
+				for _, px := range box {
+					lo, hi = min8(lo, px[ch]), max8(hi, px[ch])
+				}
+				if r := int(hi) - int(lo); r > bestRange {
+					best, bestCh, bestRange = i, ch, r
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		box := boxes[best]
+		slices.SortFunc(box, func(a, b [4]uint8) int { return int(a[bestCh]) - int(b[bestCh]) })
+		mid := len(box) / 2
+		boxes[best] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	for _, box := range boxes {
+		if len(box) == 0 {
+			continue
+		}
+		var r, g, b int
+		for _, px := range box {
+			r += int(px[0])
+			g += int(px[1])
+			b += int(px[2])
+		}
+		n := len(box)
+		p = append(p, color.NRGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xFF})
+	}
+	return p
+}
+
+func min8(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max8(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
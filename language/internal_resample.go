@@ -0,0 +1,346 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/toxyl/math"
+)
+
+// resampleFilter is a reconstruction kernel used to resample images.
+// The kernel is defined in source pixels and is zero outside of -support..support.
+type resampleFilter struct {
+	name    string
+	support float64
+	kernel  func(x float64) float64
+}
+
+func boxKernel(x float64) float64 {
+	if x >= -0.5 && x < 0.5 {
+		return 1
+	}
+	return 0
+}
+
+func triangleKernel(x float64) float64 {
+	x = math.Abs(x)
+	if x < 1 {
+		return 1 - x
+	}
+	return 0
+}
+
+// cubicKernel returns the Mitchell-Netravali cubic with parameters b and c.
+func cubicKernel(b, c float64) func(x float64) float64 {
+	return func(x float64) float64 {
+		x = math.Abs(x)
+		switch {
+		case x < 1:
+			return ((12-9*b-6*c)*x*x*x + (-18+12*b+6*c)*x*x + (6 - 2*b)) / 6
+		case x < 2:
+			return ((-b-6*c)*x*x*x + (6*b+30*c)*x*x + (-12*b-48*c)*x + (8*b + 24*c)) / 6
+		}
+		return 0
+	}
+}
+
+func lanczos3Kernel(x float64) float64 {
+	switch {
+	case x == 0:
+		return 1
+	case x > -3 && x < 3:
+		px := math.Pi * x
+		return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+	}
+	return 0
+}
+
+var resampleFilters = map[string]*resampleFilter{
+	"nearest":  {name: "nearest", support: 0.5, kernel: boxKernel},
+	"area":     {name: "area", support: 0.5, kernel: boxKernel},
+	"bilinear": {name: "bilinear", support: 1, kernel: triangleKernel},
+	"bicubic":  {name: "bicubic", support: 2, kernel: cubicKernel(0, 0.5)},
+	"mitchell": {name: "mitchell", support: 2, kernel: cubicKernel(1.0/3, 1.0/3)},
+	"lanczos3": {name: "lanczos3", support: 3, kernel: lanczos3Kernel},
+}
+
+// getResampleFilter returns the filter with the given name.
+func getResampleFilter(name string) (*resampleFilter, error) {
+	f, ok := resampleFilters[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		names := make([]string, 0, len(resampleFilters))
+		for n := range resampleFilters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown filter: %s (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return f, nil
+}
+
+// premulImage holds the premultiplied colors of an image as RGBA float32 values (0..1).
+type premulImage struct {
+	w, h int
+	pix  []float32
+}
+
+func newPremulImage(w, h int) *premulImage {
+	return &premulImage{w: w, h: h, pix: make([]float32, w*h*4)}
+}
+
+func toPremulImage(img *image.NRGBA64) *premulImage {
+	b := img.Bounds()
+	p := newPremulImage(b.Dx(), b.Dy())
+	parallelRows(0, p.h, func(y int) {
+		for x := range p.w {
+			c := img.NRGBA64At(b.Min.X+x, b.Min.Y+y)
+			a := float32(c.A) / 0xffff
+			i := (y*p.w + x) * 4
+			p.pix[i+0] = float32(c.R) / 0xffff * a
+			p.pix[i+1] = float32(c.G) / 0xffff * a
+			p.pix[i+2] = float32(c.B) / 0xffff * a
+			p.pix[i+3] = a
+		}
+	})
+	return p
+}
+
+// setPremul stores a premultiplied color at (x, y) of img. Over- and
+// undershoots of the filters are clamped.
+func setPremul(img *image.NRGBA64, x, y int, r, g, b, a float32) {
+	if a <= 0 {
+		img.SetNRGBA64(x, y, color.NRGBA64{})
+		return
+	}
+	a = math.Min(a, 1)
+	unpremul := func(v float32) uint16 {
+		return uint16(math.Clamp(v/a, 0, 1)*0xffff + 0.5)
+	}
+	img.SetNRGBA64(x, y, color.NRGBA64{R: unpremul(r), G: unpremul(g), B: unpremul(b), A: uint16(a*0xffff + 0.5)})
+}
+
+func (p *premulImage) toNRGBA64() *image.NRGBA64 {
+	img := I(p.w, p.h)
+	parallelRows(0, p.h, func(y int) {
+		for x := range p.w {
+			i := (y*p.w + x) * 4
+			setPremul(img, x, y, p.pix[i], p.pix[i+1], p.pix[i+2], p.pix[i+3])
+		}
+	})
+	return img
+}
+
+// resampleWeights lists the source pixels and their weights for one destination pixel.
+type resampleWeights struct {
+	idx []int
+	w   []float32
+}
+
+// weights returns the contributions of the srcLen source pixels to each of the dstLen destination pixels.
+// When downscaling, the kernel is stretched so every source pixel contributes.
+// Source pixels beyond the edges are clamped to the edges.
+func (f *resampleFilter) weights(srcLen, dstLen int) []resampleWeights {
+	res := make([]resampleWeights, dstLen)
+	s := float64(srcLen) / float64(dstLen)
+	for i := range res {
+		c := &res[i]
+		add := func(j int, w float64) {
+			c.idx = append(c.idx, math.Clamp(j, 0, srcLen-1))
+			c.w = append(c.w, float32(w))
+		}
+		switch f.name {
+		case "nearest":
+			add(int((float64(i)+0.5)*s), 1)
+		case "area":
+			// Weight each source pixel by how much of it the destination pixel covers
+			a, b := float64(i)*s, float64(i+1)*s
+			for j := int(math.Floor(a)); float64(j) < b; j++ {
+				if o := math.Min(b, float64(j+1)) - math.Max(a, float64(j)); o > 0 {
+					add(j, o/s)
+				}
+			}
+		default:
+			fs := math.Max(1, s)
+			center := (float64(i) + 0.5) * s
+			support := f.support * fs
+			sum := 0.0
+			for j := int(math.Floor(center - support)); j <= int(math.Ceil(center+support)); j++ {
+				if w := f.kernel((float64(j) + 0.5 - center) / fs); w != 0 {
+					add(j, w)
+					sum += w
+				}
+			}
+			if sum != 0 {
+				for k := range c.w {
+					c.w[k] /= float32(sum)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// resample resizes img to w x h pixels using filter f.
+func resample(img *image.NRGBA64, w, h int, f *resampleFilter) *image.NRGBA64 {
+	return toPremulImage(img).resample(w, h, f).toNRGBA64()
+}
+
+// resample resizes p to w x h pixels using filter f. The image is filtered
+// horizontally, then vertically, with premultiplied alpha so transparent
+// pixels don't bleed into their neighbours.
+func (p *premulImage) resample(w, h int, f *resampleFilter) *premulImage {
+	if w <= 0 || h <= 0 || p.w == 0 || p.h == 0 {
+		return newPremulImage(math.Max(w, 0), math.Max(h, 0))
+	}
+	src := p
+	if w != src.w {
+		weights := f.weights(src.w, w)
+		dst := newPremulImage(w, src.h)
+		parallelRows(0, src.h, func(y int) {
+			row := src.pix[y*src.w*4 : (y+1)*src.w*4]
+			for x, c := range weights {
+				var r, g, b, a float32
+				for k, j := range c.idx {
+					wt := c.w[k]
+					r += row[j*4] * wt
+					g += row[j*4+1] * wt
+					b += row[j*4+2] * wt
+					a += row[j*4+3] * wt
+				}
+				i := (y*w + x) * 4
+				dst.pix[i], dst.pix[i+1], dst.pix[i+2], dst.pix[i+3] = r, g, b, a
+			}
+		})
+		src = dst
+	}
+	if h != src.h {
+		weights := f.weights(src.h, h)
+		dst := newPremulImage(w, h)
+		parallelRows(0, h, func(y int) {
+			c := weights[y]
+			for x := range w {
+				var r, g, b, a float32
+				for k, j := range c.idx {
+					wt := c.w[k]
+					i := (j*w + x) * 4
+					r += src.pix[i] * wt
+					g += src.pix[i+1] * wt
+					b += src.pix[i+2] * wt
+					a += src.pix[i+3] * wt
+				}
+				i := (y*w + x) * 4
+				dst.pix[i], dst.pix[i+1], dst.pix[i+2], dst.pix[i+3] = r, g, b, a
+			}
+		})
+		src = dst
+	}
+	return src
+}
+
+// maxSampleScale limits how far the kernel is stretched when sampling, so a
+// degenerate mapping can't make a single sample cover the whole image.
+const maxSampleScale = 64
+
+// isFinite returns whether v is neither NaN nor infinite.
+func isFinite(v float64) bool {
+	return v-v == 0
+}
+
+// sample returns the premultiplied color at the continuous position (u, v),
+// where pixel (x, y) covers x..x+1 and y..y+1. The kernel is stretched by
+// scale (1..maxSampleScale) when the image is minified. Outside of the image
+// and at invalid positions is transparent.
+func (p *premulImage) sample(f *resampleFilter, u, v, scale float64) (r, g, b, a float32) {
+	if !isFinite(u) || !isFinite(v) || !isFinite(scale) ||
+		u < -maxSampleScale*f.support || v < -maxSampleScale*f.support ||
+		u > float64(p.w)+maxSampleScale*f.support || v > float64(p.h)+maxSampleScale*f.support {
+		return 0, 0, 0, 0
+	}
+	scale = math.Clamp(scale, 1, maxSampleScale)
+	if f.name == "nearest" {
+		x, y := int(math.Floor(u)), int(math.Floor(v))
+		if x < 0 || y < 0 || x >= p.w || y >= p.h {
+			return 0, 0, 0, 0
+		}
+		i := (y*p.w + x) * 4
+		return p.pix[i], p.pix[i+1], p.pix[i+2], p.pix[i+3]
+	}
+	support := f.support * scale
+	x0, x1 := int(math.Floor(u-support)), int(math.Ceil(u+support))
+	y0, y1 := int(math.Floor(v-support)), int(math.Ceil(v+support))
+	if x1 < 0 || y1 < 0 || x0 >= p.w || y0 >= p.h {
+		return 0, 0, 0, 0
+	}
+	var buf [16]float32
+	wx := buf[:0]
+	var sumX float32
+	for x := x0; x <= x1; x++ {
+		w := float32(f.kernel((float64(x) + 0.5 - u) / scale))
+		wx = append(wx, w)
+		sumX += w
+	}
+	var sumY float32
+	for y := y0; y <= y1; y++ {
+		wy := float32(f.kernel((float64(y) + 0.5 - v) / scale))
+		sumY += wy
+		if wy == 0 || y < 0 || y >= p.h {
+			continue
+		}
+		for k, wxk := range wx {
+			x := x0 + k
+			if wxk == 0 || x < 0 || x >= p.w {
+				continue
+			}
+			w := wxk * wy
+			i := (y*p.w + x) * 4
+			r += p.pix[i] * w
+			g += p.pix[i+1] * w
+			b += p.pix[i+2] * w
+			a += p.pix[i+3] * w
+		}
+	}
+	if sum := sumX * sumY; sum != 0 {
+		r, g, b, a = r/sum, g/sum, b/sum, a/sum
+	}
+	return r, g, b, a
+}
+
+// remapImage renders a w x h image where each pixel shows the point of img
+// that fn maps its center to, relative to the upper-left corner of img.
+// Where the mapping shrinks the image, the filter is widened by the local
+// scale so all source pixels contribute. Outside of img is transparent.
+func remapImage(img *image.NRGBA64, w, h int, f *resampleFilter, fn func(x, y float64) (u, v float64)) *image.NRGBA64 {
+	return toPremulImage(img).remap(w, h, f, fn).toNRGBA64()
+}
+
+// remap is remapImage for premultiplied images.
+func (p *premulImage) remap(w, h int, f *resampleFilter, fn func(x, y float64) (u, v float64)) *premulImage {
+	return p.render(w, h, f, func(cx, cy float64) (float64, float64, float64) {
+		u, v := fn(cx, cy)
+		ux, vx := fn(cx+1, cy)
+		uy, vy := fn(cx, cy+1)
+		scale := math.Sqrt(math.Abs((ux-u)*(vy-v) - (uy-u)*(vx-v)))
+		if !isFinite(scale) {
+			// The mapping is undefined around this pixel
+			scale = 1
+		}
+		return u, v, scale
+	})
+}
+
+// render returns a w x h image where each pixel shows the point of p that fn
+// maps its center to, sampled with the kernel stretched by the returned scale.
+func (p *premulImage) render(w, h int, f *resampleFilter, fn func(x, y float64) (u, v, scale float64)) *premulImage {
+	dst := newPremulImage(w, h)
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			u, v, scale := fn(float64(x)+0.5, float64(y)+0.5)
+			i := (y*w + x) * 4
+			dst.pix[i], dst.pix[i+1], dst.pix[i+2], dst.pix[i+3] = p.sample(f, u, v, scale)
+		}
+	})
+	return dst
+}
@@ -0,0 +1,342 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// scriptRun is a single execution of a script by a Language.
+//
+// Calls are bound to the run before they are evaluated: each function is
+// registered under an alias that is unique to the run (e.g. "blur#12.0", "#"
+// can't occur in function names of scripts) and the call nodes are renamed
+// to it. The alias casts and validates the arguments exactly like a regular
+// call and then calls the function with access to the run. The aliases are
+// removed when the run ends.
+type scriptRun struct {
+	dsl      *dslCollection
+	id       uint64
+	mu       sync.Mutex
+	aliases  map[string]string // function name (or fused chain) -> alias
+	space    string            // the working space images are loaded into
+	metadata *metadataRegistry // the metadata of the images of the run
+	fs       FileSystem        // the file system files are loaded from and saved to
+	fetcher  *Fetcher          // the fetcher remote files are downloaded with, nil for DefaultFetcher
+}
+
+var scriptRunID atomic.Uint64
+
+func newScriptRun(dsl *dslCollection) *scriptRun {
+	return &scriptRun{
+		dsl:      dsl,
+		id:       scriptRunID.Add(1),
+		aliases:  map[string]string{},
+		space:    WorkingSpaceSRGB,
+		metadata: newMetadataRegistry(),
+		fs:       NewOSFileSystem(""),
+	}
+}
+
+// defaultRun is used by functions called outside of a run, i.e. by the shell.
+var defaultRun = newScriptRun(&dsl)
+
+// runFuncs are the functions that depend on the run calling them.
+// The registered functions use defaultRun.
+var runFuncs map[string]func(r *scriptRun, a []any) (any, error)
+
+func init() {
+	// Set in init since some of the functions run scripts, which refers back to runFuncs
+	runFuncs = map[string]func(r *scriptRun, a []any) (any, error){
+		"load":          func(r *scriptRun, a []any) (any, error) { return r.load(a[0].(string)) },
+		"load-dng":      func(r *scriptRun, a []any) (any, error) { return r.loadDNG(a[0].(string), a[1].(string)) },
+		"working-space": func(r *scriptRun, a []any) (any, error) { return r.workingSpaceSet(a[0].(string)) },
+		"map-frames":    func(r *scriptRun, a []any) (any, error) { return r.mapFrames(a[0].(*Frames), a[1].(string)) },
+		"contact-sheet": func(r *scriptRun, a []any) (any, error) {
+			return r.contactSheet(a[0].([]any), a[1].(int), a[2].(string))
+		},
+		"exif": func(r *scriptRun, a []any) (any, error) { return r.exifMap(a[0].(image.Image)) },
+		"set-exif": func(r *scriptRun, a []any) (any, error) {
+			return r.setEXIF(a[0].(image.Image), a[1].(string), a[2].(string))
+		},
+		"strip-metadata": func(r *scriptRun, a []any) (any, error) { return r.stripMetadata(a[0].(image.Image), a[1].(bool)) },
+		"convert-profile": func(r *scriptRun, a []any) (any, error) {
+			return r.convertColorProfile(a[0].(*image.NRGBA64), a[1].(string))
+		},
+		"color-profile": func(r *scriptRun, a []any) (any, error) { return r.colorProfileName(a[0].(image.Image)) },
+		"save": func(r *scriptRun, a []any) (any, error) {
+			return r.save(a[0].(*image.NRGBA64), a[1].(string), a[2].(string), a[3].(int), a[4].(string), a[5].(string), a[6].(int), a[7].(bool), a[8].(int))
+		},
+		"merge-hdr": func(r *scriptRun, a []any) (any, error) { return r.mergeHDR(a[0].([]any), a[1].([]any)) },
+		"focus-stack": func(r *scriptRun, a []any) (any, error) {
+			return r.focusStack(a[0].([]any), a[1].(float64), a[2].(float64), a[3].(bool), a[4].(bool))
+		},
+		"load-frames": func(r *scriptRun, a []any) (any, error) { return r.loadFrames(a[0].(string)) },
+		"save-animation": func(r *scriptRun, a []any) (any, error) {
+			return r.saveAnimation(a[0].(*Frames), a[1].(string), a[2].(string), a[3].(int), a[4].(bool), a[5].(string), a[6].(int))
+		},
+		"load-csv": func(r *scriptRun, a []any) (any, error) { return r.loadCSV(a[0].(string), a[1].(string), a[2].(bool)) },
+		"load-csv-column": func(r *scriptRun, a []any) (any, error) {
+			return r.loadCSVColumn(a[0].(string), a[1].(int), a[2].(string), a[3].(bool))
+		},
+		"load-csv-row": func(r *scriptRun, a []any) (any, error) {
+			return r.loadCSVRow(a[0].(string), a[1].(int), a[2].(string), a[3].(bool))
+		},
+		"load-layers": func(r *scriptRun, a []any) (any, error) { return r.loadLayers(a[0].(string)) },
+		"save-layers": func(r *scriptRun, a []any) (any, error) { return r.saveLayers(a[0].([]any), a[1].(string)) },
+	}
+}
+
+// run runs a script like dslCollection.run, but with all calls bound to r.
+func (r *scriptRun) run(script, baseDir string, replacements map[string]string, args ...any) (*dslResult, error) {
+	d := r.dsl
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	defer r.close()
+
+	d.macros = make(map[string]*dslMacro)
+
+	script, err := r.expandIncludes(script, baseDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	if script, err = d.parseMacros(script); err != nil {
+		return nil, err
+	}
+	if script, err = d.expandMacros(script); err != nil {
+		return nil, err
+	}
+
+	for s, repl := range replacements {
+		pattern := regexp.MustCompile(`\b` + s + `\b`)
+		var result strings.Builder
+		lastIdx := 0
+		for _, match := range pattern.FindAllStringIndex(script, -1) {
+			result.WriteString(script[lastIdx:match[0]])
+			// Assignments to the name are kept
+			after := strings.TrimSpace(script[match[1]:])
+			if len(after) > 0 && after[0] == ':' {
+				result.WriteString(script[match[0]:match[1]])
+			} else {
+				result.WriteString(repl)
+			}
+			lastIdx = match[1]
+		}
+		result.WriteString(script[lastIdx:])
+		script = result.String()
+	}
+
+	res, err := r.eval(script, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &dslResult{value: res}, nil
+}
+
+// close removes the aliases of the run from the function registry.
+func (r *scriptRun) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, alias := range r.aliases {
+		dsl.funcs.unregister(alias)
+	}
+	r.aliases = map[string]string{}
+}
+
+// bind binds the calls of the statements starting at node to the run.
+func (r *scriptRun) bind(node *dslNode) {
+	for ; node != nil; node = node.next {
+		r.bindNode(node)
+	}
+}
+
+func (r *scriptRun) bindNode(node *dslNode) {
+	if node.kind == nodes.call && r.fuse(node) {
+		return
+	}
+	for _, child := range node.children {
+		r.bindNode(child)
+	}
+	switch node.kind {
+	case nodes.call:
+		// Calls that can't succeed are left as they are, so they fail with the usual error
+		if fn := dsl.funcs.get(node.data); fn != nil && positionalArgs(node) <= len(fn.meta.params) {
+			node.data = r.alias(fn)
+		}
+	case nodes.index:
+		if len(node.children) == 2 {
+			// Single indexes are evaluated by the run, so maps can be indexed by key
+			node.kind = nodes.call
+			node.data = r.indexAlias()
+		} else if len(node.children) > 0 {
+			node.children[0] = r.frameImages(node.children[0])
+		}
+	case nodes.forRange:
+		if len(node.children) > 0 {
+			node.children[0] = r.frameImages(node.children[0])
+		}
+	}
+}
+
+// indexAlias returns the alias that evaluates value[key]. Maps are indexed
+// by key, frames like the list of their images and other lists by position.
+func (r *scriptRun) indexAlias() string {
+	params := []dslParamMeta{{name: "value"}, {name: "key"}}
+	return r.register("index", "", params, nil, func(args ...any) (any, error) {
+		value, key := args[0], args[1]
+		if frames, ok := value.(*Frames); ok {
+			value = frames.Images
+		}
+		if m, ok := value.(map[string]any); ok {
+			k, ok := key.(string)
+			if !ok {
+				return nil, errors.CAST_NOT_POSSIBLE("index key", "string")
+			}
+			v, ok := m[k]
+			if !ok {
+				return nil, dslError("undefined key: %s", k)
+			}
+			return v, nil
+		}
+		list := reflect.ValueOf(value)
+		if !list.IsValid() || list.Kind() != reflect.Slice {
+			return nil, errors.CAST_NOT_POSSIBLE("index base", "slice or slice of slices")
+		}
+		f, err := dsl.toFloat64(key)
+		if err != nil {
+			return nil, err
+		}
+		i := int(f)
+		if i < 0 || i >= list.Len() {
+			return nil, errors.PSR_ARG_REF_OUT_OF_RANGE(i)
+		}
+		return list.Index(i).Interface(), nil
+	})
+}
+
+// frameImages wraps node, the value of an index or a for loop, so frames
+// are indexed and iterated like the list of their images.
+func (r *scriptRun) frameImages(node *dslNode) *dslNode {
+	alias := r.register("frame-images", "", []dslParamMeta{{name: "value"}}, nil, func(args ...any) (any, error) {
+		if frames, ok := args[0].(*Frames); ok {
+			return frames.Images, nil
+		}
+		return args[0], nil
+	})
+	return &dslNode{kind: nodes.call, data: alias, children: []*dslNode{node}, Line: node.Line, Column: node.Column}
+}
+
+// positionalArgs returns the number of positional arguments of a call.
+func positionalArgs(node *dslNode) int {
+	n := 0
+	for _, child := range node.children {
+		if !child.named {
+			n++
+		}
+	}
+	return n
+}
+
+// alias returns the name fn is registered under for this run.
+func (r *scriptRun) alias(fn *dslFnType) string {
+	return r.register(fn.meta.name, fn.meta.desc, fn.meta.params, fn.meta.returns, func(args ...any) (any, error) {
+		return r.call(fn, args)
+	})
+}
+
+// register registers function under a new alias for key, unless key
+// already has one. The parameters are registered without types, so the
+// arguments are passed to function as they are.
+func (r *scriptRun) register(key, desc string, params, returns []dslParamMeta, function func(...any) (any, error)) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if alias, ok := r.aliases[key]; ok {
+		return alias
+	}
+	name, _, _ := strings.Cut(key, " ")
+	alias := fmt.Sprintf("%s#%d.%d", name, r.id, len(r.aliases))
+	untyped := make([]dslParamMeta, len(params))
+	for i, p := range params {
+		p.typ = "any"
+		untyped[i] = p
+	}
+	dsl.funcs.register(alias, desc, untyped, returns, function)
+	r.aliases[key] = alias
+	return alias
+}
+
+// call calls fn with the arguments of a bound call. Linear-light images are
+// passed to the linear-light implementation of fn if it has one.
+//
+// Images returned by fn inherit the metadata of its image arguments.
+func (r *scriptRun) call(fn *dslFnType, args []any) (any, error) {
+	if res, ok, err := r.callLinear(fn, args); ok {
+		return res, err
+	}
+	args, err := r.castArgs(fn, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn.validate(args...); err != nil {
+		return nil, err
+	}
+	var res any
+	if f, ok := runFuncs[fn.meta.name]; ok {
+		res, err = f(r, args)
+	} else {
+		res, err = fn.data(args...)
+	}
+	return r.metadata.inherit(res, args...), err
+}
+
+// castArgs resolves variable references and casts the arguments to the
+// types of the parameters of fn, following the rules of dslFnType.call.
+func (r *scriptRun) castArgs(fn *dslFnType, args []any) ([]any, error) {
+	res := make([]any, len(args))
+	for i, arg := range args {
+		v, err := r.castArg(arg, fn.meta.params[i].typ)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = v
+	}
+	return res, nil
+}
+
+func (r *scriptRun) castArg(arg any, typ string) (any, error) {
+	if typ == "" || typ == "any" {
+		return arg, nil
+	}
+	arg = r.resolveArg(arg, typ)
+	if t := reflect.TypeOf(arg); t != nil && t.String() == typ {
+		return arg, nil
+	}
+	res, err := dsl.castValue(arg, typ)
+	return r.metadata.inherit(res, arg), err
+}
+
+// resolveArg returns the value of the variable arg refers to, if the
+// parameter it's passed to isn't a string.
+func (r *scriptRun) resolveArg(arg any, typ string) any {
+	if str, ok := arg.(string); ok && typ != "string" && r.dsl.vars.has(str) {
+		if v := r.dsl.vars.get(str); v != nil {
+			return v.get()
+		}
+	}
+	return arg
+}
+
+// unregister removes a function from the registry.
+func (r *dslFnRegistry) unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.data, name)
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	delete(r.state.data, name)
+	delete(r.state.new, name)
+}
@@ -0,0 +1,75 @@
+package language
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var reBuiltinName = regexp.MustCompile(`(?m)^\s*@Name:\s*(\S+)`)
+
+// TestRunFuncsCoverRunBuiltins checks that every builtin that delegates to
+// defaultRun has an entry in runFuncs, otherwise scripts would use the state
+// of defaultRun instead of their own run.
+func TestRunFuncsCoverRunBuiltins(t *testing.T) {
+	files, err := filepath.Glob("pxp_*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	found := 0
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Doc == nil || !usesDefaultRun(fn) {
+				continue
+			}
+			m := reBuiltinName.FindStringSubmatch(fn.Doc.Text())
+			if m == nil {
+				continue
+			}
+			found++
+			if _, ok := runFuncs[m[1]]; !ok {
+				t.Errorf("%s: builtin %s uses defaultRun but has no runFuncs entry", file, m[1])
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("no builtins using defaultRun found")
+	}
+}
+
+// usesDefaultRun reports whether the body of fn refers to defaultRun.
+func usesDefaultRun(fn *ast.FuncDecl) bool {
+	uses := false
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "defaultRun" {
+			uses = true
+		}
+		return !uses
+	})
+	return uses
+}
+
+func TestRunFuncsAreBuiltins(t *testing.T) {
+	for name := range runFuncs {
+		if dsl.funcs.get(name) == nil {
+			t.Errorf("runFuncs has an entry for %s, which is not a builtin", name)
+		}
+	}
+}
+
+func TestRunRemovesAliases(t *testing.T) {
+	before := len(dsl.funcs.data)
+	runImage(t, "a: invert($1)\nsepia(brightness(a 1.2))", testImage(4, 4))
+	if after := len(dsl.funcs.data); after != before {
+		t.Errorf("expected %d registered functions after the run, got %d", before, after)
+	}
+}
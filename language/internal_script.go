@@ -0,0 +1,95 @@
+package language
+
+import "fmt"
+
+// eval parses script, binds its calls to the run and evaluates it, sharing
+// variables with the rest of the run. It returns the result of the last
+// statement, its arguments are referenced with $1, $2, etc. Unlike run,
+// eval doesn't lock the collection, so it can be used from within functions
+// while a script is being executed.
+func (r *scriptRun) eval(script string, args ...any) (any, error) {
+	r.dsl.trimSpace(&script)
+	tokenizer := &dslTokenizer{
+		source: script,
+		token:  r.dsl.newToken("", tokens.invalid),
+		tokens: []*dslToken{},
+		state:  r.dsl.newState(),
+	}
+	if err := tokenizer.tokenize(); err != nil {
+		return nil, formatErrorWithPosition(err, tokenizer.source, tokenizer.state.Line, tokenizer.state.Column)
+	}
+	if err := tokenizer.lex(); err != nil {
+		return nil, formatErrorWithPosition(err, tokenizer.source, tokenizer.state.Line, tokenizer.state.Column)
+	}
+
+	parser := &dslParser{
+		dsl:       r.dsl,
+		tokens:    tokenizer.getTokens(),
+		formatted: tokenizer.String(),
+		types:     tokenizer.getTypes(),
+		pos:       -1,
+		args:      args,
+	}
+
+	if len(parser.tokens) == 1 {
+		res, err := parser.evaluateNode(singleTokenNode(parser.tokens[0]))
+		if err != nil {
+			return nil, formatErrorWithPosition(err, tokenizer.source, tokenizer.state.Line, tokenizer.state.Column)
+		}
+		return res, nil
+	}
+
+	var first, last *dslNode
+	for parser.advance() {
+		if parser.curr.Type == tokens.terminator || parser.curr.Type == tokens.comment {
+			continue
+		}
+		node, err := parser.parseNode()
+		if err != nil {
+			return nil, formatErrorWithPosition(err, tokenizer.source, tokenizer.state.Line, tokenizer.state.Column)
+		}
+		if node == nil {
+			continue
+		}
+		if first == nil {
+			first = node
+		} else {
+			last.next = node
+		}
+		last = node
+	}
+	if first == nil {
+		if len(parser.tokens) == 0 {
+			return nil, fmt.Errorf("script is empty")
+		}
+		return nil, fmt.Errorf("no nodes to evaluate: script may be empty or contain only comments")
+	}
+	r.bind(first)
+
+	var res any
+	for node := first; node != nil; node = node.next {
+		var err error
+		if res, err = parser.evaluateNode(node); err != nil {
+			return nil, formatErrorWithPosition(err, tokenizer.source, node.Line, node.Column)
+		}
+	}
+	return res, nil
+}
+
+// singleTokenNode returns the node of a script that consists of a single token.
+func singleTokenNode(token *dslToken) *dslNode {
+	kind := nodes.varRef
+	switch token.Type {
+	case tokens.argRef:
+		kind = nodes.argRef
+	case tokens.integer:
+		kind = nodes.integer
+	case tokens.float:
+		kind = nodes.float
+	case tokens.str:
+		kind = nodes.str
+	case tokens.boolean:
+		kind = nodes.boolean
+	}
+	return &dslNode{kind: kind, data: token.Value, children: []*dslNode{}}
+}
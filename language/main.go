@@ -1,12 +1,18 @@
 package language
 
 import (
+	"fmt"
 	"image"
+	"io"
 )
 
 // Language represents the PixelPipeline Studio language functionality
 type Language struct {
-	dsl *dslCollection
+	dsl      *dslCollection
+	inputs   map[string]any
+	inputErr error // error reading an input, returned by Run
+	fs       FileSystem
+	fetcher  *Fetcher
 }
 
 func Shell() {
@@ -19,18 +25,83 @@ func New() *Language {
 	}
 }
 
+// SetInput binds an in-memory input to a variable that scripts can use by name.
+// Encoded images ([]byte, io.Reader) are decoded like files passed to load
+// and image.Image values are used as they are, other values are passed
+// through unchanged. Inputs are decoded when the script is run, readers
+// are read right away, so the input can be used by any number of runs.
+// Errors reading an input are returned by Run.
+func (l *Language) SetInput(name string, value any) *Language {
+	if l.inputs == nil {
+		l.inputs = map[string]any{}
+	}
+	if r, ok := value.(io.Reader); ok {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			l.inputErr = fmt.Errorf("failed to read input %s: %w", name, err)
+			return l
+		}
+		value = data
+	}
+	l.inputs[name] = value
+	return l
+}
+
+// SetFileSystem sets the file system scripts load, save and include files
+// from, e.g. to sandbox scripts or to serve assets from an embedded bundle.
+// By default the OS file system is used. Remote files (http and https) can
+// only be loaded with the OS file system, unless a fetcher is set.
+func (l *Language) SetFileSystem(fsys FileSystem) *Language {
+	l.fs = fsys
+	return l
+}
+
+// SetFetcher sets the fetcher used to download remote files, e.g. to limit
+// their size or to use a test server. By default DefaultFetcher is used.
+func (l *Language) SetFetcher(f *Fetcher) *Language {
+	l.fetcher = f
+	return l
+}
+
+// Run executes the script. Arguments are referenced as $1, $2, etc. and
+// accept the same in-memory inputs as SetInput, readers are read by the run.
 func (l *Language) Run(script, baseDir string, replacements map[string]string, args ...any) (*dslResult, error) {
+	if l.inputErr != nil {
+		return nil, l.inputErr
+	}
+	r := newScriptRun(l.dsl)
 	l.dsl.storeState()
-	res, err := l.dsl.run(script, baseDir, replacements, false, args...)
+	if l.fs != nil {
+		r.fs = l.fs
+	}
+	r.fetcher = l.fetcher
+	args, err := r.decodeInputs(args, l.inputs)
+	if err != nil {
+		l.dsl.restoreState()
+		return nil, err
+	}
+	res, err := r.run(script, baseDir, replacements, args...)
 	l.dsl.restoreState()
 	if err != nil {
 		return nil, err
 	}
-	switch t := res.value.(type) {
+	src := res.value
+	switch t := src.(type) {
+	case *LinearImage:
+		res.value = l.dsl.convertNRGBA64ToNRGBA(fromLinearImage(t))
 	case *image.RGBA64:
 		res.value = l.dsl.convertRGBA64ToNRGBA(t)
 	case *image.NRGBA64:
 		res.value = l.dsl.convertNRGBA64ToNRGBA(t)
+	case *Mask:
+		res.value = l.dsl.convertNRGBA64ToNRGBA(t.Image())
+	case *Alignment:
+		res.value = l.dsl.convertNRGBA64ToNRGBA(t.Image)
+	}
+	// The metadata of the run is published for MetadataOf
+	r.metadata.inherit(res.value, src)
+	if m, ok := r.metadata.get(res.value); ok {
+		resultMetadata.set(res.value, m)
 	}
 	return res, err
 }
@@ -47,6 +118,8 @@ func (l *Language) GetLanguageDefinition() (map[string]interface{}, error) {
 
 func ImageTo8Bit(img image.Image) image.Image {
 	switch t := img.(type) {
+	case *LinearImage:
+		img = dsl.convertNRGBA64ToNRGBA(fromLinearImage(t))
 	case *image.RGBA64:
 		img = dsl.convertRGBA64ToNRGBA(t)
 	case *image.NRGBA64:
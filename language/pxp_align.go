@@ -0,0 +1,135 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/toxyl/math"
+)
+
+// phaseCorrelationMaxSize is the size images are reduced to before their
+// translation is estimated, the sub-pixel peak keeps the result precise.
+const phaseCorrelationMaxSize = 1024
+
+// phaseCorrelate returns the translation that moves img onto ref. The
+// normalized cross-power spectrum of both images has a single peak at the
+// translation, which makes the estimate robust against noise and differences
+// in brightness.
+func phaseCorrelate(ref, img *image.NRGBA64) (dx, dy float64) {
+	s := math.Min(1, float64(phaseCorrelationMaxSize)/float64(math.Max(math.Max(ref.Rect.Dx(), ref.Rect.Dy()), math.Max(img.Rect.Dx(), img.Rect.Dy()))))
+	reduce := func(i *image.NRGBA64) *grayImage {
+		if s < 1 {
+			i = resample(i, math.Max(int(float64(i.Rect.Dx())*s), 1), math.Max(int(float64(i.Rect.Dy())*s), 1), resampleFilters["area"])
+		}
+		return toGrayImage(i)
+	}
+	gr, gi := reduce(ref), reduce(img)
+	w, h := nextPowerOf2(math.Max(gr.w, gi.w)), nextPowerOf2(math.Max(gr.h, gi.h))
+
+	spectrum := func(g *grayImage) []complex128 {
+		mean := 0.0
+		for _, v := range g.pix {
+			mean += float64(v)
+		}
+		mean /= float64(len(g.pix))
+		// A Hann window fades out the edges, so they don't correlate
+		hann := func(i, n int) float64 { return 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(n)) }
+		a := make([]complex128, w*h)
+		for y := range g.h {
+			for x := range g.w {
+				a[y*w+x] = complex((float64(g.pix[y*g.w+x])-mean)*hann(x, g.w)*hann(y, g.h), 0)
+			}
+		}
+		fft2(a, w, h, false)
+		return a
+	}
+	a, b := spectrum(gr), spectrum(gi)
+	for i := range a {
+		c := a[i] * complex(real(b[i]), -imag(b[i]))
+		if m := math.Hypot(real(c), imag(c)); m > 1e-12 {
+			c /= complex(m, 0)
+		}
+		a[i] = c
+	}
+	fft2(a, w, h, true)
+
+	peak := 0
+	for i := range a {
+		if real(a[i]) > real(a[peak]) {
+			peak = i
+		}
+	}
+	px, py := peak%w, peak/w
+	at := func(x, y int) float64 { return real(a[((y+h)%h)*w+(x+w)%w]) }
+	// Refine the peak with its larger neighbour, a sub-pixel shift spreads
+	// the peak over both in the ratio of their distances (Foroosh et al.)
+	subpixel := func(l, c, r float64) float64 {
+		if r > l && r > 0 {
+			return r / (r + c)
+		} else if l > 0 {
+			return -l / (l + c)
+		}
+		return 0
+	}
+	fx := float64(px) + subpixel(at(px-1, py), at(px, py), at(px+1, py))
+	fy := float64(py) + subpixel(at(px, py-1), at(px, py), at(px, py+1))
+	// Shifts beyond half the size wrap around
+	if fx > float64(w)/2 {
+		fx -= float64(w)
+	}
+	if fy > float64(h)/2 {
+		fy -= float64(h)
+	}
+	return fx / s, fy / s
+}
+
+// estimateAlignment returns the transformation of the given mode
+// (translation, similarity or affine) that maps img onto ref.
+func estimateAlignment(ref, img *image.NRGBA64, mode string) (*Matrix, error) {
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case "translation":
+		dx, dy := phaseCorrelate(ref, img)
+		return NewAffineMatrix(1, 0, dx, 0, 1, dy), nil
+	case "similarity", "affine":
+		fit, samples := fitSimilarity, 2
+		if mode == "affine" {
+			fit, samples = fitAffine, 3
+		}
+		fr, sr := detectFeatures(ref)
+		fi, si := detectFeatures(img)
+		pr, pi := matchKeypoints(fr, fi)
+		m, in, err := ransac(pi, pr, samples, fit, 3/math.Min(sr, si), 1000)
+		if err != nil || len(in) < minInliers {
+			return nil, fmt.Errorf("not enough matching features to align the images")
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown alignment mode: %s (supported: translation, similarity, affine)", mode)
+}
+
+// @Name: align
+// @Desc: Aligns an image to a reference image, e.g. exposure brackets or before/after shots taken without a tripod. The result has the size of the reference, uncovered areas are transparent. It can be used as image, and as matrix to apply the same alignment to other images or masks, e.g. warp-affine(mask result).
+// @Param:      ref     - -   -               The reference image
+// @Param:      img     - -   -               The image to align
+// @Param:      mode    - -   "translation"   The transformation to estimate: translation (using phase correlation, robust against noise and brightness changes), similarity (translation, rotation and uniform scale) or affine (also shear and non-uniform scale)
+// @Param:      filter  - -   "bicubic"       The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -   -               The aligned image with the transformation that maps img onto ref
+func align(ref *image.NRGBA64, img *image.NRGBA64, mode string, filter string) (*Alignment, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	m, err := estimateAlignment(ref, img, mode)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := m.Invert()
+	if err != nil {
+		return nil, err
+	}
+	return &Alignment{
+		Image:     warpImage(img, inv, ref.Rect.Dx(), ref.Rect.Dy(), f),
+		Transform: m,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// @Name: load-frames
+// @Desc: Loads all frames of an animation (GIF, APNG or WebP). Multi-page TIFFs return one frame per page, other images a single frame.
+// @Param:      path    - -   -   Path to the animation
+// @Returns:    result  - -   -   The frames
+func loadFrames(path string) (*Frames, error) {
+	return defaultRun.loadFrames(path)
+}
+
+func (r *scriptRun) loadFrames(path string) (*Frames, error) {
+	_, data, err := r.loadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load animation: %v", err)
+	}
+	return DecodeFrames(data)
+}
+
+// @Name: make-frames
+// @Desc: Creates an animation from a list of images
+// @Param:      images  - -   -   The images to use as frames
+// @Param:      delay   "ms" 1..60000 100  The delay of each frame
+// @Returns:    result  - -   -   The frames
+func makeFrames(images []any, delay int) (*Frames, error) {
+	frames := NewFrames()
+	for i, v := range images {
+		img, ok := v.(image.Image)
+		if !ok {
+			return nil, fmt.Errorf("item %d is not an image", i)
+		}
+		frames.Add(imageToNRGBA64(img), delay)
+	}
+	return frames, nil
+}
+
+// @Name: frame-count
+// @Desc: Returns the number of frames of an animation
+// @Param:      frames  - -   -   The frames
+// @Returns:    result  - -   -   The number of frames
+func frameCount(frames *Frames) (int, error) {
+	return frames.Len(), nil
+}
+
+// @Name: frame
+// @Desc: Returns a frame of an animation
+// @Param:      frames  - -   -   The frames
+// @Param:      i       - -   0   The index of the frame (negative values count from the end)
+// @Returns:    result  - -   -   The frame
+func frame(frames *Frames, i int) (*image.NRGBA64, error) {
+	if i < 0 {
+		i += frames.Len()
+	}
+	if i < 0 || i >= frames.Len() {
+		return nil, fmt.Errorf("frame %d out of range (0..%d)", i, frames.Len()-1)
+	}
+	return frames.Images[i], nil
+}
+
+// @Name: map-frames
+// @Desc: Runs a pipeline on every frame of an animation. The pipeline is a script that references the frame as $1 and its index as $2, its last statement must return an image.
+// @Param:      frames    - -   -   The frames
+// @Param:      pipeline  - -   -   The script to run for every frame, e.g. "blur-gaussian($1 2)"
+// @Returns:    result    - -   -   The processed frames
+func mapFrames(frames *Frames, pipeline string) (*Frames, error) {
+	return defaultRun.mapFrames(frames, pipeline)
+}
+
+func (r *scriptRun) mapFrames(frames *Frames, pipeline string) (*Frames, error) {
+	res := frames.Clone()
+	for i, img := range frames.Images {
+		v, err := r.eval(pipeline, img, float64(i))
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+		out, ok := v.(image.Image)
+		if !ok {
+			return nil, fmt.Errorf("frame %d: pipeline returned %T instead of an image", i, v)
+		}
+		res.Images[i] = imageToNRGBA64(out)
+	}
+	return res, nil
+}
+
+// @Name: save-animation
+// @Desc: Saves an animation as GIF, APNG or lossless WebP. Only the changed area of each frame is stored and frames without changes are merged.
+// @Param:      frames  - -         -           The frames to save
+// @Param:      path    - -         -           Path where to save
+// @Param:      format  - -         ""          The format (gif, apng, webp), empty to use the file extension
+// @Param:      colors  - 2..256    256         The size of the GIF palettes
+// @Param:      dither  -           true        Whether to dither GIFs
+// @Param:      palette - -         "local"     The GIF palette mode (local: one optimized palette per frame, global: one palette for all frames)
+// @Param:      depth   - 8..16     8           The bits per channel of APNGs (8 or 16)
+// @Returns:    result  - -         -           The frames
+func saveAnimation(frames *Frames, path string, format string, colors int, dither bool, palette string, depth int) (*Frames, error) {
+	return defaultRun.saveAnimation(frames, path, format, colors, dither, palette, depth)
+}
+
+func (r *scriptRun) saveAnimation(frames *Frames, path string, format string, colors int, dither bool, palette string, depth int) (*Frames, error) {
+	opts := AnimationOptions{
+		Format:  format,
+		Colors:  colors,
+		Dither:  dither,
+		Palette: palette,
+		Depth:   depth,
+	}
+	if opts.Format == "" {
+		if opts.Format = AnimationFormatFromPath(path); opts.Format == "" {
+			return nil, fmt.Errorf("can't determine animation format from path: %s", path)
+		}
+	}
+	return frames, r.writeFile(path, func(w io.Writer) error {
+		return EncodeAnimation(w, frames, opts)
+	})
+}
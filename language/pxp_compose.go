@@ -0,0 +1,231 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/toxyl/math"
+	xdraw "golang.org/x/image/draw"
+)
+
+// composeImages converts a list of script values to images.
+func composeImages(images []any) ([]*image.NRGBA64, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images given")
+	}
+	res := make([]*image.NRGBA64, len(images))
+	for i, v := range images {
+		img, ok := v.(image.Image)
+		if !ok {
+			return nil, fmt.Errorf("item %d is not an image", i)
+		}
+		res[i] = imageToNRGBA64(img)
+	}
+	return res, nil
+}
+
+// cellSize returns the size of the largest image, which all images are scaled to fit.
+func cellSize(imgs []*image.NRGBA64) (w, h int) {
+	for _, img := range imgs {
+		w = math.Max(w, img.Rect.Dx())
+		h = math.Max(h, img.Rect.Dy())
+	}
+	return w, h
+}
+
+// fitCell scales img to the largest size fitting into w x h that preserves its aspect ratio.
+func fitCell(img *image.NRGBA64, w, h int) *image.NRGBA64 {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	if iw == 0 || ih == 0 {
+		return img
+	}
+	s := math.Min(float64(w)/float64(iw), float64(h)/float64(ih))
+	nw := math.Max(1, int(math.Round(float64(iw)*s)))
+	nh := math.Max(1, int(math.Round(float64(ih)*s)))
+	if nw == iw && nh == ih {
+		return img
+	}
+	res := I(nw, nh)
+	xdraw.CatmullRom.Scale(res, res.Rect, img, img.Rect, xdraw.Src, nil)
+	return res
+}
+
+// drawCentered draws img over dst, centered within r.
+func drawCentered(dst *image.NRGBA64, r image.Rectangle, img *image.NRGBA64) {
+	x := r.Min.X + (r.Dx()-img.Rect.Dx())/2
+	y := r.Min.Y + (r.Dy()-img.Rect.Dy())/2
+	draw.Draw(dst, image.Rect(x, y, x+img.Rect.Dx(), y+img.Rect.Dy()), img, img.Rect.Min, draw.Over)
+}
+
+// canvas returns a w x h image filled with bg (a non-premultiplied color like all colors in scripts).
+func canvas(w, h int, bg color.RGBA64) *image.NRGBA64 {
+	res := I(w, h)
+	draw.Draw(res, res.Rect, image.NewUniform(color.NRGBA64(bg)), image.Point{}, draw.Src)
+	return res
+}
+
+// backgroundColor returns the color of a bg parameter, which is either a color
+// or "transparent".
+func backgroundColor(bg any) (color.RGBA64, error) {
+	if s, ok := bg.(string); ok && strings.EqualFold(strings.TrimSpace(s), "transparent") {
+		return color.RGBA64{}, nil
+	}
+	c, err := dsl.cast(bg, "color.RGBA64")
+	if err != nil {
+		return color.RGBA64{}, fmt.Errorf("the background has to be a color or \"transparent\": %w", err)
+	}
+	return c.(color.RGBA64), nil
+}
+
+// gridLayout returns the number of columns and rows used to arrange n cells.
+// Without a column count the grid is made as square as possible.
+func gridLayout(n, cols int) (int, int) {
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	cols = math.Min(cols, n)
+	return cols, (n + cols - 1) / cols
+}
+
+// @Name: grid-compose
+// @Desc: Arranges images in a grid. All images are scaled to fit the size of the largest image and centered in their cells.
+// @Param:      images  - -       -   The images to arrange
+// @Param:      cols    - 0..     0   The number of columns (0 to make the grid as square as possible)
+// @Param:      gap     "px" 0..  0   The space between cells
+// @Param:      bg      - -       "transparent"   The background color
+// @Returns:    result  - -       -   The composed image
+func gridCompose(images []any, cols int, gap int, bg any) (*image.NRGBA64, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	c, err := backgroundColor(bg)
+	if err != nil {
+		return nil, err
+	}
+	gap = math.Max(gap, 0)
+	cw, ch := cellSize(imgs)
+	cols, rows := gridLayout(len(imgs), cols)
+	res := canvas(cols*cw+(cols-1)*gap, rows*ch+(rows-1)*gap, c)
+	parallelRows(0, len(imgs), func(i int) {
+		x, y := (i%cols)*(cw+gap), (i/cols)*(ch+gap)
+		drawCentered(res, image.Rect(x, y, x+cw, y+ch), fitCell(imgs[i], cw, ch))
+	})
+	return res, nil
+}
+
+// @Name: hstack
+// @Desc: Places images next to each other. All images are scaled to the height of the tallest image.
+// @Param:      images  - -       -   The images to stack (left to right)
+// @Param:      gap     "px" 0..  0   The space between images
+// @Param:      bg      - -       "transparent"   The background color
+// @Returns:    result  - -       -   The composed image
+func hstack(images []any, gap int, bg any) (*image.NRGBA64, error) {
+	return stack(images, gap, bg, true)
+}
+
+// @Name: vstack
+// @Desc: Places images below each other. All images are scaled to the width of the widest image.
+// @Param:      images  - -       -   The images to stack (top to bottom)
+// @Param:      gap     "px" 0..  0   The space between images
+// @Param:      bg      - -       "transparent"   The background color
+// @Returns:    result  - -       -   The composed image
+func vstack(images []any, gap int, bg any) (*image.NRGBA64, error) {
+	return stack(images, gap, bg, false)
+}
+
+func stack(images []any, gap int, bg any, horizontal bool) (*image.NRGBA64, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	c, err := backgroundColor(bg)
+	if err != nil {
+		return nil, err
+	}
+	gap = math.Max(gap, 0)
+	cw, ch := cellSize(imgs)
+	scaled := make([]*image.NRGBA64, len(imgs))
+	length := gap * (len(imgs) - 1)
+	for i, img := range imgs {
+		if horizontal {
+			scaled[i] = fitCell(img, math.MaxInt32, ch)
+			length += scaled[i].Rect.Dx()
+		} else {
+			scaled[i] = fitCell(img, cw, math.MaxInt32)
+			length += scaled[i].Rect.Dy()
+		}
+	}
+	var res *image.NRGBA64
+	if horizontal {
+		res = canvas(length, ch, c)
+	} else {
+		res = canvas(cw, length, c)
+	}
+	pos := 0
+	for _, img := range scaled {
+		if horizontal {
+			drawCentered(res, image.Rect(pos, 0, pos+img.Rect.Dx(), ch), img)
+			pos += img.Rect.Dx() + gap
+		} else {
+			drawCentered(res, image.Rect(0, pos, cw, pos+img.Rect.Dy()), img)
+			pos += img.Rect.Dy() + gap
+		}
+	}
+	return res, nil
+}
+
+const contactSheetGap = 8
+
+// @Name: contact-sheet
+// @Desc: Arranges images in a grid on a white background with a caption below each image. The caption is the result of labelFn, a script that references the image as $1 and its index as $2 (e.g. "$2" to caption images with their index). Without labelFn images are numbered starting at 1.
+// @Param:      images   - -     -   The images to arrange
+// @Param:      cols     - 0..   0   The number of columns (0 to make the grid as square as possible)
+// @Param:      labelFn  - -     ""  The script returning the caption of an image
+// @Returns:    result   - -     -   The contact sheet
+func contactSheet(images []any, cols int, labelFn string) (*image.NRGBA64, error) {
+	return defaultRun.contactSheet(images, cols, labelFn)
+}
+
+func (r *scriptRun) contactSheet(images []any, cols int, labelFn string) (*image.NRGBA64, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	cw, ch := cellSize(imgs)
+	cols, rows := gridLayout(len(imgs), cols)
+
+	// Captions are rendered first since they determine the height of the rows
+	captions := make([]*image.NRGBA64, len(imgs))
+	captionH := 0
+	for i := range imgs {
+		label := fmt.Sprint(i + 1)
+		if labelFn != "" {
+			v, err := r.eval(labelFn, images[i], float64(i))
+			if err != nil {
+				return nil, fmt.Errorf("label of image %d: %w", i, err)
+			}
+			label = fmt.Sprint(v)
+		}
+		if captions[i], err = text(label, color.RGBA64{A: 0xFFFF}, color.RGBA64{}); err != nil {
+			return nil, err
+		}
+		if captions[i].Rect.Dx() > cw {
+			captions[i] = captions[i].SubImage(image.Rect(0, 0, cw, captions[i].Rect.Dy())).(*image.NRGBA64)
+		}
+		captionH = math.Max(captionH, captions[i].Rect.Dy())
+	}
+
+	const gap = contactSheetGap
+	rowH := ch + gap/2 + captionH
+	res := canvas(cols*cw+(cols+1)*gap, rows*rowH+(rows+1)*gap, color.RGBA64{0xFFFF, 0xFFFF, 0xFFFF, 0xFFFF})
+	parallelRows(0, len(imgs), func(i int) {
+		x, y := gap+(i%cols)*(cw+gap), gap+(i/cols)*(rowH+gap)
+		drawCentered(res, image.Rect(x, y, x+cw, y+ch), fitCell(imgs[i], cw, ch))
+		drawCentered(res, image.Rect(x, y+ch+gap/2, x+cw, y+rowH), captions[i])
+	})
+	return res, nil
+}
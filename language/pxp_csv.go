@@ -15,7 +15,11 @@ import (
 // @Param:      hasHeader         true  Whether the first row is a header row
 // @Returns:    result  	- -   -   	A 2D slice with the data
 func loadCSV(path, sep string, hasHeader bool) ([][]float64, error) {
-	_, data, err := loadFile(path)
+	return defaultRun.loadCSV(path, sep, hasHeader)
+}
+
+func (r *scriptRun) loadCSV(path, sep string, hasHeader bool) ([][]float64, error) {
+	_, data, err := r.loadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +55,11 @@ func loadCSV(path, sep string, hasHeader bool) ([][]float64, error) {
 // @Param:      hasHeader         true  Whether the first row is a header row
 // @Returns:    result  	- -   -   	A slice with the data
 func loadCSVColumn(path string, index int, sep string, hasHeader bool) ([]float64, error) {
-	_, data, err := loadFile(path)
+	return defaultRun.loadCSVColumn(path, index, sep, hasHeader)
+}
+
+func (r *scriptRun) loadCSVColumn(path string, index int, sep string, hasHeader bool) ([]float64, error) {
+	_, data, err := r.loadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +95,11 @@ func loadCSVColumn(path string, index int, sep string, hasHeader bool) ([]float6
 // @Param:      hasHeader         true  Whether the first row is a header row
 // @Returns:    result  	- -   -   	A slice with the data
 func loadCSVRow(path string, index int, sep string, hasHeader bool) ([]float64, error) {
-	_, data, err := loadFile(path)
+	return defaultRun.loadCSVRow(path, index, sep, hasHeader)
+}
+
+func (r *scriptRun) loadCSVRow(path string, index int, sep string, hasHeader bool) ([]float64, error) {
+	_, data, err := r.loadFile(path)
 	if err != nil {
 		return nil, err
 	}
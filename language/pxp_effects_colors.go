@@ -12,13 +12,18 @@ import (
 // @Param:      img     - -   -   The image to invert
 // @Returns:    result  - -   -   The inverted image
 func colorInvert(img *image.NRGBA64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorInvertKernel(), NumColorConversionWorkers), nil
+}
+
+// colorInvertKernel returns the per-pixel kernel of colorInvert.
+func colorInvertKernel() dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		r = 0xFFFF - r1
 		g = 0xFFFF - g1
 		b = 0xFFFF - b1
 		a = a1 // Keep original alpha
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: grayscale
@@ -26,7 +31,12 @@ func colorInvert(img *image.NRGBA64) (*image.NRGBA64, error) {
 // @Param:      img     - -   -   The image to grayscale
 // @Returns:    result  - -   -   The grayscaled image
 func colorGrayscale(img *image.NRGBA64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorGrayscaleKernel(), NumColorConversionWorkers), nil
+}
+
+// colorGrayscaleKernel returns the per-pixel kernel of colorGrayscale.
+func colorGrayscaleKernel() dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Using luminosity method: 0.21 R + 0.72 G + 0.07 B
 		gray := uint32(float64(r1)*0.21 + float64(g1)*0.72 + float64(b1)*0.07)
 		r = gray
@@ -34,7 +44,7 @@ func colorGrayscale(img *image.NRGBA64) (*image.NRGBA64, error) {
 		b = gray
 		a = a1 // Keep original alpha
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: sepia
@@ -42,7 +52,12 @@ func colorGrayscale(img *image.NRGBA64) (*image.NRGBA64, error) {
 // @Param:      img     - -   -   The image to change to sepia tone
 // @Returns:    result  - -   -   The sepia-toned image
 func colorSepia(img *image.NRGBA64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorSepiaKernel(), NumColorConversionWorkers), nil
+}
+
+// colorSepiaKernel returns the per-pixel kernel of colorSepia.
+func colorSepiaKernel() dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		rf := float64(r1)
 		gf := float64(g1)
 		bf := float64(b1)
@@ -51,7 +66,7 @@ func colorSepia(img *image.NRGBA64) (*image.NRGBA64, error) {
 		b = uint32(math.Min((rf*0.272)+(gf*0.534)+(bf*0.131), 65535))
 		a = a1 // Keep original alpha
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: brightness
@@ -60,13 +75,18 @@ func colorSepia(img *image.NRGBA64) (*image.NRGBA64, error) {
 // @Param:      factor  - 0..2  0   The change factor
 // @Returns:    result  - -   	-   The image with brightness changed
 func colorBrightness(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorBrightnessKernel(factor), NumColorConversionWorkers), nil
+}
+
+// colorBrightnessKernel returns the per-pixel kernel of colorBrightness.
+func colorBrightnessKernel(factor float64) dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		r = uint32(math.Min(float64(r1)*factor, 0xFFFF))
 		g = uint32(math.Min(float64(g1)*factor, 0xFFFF))
 		b = uint32(math.Min(float64(b1)*factor, 0xFFFF))
 		a = a1 // Keep original alpha
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: colorize
@@ -75,6 +95,11 @@ func colorBrightness(img *image.NRGBA64, factor float64) (*image.NRGBA64, error)
 // @Param:      col  	- - -   The color that determines the hue to use for colorization
 // @Returns:    result  - - -	The colorized image
 func colorColorize(img *image.NRGBA64, col color.RGBA64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorColorizeKernel(col), NumColorConversionWorkers), nil
+}
+
+// colorColorizeKernel returns the per-pixel kernel of colorColorize.
+func colorColorizeKernel(col color.RGBA64) dslPixelProcessor {
 	// Convert target color to normalized RGB and get alpha
 	targetR := float64(col.R) / 65535.0
 	targetG := float64(col.G) / 65535.0
@@ -84,7 +109,7 @@ func colorColorize(img *image.NRGBA64, col color.RGBA64) (*image.NRGBA64, error)
 	// Convert target color to HSL to get hue and saturation
 	targetH, targetS, targetL := convertRGBToHSLFloat(targetR, targetG, targetB)
 
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Convert pixel to normalized RGB
 		rf := float64(r1) / 65535.0
 		gf := float64(g1) / 65535.0
@@ -111,7 +136,7 @@ func colorColorize(img *image.NRGBA64, col color.RGBA64) (*image.NRGBA64, error)
 		b = uint32(math.Clamp(bf*(1-alpha)+newB*alpha, 0, 1) * 65535.0)
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: contrast
@@ -120,15 +145,20 @@ func colorColorize(img *image.NRGBA64, col color.RGBA64) (*image.NRGBA64, error)
 // @Param:      factor  - 0..2  1   The contrast factor (0 = gray, 1 = unchanged, 2 = maximum)
 // @Returns:    result  - -   	-   The contrast-adjusted image
 func colorContrast(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorContrastKernel(factor), NumColorConversionWorkers), nil
+}
+
+// colorContrastKernel returns the per-pixel kernel of colorContrast.
+func colorContrastKernel(factor float64) dslPixelProcessor {
 	mid := float64(0x7FFF)
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Apply contrast formula: ((color - mid) * factor) + mid
 		r = uint32(math.Clamp(((float64(r1)-mid)*factor)+mid, 0, 0xFFFF))
 		g = uint32(math.Clamp(((float64(g1)-mid)*factor)+mid, 0, 0xFFFF))
 		b = uint32(math.Clamp(((float64(b1)-mid)*factor)+mid, 0, 0xFFFF))
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: saturation
@@ -137,7 +167,12 @@ func colorContrast(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
 // @Param:      factor  - 0..2  1   The saturation factor (0 = grayscale, 1 = unchanged, 2 = super saturated)
 // @Returns:    result  - -   	-   The saturation-adjusted image
 func colorSaturation(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorSaturationKernel(factor), NumColorConversionWorkers), nil
+}
+
+// colorSaturationKernel returns the per-pixel kernel of colorSaturation.
+func colorSaturationKernel(factor float64) dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Convert to HSL
 		h, s, l := convertRGBToHSLFloat(float64(r1)/65535.0, float64(g1)/65535.0, float64(b1)/65535.0)
 
@@ -152,7 +187,7 @@ func colorSaturation(img *image.NRGBA64, factor float64) (*image.NRGBA64, error)
 		b = uint32(bf * 65535.0)
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: opacity
@@ -161,11 +196,16 @@ func colorSaturation(img *image.NRGBA64, factor float64) (*image.NRGBA64, error)
 // @Param:      amount   - 0..1  	1   The opacity amount (0 = fully transparent, 1 = unchanged)
 // @Returns:    result   - -   		-   The opacity-adjusted image
 func colorOpacity(img *image.NRGBA64, amount float64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorOpacityKernel(amount), NumColorConversionWorkers), nil
+}
+
+// colorOpacityKernel returns the per-pixel kernel of colorOpacity.
+func colorOpacityKernel(amount float64) dslPixelProcessor {
 	alphaMultiplier := uint32(amount * 65535.0)
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Multiply existing alpha by the opacity amount while preserving color
 		return r1, g1, b1, uint32((a1 * alphaMultiplier) / 65535)
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: chromatic-aberration
@@ -213,8 +253,13 @@ func colorChromaticAberration(img *image.NRGBA64, amount float64) (*image.NRGBA6
 // @Param:      angle   "°" 0..360 	0  The angle in degrees (0-360)
 // @Returns:    result  - 	-   	-   The hue-rotated image
 func colorHueRotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorHueRotateKernel(angle), NumColorConversionWorkers), nil
+}
+
+// colorHueRotateKernel returns the per-pixel kernel of colorHueRotate.
+func colorHueRotateKernel(angle float64) dslPixelProcessor {
 	hueShift := angle / 360.0 // Normalize angle to 0.0-1.0 range for HSL calculation
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		h, s, l := convertRGBToHSLFloat(float64(r1)/65535.0, float64(g1)/65535.0, float64(b1)/65535.0)
 
 		// Rotate hue
@@ -232,7 +277,7 @@ func colorHueRotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
 		b = uint32(bf * 65535.0)
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: color-balance
@@ -243,13 +288,18 @@ func colorHueRotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
 // @Param:      bFactor   - 0..2  	1   Blue channel adjustment factor
 // @Returns:    result    - -   	-   The color-balanced image
 func colorBalance(img *image.NRGBA64, rFactor, gFactor, bFactor float64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorBalanceKernel(rFactor, gFactor, bFactor), NumColorConversionWorkers), nil
+}
+
+// colorBalanceKernel returns the per-pixel kernel of colorBalance.
+func colorBalanceKernel(rFactor, gFactor, bFactor float64) dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		r = uint32(math.Clamp(float64(r1)*rFactor, 0, 0xFFFF))
 		g = uint32(math.Clamp(float64(g1)*gFactor, 0, 0xFFFF))
 		b = uint32(math.Clamp(float64(b1)*bFactor, 0, 0xFFFF))
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: posterize
@@ -258,16 +308,21 @@ func colorBalance(img *image.NRGBA64, rFactor, gFactor, bFactor float64) (*image
 // @Param:      levels  - 2..16 4   Number of color levels per channel (2-16)
 // @Returns:    result  - -   	-   The posterized image
 func colorPosterize(img *image.NRGBA64, levels int) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorPosterizeKernel(levels), NumColorConversionWorkers), nil
+}
+
+// colorPosterizeKernel returns the per-pixel kernel of colorPosterize.
+func colorPosterizeKernel(levels int) dslPixelProcessor {
 	numLevels := float64(levels)
 	levelStep := 65535.0 / (numLevels - 1) // Size of each color step
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Quantize each channel
 		r = uint32(math.Round(float64(r1)/levelStep) * levelStep)
 		g = uint32(math.Round(float64(g1)/levelStep) * levelStep)
 		b = uint32(math.Round(float64(b1)/levelStep) * levelStep)
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: threshold
@@ -276,8 +331,13 @@ func colorPosterize(img *image.NRGBA64, levels int) (*image.NRGBA64, error) {
 // @Param:      level   - 0..1 	0.5 The brightness threshold
 // @Returns:    result  - -   	-   The thresholded (black and white) image
 func colorThreshold(img *image.NRGBA64, level float64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorThresholdKernel(level), NumColorConversionWorkers), nil
+}
+
+// colorThresholdKernel returns the per-pixel kernel of colorThreshold.
+func colorThresholdKernel(level float64) dslPixelProcessor {
 	thresholdLevel := float64(level) * 65535.0
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		// Using luminosity method (same as grayscale)
 		if float64(r1)*0.21+float64(g1)*0.72+float64(b1)*0.07 > thresholdLevel {
 			r, g, b, a = 0xFFFF, 0xFFFF, 0xFFFF, a1
@@ -285,7 +345,7 @@ func colorThreshold(img *image.NRGBA64, level float64) (*image.NRGBA64, error) {
 			r, g, b, a = 0, 0, 0, a1
 		}
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: edge-detect
@@ -402,7 +462,12 @@ func colorVignette(img *image.NRGBA64, strength float64, falloff float64) (*imag
 // @Param:      factor  - -1..1 0   The vibrance adjustment factor (-1 = less vibrant, 0 = unchanged, 1 = more vibrant)
 // @Returns:    result  - -   	-   The vibrance-adjusted image
 func colorVibrance(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return dsl.parallelProcessNRGBA64(img, colorVibranceKernel(factor), NumColorConversionWorkers), nil
+}
+
+// colorVibranceKernel returns the per-pixel kernel of colorVibrance.
+func colorVibranceKernel(factor float64) dslPixelProcessor {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		h, s, l := convertRGBToHSLFloat(float64(r1)/65535.0, float64(g1)/65535.0, float64(b1)/65535.0)
 
 		// Calculate saturation adjustment - more effect on less saturated colors
@@ -428,7 +493,7 @@ func colorVibrance(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
 		b = uint32(bf * 65535.0)
 		a = a1
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: exposure
@@ -437,26 +502,31 @@ func colorVibrance(img *image.NRGBA64, factor float64) (*image.NRGBA64, error) {
 // @Param:      level   - -2..2 0   The exposure level adjustment (-2 = much darker, 0 = unchanged, 2 = much brighter)
 // @Returns:    result  - -   	-   The exposure-adjusted image
 func colorExposure(img *image.NRGBA64, level float64) (*image.NRGBA64, error) {
+	return dsl.parallelProcessNRGBA64(img, colorExposureKernel(level), NumColorConversionWorkers), nil
+}
+
+// colorExposureKernel returns the per-pixel kernel of colorExposure.
+func colorExposureKernel(level float64) dslPixelProcessor {
 	factor := math.Pow(2, level) // Exponential adjustment factor
-	return dsl.parallelProcessNRGBA64(img, func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
+	return func(r1, g1, b1, a1 uint32) (r, g, b, a uint32) {
 		r = uint32(math.Clamp(float64(r1)*factor, 0, 0xFFFF))
 		g = uint32(math.Clamp(float64(g1)*factor, 0, 0xFFFF))
 		b = uint32(math.Clamp(float64(b1)*factor, 0, 0xFFFF))
 		a = a1 // Keep original alpha
 		return
-	}, NumColorConversionWorkers), nil
+	}
 }
 
 // @Name: select-hue
-// @Desc: Selects a specific hue from the image and makes everything else transparent
+// @Desc: Selects a specific hue from the image. Used as an image, the mask keeps the selected pixels and makes everything else transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.
 // @Param:      img            - -   		-   The image to process (16-bit)
 // @Param:      hue            "°" 0..360 	0   The target hue to keep (in degrees)
 // @Param:      toleranceLeft  "°" 0..180 	30  How much to include to the left (lower hue, in degrees)
 // @Param:      toleranceRight "°" 0..180 	30  How much to include to the right (higher hue, in degrees)
 // @Param:      softness       - 0..1   	0.5 How soft the transition should be (0 = hard cut, 1 = very soft)
 // @Param:      minSaturation  - 0..1 0.15 The minimum saturation required to keep a pixel (smooth fade below)
-// @Returns:    result         - -   		-   The image with only the selected hue visible (16-bit)
-func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, softness, minSaturation float64) (*image.NRGBA64, error) {
+// @Returns:    result         - -   		-   The mask of the selected hue
+func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, softness, minSaturation float64) (*Mask, error) {
 	// Convert hue and tolerances to 0-1 range for HSL calculations
 	targetHue := hue / 360.0
 	tolL := toleranceLeft / 360.0
@@ -466,7 +536,8 @@ func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, soft
 	satFade := 0.10 // Range below minSaturation for smooth fade
 
 	bounds := img.Bounds()
-	result := IFromBounds(bounds)
+	mask := NewMask(bounds.Dx(), bounds.Dy())
+	mask.Source = img
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
@@ -475,7 +546,6 @@ func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, soft
 			rf := float64(c.R) / 65535.0
 			gf := float64(c.G) / 65535.0
 			bf := float64(c.B) / 65535.0
-			af := float64(c.A) / 65535.0
 
 			// Convert to HSL
 			h, s, _ := convertRGBToHSLFloat(rf, gf, bf)
@@ -521,23 +591,15 @@ func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, soft
 			}
 			alpha *= satAlpha
 
-			// Apply the alpha to the original color, preserve original alpha channel
-			finalAlpha := alpha * af
-
-			result.SetNRGBA64(x, y, color.NRGBA64{
-				R: uint16(rf * 65535.0),
-				G: uint16(gf * 65535.0),
-				B: uint16(bf * 65535.0),
-				A: uint16(finalAlpha * 65535.0),
-			})
+			mask.Set(x-bounds.Min.X, y-bounds.Min.Y, float32(alpha))
 		}
 	}
 
-	return result, nil
+	return mask, nil
 }
 
 // @Name: select-hsl
-// @Desc: Selects pixels based on their hue, saturation, and luminance, making pixels outside the specified ranges transparent
+// @Desc: Selects pixels based on their hue, saturation, and luminance. Used as an image, the mask makes pixels outside the specified ranges transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.
 // @Param:      img            - -   		-   The image to process (16-bit)
 // @Param:      lowerHue       "°" 0..360 	0   The lower hue threshold (below this becomes transparent)
 // @Param:      minHue         "°" 0..360 	30  The minimum hue for full opacity (fade from 0% to 100% between lowerHue and this)
@@ -551,12 +613,12 @@ func colorSelectHue(img *image.NRGBA64, hue, toleranceLeft, toleranceRight, soft
 // @Param:      minLum         - 0..1 	0.2  The minimum luminance for full opacity (fade from 0% to 100% between lowerLum and this)
 // @Param:      maxLum         - 0..1 	0.8  The maximum luminance for full opacity (fade from 100% to 0% between this and upperLum)
 // @Param:      upperLum       - 0..1 	0.9  The upper luminance threshold (above this becomes transparent)
-// @Returns:    result         - -   		-   The image with only pixels in all specified ranges visible (16-bit)
+// @Returns:    result         - -   		-   The mask of the pixels in all specified ranges
 func colorSelectHSL(img *image.NRGBA64,
 	lowerHue, minHue, maxHue, upperHue float64,
 	lowerSat, minSat, maxSat, upperSat float64,
 	lowerLum, minLum, maxLum, upperLum float64,
-) (*image.NRGBA64, error) {
+) (*Mask, error) {
 
 	// Convert hue values to 0-1 range for HSL calculations
 	lowerH := lowerHue / 360.0
@@ -565,7 +627,8 @@ func colorSelectHSL(img *image.NRGBA64,
 	upperH := upperHue / 360.0
 
 	bounds := img.Bounds()
-	result := IFromBounds(bounds)
+	mask := NewMask(bounds.Dx(), bounds.Dy())
+	mask.Source = img
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
@@ -574,7 +637,6 @@ func colorSelectHSL(img *image.NRGBA64,
 			rf := float64(c.R) / 65535.0
 			gf := float64(c.G) / 65535.0
 			bf := float64(c.B) / 65535.0
-			af := float64(c.A) / 65535.0
 
 			// Convert to HSL
 			h, s, l := convertRGBToHSLFloat(rf, gf, bf)
@@ -619,19 +681,11 @@ func colorSelectHSL(img *image.NRGBA64,
 			alpha := alphaH * alphaS * alphaL
 			alpha = math.Clamp(alpha, 0.0, 1.0)
 
-			// Apply the alpha to the original color, preserve original alpha channel
-			finalAlpha := alpha * af
-
-			result.SetNRGBA64(x, y, color.NRGBA64{
-				R: uint16(rf * 65535.0),
-				G: uint16(gf * 65535.0),
-				B: uint16(bf * 65535.0),
-				A: uint16(finalAlpha * 65535.0),
-			})
+			mask.Set(x-bounds.Min.X, y-bounds.Min.Y, float32(alpha))
 		}
 	}
 
-	return result, nil
+	return mask, nil
 }
 
 // @Name: remove-hsl
@@ -664,7 +718,7 @@ func colorRemoveHSL(img *image.NRGBA64,
 	if err != nil {
 		return nil, err
 	}
-	return blendErase(img, hsl)
+	return blendErase(img, hsl.Image())
 
 }
 
@@ -698,11 +752,11 @@ func colorInvertHSL(img *image.NRGBA64,
 	if err != nil {
 		return nil, err
 	}
-	hsl, err = colorInvert(hsl)
+	selected, err := colorInvert(hsl.Image())
 	if err != nil {
 		return nil, err
 	}
-	return blendNormal(img, hsl)
+	return blendNormal(img, selected)
 
 }
 
@@ -738,11 +792,11 @@ func colorRotateHSL(img *image.NRGBA64,
 	if err != nil {
 		return nil, err
 	}
-	hsl, err = colorHueRotate(hsl, rotate)
+	selected, err := colorHueRotate(hsl.Image(), rotate)
 	if err != nil {
 		return nil, err
 	}
-	return blendNormal(img, hsl)
+	return blendNormal(img, selected)
 
 }
 
@@ -1038,27 +1092,27 @@ func colorAutoTone(
 }
 
 // @Name: select-brightness
-// @Desc: Selects pixels based on their brightness, making pixels outside the specified range transparent
+// @Desc: Selects pixels based on their brightness. Used as an image, the mask makes pixels outside the specified range transparent. Where a script expects an image, and as final result of Run, the mask is converted to the selected pixels.
 // @Param:      img            - -   		-   The image to process (16-bit)
 // @Param:      lowerBright    - 0..1 	0.1  The lower brightness threshold (below this becomes transparent)
 // @Param:      minBright      - 0..1 	0.2  The minimum brightness for full opacity (fade from 0% to 100% between lowerBright and this)
 // @Param:      maxBright      - 0..1 	0.8  The maximum brightness for full opacity (fade from 100% to 0% between this and upperBright)
 // @Param:      upperBright    - 0..1 	0.9  The upper brightness threshold (above this becomes transparent)
-// @Returns:    result         - -   		-   The image with only pixels in the specified brightness range visible (16-bit)
+// @Returns:    result         - -   		-   The mask of the pixels in the specified brightness range
 func colorSelectBrightness(img *image.NRGBA64,
 	lowerBright, minBright, maxBright, upperBright float64,
-) (*image.NRGBA64, error) {
+) (*Mask, error) {
 	bounds := img.Bounds()
-	result := IFromBounds(bounds)
+	mask := NewMask(bounds.Dx(), bounds.Dy())
+	mask.Source = img
 
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, a := dsl.getColor(img, x, y)
+			r, g, b, _ := dsl.getColor(img, x, y)
 			// Normalize to 0-1
 			rf := float64(r) / 65535.0
 			gf := float64(g) / 65535.0
 			bf := float64(b) / 65535.0
-			af := float64(a) / 65535.0
 
 			// Calculate brightness using luminosity method
 			brightness := rf*0.21 + gf*0.72 + bf*0.07
@@ -1078,13 +1132,11 @@ func colorSelectBrightness(img *image.NRGBA64,
 			// Clamp alpha to [0,1]
 			alpha = math.Clamp(alpha, 0.0, 1.0)
 
-			// Apply the alpha to the original color, preserve original alpha channel
-			finalAlpha := alpha * af
-			dsl.setColor(img, x, y, uint32(rf*65535.0), uint32(gf*65535.0), uint32(bf*65535.0), uint32(finalAlpha*65535.0))
+			mask.Set(x-bounds.Min.X, y-bounds.Min.Y, float32(alpha))
 		}
 	}
 
-	return result, nil
+	return mask, nil
 }
 
 // @Name: remove-brightness
@@ -1105,7 +1157,7 @@ func colorRemoveBrightness(img *image.NRGBA64,
 	if err != nil {
 		return nil, err
 	}
-	return blendErase(img, bright)
+	return blendErase(img, bright.Image())
 }
 
 // @Name: remap-color
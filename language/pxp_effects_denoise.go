@@ -0,0 +1,334 @@
+package language
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/toxyl/math"
+)
+
+// negExpTable holds exp(-x) for 0 <= x < negExpMax, the weights of the
+// denoise filters need millions of evaluations per image.
+const (
+	negExpMax   = 16
+	negExpSteps = 4096
+)
+
+var negExpTable = func() []float32 {
+	t := make([]float32, negExpSteps)
+	for i := range t {
+		t[i] = float32(math.Exp(-float64(i) * negExpMax / negExpSteps))
+	}
+	return t
+}()
+
+// negExp returns an approximation of exp(-x) for x >= 0.
+func negExp(x float32) float32 {
+	if x >= negExpMax {
+		return 0
+	}
+	return negExpTable[int(math.Max(x, 0)*(negExpSteps/negExpMax))]
+}
+
+// straightFloatImage returns the straight (non-premultiplied) RGBA values of img (0..1).
+func straightFloatImage(img *image.NRGBA64) *floatImage {
+	b := img.Bounds()
+	res := newFloatImage(b.Dx(), b.Dy(), 4)
+	parallelRows(0, res.h, func(y int) {
+		for x := range res.w {
+			c := img.NRGBA64At(b.Min.X+x, b.Min.Y+y)
+			i := (y*res.w + x) * 4
+			res.pix[i], res.pix[i+1], res.pix[i+2], res.pix[i+3] = float32(c.R)/0xFFFF, float32(c.G)/0xFFFF, float32(c.B)/0xFFFF, float32(c.A)/0xFFFF
+		}
+	})
+	return res
+}
+
+// denoiseResult returns the image with the colors of f and the alpha of img.
+func denoiseResult(img *image.NRGBA64, f *floatImage) *image.NRGBA64 {
+	b := img.Bounds()
+	result := IFromBounds(b)
+	parallelRows(0, f.h, func(y int) {
+		for x := range f.w {
+			i := (y*f.w + x) * 4
+			result.SetNRGBA64(b.Min.X+x, b.Min.Y+y, color.NRGBA64{
+				R: unitToU16(f.pix[i]),
+				G: unitToU16(f.pix[i+1]),
+				B: unitToU16(f.pix[i+2]),
+				A: img.NRGBA64At(b.Min.X+x, b.Min.Y+y).A,
+			})
+		}
+	})
+	return result
+}
+
+// selectMedian returns the median of v, reordering v (quickselect).
+func selectMedian(v []float32) float32 {
+	k := len(v) / 2
+	lo, hi := 0, len(v)-1
+	for lo < hi {
+		pivot := v[(lo+hi)/2]
+		i, j := lo, hi
+		for i <= j {
+			for v[i] < pivot {
+				i++
+			}
+			for v[j] > pivot {
+				j--
+			}
+			if i <= j {
+				v[i], v[j] = v[j], v[i]
+				i++
+				j--
+			}
+		}
+		if k <= j {
+			hi = j
+		} else if k >= i {
+			lo = i
+		} else {
+			break
+		}
+	}
+	return v[k]
+}
+
+// @Name: median
+// @Desc: Replaces every pixel with the median of its neighbourhood per channel, which removes salt-and-pepper noise and specks while keeping edges sharp. Transparent pixels are ignored.
+// @Param:      img     - -       -   The image to denoise
+// @Param:      radius  "px" 1..10 1  The radius of the neighbourhood
+// @Returns:    result  - -       -   The denoised image
+func median(img *image.NRGBA64, radius int) (*image.NRGBA64, error) {
+	if radius <= 0 {
+		return img, nil
+	}
+	src := straightFloatImage(img)
+	res := newFloatImage(src.w, src.h, 4)
+	parallelRows(0, src.h, func(y int) {
+		var values [3][]float32
+		for x := range src.w {
+			for ch := range values {
+				values[ch] = values[ch][:0]
+			}
+			for sy := math.Max(y-radius, 0); sy <= math.Min(y+radius, src.h-1); sy++ {
+				for sx := math.Max(x-radius, 0); sx <= math.Min(x+radius, src.w-1); sx++ {
+					i := (sy*src.w + sx) * 4
+					if src.pix[i+3] <= 0 {
+						continue
+					}
+					for ch := range values {
+						values[ch] = append(values[ch], src.pix[i+ch])
+					}
+				}
+			}
+			if len(values[0]) == 0 {
+				continue
+			}
+			for ch := range values {
+				res.pix[(y*src.w+x)*4+ch] = selectMedian(values[ch])
+			}
+		}
+	})
+	return denoiseResult(img, res), nil
+}
+
+// @Name: bilateral
+// @Desc: Smooths an image while preserving edges: every pixel is averaged with the neighbours that are close to it and have a similar color. Transparent pixels are ignored.
+// @Param:      img             - -       -       The image to denoise
+// @Param:      spatialSigma    "px" 0.5..10 2    The spatial extent of the filter (higher = smoother areas)
+// @Param:      rangeSigma      - 0.01..1 0.1     The color difference that is still averaged (higher = more edges are smoothed)
+// @Returns:    result          - -       -       The denoised image
+func bilateral(img *image.NRGBA64, spatialSigma float64, rangeSigma float64) (*image.NRGBA64, error) {
+	if spatialSigma <= 0 || rangeSigma <= 0 {
+		return img, nil
+	}
+	src := straightFloatImage(img)
+	res := newFloatImage(src.w, src.h, 4)
+	radius := int(math.Ceil(2 * spatialSigma))
+	size := 2*radius + 1
+	spatial := make([]float32, size*size)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*size+dx+radius] = float32(math.Exp(-float64(dx*dx+dy*dy) / (2 * spatialSigma * spatialSigma)))
+		}
+	}
+	rangeScale := float32(1 / (2 * rangeSigma * rangeSigma))
+	parallelRows(0, src.h, func(y int) {
+		for x := range src.w {
+			c := src.pix[(y*src.w+x)*4:]
+			var r, g, b, sum float32
+			for sy := math.Max(y-radius, 0); sy <= math.Min(y+radius, src.h-1); sy++ {
+				for sx := math.Max(x-radius, 0); sx <= math.Min(x+radius, src.w-1); sx++ {
+					n := src.pix[(sy*src.w+sx)*4:]
+					dr, dg, db := n[0]-c[0], n[1]-c[1], n[2]-c[2]
+					w := spatial[(sy-y+radius)*size+sx-x+radius] * negExp((dr*dr+dg*dg+db*db)*rangeScale) * n[3]
+					r += w * n[0]
+					g += w * n[1]
+					b += w * n[2]
+					sum += w
+				}
+			}
+			if sum > 0 {
+				i := (y*src.w + x) * 4
+				res.pix[i], res.pix[i+1], res.pix[i+2] = r/sum, g/sum, b/sum
+			}
+		}
+	})
+	return denoiseResult(img, res), nil
+}
+
+// boxFilterPlane replaces every value of the single-channel image f with
+// the mean of the (2*radius+1)² values around it, using running sums.
+func boxFilterPlane(f *floatImage, radius int) *floatImage {
+	pass := func(src *floatImage, horizontal bool) *floatImage {
+		res := newFloatImage(src.w, src.h, 1)
+		lines, length := src.h, src.w
+		if !horizontal {
+			lines, length = src.w, src.h
+		}
+		idx := func(line, i int) int {
+			if horizontal {
+				return line*src.w + i
+			}
+			return i*src.w + line
+		}
+		parallelRows(0, lines, func(line int) {
+			var sum float32
+			n := 0
+			for i := 0; i < radius && i < length; i++ {
+				sum += src.pix[idx(line, i)]
+				n++
+			}
+			for i := range length {
+				if j := i + radius; j < length {
+					sum += src.pix[idx(line, j)]
+					n++
+				}
+				if j := i - radius - 1; j >= 0 {
+					sum -= src.pix[idx(line, j)]
+					n--
+				}
+				res.pix[idx(line, i)] = sum / float32(n)
+			}
+		})
+		return res
+	}
+	return pass(pass(f, true), false)
+}
+
+// @Name: nl-means
+// @Desc: Removes noise with non-local means: every pixel is averaged with all pixels in the search window whose surrounding patch looks similar, so textures and edges are preserved much better than by blurring. Slower than the other denoise filters. Transparent pixels are ignored.
+// @Param:      img     - -       -       The image to denoise
+// @Param:      h       - 0.01..0.5 0.05  The filter strength, roughly the standard deviation of the noise (higher = smoother)
+// @Param:      patch   "px" 1..5 1       The radius of the patches that are compared
+// @Param:      window  "px" 2..15 7      The radius of the search window
+// @Returns:    result  - -       -       The denoised image
+func nlMeans(img *image.NRGBA64, h float64, patch int, window int) (*image.NRGBA64, error) {
+	if h <= 0 || window <= 0 {
+		return img, nil
+	}
+	src := straightFloatImage(img)
+	w, ht := src.w, src.h
+	acc := newFloatImage(w, ht, 4) // weighted R, G, B and the sum of weights
+	diff := newFloatImage(w, ht, 1)
+	scale := float32(1 / (h * h))
+	// Instead of comparing patches pixel by pixel, the squared differences
+	// for each offset are box filtered, so every comparison costs the same
+	// regardless of the patch size
+	for dy := -window; dy <= window; dy++ {
+		for dx := -window; dx <= window; dx++ {
+			parallelRows(0, ht, func(y int) {
+				for x := range w {
+					sx, sy := math.Clamp(x+dx, 0, w-1), math.Clamp(y+dy, 0, ht-1)
+					a, b := src.pix[(y*w+x)*4:], src.pix[(sy*w+sx)*4:]
+					dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+					diff.pix[y*w+x] = (dr*dr + dg*dg + db*db) / 3
+				}
+			})
+			dist := boxFilterPlane(diff, patch)
+			parallelRows(0, ht, func(y int) {
+				for x := range w {
+					sx, sy := x+dx, y+dy
+					if sx < 0 || sy < 0 || sx >= w || sy >= ht {
+						continue
+					}
+					n := src.pix[(sy*w+sx)*4:]
+					wt := negExp(dist.pix[y*w+x]*scale) * n[3]
+					a := acc.pix[(y*w+x)*4:]
+					a[0] += wt * n[0]
+					a[1] += wt * n[1]
+					a[2] += wt * n[2]
+					a[3] += wt
+				}
+			})
+		}
+	}
+	parallelRows(0, ht, func(y int) {
+		for i := y * w * 4; i < (y+1)*w*4; i += 4 {
+			if s := acc.pix[i+3]; s > 0 {
+				acc.pix[i], acc.pix[i+1], acc.pix[i+2] = acc.pix[i]/s, acc.pix[i+1]/s, acc.pix[i+2]/s
+			}
+		}
+	})
+	return denoiseResult(img, acc), nil
+}
+
+// @Name: denoise-chroma
+// @Desc: Removes color noise (the colored blotches of high-ISO photos) by smoothing only the chroma, the luma and with it all details stay untouched. Chroma is not smoothed across edges in the luma. Transparent pixels are ignored.
+// @Param:      img     - -       -       The image to denoise
+// @Param:      radius  "px" 1..20 4      The radius of the smoothing (color noise is usually coarse)
+// @Param:      amount  - 0..1    1       The strength of the effect
+// @Returns:    result  - -       -       The denoised image
+func denoiseChroma(img *image.NRGBA64, radius int, amount float64) (*image.NRGBA64, error) {
+	if radius <= 0 || amount <= 0 {
+		return img, nil
+	}
+	src := straightFloatImage(img)
+	w, h := src.w, src.h
+	// Work in YCbCr (BT.601)
+	ycc := newFloatImage(w, h, 4)
+	parallelRows(0, h, func(y int) {
+		for i := y * w * 4; i < (y+1)*w*4; i += 4 {
+			r, g, b := src.pix[i], src.pix[i+1], src.pix[i+2]
+			yv := 0.299*r + 0.587*g + 0.114*b
+			ycc.pix[i], ycc.pix[i+1], ycc.pix[i+2], ycc.pix[i+3] = yv, (b-yv)*0.564, (r-yv)*0.713, src.pix[i+3]
+		}
+	})
+	sigma := float64(radius) / 2
+	size := 2*radius + 1
+	spatial := make([]float32, size*size)
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			spatial[(dy+radius)*size+dx+radius] = float32(math.Exp(-float64(dx*dx+dy*dy) / (2 * sigma * sigma)))
+		}
+	}
+	lumaScale := float32(1 / (2 * 0.1 * 0.1))
+	res := newFloatImage(w, h, 4)
+	amt := float32(math.Clamp(amount, 0, 1))
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			c := ycc.pix[(y*w+x)*4:]
+			var cb, cr, sum float32
+			for sy := math.Max(y-radius, 0); sy <= math.Min(y+radius, h-1); sy++ {
+				for sx := math.Max(x-radius, 0); sx <= math.Min(x+radius, w-1); sx++ {
+					n := ycc.pix[(sy*w+sx)*4:]
+					dl := n[0] - c[0]
+					wt := spatial[(sy-y+radius)*size+sx-x+radius] * negExp(dl*dl*lumaScale) * n[3]
+					cb += wt * n[1]
+					cr += wt * n[2]
+					sum += wt
+				}
+			}
+			if sum > 0 {
+				cb, cr = c[1]+(cb/sum-c[1])*amt, c[2]+(cr/sum-c[2])*amt
+			} else {
+				cb, cr = c[1], c[2]
+			}
+			i := (y*w + x) * 4
+			res.pix[i] = c[0] + cr/0.713
+			res.pix[i+2] = c[0] + cb/0.564
+			res.pix[i+1] = (c[0] - 0.299*res.pix[i] - 0.114*res.pix[i+2]) / 0.587
+		}
+	})
+	return denoiseResult(img, res), nil
+}
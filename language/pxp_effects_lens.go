@@ -0,0 +1,208 @@
+package language
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/toxyl/math"
+)
+
+// lensGeometry converts between pixel coordinates and the normalized
+// coordinates of the lens model: the origin is the optical center and a
+// radius of 1 is half the shorter side of the image.
+type lensGeometry struct {
+	cx, cy, norm float64
+}
+
+func newLensGeometry(bounds image.Rectangle, cx, cy float64) lensGeometry {
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	return lensGeometry{cx: cx * w, cy: cy * h, norm: math.Min(w, h) / 2}
+}
+
+func (g lensGeometry) toLens(px, py float64) (x, y float64) {
+	return (px - g.cx) / g.norm, (py - g.cy) / g.norm
+}
+
+func (g lensGeometry) toPixel(x, y float64) (px, py float64) {
+	return x*g.norm + g.cx, y*g.norm + g.cy
+}
+
+// brownConrady applies the radial (k1, k2, k3) and tangential (p1, p2)
+// distortion of the Brown-Conrady model to the undistorted point (x, y).
+func brownConrady(x, y, k1, k2, k3, p1, p2 float64) (xd, yd float64) {
+	r2 := x*x + y*y
+	radial := 1 + r2*(k1+r2*(k2+r2*k3))
+	xd = x*radial + 2*p1*x*y + p2*(r2+2*x*x)
+	yd = y*radial + p1*(r2+2*y*y) + 2*p2*x*y
+	return xd, yd
+}
+
+// @Name: lens-correct
+// @Desc: Removes lens distortion using the Brown-Conrady model, e.g. the barrel distortion of drone and action cameras. Coordinates are normalized so a radius of 1 is half the shorter side of the image. Areas without source pixels are transparent.
+// @Param:      img     - -        -           The image to correct
+// @Param:      k1      - -1..1    0           The first radial distortion coefficient (negative = barrel, positive = pincushion)
+// @Param:      k2      - -1..1    0           The second radial distortion coefficient
+// @Param:      k3      - -1..1    0           The third radial distortion coefficient
+// @Param:      p1      - -0.1..0.1 0          The first tangential distortion coefficient
+// @Param:      p2      - -0.1..0.1 0          The second tangential distortion coefficient
+// @Param:      cx      - 0..1     0.5         The horizontal position of the optical center (fraction of the width)
+// @Param:      cy      - 0..1     0.5         The vertical position of the optical center (fraction of the height)
+// @Param:      filter  - -        "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -        -           The corrected image
+func lensCorrect(img *image.NRGBA64, k1, k2, k3, p1, p2, cx, cy float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	g := newLensGeometry(img.Rect, cx, cy)
+	return remapImage(img, img.Rect.Dx(), img.Rect.Dy(), f, g.correction(k1, k2, k3, p1, p2)), nil
+}
+
+// correction maps each pixel of the corrected image to the point the lens
+// distorted it to.
+func (g lensGeometry) correction(k1, k2, k3, p1, p2 float64) func(px, py float64) (float64, float64) {
+	return func(px, py float64) (float64, float64) {
+		x, y := g.toLens(px, py)
+		return g.toPixel(brownConrady(x, y, k1, k2, k3, p1, p2))
+	}
+}
+
+// @Name: lens-distort
+// @Desc: Applies lens distortion using the Brown-Conrady model, the inverse of lens-correct with the same coefficients. Coordinates are normalized so a radius of 1 is half the shorter side of the image. Areas without source pixels are transparent.
+// @Param:      img     - -        -           The image to distort
+// @Param:      k1      - -1..1    0           The first radial distortion coefficient (negative = barrel, positive = pincushion)
+// @Param:      k2      - -1..1    0           The second radial distortion coefficient
+// @Param:      k3      - -1..1    0           The third radial distortion coefficient
+// @Param:      p1      - -0.1..0.1 0          The first tangential distortion coefficient
+// @Param:      p2      - -0.1..0.1 0          The second tangential distortion coefficient
+// @Param:      cx      - 0..1     0.5         The horizontal position of the optical center (fraction of the width)
+// @Param:      cy      - 0..1     0.5         The vertical position of the optical center (fraction of the height)
+// @Param:      filter  - -        "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -        -           The distorted image
+func lensDistort(img *image.NRGBA64, k1, k2, k3, p1, p2, cx, cy float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	g := newLensGeometry(img.Rect, cx, cy)
+	return remapImage(img, img.Rect.Dx(), img.Rect.Dy(), f, g.distortion(k1, k2, k3, p1, p2)), nil
+}
+
+// distortion maps each pixel of the distorted image to the undistorted point
+// the lens renders there, pixels without one map to NaN.
+func (g lensGeometry) distortion(k1, k2, k3, p1, p2 float64) func(px, py float64) (float64, float64) {
+	return func(px, py float64) (float64, float64) {
+		// The model has no closed-form inverse, so find the undistorted point
+		// that maps to this pixel with Newton's method
+		xd, yd := g.toLens(px, py)
+		x, y := xd, yd
+		const h = 1e-6
+		converged := false
+		for range 30 {
+			ex, ey := brownConrady(x, y, k1, k2, k3, p1, p2)
+			ex, ey = ex-xd, ey-yd
+			if math.Abs(ex)+math.Abs(ey) < 1e-3/g.norm {
+				converged = true
+				break
+			}
+			ax, ay := brownConrady(x+h, y, k1, k2, k3, p1, p2)
+			bx, by := brownConrady(x, y+h, k1, k2, k3, p1, p2)
+			j00, j10, j01, j11 := (ax-xd-ex)/h, (ay-yd-ey)/h, (bx-xd-ex)/h, (by-yd-ey)/h
+			det := j00*j11 - j01*j10
+			// Strong distortion folds the image over itself (e.g. barrel
+			// distortion towards the corners), beyond the fold there is no
+			// source point and the pixel stays transparent
+			if det <= 0 {
+				break
+			}
+			x -= (j11*ex - j01*ey) / det
+			y -= (j00*ey - j10*ex) / det
+		}
+		if !converged {
+			return math.NaN[float64](), math.NaN[float64]()
+		}
+		return g.toPixel(x, y)
+	}
+}
+
+// @Name: vignette-correct
+// @Desc: Removes lens vignetting by brightening the image towards the edges. The brightness falloff of the lens is modelled as 1 + k1*r² + k2*r⁴ + k3*r⁶, where a radius of 1 is half the shorter side of the image, and divided out in linear light.
+// @Param:      img     - -        -     The image to correct
+// @Param:      k1      - -1..1    -0.3  The first falloff coefficient (negative = darker edges)
+// @Param:      k2      - -1..1    0     The second falloff coefficient
+// @Param:      k3      - -1..1    0     The third falloff coefficient
+// @Param:      cx      - 0..1     0.5   The horizontal position of the optical center (fraction of the width)
+// @Param:      cy      - 0..1     0.5   The vertical position of the optical center (fraction of the height)
+// @Returns:    result  - -        -     The corrected image
+func vignetteCorrect(img *image.NRGBA64, k1, k2, k3, cx, cy float64) (*image.NRGBA64, error) {
+	bounds := img.Bounds()
+	result := IFromBounds(bounds)
+	g := newLensGeometry(img.Rect, cx, cy)
+	parallelRows(bounds.Min.Y, bounds.Max.Y, func(y int) {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.NRGBA64At(x, y)
+			lx, ly := g.toLens(float64(x-bounds.Min.X)+0.5, float64(y-bounds.Min.Y)+0.5)
+			r2 := lx*lx + ly*ly
+			falloff := 1 + r2*(k1+r2*(k2+r2*k3))
+			gain := float32(1 / math.Max(falloff, 0.01))
+			result.SetNRGBA64(x, y, color.NRGBA64{
+				R: linearToSRGB16(srgbToLinear16(uint32(c.R)) * gain),
+				G: linearToSRGB16(srgbToLinear16(uint32(c.G)) * gain),
+				B: linearToSRGB16(srgbToLinear16(uint32(c.B)) * gain),
+				A: c.A,
+			})
+		}
+	})
+	return result, nil
+}
+
+// @Name: tca-correct
+// @Desc: Removes transverse (lateral) chromatic aberration, the colored fringes towards the edges of an image that appear because the lens renders the red and blue channels at a slightly different size than the green channel.
+// @Param:      img     - -            -           The image to correct
+// @Param:      red     - 0.99..1.01   1           The size of the red channel relative to the green channel (1 = no aberration)
+// @Param:      blue    - 0.99..1.01   1           The size of the blue channel relative to the green channel (1 = no aberration)
+// @Param:      cx      - 0..1         0.5         The horizontal position of the optical center (fraction of the width)
+// @Param:      cy      - 0..1         0.5         The vertical position of the optical center (fraction of the height)
+// @Param:      filter  - -            "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -            -           The corrected image
+func tcaCorrect(img *image.NRGBA64, red, blue, cx, cy float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return tcaCorrectPremul(toPremulImage(img), red, blue, cx, cy, f).toNRGBA64(), nil
+}
+
+// tcaCorrectPremul is tca-correct for premultiplied images.
+func tcaCorrectPremul(src *premulImage, red, blue, cx, cy float64, f *resampleFilter) *premulImage {
+	w, h := src.w, src.h
+	g := newLensGeometry(image.Rect(0, 0, w, h), cx, cy)
+	result := newPremulImage(w, h)
+	// sampleChannel returns the (non-premultiplied) channel ch of the
+	// point scaled by s around the optical center
+	sampleChannel := func(px, py, s float64, ch int) float32 {
+		x, y := g.toLens(px, py)
+		u, v := g.toPixel(x*s, y*s)
+		c := [4]float32{}
+		c[0], c[1], c[2], c[3] = src.sample(f, u, v, 1)
+		if c[3] <= 0 {
+			return 0
+		}
+		return c[ch] / c[3]
+	}
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			i := (y*w + x) * 4
+			a := src.pix[i+3]
+			if a <= 0 {
+				continue
+			}
+			r := sampleChannel(px, py, red, 0)
+			gr := src.pix[i+1] / a
+			b := sampleChannel(px, py, blue, 2)
+			result.pix[i], result.pix[i+1], result.pix[i+2], result.pix[i+3] = r*a, gr*a, b*a, a
+		}
+	})
+	return result
+}
@@ -156,3 +156,42 @@ func clarity(img *image.NRGBA64, intensity float64, radius float64, rWeight floa
 
 	return res, nil
 }
+
+// laplacianKernel is the 3x3 discrete Laplacian, the sum of the second
+// derivatives in x and y. Its magnitude is large at fine details.
+var laplacianKernel = [3][3]float32{
+	{0, 1, 0},
+	{1, -4, 1},
+	{0, 1, 0},
+}
+
+// laplacian convolves the single-channel image f with laplacianKernel, the edges are extended.
+func laplacian(f *floatImage) *floatImage {
+	res := newFloatImage(f.w, f.h, 1)
+	parallelRows(0, f.h, func(y int) {
+		for x := range f.w {
+			var sum float32
+			for ky, row := range laplacianKernel {
+				sy := math.Clamp(y+ky-1, 0, f.h-1)
+				for kx, k := range row {
+					if k != 0 {
+						sum += k * f.pix[sy*f.w+math.Clamp(x+kx-1, 0, f.w-1)]
+					}
+				}
+			}
+			res.pix[y*f.w+x] = sum
+		}
+	})
+	return res
+}
+
+// sharpnessMap returns the energy of the Laplacian of the gray values f,
+// averaged with the Gaussian kernel of the given radius (like highpass),
+// so in-focus regions have high values.
+func sharpnessMap(f *floatImage, radius float64) *floatImage {
+	lap := laplacian(f)
+	for i, v := range lap.pix {
+		lap.pix[i] = v * v
+	}
+	return lap.blur(radius)
+}
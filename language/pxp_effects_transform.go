@@ -1,8 +1,10 @@
 package language
 
 import (
+	"fmt"
 	"image"
 	"image/color"
+	"strings"
 
 	"github.com/toxyl/math"
 )
@@ -45,13 +47,22 @@ func translate(img *image.NRGBA64, dx float64, dy float64) (*image.NRGBA64, erro
 
 // @Name: rotate
 // @Desc: Rotates an image around its center by a specified angle
-// @Param:      img     - -   			-   The image to rotate
-// @Param:      angle   - -360..360   	0   The rotation angle in degrees (positive = clockwise)
-// @Returns:    result  - -   			-   The rotated image
-func rotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
+// @Param:      img     - -   			-   		The image to rotate
+// @Param:      angle   - -360..360   	0   		The rotation angle in degrees (positive = clockwise)
+// @Param:      filter  - -   			"nearest"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -   			-   		The rotated image
+func rotate(img *image.NRGBA64, angle float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return rotatePremul(toPremulImage(img), angle, f).toNRGBA64(), nil
+}
+
+// rotatePremul rotates src around its center, the result is enlarged to fit.
+func rotatePremul(src *premulImage, angle float64, f *resampleFilter) *premulImage {
+	width := src.w
+	height := src.h
 
 	// Convert angle to radians
 	angleRad := angle * math.Pi / 180.0
@@ -64,10 +75,6 @@ func rotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
 	newWidth := int(math.Round(float64(width)*cosA + float64(height)*sinA))
 	newHeight := int(math.Round(float64(width)*sinA + float64(height)*cosA))
 
-	// Create a new image with the calculated dimensions
-	newBounds := image.Rect(0, 0, newWidth, newHeight)
-	result := IFromBounds(newBounds)
-
 	// Calculate center points for both original and new image
 	oldCenterX := float64(width) / 2.0
 	oldCenterY := float64(height) / 2.0
@@ -78,97 +85,66 @@ func rotate(img *image.NRGBA64, angle float64) (*image.NRGBA64, error) {
 	sinA = math.Sin(angleRad)
 	cosA = math.Cos(angleRad)
 
-	// Fill with transparent black initially
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			result.Set(x, y, color.NRGBA{0, 0, 0, 0})
-		}
-	}
-
-	// Rotate each pixel
-	for y := range newHeight {
-		for x := range newWidth {
-			// Translate to origin of new image
-			dx := float64(x) - newCenterX
-			dy := float64(y) - newCenterY
-
-			// Rotate backwards (use negative angle to get source position)
-			srcX := int(math.Round(dx*cosA + dy*sinA + oldCenterX))
-			srcY := int(math.Round(-dx*sinA + dy*cosA + oldCenterY))
-
-			// Copy pixel if within bounds of original image
-			if srcX >= bounds.Min.X && srcX < bounds.Max.X && srcY >= bounds.Min.Y && srcY < bounds.Max.Y {
-				result.Set(x, y, img.NRGBA64At(srcX, srcY))
-			}
-		}
-	}
-
-	return result, nil
+	// Sample the source at the rotated center of each pixel
+	return src.render(newWidth, newHeight, f, func(x, y float64) (float64, float64, float64) {
+		// Translate to origin of new image
+		dx := x - newCenterX
+		dy := y - newCenterY
+
+		// Rotate backwards (use negative angle to get source position)
+		srcX := dx*cosA + dy*sinA + oldCenterX
+		srcY := -dx*sinA + dy*cosA + oldCenterY
+		return srcX, srcY, 1
+	})
 }
 
 // @Name: scale
 // @Desc: Scales an image by specified factors
-// @Param:      img     - -   	-   The image to scale
-// @Param:      sx      - -  	0   The horizontal scale factor
-// @Param:      sy      - -  	0   The vertical scale factor
-// @Returns:    result  - -   	-   The scaled image
-func scale(img *image.NRGBA64, sx float64, sy float64) (*image.NRGBA64, error) {
-	sx += 1
-	sy += 1
-
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-
-	// Calculate new dimensions
-	newWidth := int(math.Round(float64(width) * sx))
-	newHeight := int(math.Round(float64(height) * sy))
-
-	// Create result image with new dimensions
-	newBounds := image.Rect(0, 0, newWidth, newHeight)
-	result := IFromBounds(newBounds)
-
-	// Fill with transparent black initially
-	for y := range newHeight {
-		for x := range newWidth {
-			result.Set(x, y, color.NRGBA{0, 0, 0, 0})
-		}
-	}
-
-	// Scale each pixel
-	for y := range newHeight {
-		for x := range newWidth {
-			// Calculate source pixel coordinates
-			srcX := int(math.Round(float64(x) / sx))
-			srcY := int(math.Round(float64(y) / sy))
-
-			// Copy pixel if within bounds of source image
-			if srcX >= bounds.Min.X && srcX < bounds.Max.X && srcY >= bounds.Min.Y && srcY < bounds.Max.Y {
-				result.Set(x, y, img.NRGBA64At(srcX, srcY))
-			}
-		}
-	}
+// @Param:      img     - -   	-   		The image to scale
+// @Param:      sx      - -  	0   		The horizontal scale factor
+// @Param:      sy      - -  	0   		The vertical scale factor
+// @Param:      filter  - -   	"nearest"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
+// @Returns:    result  - -   	-   		The scaled image
+func scale(img *image.NRGBA64, sx float64, sy float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	newWidth, newHeight := scaleSize(img.Bounds(), sx, sy)
+	return resample(img, newWidth, newHeight, f), nil
+}
 
-	return result, nil
+// scaleSize returns the size of an image with the given bounds scaled by
+// 1+sx and 1+sy.
+func scaleSize(bounds image.Rectangle, sx, sy float64) (w, h int) {
+	return int(math.Round(float64(bounds.Dx()) * (sx + 1))), int(math.Round(float64(bounds.Dy()) * (sy + 1)))
 }
 
 // @Name: transform
 // @Desc: Applies translation, rotation, and scaling to an image in one operation
-// @Param:      img     - -   	-   The image to transform
-// @Param:      dx      - -   	0   The horizontal translation in pixels
-// @Param:      dy      - -   	0   The vertical translation in pixels
-// @Param:      angle   - -   	0   The rotation angle in degrees (clockwise)
-// @Param:      sx      - -  	0   The horizontal scale factor
-// @Param:      sy      - -  	0   The vertical scale factor
-// @Returns:    result  - -   	-   The transformed image
-func transform(img *image.NRGBA64, dx float64, dy float64, angle float64, sx float64, sy float64) (*image.NRGBA64, error) {
+// @Param:      img     - -   	-   		The image to transform
+// @Param:      dx      - -   	0   		The horizontal translation in pixels
+// @Param:      dy      - -   	0   		The vertical translation in pixels
+// @Param:      angle   - -   	0   		The rotation angle in degrees (clockwise)
+// @Param:      sx      - -  	0   		The horizontal scale factor
+// @Param:      sy      - -  	0   		The vertical scale factor
+// @Param:      filter  - -   	"nearest"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -   	-   		The transformed image
+func transform(img *image.NRGBA64, dx float64, dy float64, angle float64, sx float64, sy float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return transformPremul(toPremulImage(img), dx, dy, angle, sx, sy, f).toNRGBA64(), nil
+}
+
+// transformPremul translates, rotates and scales src, the result has the size of src.
+func transformPremul(src *premulImage, dx, dy, angle, sx, sy float64, f *resampleFilter) *premulImage {
 	sx += 1
 	sy += 1
 
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	height := bounds.Dy()
-	result := IFromBounds(bounds)
+	width := src.w
+	height := src.h
 
 	dx *= float64(width)
 	dy *= float64(height)
@@ -184,36 +160,24 @@ func transform(img *image.NRGBA64, dx float64, dy float64, angle float64, sx flo
 	sinA := math.Sin(-angleRad) // Negative angle for clockwise rotation
 	cosA := math.Cos(-angleRad)
 
-	// Fill with transparent black initially
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			result.Set(x, y, color.NRGBA{0, 0, 0, 0})
-		}
-	}
+	// Widen the filter when shrinking so all source pixels contribute
+	filterScale := math.Max(1, 1/math.Min(math.Abs(sx), math.Abs(sy)))
 
 	// Apply transformation to each pixel
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			// Translate to origin
-			px := (float64(x) - dx) - centerX
-			py := (float64(y) - dy) - centerY
-
-			// Apply scale
-			px /= sx
-			py /= sy
-
-			// Apply rotation
-			srcX := int(math.Round(px*cosA - py*sinA + centerX))
-			srcY := int(math.Round(px*sinA + py*cosA + centerY))
-
-			// Copy pixel if within bounds
-			if srcX >= bounds.Min.X && srcX < bounds.Max.X && srcY >= bounds.Min.Y && srcY < bounds.Max.Y {
-				result.Set(x, y, img.NRGBA64At(srcX, srcY))
-			}
-		}
-	}
-
-	return result, nil
+	return src.render(width, height, f, func(x, y float64) (float64, float64, float64) {
+		// Translate to origin
+		px := (x - dx) - centerX
+		py := (y - dy) - centerY
+
+		// Apply scale
+		px /= sx
+		py /= sy
+
+		// Apply rotation
+		srcX := px*cosA - py*sinA + centerX
+		srcY := px*sinA + py*cosA + centerY
+		return srcX, srcY, filterScale
+	})
 }
 
 // @Name: flip-v
@@ -624,35 +588,37 @@ func expandPx(img *image.NRGBA64, left int, right int, top int, bottom int) (*im
 
 // @Name: resize-max-mp
 // @Desc: Resize an image to stay within a maximum amount of megapixels
-// @Param:      img     - -   	-   The image to resize
-// @Param:      mpMax    - -   	0   The maximum amount of megapixels
-// @Returns:    result  - -   	-   The resized image
-func resizeToMaxMP(img *image.NRGBA64, mpMax int) (*image.NRGBA64, error) {
-	bounds := img.Bounds()
-	w := bounds.Dx()
-	h := bounds.Dy()
-
-	ow := w
-	oh := h
-	mp := w * h
-	for mp > mpMax {
-		w >>= 1
-		h >>= 1
-		mp = w * h
-	}
-	if ow != w || oh != h {
-		// Create a new image with the target dimensions and resample using nearest-neighbor
-		dst := I(w, h)
-		for y := 0; y < h; y++ {
-			srcY := y * oh / h
-			for x := 0; x < w; x++ {
-				srcX := x * ow / w
-				dst.SetNRGBA64(x, y, img.NRGBA64At(srcX, srcY))
-			}
-		}
-		img = dst
+// @Param:      img     - -   	-   		The image to resize
+// @Param:      mpMax    - -   	0   		The maximum amount of megapixels (e.g. 0.5 for 500,000 pixels), 0 to keep the size
+// @Param:      filter  - -   	"nearest"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
+// @Returns:    result  - -   	-   		The resized image
+func resizeToMaxMP(img *image.NRGBA64, mpMax float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	w, h, ok := maxMPSize(img.Bounds(), mpMax)
+	if !ok {
+		return img, nil
 	}
-	return img, nil
+	return resample(img, w, h, f), nil
+}
+
+// maxMPSize returns the size of an image with the given bounds shrunk to at
+// most mpMax megapixels, ok is false if it doesn't have to be resized.
+func maxMPSize(bounds image.Rectangle, mpMax float64) (w, h int, ok bool) {
+	w = bounds.Dx()
+	h = bounds.Dy()
+
+	pixels, maxPixels := float64(w*h), mpMax*1e6
+	if mpMax <= 0 || pixels <= maxPixels {
+		return w, h, false
+	}
+	// Scale both sides by the same factor so the aspect ratio is preserved
+	s := math.Sqrt(maxPixels / pixels)
+	w = math.Max(int(float64(w)*s), 1)
+	h = math.Max(int(float64(h)*s), 1)
+	return w, h, true
 }
 
 // @Name: resize-fit
@@ -660,14 +626,28 @@ func resizeToMaxMP(img *image.NRGBA64, mpMax int) (*image.NRGBA64, error) {
 // @Param:      img     - -   	-   The image to resize
 // @Param:      maxW    - -   	0   The maximum width (pixels)
 // @Param:      maxH    - -   	0   The maximum height (pixels)
+// @Param:      filter  - -   	"nearest"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
 // @Returns:    result  - -   	-   The resized image
-func resizeToFit(img *image.NRGBA64, maxW int, maxH int) (*image.NRGBA64, error) {
-	bounds := img.Bounds()
+func resizeToFit(img *image.NRGBA64, maxW int, maxH int, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	nw, nh, ok := fitSize(img.Bounds(), maxW, maxH)
+	if !ok {
+		return img, nil
+	}
+	return resample(img, nw, nh, f), nil
+}
+
+// fitSize returns the size of an image with the given bounds shrunk to fit
+// maxW x maxH pixels, ok is false if it doesn't have to be resized.
+func fitSize(bounds image.Rectangle, maxW, maxH int) (nw, nh int, ok bool) {
 	ow := bounds.Dx()
 	oh := bounds.Dy()
 
 	if maxW <= 0 && maxH <= 0 {
-		return img, nil
+		return ow, oh, false
 	}
 	if maxW <= 0 {
 		maxW = ow
@@ -677,29 +657,240 @@ func resizeToFit(img *image.NRGBA64, maxW int, maxH int) (*image.NRGBA64, error)
 	}
 
 	if ow <= maxW && oh <= maxH {
-		return img, nil
+		return ow, oh, false
 	}
 
 	scaleW := float64(maxW) / float64(ow)
 	scaleH := float64(maxH) / float64(oh)
 	scale := math.Min(scaleW, scaleH)
 
-	nw := int(math.Round(float64(ow) * scale))
-	nh := int(math.Round(float64(oh) * scale))
+	nw = int(math.Round(float64(ow) * scale))
+	nh = int(math.Round(float64(oh) * scale))
 	if nw < 1 {
 		nw = 1
 	}
 	if nh < 1 {
 		nh = 1
 	}
+	return nw, nh, true
+}
+
+// @Name: resize
+// @Desc: Resize an image to exact dimensions. If either width or height is 0, it is derived from the aspect ratio.
+// @Param:      img     - -   	-   		The image to resize
+// @Param:      w       "px" -  	0   		The width of the result
+// @Param:      h       "px" -  	0   		The height of the result
+// @Param:      filter  - -   	"lanczos3"  The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
+// @Returns:    result  - -   	-   		The resized image
+func resize(img *image.NRGBA64, w int, h int, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	w, h, err = resizeSize(img.Bounds(), w, h)
+	if err != nil {
+		return nil, err
+	}
+	return resample(img, w, h, f), nil
+}
 
-	dst := I(nw, nh)
-	for y := 0; y < nh; y++ {
-		srcY := y * oh / nh
-		for x := 0; x < nw; x++ {
-			srcX := x * ow / nw
-			dst.SetNRGBA64(x, y, img.NRGBA64At(srcX, srcY))
+// resizeSize returns the size resize uses for an image with the given
+// bounds, deriving a missing width or height from the aspect ratio.
+func resizeSize(bounds image.Rectangle, w, h int) (int, int, error) {
+	ow, oh := bounds.Dx(), bounds.Dy()
+	if w <= 0 && h <= 0 {
+		return 0, 0, fmt.Errorf("width or height must be greater than 0")
+	}
+	if w <= 0 {
+		w = math.Max(int(math.Round(float64(ow)*float64(h)/float64(oh))), 1)
+	}
+	if h <= 0 {
+		h = math.Max(int(math.Round(float64(oh)*float64(w)/float64(ow))), 1)
+	}
+	return w, h, nil
+}
+
+// resizeToCover scales img so it covers w x h pixels while preserving the aspect ratio.
+func resizeToCover(img *image.NRGBA64, w, h int, f *resampleFilter) (*image.NRGBA64, error) {
+	nw, nh, err := coverSize(img.Bounds(), w, h)
+	if err != nil {
+		return nil, err
+	}
+	return resample(img, nw, nh, f), nil
+}
+
+// coverSize returns the size of an image with the given bounds scaled to
+// cover w x h pixels while preserving the aspect ratio.
+func coverSize(bounds image.Rectangle, w, h int) (nw, nh int, err error) {
+	if w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("width and height must be greater than 0")
+	}
+	ow, oh := bounds.Dx(), bounds.Dy()
+	s := math.Max(float64(w)/float64(ow), float64(h)/float64(oh))
+	nw = math.Max(int(math.Round(float64(ow)*s)), w)
+	nh = math.Max(int(math.Round(float64(oh)*s)), h)
+	return nw, nh, nil
+}
+
+// anchorOffset returns the position of a box within free space of dx x dy
+// pixels for the given anchor (TL, T, TR, L, C, R, BL, B, BR).
+func anchorOffset(anchor string, dx, dy int) (x, y int, err error) {
+	a := strings.ToUpper(strings.TrimSpace(anchor))
+	switch a {
+	case "TL", "T", "TR", "L", "C", "R", "BL", "B", "BR":
+	default:
+		return 0, 0, fmt.Errorf("unknown anchor: %s (supported: TL, T, TR, L, C, R, BL, B, BR)", anchor)
+	}
+	x, y = dx/2, dy/2
+	switch {
+	case strings.HasPrefix(a, "T"):
+		y = 0
+	case strings.HasPrefix(a, "B"):
+		y = dy
+	}
+	switch {
+	case strings.HasSuffix(a, "L"):
+		x = 0
+	case strings.HasSuffix(a, "R"):
+		x = dx
+	}
+	return x, y, nil
+}
+
+// @Name: resize-fill
+// @Desc: Resize an image to cover exact dimensions while preserving aspect ratio, the overflow is cropped
+// @Param:      img     - -   	-   		The image to resize
+// @Param:      w       "px" -  	0   		The width of the result
+// @Param:      h       "px" -  	0   		The height of the result
+// @Param:      anchor  - -   	"C"         The part of the image to keep (TL, T, TR, L, C, R, BL, B, BR)
+// @Param:      filter  - -   	"lanczos3"  The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
+// @Returns:    result  - -   	-   		The resized image
+func resizeFill(img *image.NRGBA64, w int, h int, anchor string, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	scaled, err := resizeToCover(img, w, h, f)
+	if err != nil {
+		return nil, err
+	}
+	r, err := fillRect(scaled.Rect, w, h, anchor)
+	if err != nil {
+		return nil, err
+	}
+	return cropPx(scaled, r.Min.X, scaled.Rect.Dx()-r.Max.X, r.Min.Y, scaled.Rect.Dy()-r.Max.Y)
+}
+
+// fillRect returns the w x h part of bounds that resize-fill keeps for the anchor.
+func fillRect(bounds image.Rectangle, w, h int, anchor string) (image.Rectangle, error) {
+	x, y, err := anchorOffset(anchor, bounds.Dx()-w, bounds.Dy()-h)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	return image.Rect(x, y, x+w, y+h).Add(bounds.Min), nil
+}
+
+// @Name: smart-crop
+// @Desc: Resize an image to cover exact dimensions while preserving aspect ratio and crops the overflow where the image has the least detail. The detail is measured using edge-detect.
+// @Param:      img     - -   	-   		The image to crop
+// @Param:      w       "px" -  	0   		The width of the result
+// @Param:      h       "px" -  	0   		The height of the result
+// @Param:      filter  - -   	"lanczos3"  The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area (best for downscaling)
+// @Returns:    result  - -   	-   		The cropped image
+func smartCrop(img *image.NRGBA64, w int, h int, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	scaled, err := resizeToCover(img, w, h, f)
+	if err != nil {
+		return nil, err
+	}
+	r, err := smartCropRect(scaled, w, h)
+	if err != nil {
+		return nil, err
+	}
+	return cropPx(scaled, r.Min.X, scaled.Rect.Dx()-r.Max.X, r.Min.Y, scaled.Rect.Dy()-r.Max.Y)
+}
+
+// smartCropRect returns the w x h part of scaled with the most detail.
+func smartCropRect(scaled *image.NRGBA64, w, h int) (image.Rectangle, error) {
+	edges, err := colorEdgeDetect(scaled)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	sw, sh := scaled.Rect.Dx(), scaled.Rect.Dy()
+	horizontal := sw-w > sh-h
+	free := sw - w
+	n := sw
+	if !horizontal {
+		free = sh - h
+		n = sh
+	}
+	if free <= 0 {
+		return scaled.Rect, nil
+	}
+
+	// Sum the edge energy of each column (or row), transparent pixels don't count
+	energy := make([]float64, n)
+	for y := range sh {
+		for x := range sw {
+			c := edges.NRGBA64At(x, y)
+			e := float64(c.R) * float64(c.A) / 0xffff
+			if horizontal {
+				energy[x] += e
+			} else {
+				energy[y] += e
+			}
+		}
+	}
+
+	// Slide the window along the free axis, ties are broken towards the center
+	size := n - free
+	sum := 0.0
+	for i := range size {
+		sum += energy[i]
+	}
+	best, bestSum := 0, sum
+	for o := 1; o <= free; o++ {
+		sum += energy[o+size-1] - energy[o-1]
+		if sum > bestSum || (sum == bestSum && math.Abs(2*o-free) < math.Abs(2*best-free)) {
+			best, bestSum = o, sum
 		}
 	}
-	return dst, nil
+	if horizontal {
+		return image.Rect(best, 0, best+w, h), nil
+	}
+	return image.Rect(0, best, w, best+h), nil
+}
+
+// @Name: detect-skew
+// @Desc: Detects how much the dominant lines of an image (e.g. text lines, edges of documents or the horizon) are tilted, using a Hough transform of the edge-detect result
+// @Param:      img       - -   	-   	The image to analyze
+// @Param:      maxAngle  "°" 0..45	45  	The largest tilt to look for
+// @Returns:    result    - -   	-   	The tilt in degrees (positive = clockwise), rotate by the negative value to straighten the image, 0 if the image has no lines
+func detectSkew(img *image.NRGBA64, maxAngle float64) (float64, error) {
+	return detectSkewAngle(img, maxAngle), nil
+}
+
+// @Name: deskew
+// @Desc: Straightens an image by detecting its tilt with detect-skew and rotating it back. The result is cropped to the largest rectangle without transparent corners.
+// @Param:      img       - -   	-   		The image to straighten
+// @Param:      maxAngle  "°" 0..45	10  		The largest tilt to correct
+// @Param:      filter    - -   	"bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result    - -   	-   		The straightened image
+func deskew(img *image.NRGBA64, maxAngle float64, filter string) (*image.NRGBA64, error) {
+	angle := detectSkewAngle(img, maxAngle)
+	if angle == 0 {
+		return img, nil
+	}
+	rotated, err := rotate(img, -angle, filter)
+	if err != nil {
+		return nil, err
+	}
+	iw, ih := innerRect(float64(img.Rect.Dx()), float64(img.Rect.Dy()), angle)
+	// Leave out the antialiased pixels along the edges
+	w, h := math.Max(int(iw)-2, 1), math.Max(int(ih)-2, 1)
+	dx, dy := rotated.Rect.Dx()-w, rotated.Rect.Dy()-h
+	return cropPx(rotated, dx/2, dx-dx/2, dy/2, dy-dy/2)
 }
@@ -0,0 +1,94 @@
+package language
+
+import (
+	"github.com/toxyl/math"
+)
+
+// @Name: focus-stack
+// @Desc: Combines a focus bracket (e.g. macro shots focused at different distances) into a single image that is sharp everywhere. The images are aligned to the first one, then every region is taken from the image where it's sharpest (measured by the energy of the Laplacian) and the transitions are blended smoothly.
+// @Param:      images      - -       -       The images of the bracket, ordered by focus distance
+// @Param:      radius      "px" 1..10 2      The radius sharpness is measured over (higher = less noise in the selection, but less precise at edges)
+// @Param:      smoothing   "px" 0..50 4      How much the selection is smoothed
+// @Param:      autoAlign   -         true    Whether to align the images first (corrects shifts and the change of scale caused by refocusing)
+// @Param:      depth       -         false   Whether to return the depth map instead, a mask from 0 (sharpest in the first image) to 1 (sharpest in the last image)
+// @Returns:    result      - -       -       The stacked image or the depth map
+func focusStack(images []any, radius float64, smoothing float64, autoAlign bool, depth bool) (any, error) {
+	return defaultRun.focusStack(images, radius, smoothing, autoAlign, depth)
+}
+
+func (r *scriptRun) focusStack(images []any, radius float64, smoothing float64, autoAlign bool, depth bool) (any, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	w, h := imgs[0].Rect.Dx(), imgs[0].Rect.Dy()
+	f := resampleFilters["bicubic"]
+	// Neighbouring images have the most similar focus, so each image is
+	// aligned to its predecessor and the transformations are chained
+	t, prev := NewMatrix(), imgs[0]
+	for i := 1; i < len(imgs); i++ {
+		img := imgs[i]
+		if !autoAlign && img.Rect.Dx() == w && img.Rect.Dy() == h {
+			continue
+		}
+		if autoAlign {
+			// Refocusing mostly scales the image, fall back to a translation if there are too few features
+			m, err := estimateAlignment(prev, img, "similarity")
+			if err != nil {
+				m, _ = estimateAlignment(prev, img, "translation")
+			}
+			t, prev = t.Mul(m), img
+		}
+		inv, err := t.Invert()
+		if err != nil {
+			return nil, err
+		}
+		imgs[i] = warpImage(img, inv, w, h, f)
+	}
+
+	// Select the sharpest image per pixel, uncovered pixels (after alignment) can't be selected
+	n := len(imgs)
+	srcs := make([]*floatImage, n)
+	sharpness := make([]*floatImage, n)
+	for i, img := range imgs {
+		p := toPremulImage(img)
+		srcs[i] = floatImageFromPremul(p)
+		sharpness[i] = sharpnessMap(grayFloatImage(p), radius)
+		for j := range sharpness[i].pix {
+			if p.pix[j*4+3] < 1 {
+				sharpness[i].pix[j] = -1
+			}
+		}
+	}
+	weights := make([]*floatImage, n)
+	for i := range weights {
+		weights[i] = newFloatImage(w, h, 1)
+	}
+	parallelRows(0, h, func(y int) {
+		for j := y * w; j < (y+1)*w; j++ {
+			best := 0
+			for i := range sharpness {
+				if sharpness[i].pix[j] > sharpness[best].pix[j] {
+					best = i
+				}
+			}
+			weights[best].pix[j] = 1
+		}
+	})
+	for i := range weights {
+		weights[i] = weights[i].blur(smoothing)
+	}
+	normalizeWeights(weights)
+
+	if depth {
+		m := NewMask(w, h)
+		for i, wm := range weights {
+			v := float32(i) / float32(math.Max(n-1, 1))
+			for j, wv := range wm.pix {
+				m.Values[j] += wv * v
+			}
+		}
+		return m, nil
+	}
+	return r.metadata.inherit(blendPyramids(srcs, weights).premul().toNRGBA64(), images[0]), nil
+}
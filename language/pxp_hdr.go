@@ -0,0 +1,274 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"slices"
+
+	"github.com/toxyl/math"
+)
+
+// luminance returns the relative luminance of linear-light Rec. 709 colors.
+func luminance(r, g, b float32) float32 {
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// exposureValues returns the exposure values of the images, either from the
+// given list or, if it's empty, calculated from the EXIF exposure time,
+// aperture and ISO. The values from EXIF are relative to the middle exposure.
+func (r *scriptRun) exposureValues(images []any, evs []any) ([]float64, error) {
+	res := make([]float64, len(images))
+	if len(evs) > 0 {
+		if len(evs) != len(images) {
+			return nil, fmt.Errorf("expected %d exposure values, got %d", len(images), len(evs))
+		}
+		for i, v := range evs {
+			ev, err := dsl.toFloat64(v)
+			if err != nil {
+				return nil, fmt.Errorf("exposure value %d is not a number", i)
+			}
+			res[i] = ev
+		}
+		return res, nil
+	}
+	for i, v := range images {
+		m, _ := r.metadata.get(v)
+		tags := m.exifTags()
+		t, _ := tags["ExposureTime"].(float64)
+		n, _ := tags["FNumber"].(float64)
+		if t <= 0 || n <= 0 {
+			return nil, fmt.Errorf("image %d has no exposure metadata, the exposure values have to be given", i)
+		}
+		iso := 100.0
+		switch v := tags["ISOSpeedRatings"].(type) {
+		case float64:
+			iso = v
+		case []any:
+			iso, _ = v[0].(float64)
+		}
+		res[i] = math.Log2(t * math.Max(iso, 1) / 100 / (n * n))
+	}
+	sorted := slices.Clone(res)
+	slices.Sort(sorted)
+	mid := sorted[len(sorted)/2]
+	for i := range res {
+		res[i] -= mid
+	}
+	return res, nil
+}
+
+// @Name: merge-hdr
+// @Desc: Merges an exposure bracket into a linear-light HDR image with the radiance of the scene. Each pixel is a weighted average of the exposures, well-exposed values count most while clipped highlights and noisy shadows are ignored (Debevec weighting). The images have to be aligned (see align). Use a tonemap function to display the result.
+// @Param:      images      - -   -   The images of the bracket, in any order
+// @Param:      exposuresEV "EV" - -   The exposure value of each image relative to the result (e.g. {-2 0 2}), an empty list ({}) calculates them from the EXIF exposure time, aperture and ISO
+// @Returns:    result      - -   -   The linear-light HDR image
+func mergeHDR(images []any, exposuresEV []any) (*LinearImage, error) {
+	return defaultRun.mergeHDR(images, exposuresEV)
+}
+
+func (r *scriptRun) mergeHDR(images []any, exposuresEV []any) (*LinearImage, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	evs, err := r.exposureValues(images, exposuresEV)
+	if err != nil {
+		return nil, err
+	}
+	w, h := imgs[0].Rect.Dx(), imgs[0].Rect.Dy()
+	for i, img := range imgs {
+		if img.Rect.Dx() != w || img.Rect.Dy() != h {
+			return nil, fmt.Errorf("image %d has a different size (%dx%d instead of %dx%d)", i, img.Rect.Dx(), img.Rect.Dy(), w, h)
+		}
+	}
+	// Clipped values are taken from the darkest exposure, black ones from the brightest
+	darkest, brightest := 0, 0
+	scales := make([]float32, len(imgs))
+	for i, ev := range evs {
+		scales[i] = float32(math.Pow(2, -ev))
+		if ev < evs[darkest] {
+			darkest = i
+		}
+		if ev > evs[brightest] {
+			brightest = i
+		}
+	}
+
+	result := NewLinearImage(image.Rect(0, 0, w, h))
+	parallelRows(0, h, func(y int) {
+		var v, sum [3]float32
+		for x := range w {
+			v, sum = [3]float32{}, [3]float32{}
+			var alpha float32
+			for i, img := range imgs {
+				c := img.NRGBA64At(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+				alpha += float32(c.A) / 0xFFFF
+				for ch, z := range [3]uint16{c.R, c.G, c.B} {
+					// Hat weighting, values near the middle of the range are the most reliable
+					wz := float32(math.Min(z, 0xFFFF-z)) / 0x7FFF
+					v[ch] += wz * srgbToLinear16(uint32(z)) * scales[i]
+					sum[ch] += wz
+				}
+			}
+			for ch := range v {
+				if sum[ch] > 0 {
+					v[ch] /= sum[ch]
+					continue
+				}
+				i := brightest
+				c := imgs[darkest].NRGBA64At(imgs[darkest].Rect.Min.X+x, imgs[darkest].Rect.Min.Y+y)
+				if [3]uint16{c.R, c.G, c.B}[ch] > 0x7FFF {
+					i = darkest
+				}
+				c = imgs[i].NRGBA64At(imgs[i].Rect.Min.X+x, imgs[i].Rect.Min.Y+y)
+				v[ch] = srgbToLinear16(uint32([3]uint16{c.R, c.G, c.B}[ch])) * scales[i]
+			}
+			result.SetLinear(x, y, v[0], v[1], v[2], alpha/float32(len(imgs)))
+		}
+	})
+	return result, nil
+}
+
+// tonemap maps the luminance of every pixel of img with fn and returns the
+// sRGB-encoded result. Colors are scaled along with their luminance, so hues
+// and saturation are preserved.
+func tonemap(img *LinearImage, fn func(l float32) float32) *image.NRGBA64 {
+	b := img.Bounds()
+	result := IFromBounds(b)
+	parallelRows(b.Min.Y, b.Max.Y, func(y int) {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.LinearAt(x, y)
+			r, g, bl = math.Max(r, 0), math.Max(g, 0), math.Max(bl, 0)
+			s := float32(0)
+			if l := luminance(r, g, bl); l > 0 {
+				s = fn(l) / l
+			}
+			dsl.setColor(result, x, y,
+				uint32(linearToSRGB16(r*s)),
+				uint32(linearToSRGB16(g*s)),
+				uint32(linearToSRGB16(bl*s)),
+				uint32(unitToU16(a)),
+			)
+		}
+	})
+	return result
+}
+
+// luminanceStats returns the log-average and the maximum luminance of img.
+func luminanceStats(img *LinearImage) (logAvg, maxL float64) {
+	b := img.Bounds()
+	sum, n := 0.0, 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.LinearAt(x, y)
+			l := float64(luminance(math.Max(r, 0), math.Max(g, 0), math.Max(bl, 0)))
+			sum += math.Log(l + 1e-6)
+			maxL = math.Max(maxL, l)
+			n++
+		}
+	}
+	if n == 0 {
+		return 1, 1
+	}
+	return math.Exp(sum / float64(n)), maxL
+}
+
+// @Name: tonemap-reinhard
+// @Desc: Maps an HDR image to the displayable range with the photographic operator of Reinhard et al. The scene is scaled to the given key, then highlights are compressed smoothly.
+// @Param:      img     - -       -       The HDR image
+// @Param:      key     - 0..1    0.18    The brightness of the average luminance of the result (0.18 = middle gray)
+// @Param:      white   - -       0       The scaled luminance that becomes pure white (0 = the brightest pixel)
+// @Returns:    result  - -       -       The tone mapped image
+func tonemapReinhard(img *LinearImage, key float64, white float64) (*image.NRGBA64, error) {
+	logAvg, maxL := luminanceStats(img)
+	scale := float32(key / logAvg)
+	if white <= 0 {
+		white = maxL * float64(scale)
+	}
+	white2 := float32(math.Max(white*white, 1e-6))
+	return tonemap(img, func(l float32) float32 {
+		l *= scale
+		return l * (1 + l/white2) / (1 + l)
+	}), nil
+}
+
+// @Name: tonemap-aces
+// @Desc: Maps an HDR image to the displayable range with a fit of the ACES filmic curve (Narkowicz), which gives contrasty midtones and soft highlight roll-off
+// @Param:      img         - -       -   The HDR image
+// @Param:      exposure    "EV" -4..4 0   The exposure adjustment before mapping
+// @Returns:    result      - -       -   The tone mapped image
+func tonemapACES(img *LinearImage, exposure float64) (*image.NRGBA64, error) {
+	scale := float32(math.Pow(2, exposure) * 0.6)
+	return tonemap(img, func(l float32) float32 {
+		l *= scale
+		return math.Clamp((l*(2.51*l+0.03))/(l*(2.43*l+0.59)+0.14), 0, 1)
+	}), nil
+}
+
+// @Name: tonemap-drago
+// @Desc: Maps an HDR image to the displayable range with the adaptive logarithmic operator of Drago et al., which keeps details in very bright and very dark areas
+// @Param:      img         - -       -       The HDR image
+// @Param:      bias        - 0.5..1  0.85    How much dark areas are brightened and contrast is compressed (lower = brighter, flatter result)
+// @Param:      exposure    "EV" -4..4 0       The exposure adjustment before mapping
+// @Returns:    result      - -       -       The tone mapped image
+func tonemapDrago(img *LinearImage, bias float64, exposure float64) (*image.NRGBA64, error) {
+	logAvg, maxL := luminanceStats(img)
+	scale := math.Pow(2, exposure) / logAvg
+	lMax := math.Max(maxL*scale, 1e-6)
+	p := math.Log(math.Clamp(bias, 0.01, 1)) / math.Log(0.5)
+	norm := 1 / math.Log10(lMax+1)
+	return tonemap(img, func(l float32) float32 {
+		lw := float64(l) * scale
+		return float32(norm * math.Log(lw+1) / math.Log(2+8*math.Pow(lw/lMax, p)))
+	}), nil
+}
+
+// @Name: exposure-fusion
+// @Desc: Fuses an exposure bracket directly into a displayable image (Mertens et al.), without exposure values or tone mapping. Every pixel is weighted by its contrast, saturation and well-exposedness and the images are blended seamlessly at multiple scales. The images have to be aligned (see align).
+// @Param:      images      - -       -   The images of the bracket, in any order
+// @Param:      contrast    - 0..2    1   The importance of local contrast (details)
+// @Param:      saturation  - 0..2    1   The importance of color saturation
+// @Param:      exposure    - 0..2    1   The importance of values close to middle gray
+// @Returns:    result      - -       -   The fused image
+func exposureFusion(images []any, contrast float64, saturation float64, exposure float64) (*image.NRGBA64, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	w, h := imgs[0].Rect.Dx(), imgs[0].Rect.Dy()
+	for i, img := range imgs {
+		if img.Rect.Dx() != w || img.Rect.Dy() != h {
+			return nil, fmt.Errorf("image %d has a different size (%dx%d instead of %dx%d)", i, img.Rect.Dx(), img.Rect.Dy(), w, h)
+		}
+	}
+
+	srcs := make([]*floatImage, len(imgs))
+	weights := make([]*floatImage, len(imgs))
+	for i, img := range imgs {
+		p := toPremulImage(img)
+		srcs[i] = floatImageFromPremul(p)
+		lap := laplacian(grayFloatImage(p))
+		wm := newFloatImage(w, h, 1)
+		parallelRows(0, h, func(y int) {
+			for x := range w {
+				j := y*w + x
+				a := p.pix[j*4+3]
+				if a <= 0 {
+					continue
+				}
+				r, g, b := p.pix[j*4]/a, p.pix[j*4+1]/a, p.pix[j*4+2]/a
+				// Contrast: the magnitude of the Laplacian of the gray values
+				c := math.Abs(lap.pix[j])
+				// Saturation: the standard deviation of the channels
+				mu := (r + g + b) / 3
+				s := math.Sqrt(((r-mu)*(r-mu) + (g-mu)*(g-mu) + (b-mu)*(b-mu)) / 3)
+				// Well-exposedness: closeness of each channel to 0.5
+				e := math.Exp(-((r-0.5)*(r-0.5) + (g-0.5)*(g-0.5) + (b-0.5)*(b-0.5)) / (2 * 0.2 * 0.2))
+				wm.pix[j] = a * float32(math.Pow(float64(c)+1e-3, contrast)*math.Pow(float64(s)+1e-3, saturation)*math.Pow(float64(e), exposure))
+			}
+		})
+		weights[i] = wm
+	}
+	normalizeWeights(weights)
+	return blendPyramids(srcs, weights).premul().toNRGBA64(), nil
+}
@@ -2,13 +2,16 @@ package language
 
 import (
 	"bytes"
+	"fmt"
 	"image"
 	"strings"
 
 	"github.com/toxyl/math"
 )
 
-// DetectImageType determines the image type from magic bytes
+// DetectImageType determines the image type from magic bytes.
+// AVIF files are detected as "avif", but decoding them needs a system
+// libheif with an AV1 decoder, the embedded fallback only decodes HEIC.
 func DetectImageType(data []byte) string {
 	if len(data) < 8 {
 		return ""
@@ -29,9 +32,35 @@ func DetectImageType(data []byte) string {
 		return "gif"
 	}
 
-	// Check for HEIC
-	// HEIC files start with 'ftyp' box which contains 'heic' or 'mif1'
+	// Check for WebP
+	if len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")) {
+		return "webp"
+	}
+
+	// Check for TIFF (little and big endian)
+	if bytes.Equal(data[:4], []byte{'I', 'I', 0x2A, 0x00}) || bytes.Equal(data[:4], []byte{'M', 'M', 0x00, 0x2A}) {
+		if isDNG(data) {
+			return "dng"
+		}
+		return "tiff"
+	}
+
+	// Check for BMP
+	if bytes.Equal(data[:2], []byte("BM")) {
+		return "bmp"
+	}
+
+	// Check for HEIC and AVIF
+	// Both start with an 'ftyp' box which contains the major brand followed by compatible brands.
+	// AVIF files may use the generic 'mif1' major brand, so the compatible brands decide.
 	if len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")) {
+		boxSize := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		boxSize = math.Clamp(boxSize, 12, len(data))
+		for i := 8; i+4 <= boxSize; i += 4 {
+			if brand := string(data[i : i+4]); brand == "avif" || brand == "avis" {
+				return "avif"
+			}
+		}
 		if bytes.Equal(data[8:12], []byte("heic")) || bytes.Equal(data[8:12], []byte("mif1")) {
 			return "heic"
 		}
@@ -40,6 +69,38 @@ func DetectImageType(data []byte) string {
 	return ""
 }
 
+// @Name: working-space
+// @Desc: Sets the working space of images loaded afterwards, scripts start in "srgb". In "linear" mode images are decoded to linear-light float32 values, so blurs, scaling and blend modes are physically correct and values outside 0..1 are preserved until saving. Only exposure, brightness, contrast, grayscale, opacity, blur-gaussian, blur-box, the blend functions and the resampling functions (scale, resize, resize-fit, resize-fill, resize-max-mp, smart-crop, rotate, transform, skew, the warps and the lens corrections except vignette-correct) process linear-light values, all other functions receive the image converted to sRGB, which clips values outside 0..1.
+// @Param:      space   - -   "srgb"  The working space (srgb, linear)
+// @Returns:    result  - -   -       The previous working space
+func workingSpaceSet(space string) (string, error) {
+	return defaultRun.workingSpaceSet(space)
+}
+
+func (r *scriptRun) workingSpaceSet(space string) (string, error) {
+	space = strings.ToLower(strings.TrimSpace(space))
+	if space != WorkingSpaceSRGB && space != WorkingSpaceLinear {
+		return r.workingSpace(), fmt.Errorf("unknown working space: %s (expected %s or %s)", space, WorkingSpaceSRGB, WorkingSpaceLinear)
+	}
+	return r.setWorkingSpace(space), nil
+}
+
+// @Name: to-linear
+// @Desc: Converts an image to linear-light float32 values
+// @Param:      img     - - -   The image to convert
+// @Returns:    result  - - -	The linear-light image
+func imageToLinear(img *image.NRGBA64) (*LinearImage, error) {
+	return toLinearImage(img), nil
+}
+
+// @Name: to-srgb
+// @Desc: Converts a linear-light image to sRGB, clipping values outside of the displayable range
+// @Param:      img     - - -   The image to convert
+// @Returns:    result  - - -	The sRGB image
+func imageToSRGB(img *LinearImage) (*image.NRGBA64, error) {
+	return fromLinearImage(img), nil
+}
+
 // @Name: It
 // @Desc: Translates the given image by expanding/cropping the left + top borders.
 // @Param:      img     - - -   The image to translate
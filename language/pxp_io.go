@@ -1,184 +1,198 @@
 package language
 
 import (
-	"bytes"
-	"crypto/sha256"
 	"fmt"
 	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
 	"io"
-	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
-
-	"github.com/gen2brain/heic"
-	"github.com/rwcarlsen/goexif/exif"
-	"github.com/tidwall/gjson"
-	"github.com/toxyl/flo"
 )
 
-func loadFile(filePath string) (string, []byte, error) {
-	filePath = strings.TrimSpace(filePath)
-	isRemote := strings.HasPrefix(filePath, "http://") || strings.HasPrefix(filePath, "https://")
+// @Name: load
+// @Desc: Loads an image (PNG, JPEG, GIF, WebP, TIFF, BMP, HEIC, AVIF or DNG). Multi-page TIFFs are returned as a slice of images. EXIF, XMP and ICC metadata is kept with the image. AVIF needs a system libheif with an AV1 decoder.
+// @Param:      path    - -   -   Path to the image
+// @Returns:    result  - -   -   The loaded image
+func load(path string) (any, error) {
+	return defaultRun.load(path)
+}
 
-	var data []byte
-	if isRemote {
-		resp, err := http.Get(filePath)
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to download file '%s': %s", filePath, err.Error())
-		}
-		defer resp.Body.Close()
+func (r *scriptRun) load(path string) (any, error) {
+	imgs, meta, err := r.loadImages(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.imagesToWorkingSpace(imgs, meta), nil
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return "", nil, fmt.Errorf("failed to download file: status %s", resp.Status)
-		}
+// @Name: load-dng
+// @Desc: Loads a DNG raw image and develops it with the given demosaicing algorithm. AHD produces fewer artifacts along edges, bilinear is faster. `load` uses AHD.
+// @Param:      path        - -   -       Path to the DNG file
+// @Param:      demosaic    - -   "ahd"   The demosaicing algorithm (ahd, bilinear)
+// @Returns:    result      - -   -       The developed image
+func loadDNG(path string, demosaic string) (any, error) {
+	return defaultRun.loadDNG(path, demosaic)
+}
 
-		data, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to read body: %s", err.Error())
-		}
-		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(filePath)))
-		filePath = flo.File(os.TempDir()).Dir("pxp").Dir(hash).File(filepath.Base(filePath)).Path()
-		f := flo.File(filePath)
-		_ = f.Mkparent(0755)
-		if err = f.StoreBytes(data); err != nil {
-			return "", nil, fmt.Errorf("could not store downloaded file: %s", err.Error())
-		}
+func (r *scriptRun) loadDNG(path string, demosaic string) (any, error) {
+	_, data, err := r.loadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %v", err)
 	}
+	img, err := DecodeDNG(data, DNGOptions{Demosaic: demosaic})
+	if err != nil {
+		return nil, err
+	}
+	imgs, meta, err := withMetadata(data, []image.Image{img})
+	if err != nil {
+		return nil, err
+	}
+	return r.imagesToWorkingSpace(imgs, meta), nil
+}
 
-	return filePath, flo.File(filePath).AsBytes(), nil
+// imagesToWorkingSpace converts decoded images to the working space of the
+// run and attaches their metadata. Multiple images (pages) are returned as a slice.
+func (r *scriptRun) imagesToWorkingSpace(imgs []*image.NRGBA64, meta *Metadata) any {
+	// Images with a color profile are converted to the working space,
+	// linear images keep colors outside of the sRGB gamut
+	profile, meta := meta.sourceProfile()
+	toWorkingSpace := func(img *image.NRGBA64) any {
+		var res any = img
+		switch linear := r.workingSpace() == WorkingSpaceLinear; {
+		case linear && profile != nil:
+			res = convertProfileToLinear(img, profile)
+		case linear:
+			res = toLinearImage(img)
+		case profile != nil:
+			res = convertProfile(img, profile, builtinProfiles[ProfileSRGB])
+		}
+		r.metadata.set(res, meta)
+		return res
+	}
+	if len(imgs) == 1 {
+		return toWorkingSpace(imgs[0])
+	}
+	pages := make([]any, len(imgs))
+	for i, img := range imgs {
+		pages[i] = toWorkingSpace(img)
+	}
+	return pages
 }
 
-// @Name: load
-// @Desc: Loads an image
-// @Param:      path    - -   -   Path to the image
-// @Returns:    result  - -   -   The loaded image
-func load(path string) (any, error) {
+// loadImages loads and decodes all images (pages) of a file and its metadata.
+func (r *scriptRun) loadImages(path string) ([]*image.NRGBA64, *Metadata, error) {
 	path = strings.TrimSpace(path)
-	var nrgba *image.NRGBA64
 
 	// // Check if the image is in cache
 	// if cachedImg, found := ImagesCache.Get(path); found {
 	// 	ImagesCache.UpdateTimestamp(path)
 	// 	return cachedImg, nil
 	// }
-	localPath, data, err := loadFile(path)
+	_, data, err := r.loadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %v", err)
-	}
-
-	// Create a bytes reader for image decoding
-	reader := bytes.NewReader(data)
-
-	// Detect image type from magic bytes
-	imgType := DetectImageType(data)
-	if imgType == "" {
-		return nil, fmt.Errorf("unsupported image format or corrupted file")
-	}
-
-	// Decode the image based on its type
-	var img image.Image
-	switch imgType {
-	case "gif":
-		img, err = gif.Decode(reader)
-	case "png":
-		img, err = png.Decode(reader)
-	case "jpeg":
-		img, err = jpeg.Decode(reader)
-	case "heic":
-		img, err = heic.Decode(reader)
-	default:
-		return nil, fmt.Errorf("unsupported image format: %s", imgType)
+		return nil, nil, fmt.Errorf("failed to load image: %v", err)
 	}
+	return decodeImageData(data)
+}
 
+// decodeImageData decodes all images (pages) of an encoded file and its metadata.
+func decodeImageData(data []byte) ([]*image.NRGBA64, *Metadata, error) {
+	decoded, err := DecodeImages(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %v", err)
+		return nil, nil, err
 	}
+	return withMetadata(data, decoded)
+}
 
-	// Convert to NRGBA format which our functions expect
-	bounds := img.Bounds()
-	nrgba = IFromBounds(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			nrgba.Set(x, y, img.At(x, y))
-		}
-	}
+// withMetadata converts the images decoded from data to NRGBA64 and reads
+// the metadata of data. The EXIF orientation is applied to the pixels and
+// reset in the metadata.
+func withMetadata(data []byte, decoded []image.Image) ([]*image.NRGBA64, *Metadata, error) {
+	var err error
 
-	// Get EXIF
-	f, err := os.Open(localPath)
-	if err != nil {
-		return nil, err
+	// Convert to NRGBA64 format which our functions expect
+	imgs := make([]*image.NRGBA64, len(decoded))
+	for i, img := range decoded {
+		imgs[i] = imageToNRGBA64(img)
 	}
-	defer f.Close()
-
-	// defer func() {
-	// 	if nrgba != nil {
-	// 		// Store the result in cache before returning
-	// 		ImagesCache.Put(localPath, nrgba)
-	// 	}
-	// }()
 
 	// Apple is a kid with special needs, as usual ...
-	metaData, err := exif.Decode(f)
-	if err == nil {
-		jsonByte, err := metaData.MarshalJSON()
-		if err == nil {
-			jsonString := string(jsonByte)
-			or := gjson.Get(jsonString, "Orientation").Array()
-			if len(or) > 0 {
-				orientation := or[0]
-				switch orientation.String() {
-				case "1": // 1 = Horizontal (normal) = no change
-				case "2": // 2 = Mirror horizontal
-					nrgba, err = flipHorizontal(nrgba)
-					return nrgba, err
-				case "3": // 3 = Rotate 180
-					nrgba, err = rotate(nrgba, 180)
-					return nrgba, err
-				case "4": // 4 = Mirror vertical
-					nrgba, err = flipVertical(nrgba)
-					return nrgba, err
-				case "5": // 5 = Mirror horizontal and rotate 270 CW
-					res, err := flipHorizontal(nrgba)
-					if err != nil {
-						return nil, err
-					}
-					nrgba, err = rotate(res, 270)
-					return nrgba, err
-				case "6": // 6 = Rotate 90 CW
-					nrgba, err = rotate(nrgba, 90)
-					return nrgba, err
-				case "7": // 7 = Mirror horizontal and rotate 90 CW
-					res, err := flipHorizontal(nrgba)
-					if err != nil {
-						return nil, err
-					}
-					nrgba, err = rotate(res, 90)
-					return nrgba, err
-				case "8": // 8 = Rotate 270 CW
-					nrgba, err = rotate(nrgba, 270)
-					return nrgba, err
-				}
+	meta := ReadMetadata(data)
+	if orientation := meta.orientation(); orientation != 1 {
+		for i, img := range imgs {
+			if imgs[i], err = applyOrientation(img, orientation); err != nil {
+				return nil, nil, err
 			}
 		}
+		if meta, err = meta.withTag("Orientation", "1"); err != nil {
+			return nil, nil, err
+		}
 	}
 
+	return imgs, meta, nil
+}
+
+// applyOrientation rotates and mirrors img according to the given EXIF orientation.
+func applyOrientation(nrgba *image.NRGBA64, orientation int) (*image.NRGBA64, error) {
+	switch orientation {
+	case 1: // 1 = Horizontal (normal) = no change
+	case 2: // 2 = Mirror horizontal
+		return flipHorizontal(nrgba)
+	case 3: // 3 = Rotate 180
+		return rotate(nrgba, 180, "nearest")
+	case 4: // 4 = Mirror vertical
+		return flipVertical(nrgba)
+	case 5: // 5 = Mirror horizontal and rotate 270 CW
+		res, err := flipHorizontal(nrgba)
+		if err != nil {
+			return nil, err
+		}
+		return rotate(res, 270, "nearest")
+	case 6: // 6 = Rotate 90 CW
+		return rotate(nrgba, 90, "nearest")
+	case 7: // 7 = Mirror horizontal and rotate 90 CW
+		res, err := flipHorizontal(nrgba)
+		if err != nil {
+			return nil, err
+		}
+		return rotate(res, 90, "nearest")
+	case 8: // 8 = Rotate 270 CW
+		return rotate(nrgba, 270, "nearest")
+	}
 	return nrgba, nil
 }
 
 // @Name: save
-// @Desc: Saves an image
-// @Param:      img     - -   -    The image to save
-// @Param:      path    - -   -    Path where to save
-func save(img *image.NRGBA64, path string) (any, error) {
-	tmp := flo.File(path + ".part")
-	w := bytes.Buffer{}
-	png.Encode(&w, img) // note that we use NRGBA because storing PNGs is much faster that way
-	if err := tmp.StoreBytes(w.Bytes()); err != nil {
-		return img, err
+// @Desc: Saves an image. The format is derived from the file extension unless given explicitly. JPEGs and PNGs include the image's EXIF, XMP and ICC metadata.
+// @Param:      img         - -         -           The image to save
+// @Param:      path        - -         -           Path where to save
+// @Param:      format      - -         ""          The format (png, jpeg, webp, tiff, gif, bmp), empty to use the file extension
+// @Param:      quality     - 1..100    90          The JPEG quality
+// @Param:      subsampling - -         "420"       The JPEG chroma subsampling (444, 422, 420)
+// @Param:      compression - -         "default"   The PNG (none, fast, default, best) or TIFF (none, default) compression
+// @Param:      colors      - 2..256    256         The size of the GIF palette
+// @Param:      dither      -           true        Whether to dither GIFs
+// @Param:      depth       - 0..16     0           The bits per channel of PNGs and TIFFs (8, 16 or 0 to keep the image's depth)
+func save(img *image.NRGBA64, path string, format string, quality int, subsampling string, compression string, colors int, dither bool, depth int) (any, error) {
+	return defaultRun.save(img, path, format, quality, subsampling, compression, colors, dither, depth)
+}
+
+func (r *scriptRun) save(img *image.NRGBA64, path string, format string, quality int, subsampling string, compression string, colors int, dither bool, depth int) (any, error) {
+	meta, _ := r.metadata.get(img)
+	opts := SaveOptions{
+		Format:      format,
+		Quality:     quality,
+		Subsampling: subsampling,
+		Compression: compression,
+		Colors:      colors,
+		Dither:      dither,
+		Depth:       depth,
+		Metadata:    meta,
+	}
+	if opts.Format == "" {
+		if opts.Format = FormatFromPath(path); opts.Format == "" {
+			return nil, fmt.Errorf("can't determine image format from path: %s", path)
+		}
 	}
-	return img, os.Rename(tmp.Path(), path)
+	return img, r.writeFile(path, func(w io.Writer) error {
+		return EncodeImage(w, img, opts)
+	})
 }
@@ -0,0 +1,203 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/toxyl/math"
+)
+
+// @Name: layer
+// @Desc: Creates a layer that can be composited with flatten
+// @Param:      img      - -          -          The image of the layer
+// @Param:      mode     - -          "normal"   The blend mode used to composite the layer onto the layers below
+// @Param:      opacity  "%" 0.0..1.0 1.0        The opacity of the layer
+// @Param:      x        "px" -       0          The horizontal offset on the canvas
+// @Param:      y        "px" -       0          The vertical offset on the canvas
+// @Returns:    result   - -          -          The layer
+func makeLayer(img *image.NRGBA64, mode string, opacity float64, x int, y int) (*Layer, error) {
+	if blenders.get(mode) == nil {
+		return nil, fmt.Errorf("unknown blend mode: %s", mode)
+	}
+	l := NewLayer(img)
+	l.BlendMode = mode
+	l.Opacity = math.Clamp(opacity, 0, 1)
+	l.X, l.Y = x, y
+	return l, nil
+}
+
+// @Name: layer-mask
+// @Desc: Sets the mask of a layer. The mask scales the opacity of the layer and is placed at the position of the layer. Images can be used as masks, their luminance multiplied by their alpha is used.
+// @Param:      l       - -   -   The layer
+// @Param:      mask    - -   -   The mask
+// @Returns:    result  - -   -   The masked layer
+func layerMask(l *Layer, mask *Mask) (*Layer, error) {
+	res := l.Clone()
+	res.Mask = mask
+	return res, nil
+}
+
+// @Name: layer-visible
+// @Desc: Shows or hides a layer
+// @Param:      l        - -   -   The layer
+// @Param:      visible  -     true Whether the layer is visible
+// @Returns:    result   - -   -   The layer
+func layerVisible(l *Layer, visible bool) (*Layer, error) {
+	res := l.Clone()
+	res.Visible = visible
+	return res, nil
+}
+
+// @Name: layer-image
+// @Desc: Returns the image of a layer
+// @Param:      l       - -   -   The layer
+// @Returns:    result  - -   -   The image
+func layerImage(l *Layer) (*image.NRGBA64, error) {
+	return l.Image, nil
+}
+
+// flattenLayer is a visible layer prepared for compositing.
+type flattenLayer struct {
+	*Layer
+	blender *Blender
+	bounds  image.Rectangle
+}
+
+// maskAt returns the mask value (0..1) at canvas position (x, y).
+func (l *flattenLayer) maskAt(x, y int) float64 {
+	if l.Mask == nil {
+		return 1
+	}
+	return float64(l.Mask.At(x-l.X, y-l.Y))
+}
+
+// @Name: flatten
+// @Desc: Composites a stack of layers (bottom to top) into a single image. The canvas spans from 0,0 to the far edges of all visible layers. Blend modes are applied according to the W3C compositing model, so layers blend with what's below them and keep their colors where nothing is below.
+// @Param:      layers  - -   -   The layers to composite, the first one is the bottom layer
+// @Returns:    result  - -   -   The flattened image
+func flatten(layers []any) (*image.NRGBA64, error) {
+	var stack []*flattenLayer
+	var canvas image.Rectangle
+	list, err := layerList(layers)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range list {
+		if !l.Visible || l.Opacity <= 0 {
+			continue
+		}
+		b := blenders.get(l.BlendMode)
+		if b == nil {
+			return nil, fmt.Errorf("layer %d: unknown blend mode: %s", i, l.BlendMode)
+		}
+		fl := &flattenLayer{Layer: l, blender: b, bounds: l.Bounds()}
+		stack = append(stack, fl)
+		canvas = canvas.Union(image.Rect(0, 0, math.Max(0, fl.bounds.Max.X), math.Max(0, fl.bounds.Max.Y)))
+	}
+	if canvas.Empty() {
+		return nil, fmt.Errorf("no visible layers to flatten")
+	}
+
+	res := IFromBounds(canvas)
+	parallelRows(canvas.Min.Y, canvas.Max.Y, func(y int) {
+		for x := canvas.Min.X; x < canvas.Max.X; x++ {
+			var r1, g1, b1, a1 float64 // non-premultiplied, colors 0..0xffff, alpha 0..1
+			for _, l := range stack {
+				if !(image.Point{x, y}.In(l.bounds)) {
+					continue
+				}
+				r2, g2, b2, a2 := dsl.getColor(l.Image, x-l.X+l.Image.Rect.Min.X, y-l.Y+l.Image.Rect.Min.Y)
+				alpha := float64(a2) / 0xffff * l.Opacity
+				if alpha > 0 {
+					alpha *= l.maskAt(x, y)
+				}
+				if alpha <= 0 {
+					continue
+				}
+				if l.BlendMode == ERASE {
+					a1 *= 1 - alpha
+					continue
+				}
+				cr, cg, cb := float64(r2), float64(g2), float64(b2)
+				if a1 > 0 {
+					// Where there is something below, the color is the blend result
+					br, bg, bb, _ := l.blender.process(uint32(r1), uint32(g1), uint32(b1), 0xffff, r2, g2, b2, 0xffff)
+					cr += (float64(br) - cr) * a1
+					cg += (float64(bg) - cg) * a1
+					cb += (float64(bb) - cb) * a1
+				}
+				a := alpha + a1*(1-alpha)
+				r1 = (cr*alpha + r1*a1*(1-alpha)) / a
+				g1 = (cg*alpha + g1*a1*(1-alpha)) / a
+				b1 = (cb*alpha + b1*a1*(1-alpha)) / a
+				a1 = a
+			}
+			if a1 > 0 {
+				dsl.setColor(res, x, y, uint32(math.Clamp(r1, 0, 0xffff)), uint32(math.Clamp(g1, 0, 0xffff)), uint32(math.Clamp(b1, 0, 0xffff)), uint32(math.Clamp(a1, 0, 1)*0xffff))
+			}
+		}
+	})
+	return res, nil
+}
+
+// layerList converts a list of script values to layers.
+func layerList(layers []any) ([]*Layer, error) {
+	res := make([]*Layer, len(layers))
+	for i, v := range layers {
+		l, ok := v.(*Layer)
+		if !ok {
+			return nil, fmt.Errorf("item %d is not a layer", i)
+		}
+		res[i] = l
+	}
+	return res, nil
+}
+
+// @Name: load-layers
+// @Desc: Loads the layers of an OpenRaster (.ora) or Photoshop document (.psd, RGB with 8 or 16 bits per channel), bottom layer first. Names, blend modes, opacity, offsets, visibility and PSD layer masks are kept, use flatten to composite the layers.
+// @Param:      path    - -   -   Path to the document
+// @Returns:    result  - -   -   The layers
+func loadLayers(path string) ([]any, error) {
+	return defaultRun.loadLayers(path)
+}
+
+func (r *scriptRun) loadLayers(path string) ([]any, error) {
+	_, data, err := r.loadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load layers: %v", err)
+	}
+	layers, err := DecodeLayers(data)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]any, len(layers))
+	for i, l := range layers {
+		res[i] = l
+	}
+	return res, nil
+}
+
+// @Name: save-layers
+// @Desc: Saves layers as OpenRaster document (.ora) that can be opened by GIMP, Krita and MyPaint. Blend modes without an OpenRaster equivalent are stored as "pxp:<mode>", masks are applied to the alpha of their layers.
+// @Param:      layers  - -   -   The layers to save, the first one is the bottom layer
+// @Param:      path    - -   -   Path where to save
+// @Returns:    result  - -   -   The layers
+func saveLayers(layers []any, path string) ([]any, error) {
+	return defaultRun.saveLayers(layers, path)
+}
+
+func (r *scriptRun) saveLayers(layers []any, path string) ([]any, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".ora" {
+		return nil, fmt.Errorf("layers can only be saved as OpenRaster (.ora), not %s", ext)
+	}
+	list, err := layerList(layers)
+	if err != nil {
+		return nil, err
+	}
+	return layers, r.writeFile(path, func(w io.Writer) error {
+		return EncodeORA(w, list)
+	})
+}
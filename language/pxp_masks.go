@@ -0,0 +1,216 @@
+package language
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/toxyl/math"
+)
+
+// combineMasks merges two masks using fn. The result covers both masks
+// (union) or only their overlap (intersection), values outside of a mask are 0.
+func combineMasks(a, b *Mask, union bool, fn func(va, vb float32) float32) *Mask {
+	r := a.Rect.Intersect(b.Rect)
+	if union {
+		r = a.Rect.Union(b.Rect)
+	}
+	res := NewMask(r.Dx(), r.Dy())
+	res.Source = a.Source
+	parallelRows(0, r.Dy(), func(y int) {
+		for x := range r.Dx() {
+			res.Values[y*r.Dx()+x] = fn(a.At(x, y), b.At(x, y))
+		}
+	})
+	return res
+}
+
+// @Name: mask-union
+// @Desc: Combines two masks, a pixel is selected as much as it is selected in either mask
+// @Param:      a       - -   -   The first mask
+// @Param:      b       - -   -   The second mask
+// @Returns:    result  - -   -   The combined mask
+func maskUnion(a, b *Mask) (*Mask, error) {
+	return combineMasks(a, b, true, func(va, vb float32) float32 { return math.Max(va, vb) }), nil
+}
+
+// @Name: mask-intersect
+// @Desc: Intersects two masks, a pixel is selected as much as it is selected in both masks
+// @Param:      a       - -   -   The first mask
+// @Param:      b       - -   -   The second mask
+// @Returns:    result  - -   -   The intersected mask
+func maskIntersect(a, b *Mask) (*Mask, error) {
+	return combineMasks(a, b, false, func(va, vb float32) float32 { return math.Min(va, vb) }), nil
+}
+
+// @Name: mask-invert
+// @Desc: Inverts a mask, selecting everything that wasn't selected
+// @Param:      m       - -   -   The mask to invert
+// @Returns:    result  - -   -   The inverted mask
+func maskInvert(m *Mask) (*Mask, error) {
+	res := m.Clone()
+	for i, v := range res.Values {
+		res.Values[i] = 1 - v
+	}
+	return res, nil
+}
+
+// @Name: mask-feather
+// @Desc: Softens the edges of a mask with a Gaussian blur
+// @Param:      m       - -         -   The mask to feather
+// @Param:      radius  "px" 0..100 5   The width of the soft edge
+// @Returns:    result  - -         -   The feathered mask
+func maskFeather(m *Mask, radius float64) (*Mask, error) {
+	if radius <= 0 {
+		return m, nil
+	}
+	sigma := radius / 3
+	n := int(math.Ceil(radius))
+	kernel := make([]float32, 2*n+1)
+	sum := float32(0)
+	for i := -n; i <= n; i++ {
+		kernel[i+n] = float32(math.Exp(-float64(i*i) / (2 * sigma * sigma)))
+		sum += kernel[i+n]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	tmp := NewMask(w, h)
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			v := float32(0)
+			for i, k := range kernel {
+				v += k * m.Values[y*w+math.Clamp(x+i-n, 0, w-1)]
+			}
+			tmp.Values[y*w+x] = v
+		}
+	})
+	res := NewMask(w, h)
+	res.Source = m.Source
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			v := float32(0)
+			for i, k := range kernel {
+				v += k * tmp.Values[math.Clamp(y+i-n, 0, h-1)*w+x]
+			}
+			res.Values[y*w+x] = math.Clamp(v, 0, 1)
+		}
+	})
+	return res, nil
+}
+
+// slidingExtreme writes the maximum (or minimum) of src within x-r..x+r to
+// dst[x], using a monotonic queue so each value is handled once.
+func slidingExtreme(src, dst []float32, r int, max bool) {
+	better := func(a, b float32) bool {
+		if max {
+			return a >= b
+		}
+		return a <= b
+	}
+	queue := make([]int, 0, len(src))
+	head, next := 0, 0
+	for x := range src {
+		for ; next < len(src) && next <= x+r; next++ {
+			for len(queue) > head && better(src[next], src[queue[len(queue)-1]]) {
+				queue = queue[:len(queue)-1]
+			}
+			queue = append(queue, next)
+		}
+		for queue[head] < x-r {
+			head++
+		}
+		dst[x] = src[queue[head]]
+	}
+}
+
+// morphMask grows (dilates) or shrinks (erodes) m by a circle of the given radius.
+// Each output row combines the sliding extremes of the rows within the
+// radius, with the width of the circle at that row.
+func morphMask(m *Mask, radius int, grow bool) *Mask {
+	if radius <= 0 {
+		return m
+	}
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	res := NewMask(w, h)
+	res.Source = m.Source
+	parallelRows(0, h, func(y int) {
+		out := res.Values[y*w : (y+1)*w]
+		row := make([]float32, w)
+		first := true
+		for dy := -radius; dy <= radius; dy++ {
+			sy := y + dy
+			if sy < 0 || sy >= h {
+				continue
+			}
+			hw := int(math.Sqrt(float64(radius*radius - dy*dy)))
+			slidingExtreme(m.Values[sy*w:(sy+1)*w], row, hw, grow)
+			for x, v := range row {
+				if first || (grow && v > out[x]) || (!grow && v < out[x]) {
+					out[x] = v
+				}
+			}
+			first = false
+		}
+	})
+	return res
+}
+
+// @Name: mask-grow
+// @Desc: Expands the selected area of a mask
+// @Param:      m       - -         -   The mask to grow
+// @Param:      radius  "px" 0..100 1   The number of pixels to grow by
+// @Returns:    result  - -         -   The grown mask
+func maskGrow(m *Mask, radius int) (*Mask, error) {
+	return morphMask(m, radius, true), nil
+}
+
+// @Name: mask-shrink
+// @Desc: Contracts the selected area of a mask
+// @Param:      m       - -         -   The mask to shrink
+// @Param:      radius  "px" 0..100 1   The number of pixels to shrink by
+// @Returns:    result  - -         -   The shrunk mask
+func maskShrink(m *Mask, radius int) (*Mask, error) {
+	return morphMask(m, radius, false), nil
+}
+
+// @Name: apply-masked
+// @Desc: Restricts an effect to the selected area: where the mask is selected the result of the effect is used, elsewhere the original image. Partially selected pixels are mixed.
+// @Param:      img           - -   -   The original image
+// @Param:      effectResult  - -   -   The image with the effect applied (e.g. sharpen(img 1))
+// @Param:      mask          - -   -   The mask restricting the effect, aligned with the upper-left corner of img
+// @Returns:    result        - -   -   The resulting image
+func applyMasked(img *image.NRGBA64, effectResult *image.NRGBA64, mask *Mask) (*image.NRGBA64, error) {
+	b := img.Bounds()
+	eb := effectResult.Bounds()
+	res := IFromBounds(b)
+	parallelRows(b.Min.Y, b.Max.Y, func(y int) {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c1 := img.NRGBA64At(x, y)
+			m := float64(mask.At(x-b.Min.X, y-b.Min.Y))
+			ex, ey := eb.Min.X+x-b.Min.X, eb.Min.Y+y-b.Min.Y
+			if m <= 0 || !(image.Point{ex, ey}.In(eb)) {
+				res.SetNRGBA64(x, y, c1)
+				continue
+			}
+			c2 := effectResult.NRGBA64At(ex, ey)
+			if m >= 1 {
+				res.SetNRGBA64(x, y, c2)
+				continue
+			}
+			// Mix premultiplied colors so transparent pixels don't bleed
+			a1, a2 := float64(c1.A), float64(c2.A)
+			a := a1 + (a2-a1)*m
+			if a <= 0 {
+				continue
+			}
+			mix := func(v1, v2 uint16) uint16 {
+				p := float64(v1)*a1 + (float64(v2)*a2-float64(v1)*a1)*m
+				return uint16(math.Clamp(p/a+0.5, 0, 0xffff))
+			}
+			res.SetNRGBA64(x, y, color.NRGBA64{R: mix(c1.R, c2.R), G: mix(c1.G, c2.G), B: mix(c1.B, c2.B), A: uint16(a + 0.5)})
+		}
+	})
+	return res, nil
+}
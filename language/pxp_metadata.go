@@ -0,0 +1,94 @@
+package language
+
+import "image"
+
+// @Name: exif
+// @Desc: Returns the EXIF tags of an image as a map, tags are accessed by name, e.g. tags["Model"]. Rationals are returned as numbers, tags with multiple values as lists.
+// @Param:      img     - -   -   The image
+// @Returns:    result  - -   -   The EXIF tags
+func exifMap(img image.Image) (map[string]any, error) {
+	return defaultRun.exifMap(img)
+}
+
+func (r *scriptRun) exifMap(img image.Image) (map[string]any, error) {
+	m, _ := r.metadata.get(img)
+	return m.exifTags(), nil
+}
+
+// @Name: set-exif
+// @Desc: Sets an EXIF tag of an image. Text tags take the value as is, numeric tags one or more space-separated numbers.
+// @Param:      img     - -   -   The image
+// @Param:      tag     - -   -   The name of the tag, e.g. "Artist" or "Copyright"
+// @Param:      value   - -   -   The value of the tag
+// @Returns:    result  - -   -   The image with the new tag
+func setEXIF(img image.Image, tag string, value string) (image.Image, error) {
+	return defaultRun.setEXIF(img, tag, value)
+}
+
+func (r *scriptRun) setEXIF(img image.Image, tag string, value string) (image.Image, error) {
+	m, _ := r.metadata.get(img)
+	m, err := m.withTag(tag, value)
+	if err != nil {
+		return nil, err
+	}
+	res := shallowCopyImage(img)
+	r.metadata.set(res, m)
+	return res, nil
+}
+
+// @Name: strip-metadata
+// @Desc: Removes all metadata (EXIF, XMP and optionally the ICC profile) from an image
+// @Param:      img         - -   -       The image
+// @Param:      keepProfile -     false   Whether to keep the ICC color profile
+// @Returns:    result      - -   -       The image without metadata
+func stripMetadata(img image.Image, keepProfile bool) (image.Image, error) {
+	return defaultRun.stripMetadata(img, keepProfile)
+}
+
+func (r *scriptRun) stripMetadata(img image.Image, keepProfile bool) (image.Image, error) {
+	m := &Metadata{}
+	if old, _ := r.metadata.get(img); keepProfile && old != nil {
+		m.ICC = old.ICC
+	}
+	res := shallowCopyImage(img)
+	r.metadata.set(res, m)
+	return res, nil
+}
+
+// @Name: convert-profile
+// @Desc: Converts an image to another color profile and tags it with that profile, so it's embedded when saving. Images without a profile are treated as sRGB, colors outside of the target gamut are clipped.
+// @Param:      img     - -   -       The image
+// @Param:      profile - -   "srgb"  The target profile (srgb, display-p3, adobe-rgb, rec2020)
+// @Returns:    result  - -   -       The converted image
+func convertColorProfile(img *image.NRGBA64, profile string) (*image.NRGBA64, error) {
+	return defaultRun.convertColorProfile(img, profile)
+}
+
+func (r *scriptRun) convertColorProfile(img *image.NRGBA64, profile string) (*image.NRGBA64, error) {
+	dst, err := builtinProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := r.metadata.get(img)
+	res := convertProfile(img, m.profile(), dst)
+	m = m.Clone()
+	m.ICC = dst.bytes()
+	r.metadata.set(res, m)
+	return res, nil
+}
+
+// @Name: color-profile
+// @Desc: Returns the description of the color profile of an image, "sRGB" if it has none
+// @Param:      img     - -   -   The image
+// @Returns:    result  - -   -   The profile description
+func colorProfileName(img image.Image) (string, error) {
+	return defaultRun.colorProfileName(img)
+}
+
+func (r *scriptRun) colorProfileName(img image.Image) (string, error) {
+	m, _ := r.metadata.get(img)
+	if p := m.profile(); p.name != "" {
+		return p.name, nil
+	}
+	return "unnamed profile", nil
+}
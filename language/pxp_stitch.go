@@ -0,0 +1,168 @@
+package language
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/toxyl/math"
+)
+
+const (
+	featureMaxSize   = 1200 // images are reduced to this size to detect features
+	featureMaxPoints = 1500 // the number of features detected per image
+	minInliers       = 15   // the number of matches two images need to be considered overlapping
+)
+
+// detectFeatures detects the ORB features of img at reduced size, their
+// positions are given in the coordinates of img. The second value is the
+// reduction factor, so pixel tolerances can be adjusted.
+func detectFeatures(img *image.NRGBA64) ([]keypoint, float64) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	s := math.Min(1, float64(featureMaxSize)/float64(math.Max(w, h)))
+	if s < 1 {
+		img = resample(img, math.Max(int(float64(w)*s), 1), math.Max(int(float64(h)*s), 1), resampleFilters["area"])
+	}
+	points := detectORB(img, featureMaxPoints)
+	for i := range points {
+		points[i].X = (points[i].X + 0.5) / s
+		points[i].Y = (points[i].Y + 0.5) / s
+	}
+	return points, s
+}
+
+// @Name: stitch
+// @Desc: Stitches overlapping images (e.g. 2-6 shots of a panorama, in any order) into a single image. Matching features are detected in the overlapping areas, the images are projected onto the plane of the image overlapping the others most and blended with soft seams. Works best with little parallax and a total field of view well below 180°.
+// @Param:      images  - -   -   The overlapping images
+// @Returns:    result  - -   -   The stitched image
+func stitch(images []any) (*image.NRGBA64, error) {
+	imgs, err := composeImages(images)
+	if err != nil {
+		return nil, err
+	}
+	n := len(imgs)
+	if n == 1 {
+		return imgs[0], nil
+	}
+
+	features := make([][]keypoint, n)
+	scales := make([]float64, n)
+	for i, img := range imgs {
+		features[i], scales[i] = detectFeatures(img)
+	}
+
+	// Estimate the homographies between all pairs, H[i][j] maps image j onto image i
+	H := make([][]*Matrix, n)
+	inliers := make([][]int, n)
+	for i := range H {
+		H[i] = make([]*Matrix, n)
+		inliers[i] = make([]int, n)
+	}
+	for i := range n {
+		for j := i + 1; j < n; j++ {
+			pi, pj := matchKeypoints(features[i], features[j])
+			threshold := 3 / math.Min(scales[i], scales[j])
+			m, in, err := ransac(pj, pi, 4, fitHomography, threshold, 1000)
+			if err != nil || len(in) < minInliers || m[0]*m[4]-m[1]*m[3] <= 0 {
+				continue // no overlap, or a mirrored mapping that can't be right
+			}
+			inv, err := m.Invert()
+			if err != nil {
+				continue
+			}
+			H[i][j], H[j][i] = m, inv
+			inliers[i][j], inliers[j][i] = len(in), len(in)
+		}
+	}
+
+	// Use the image overlapping the others most as reference and connect the
+	// others through their strongest overlaps (a maximum spanning tree)
+	root, rootScore := 0, -1
+	for i := range n {
+		score := 0
+		for _, c := range inliers[i] {
+			score += c
+		}
+		if score > rootScore {
+			root, rootScore = i, score
+		}
+	}
+	transforms := make([]*Matrix, n)
+	transforms[root] = NewMatrix()
+	for range n - 1 {
+		from, to := -1, -1
+		for i := range n {
+			for j := range n {
+				if transforms[i] != nil && transforms[j] == nil && H[i][j] != nil && (from < 0 || inliers[i][j] > inliers[from][to]) {
+					from, to = i, j
+				}
+			}
+		}
+		if from < 0 {
+			break
+		}
+		transforms[to] = transforms[from].Mul(H[from][to])
+	}
+	for i, t := range transforms {
+		if t == nil {
+			return nil, fmt.Errorf("image %d doesn't overlap with the other images", i)
+		}
+	}
+
+	// The canvas covers all projected images
+	minX, minY, maxX, maxY := 0.0, 0.0, 0.0, 0.0
+	area := 0
+	for i, img := range imgs {
+		w, h := float64(img.Rect.Dx()), float64(img.Rect.Dy())
+		area += img.Rect.Dx() * img.Rect.Dy()
+		for j, c := range [][2]float64{{0, 0}, {w, 0}, {w, h}, {0, h}} {
+			x, y := transforms[i].Apply(c[0], c[1])
+			if i == 0 && j == 0 {
+				minX, minY, maxX, maxY = x, y, x, y
+			}
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+	w, h := int(math.Ceil(maxX-minX)), int(math.Ceil(maxY-minY))
+	if w <= 0 || h <= 0 || float64(w)*float64(h) > 4*float64(area) {
+		return nil, fmt.Errorf("the images can't be projected onto a common plane, the field of view might be too wide")
+	}
+
+	srcs := make([]*premulImage, n)
+	invs := make([]*Matrix, n)
+	for i, img := range imgs {
+		srcs[i] = toPremulImage(img)
+		inv, err := transforms[i].Invert()
+		if err != nil {
+			return nil, err
+		}
+		invs[i] = inv.Mul(NewAffineMatrix(1, 0, minX, 0, 1, minY))
+	}
+
+	// Blend the images, each weighted by its distance to its edges, so seams fade smoothly
+	f := resampleFilters["bicubic"]
+	result := I(w, h)
+	parallelRows(0, h, func(y int) {
+		for x := range w {
+			var r, g, b, a, sum float32
+			for i, src := range srcs {
+				u, v := invs[i].Apply(float64(x)+0.5, float64(y)+0.5)
+				sw, sh := float64(src.w), float64(src.h)
+				if u < 0 || v < 0 || u >= sw || v >= sh {
+					continue
+				}
+				weight := float32((1-math.Abs(2*u/sw-1))*(1-math.Abs(2*v/sh-1))) + 1e-6
+				sr, sg, sb, sa := src.sample(f, u, v, 1)
+				r += sr * weight
+				g += sg * weight
+				b += sb * weight
+				a += sa * weight
+				sum += weight
+			}
+			if sum > 0 {
+				setPremul(result, x, y, r/sum, g/sum, b/sum, a/sum)
+			}
+		}
+	})
+	return result, nil
+}
@@ -200,6 +200,27 @@ func makeCircle(centerX, centerY, radius float64) (Ellipse, error) {
 	return E(centerX, centerY, radius, radius), nil
 }
 
+// //////// QUAD ///////////////////////////////////////////////////
+func Q(p1, p2, p3, p4 Point) Quad {
+	return Quad{
+		P1: P(p1.X, p1.Y),
+		P2: P(p2.X, p2.Y),
+		P3: P(p3.X, p3.Y),
+		P4: P(p4.X, p4.Y),
+	}
+}
+
+// @Name: Q
+// @Desc: Creates a new quad from four corners, e.g. upper-left, upper-right, lower-right and lower-left.
+// @Param:      p1       - - -   The first corner
+// @Param:      p2       - - -   The second corner
+// @Param:      p3       - - -   The third corner
+// @Param:      p4       - - -   The fourth corner
+// @Returns:    result   - - -	 A new quad
+func makeQuad(p1, p2, p3, p4 Point) (Quad, error) {
+	return Q(p1, p2, p3, p4), nil
+}
+
 // //////// IMAGES ///////////////////////////////////////////////////
 
 // @Name: Iw
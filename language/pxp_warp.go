@@ -0,0 +1,168 @@
+package language
+
+import (
+	"image"
+
+	"github.com/toxyl/math"
+)
+
+// warpImage renders a w x h image where each pixel shows the point of img that
+// inv maps it to, relative to the upper-left corner of img.
+func warpImage(img *image.NRGBA64, inv *Matrix, w, h int, f *resampleFilter) *image.NRGBA64 {
+	return remapImage(img, w, h, f, inv.Apply)
+}
+
+// @Name: matrix
+// @Desc: Creates an affine transformation matrix that maps (x, y) to (a*x + b*y + c, d*x + e*y + f). Coordinates are in pixels with the origin in the upper-left corner.
+// @Param:      a       - -   1   The horizontal scale
+// @Param:      b       - -   0   The horizontal shear
+// @Param:      c       - -   0   The horizontal translation
+// @Param:      d       - -   0   The vertical shear
+// @Param:      e       - -   1   The vertical scale
+// @Param:      f       - -   0   The vertical translation
+// @Returns:    result  - -   -   The matrix
+func makeMatrix(a, b, c, d, e, f float64) (*Matrix, error) {
+	return NewAffineMatrix(a, b, c, d, e, f), nil
+}
+
+// @Name: matrix-translate
+// @Desc: Creates a matrix that moves points
+// @Param:      dx      "px" -   0   The horizontal translation
+// @Param:      dy      "px" -   0   The vertical translation
+// @Returns:    result  - -      -   The matrix
+func matrixTranslate(dx, dy float64) (*Matrix, error) {
+	return NewAffineMatrix(1, 0, dx, 0, 1, dy), nil
+}
+
+// @Name: matrix-rotate
+// @Desc: Creates a matrix that rotates points around the origin
+// @Param:      angle   "°" -360..360   0   The rotation angle in degrees (positive = clockwise)
+// @Returns:    result  - -             -   The matrix
+func matrixRotate(angle float64) (*Matrix, error) {
+	sin, cos := math.Sin(angle*math.Pi/180), math.Cos(angle*math.Pi/180)
+	return NewAffineMatrix(cos, -sin, 0, sin, cos, 0), nil
+}
+
+// @Name: matrix-scale
+// @Desc: Creates a matrix that scales points relative to the origin
+// @Param:      sx      - -   1   The horizontal scale factor (1 = unchanged)
+// @Param:      sy      - -   1   The vertical scale factor (1 = unchanged)
+// @Returns:    result  - -   -   The matrix
+func matrixScale(sx, sy float64) (*Matrix, error) {
+	return NewAffineMatrix(sx, 0, 0, 0, sy, 0), nil
+}
+
+// @Name: matrix-skew
+// @Desc: Creates a matrix that skews (shears) points relative to the origin
+// @Param:      ax      "°" -89..89   0   The horizontal skew angle in degrees
+// @Param:      ay      "°" -89..89   0   The vertical skew angle in degrees
+// @Returns:    result  - -           -   The matrix
+func matrixSkew(ax, ay float64) (*Matrix, error) {
+	return NewAffineMatrix(1, math.Tan(ax*math.Pi/180), 0, math.Tan(ay*math.Pi/180), 1, 0), nil
+}
+
+// @Name: matrix-compose
+// @Desc: Combines two transformations into one that applies a first and b second
+// @Param:      a       - -   -   The first transformation
+// @Param:      b       - -   -   The second transformation
+// @Returns:    result  - -   -   The combined matrix
+func matrixCompose(a, b *Matrix) (*Matrix, error) {
+	return b.Mul(a), nil
+}
+
+// @Name: matrix-invert
+// @Desc: Inverts a matrix, so the result undoes the transformation
+// @Param:      m       - -   -   The matrix to invert
+// @Returns:    result  - -   -   The inverted matrix
+func matrixInvert(m *Matrix) (*Matrix, error) {
+	return m.Invert()
+}
+
+// @Name: warp-affine
+// @Desc: Transforms an image with a matrix, e.g. matrix-compose(matrix-rotate(10) matrix-translate(20 0)). The result has the size of the image, areas without source pixels are transparent.
+// @Param:      img     - -   -           The image to warp
+// @Param:      m       - -   -           The transformation matrix, mapping source to result coordinates
+// @Param:      filter  - -   "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -   -           The warped image
+func warpAffine(img *image.NRGBA64, m *Matrix, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := m.Invert()
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	return warpImage(img, inv, b.Dx(), b.Dy(), f), nil
+}
+
+// @Name: warp-perspective
+// @Desc: Applies a perspective transformation that moves the corners of srcQuad to the corners of dstQuad, e.g. to correct keystone distortion by mapping the corners of a tilted facade to a rectangle. The result has the size of the image, areas without source pixels are transparent.
+// @Param:      img      - -   -           The image to warp
+// @Param:      srcQuad  - -   -           The corners in the image
+// @Param:      dstQuad  - -   -           Where the corners end up, in the same order
+// @Param:      filter   - -   "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result   - -   -           The warped image
+func warpPerspective(img *image.NRGBA64, srcQuad Quad, dstQuad Quad, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := perspectiveInverse(srcQuad, dstQuad)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	return warpImage(img, inv, b.Dx(), b.Dy(), f), nil
+}
+
+// perspectiveInverse returns the homography that maps the corners of dstQuad
+// back to the corners of srcQuad.
+func perspectiveInverse(srcQuad, dstQuad Quad) (*Matrix, error) {
+	corners := func(q Quad) [4]Point {
+		return [4]Point{*q.P1, *q.P2, *q.P3, *q.P4}
+	}
+	// Map the result back to the image, so solve for dst -> src directly
+	return Homography(corners(dstQuad), corners(srcQuad))
+}
+
+// @Name: skew
+// @Desc: Skews (shears) an image. The result is enlarged to fit the skewed image.
+// @Param:      img     - -   -           The image to skew
+// @Param:      ax      "°" -89..89   0   The horizontal skew angle in degrees, positive values shift the bottom to the right
+// @Param:      ay      "°" -89..89   0   The vertical skew angle in degrees, positive values shift the right side down
+// @Param:      filter  - -   "bicubic"   The resampling filter: nearest, bilinear, bicubic, mitchell, lanczos3 or area
+// @Returns:    result  - -   -           The skewed image
+func skew(img *image.NRGBA64, ax float64, ay float64, filter string) (*image.NRGBA64, error) {
+	f, err := getResampleFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	inv, w, h, err := skewInverse(img.Bounds(), ax, ay)
+	if err != nil {
+		return nil, err
+	}
+	return warpImage(img, inv, w, h, f), nil
+}
+
+// skewInverse returns the matrix that maps the skewed image of the given
+// bounds back to the image, and the size of the skewed image.
+func skewInverse(b image.Rectangle, ax, ay float64) (inv *Matrix, w, h int, err error) {
+	m, _ := matrixSkew(ax, ay)
+	bw, bh := float64(b.Dx()), float64(b.Dy())
+
+	// Fit the result to the skewed corners
+	minX, minY, maxX, maxY := 0.0, 0.0, 0.0, 0.0
+	for _, p := range [][2]float64{{bw, 0}, {bw, bh}, {0, bh}} {
+		x, y := m.Apply(p[0], p[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	m = NewAffineMatrix(1, 0, -minX, 0, 1, -minY).Mul(m)
+	inv, err = m.Invert()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return inv, int(math.Ceil(maxX - minX - 1e-9)), int(math.Ceil(maxY - minY - 1e-9)), nil
+}
@@ -0,0 +1,31 @@
+package language
+
+import (
+	"fmt"
+	"image"
+)
+
+// Alignment is an image aligned to a reference image together with the
+// transformation that was applied, which maps the coordinates of the
+// original image to those of the reference. Alignments can be used as
+// images, and as matrices to apply the same transformation to other images.
+type Alignment struct {
+	Image     *image.NRGBA64
+	Transform *Matrix
+}
+
+func (a *Alignment) String() string {
+	return fmt.Sprintf("Alignment(%dx%d %s)", a.Image.Rect.Dx(), a.Image.Rect.Dy(), a.Transform.String())
+}
+
+// castAlignment converts an alignment to the given type, matrices are the
+// transformation, anything else is created from the aligned image.
+func (dsl *dslCollection) castAlignment(a *Alignment, targetType string) (any, error) {
+	switch targetType {
+	case "*Alignment", "*language.Alignment":
+		return a, nil
+	case "*Matrix", "*language.Matrix":
+		return a.Transform, nil
+	}
+	return dsl.castValue(a.Image, targetType)
+}
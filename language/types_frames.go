@@ -0,0 +1,81 @@
+package language
+
+import (
+	"fmt"
+	"image"
+)
+
+const (
+	DisposalNone       = 0 // Leave the frame in place
+	DisposalBackground = 1 // Clear the frame's area to transparent before the next frame
+	DisposalPrevious   = 2 // Restore the canvas to the state before the frame
+)
+
+// Frames is a sequence of images forming an animation. Each image is a fully
+// composited canvas, so frames can be processed independently of each other.
+// Delays are given in milliseconds, Disposal holds the disposal method of
+// each frame as found in the source (informational, encoders compute their own).
+type Frames struct {
+	Images    []*image.NRGBA64
+	Delays    []int
+	Disposal  []int
+	LoopCount int // 0 loops forever, -1 plays once, n > 0 repeats n times
+}
+
+func NewFrames() *Frames {
+	return &Frames{}
+}
+
+// Add appends img with the given delay (in milliseconds).
+func (f *Frames) Add(img *image.NRGBA64, delay int) *Frames {
+	f.Images = append(f.Images, img)
+	f.Delays = append(f.Delays, delay)
+	f.Disposal = append(f.Disposal, DisposalNone)
+	return f
+}
+
+func (f *Frames) Len() int {
+	return len(f.Images)
+}
+
+// Delay returns the delay of frame i in milliseconds.
+func (f *Frames) Delay(i int) int {
+	if i < 0 || i >= len(f.Delays) {
+		return 0
+	}
+	return f.Delays[i]
+}
+
+// Bounds returns the canvas that covers all frames.
+func (f *Frames) Bounds() image.Rectangle {
+	w, h := 0, 0
+	for _, img := range f.Images {
+		b := img.Bounds()
+		if b.Dx() > w {
+			w = b.Dx()
+		}
+		if b.Dy() > h {
+			h = b.Dy()
+		}
+	}
+	return image.Rect(0, 0, w, h)
+}
+
+// Clone returns a copy of f that shares the images but not the slices.
+func (f *Frames) Clone() *Frames {
+	return &Frames{
+		Images:    append([]*image.NRGBA64{}, f.Images...),
+		Delays:    append([]int{}, f.Delays...),
+		Disposal:  append([]int{}, f.Disposal...),
+		LoopCount: f.LoopCount,
+	}
+}
+
+func (f *Frames) String() string {
+	b := f.Bounds()
+	total := 0
+	for _, d := range f.Delays {
+		total += d
+	}
+	return fmt.Sprintf("Frames(%d %dx%d %dms)", f.Len(), b.Dx(), b.Dy(), total)
+}
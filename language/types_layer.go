@@ -0,0 +1,46 @@
+package language
+
+import (
+	"fmt"
+	"image"
+)
+
+// Layer is an image in a layer stack. The image is placed at (X, Y) of the
+// canvas and composited onto the layers below using BlendMode (one of the
+// registered blend modes) and Opacity (0..1). If Mask is set, it scales the
+// opacity of each pixel. The mask is placed at the same position as the
+// image, outside of it the layer is hidden.
+type Layer struct {
+	Name      string
+	Image     *image.NRGBA64
+	BlendMode string
+	Opacity   float64
+	X, Y      int
+	Mask      *Mask
+	Visible   bool
+}
+
+func NewLayer(img *image.NRGBA64) *Layer {
+	return &Layer{
+		Image:     img,
+		BlendMode: NORMAL,
+		Opacity:   1,
+		Visible:   true,
+	}
+}
+
+// Bounds returns the area the layer covers on the canvas.
+func (l *Layer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, l.Image.Rect.Dx(), l.Image.Rect.Dy()).Add(image.Pt(l.X, l.Y))
+}
+
+// Clone returns a shallow copy of l, images are shared.
+func (l *Layer) Clone() *Layer {
+	c := *l
+	return &c
+}
+
+func (l *Layer) String() string {
+	b := l.Bounds()
+	return fmt.Sprintf("Layer(%q %dx%d+%d+%d %s %.2f)", l.Name, b.Dx(), b.Dy(), l.X, l.Y, l.BlendMode, l.Opacity)
+}
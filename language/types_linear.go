@@ -0,0 +1,69 @@
+package language
+
+import (
+	"image"
+	"image/color"
+)
+
+// LinearImage is an image with float32 channels in linear-light RGB and
+// straight (non-premultiplied) alpha. Color values are not limited to 0..1,
+// so intermediate results can exceed the displayable range without clipping.
+type LinearImage struct {
+	Pix    []float32 // R, G, B, A for each pixel, row by row
+	Stride int       // Distance between two vertically adjacent pixels in Pix
+	Rect   image.Rectangle
+}
+
+func NewLinearImage(r image.Rectangle) *LinearImage {
+	return &LinearImage{
+		Pix:    make([]float32, 4*r.Dx()*r.Dy()),
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+func (p *LinearImage) ColorModel() color.Model { return color.NRGBA64Model }
+
+func (p *LinearImage) Bounds() image.Rectangle { return p.Rect }
+
+// At returns the sRGB-encoded color at (x, y), clipped to the displayable range.
+func (p *LinearImage) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return color.NRGBA64{}
+	}
+	r, g, b, a := p.LinearAt(x, y)
+	return color.NRGBA64{
+		R: linearToSRGB16(r),
+		G: linearToSRGB16(g),
+		B: linearToSRGB16(b),
+		A: unitToU16(a),
+	}
+}
+
+func (p *LinearImage) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+// LinearAt returns the linear-light channels at (x, y).
+func (p *LinearImage) LinearAt(x, y int) (r, g, b, a float32) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	return s[0], s[1], s[2], s[3]
+}
+
+// SetLinear sets the linear-light channels at (x, y).
+func (p *LinearImage) SetLinear(x, y int, r, g, b, a float32) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4]
+	s[0], s[1], s[2], s[3] = r, g, b, a
+}
+
+func (p *LinearImage) String() string {
+	return "LinearImage" + p.Rect.String()
+}
@@ -0,0 +1,113 @@
+package language
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// Mask is a single-channel selection, each value ranges from 0 (not
+// selected) to 1 (fully selected). Masks start at 0,0, when applied to an
+// image they are aligned with its upper-left corner.
+// Source is the image the mask was selected from (if any), converting the
+// mask to an image applies it to Source, so the selection functions can be
+// used like they were before masks existed.
+type Mask struct {
+	Rect   image.Rectangle
+	Values []float32
+	Source *image.NRGBA64
+}
+
+func NewMask(w, h int) *Mask {
+	return &Mask{
+		Rect:   image.Rect(0, 0, w, h),
+		Values: make([]float32, w*h),
+	}
+}
+
+// maskFromImage uses the luminance multiplied by the alpha of img as mask.
+func maskFromImage(img image.Image) *Mask {
+	src := imageToNRGBA64(img)
+	b := src.Bounds()
+	m := NewMask(b.Dx(), b.Dy())
+	parallelRows(0, b.Dy(), func(y int) {
+		for x := range b.Dx() {
+			r, g, bl, a := dsl.getColor(src, b.Min.X+x, b.Min.Y+y)
+			m.Values[y*b.Dx()+x] = float32((0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(bl)) / 0xffff * float64(a) / 0xffff)
+		}
+	})
+	return m
+}
+
+// At returns the mask value at (x, y), 0 outside of the mask.
+func (m *Mask) At(x, y int) float32 {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return 0
+	}
+	return m.Values[y*m.Rect.Dx()+x]
+}
+
+func (m *Mask) Set(x, y int, v float32) {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return
+	}
+	m.Values[y*m.Rect.Dx()+x] = v
+}
+
+// Clone returns a copy of m that shares the source image.
+func (m *Mask) Clone() *Mask {
+	return &Mask{
+		Rect:   m.Rect,
+		Values: append([]float32{}, m.Values...),
+		Source: m.Source,
+	}
+}
+
+// Image returns the source image with its alpha scaled by the mask or, if
+// there is no source, the mask as grayscale image (white is selected).
+func (m *Mask) Image() *image.NRGBA64 {
+	if m.Source == nil {
+		res := IFromBounds(m.Rect)
+		parallelRows(m.Rect.Min.Y, m.Rect.Max.Y, func(y int) {
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				v := uint16(m.At(x, y)*0xffff + 0.5)
+				res.SetNRGBA64(x, y, color.NRGBA64{R: v, G: v, B: v, A: 0xffff})
+			}
+		})
+		return res
+	}
+	b := m.Source.Bounds()
+	res := IFromBounds(b)
+	parallelRows(b.Min.Y, b.Max.Y, func(y int) {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := m.Source.NRGBA64At(x, y)
+			c.A = uint16(float32(c.A)*m.At(x-b.Min.X, y-b.Min.Y) + 0.5)
+			res.SetNRGBA64(x, y, c)
+		}
+	})
+	return res
+}
+
+func (m *Mask) String() string {
+	sum := 0.0
+	for _, v := range m.Values {
+		sum += float64(v)
+	}
+	coverage := 0.0
+	if len(m.Values) > 0 {
+		coverage = sum / float64(len(m.Values)) * 100
+	}
+	return fmt.Sprintf("Mask(%dx%d %.1f%%)", m.Rect.Dx(), m.Rect.Dy(), coverage)
+}
+
+func isMaskType(typ string) bool {
+	return typ == "*Mask" || typ == "*language.Mask"
+}
+
+// castMask converts a mask to the given type, images are created with Image.
+func (dsl *dslCollection) castMask(m *Mask, targetType string) (any, error) {
+	if isMaskType(targetType) {
+		return m, nil
+	}
+	return dsl.castValue(m.Image(), targetType)
+}
@@ -0,0 +1,83 @@
+package language
+
+import (
+	"fmt"
+
+	"github.com/toxyl/math"
+)
+
+// Matrix is a 3x3 matrix in row-major order that transforms points given in
+// homogeneous coordinates. Affine transformations use the first two rows,
+// the last row is 0 0 1. Perspective transformations (homographies) use all nine values.
+type Matrix [9]float64
+
+func NewMatrix() *Matrix {
+	return &Matrix{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// NewAffineMatrix returns the matrix mapping (x, y) to (a*x + b*y + c, d*x + e*y + f).
+func NewAffineMatrix(a, b, c, d, e, f float64) *Matrix {
+	return &Matrix{a, b, c, d, e, f, 0, 0, 1}
+}
+
+// Mul returns m * o, i.e. the transformation that applies o first and m second.
+func (m *Matrix) Mul(o *Matrix) *Matrix {
+	var res Matrix
+	for r := range 3 {
+		for c := range 3 {
+			res[r*3+c] = m[r*3]*o[c] + m[r*3+1]*o[3+c] + m[r*3+2]*o[6+c]
+		}
+	}
+	return &res
+}
+
+// Invert returns the inverse of m or an error if m is singular.
+func (m *Matrix) Invert() (*Matrix, error) {
+	det := m[0]*(m[4]*m[8]-m[5]*m[7]) - m[1]*(m[3]*m[8]-m[5]*m[6]) + m[2]*(m[3]*m[7]-m[4]*m[6])
+	if math.Abs(det) < 1e-12 {
+		return nil, fmt.Errorf("matrix is not invertible")
+	}
+	return &Matrix{
+		(m[4]*m[8] - m[5]*m[7]) / det,
+		(m[2]*m[7] - m[1]*m[8]) / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		(m[5]*m[6] - m[3]*m[8]) / det,
+		(m[0]*m[8] - m[2]*m[6]) / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+		(m[3]*m[7] - m[4]*m[6]) / det,
+		(m[1]*m[6] - m[0]*m[7]) / det,
+		(m[0]*m[4] - m[1]*m[3]) / det,
+	}, nil
+}
+
+// Apply transforms the point (x, y).
+func (m *Matrix) Apply(x, y float64) (float64, float64) {
+	w := m[6]*x + m[7]*y + m[8]
+	if w == 0 {
+		w = 1e-12
+	}
+	return (m[0]*x + m[1]*y + m[2]) / w, (m[3]*x + m[4]*y + m[5]) / w
+}
+
+// IsAffine reports whether m has no perspective component.
+func (m *Matrix) IsAffine() bool {
+	return m[6] == 0 && m[7] == 0 && m[8] == 1
+}
+
+func (m *Matrix) String() string {
+	if m.IsAffine() {
+		return fmt.Sprintf("Matrix(%g %g %g | %g %g %g)", m[0], m[1], m[2], m[3], m[4], m[5])
+	}
+	return fmt.Sprintf("Matrix(%g %g %g | %g %g %g | %g %g %g)", m[0], m[1], m[2], m[3], m[4], m[5], m[6], m[7], m[8])
+}
+
+// Homography returns the perspective transformation that maps the corners
+// of src to the corners of dst, or an error if the corners are degenerate
+// (e.g. three of them are on a line).
+func Homography(src, dst [4]Point) (*Matrix, error) {
+	m, err := fitHomography(src[:], dst[:])
+	if err != nil {
+		return nil, fmt.Errorf("the quad corners are degenerate")
+	}
+	return m, nil
+}
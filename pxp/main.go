@@ -3,8 +3,8 @@ package pxp
 import (
 	"fmt"
 	"image"
+	"io"
 	"runtime"
-	"strings"
 	"time"
 
 	"github.com/toxyl/pxp/language"
@@ -26,18 +26,28 @@ func init() {
 // The script must use the variable `img` to store the final result.
 //
 // When `maxW` and `maxH` are greater than zero, the output image will be resized to fit within the given dimensions.
-func RenderToFile(script, baseDir, path string, maxW, maxH int, replacements map[string]string) (err error) {
-	sb := strings.Builder{}
-	sb.WriteString(script + "\n")
-	sb.WriteString(`save(`)
+//
+// The output format is derived from the extension of `path`. The optional `options` configure the encoder,
+// start from `language.DefaultSaveOptions()` to change single settings. Unless `options` sets the metadata,
+// the EXIF, XMP and ICC metadata of the result is embedded into JPEGs and PNGs.
+func RenderToFile(script, baseDir, path string, maxW, maxH int, replacements map[string]string, options ...language.SaveOptions) error {
 	if maxW > 0 && maxH > 0 {
-		sb.WriteString(fmt.Sprintf("resize-fit(img %d %d)", maxW, maxH))
+		script += fmt.Sprintf("\nresize-fit(img %d %d)", maxW, maxH)
 	} else {
-		sb.WriteString(`img`)
+		script += "\nimg"
+	}
+	img, err := New().Script(script).run(baseDir, replacements)
+	if err != nil {
+		return err
+	}
+	opts := language.DefaultSaveOptions()
+	if len(options) > 0 {
+		opts = options[0]
 	}
-	sb.WriteString(` "` + path + `")`)
-	_, err = New().Script(sb.String()).Render(baseDir, replacements)
-	return
+	if opts.Metadata == nil {
+		opts.Metadata = language.MetadataOf(img)
+	}
+	return language.SaveImage(path, img, opts)
 }
 
 func DocMarkdown() string                { return language.DocMarkdown() }
@@ -49,6 +59,7 @@ type PXP struct {
 	lang   *language.Language
 	err    error
 	script string
+	args   []any
 }
 
 func New() *PXP {
@@ -66,7 +77,79 @@ func (p *PXP) Script(script string) *PXP {
 	return p
 }
 
+// Input binds an in-memory input to a variable of the script.
+// Supported are encoded images ([]byte, io.Reader) and image.Image values,
+// other values (e.g. numbers or strings) are passed through unchanged.
+func (p *PXP) Input(name string, value any) *PXP {
+	if p.err != nil {
+		return p
+	}
+	p.lang.SetInput(name, value)
+	return p
+}
+
+// FileSystem sets the file system the script loads, saves and includes files from.
+func (p *PXP) FileSystem(fsys language.FileSystem) *PXP {
+	if p.err != nil {
+		return p
+	}
+	p.lang.SetFileSystem(fsys)
+	return p
+}
+
+// Fetcher sets the fetcher the script downloads remote files with.
+func (p *PXP) Fetcher(f *language.Fetcher) *PXP {
+	if p.err != nil {
+		return p
+	}
+	p.lang.SetFetcher(f)
+	return p
+}
+
+// Args sets the script arguments ($1, $2, etc.), they accept the same values as Input.
+// Readers are read right away, so the arguments can be used by any number of renders.
+func (p *PXP) Args(args ...any) *PXP {
+	if p.err != nil {
+		return p
+	}
+	p.args = make([]any, len(args))
+	for i, arg := range args {
+		if r, ok := arg.(io.Reader); ok {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				p.err = fmt.Errorf("failed to read argument $%d: %w", i+1, err)
+				return p
+			}
+			arg = data
+		}
+		p.args[i] = arg
+	}
+	return p
+}
+
 func (p *PXP) Render(baseDir string, replacements map[string]string) (*image.NRGBA, error) {
+	img, err := p.run(baseDir, replacements)
+	if err != nil {
+		return nil, err
+	}
+
+	if res, ok := img.(*image.NRGBA); ok {
+		return res, nil
+	}
+
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			nrgba.Set(x, y, img.At(x, y))
+		}
+	}
+
+	return nrgba, nil
+}
+
+// run executes the script and returns the resulting image.
+func (p *PXP) run(baseDir string, replacements map[string]string) (image.Image, error) {
 	if p.err != nil {
 		return nil, p.err
 	}
@@ -74,7 +157,11 @@ func (p *PXP) Render(baseDir string, replacements map[string]string) (*image.NRG
 		time.Sleep(10 * time.Second)
 	}
 	activeRenders.Inc()
-	res, err := p.lang.Run(p.script, baseDir, replacements, []any{"dummy"}...)
+	args := p.args
+	if len(args) == 0 {
+		args = []any{"dummy"}
+	}
+	res, err := p.lang.Run(p.script, baseDir, replacements, args...)
 	activeRenders.Dec()
 	if err != nil {
 		return nil, fmt.Errorf("script execution error: %w", err)
@@ -100,18 +187,5 @@ func (p *PXP) Render(baseDir string, replacements map[string]string) (*image.NRG
 	if img == nil {
 		return nil, fmt.Errorf("no image data returned")
 	}
-
-	if res, ok := img.(*image.NRGBA); ok {
-		return res, nil
-	}
-
-	bounds := img.Bounds()
-	nrgba := image.NewNRGBA(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			nrgba.Set(x, y, img.At(x, y))
-		}
-	}
-
-	return nrgba, nil
+	return img, nil
 }